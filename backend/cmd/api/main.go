@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"strconv"
 	"syscall"
 	"time"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/Aditya-Pimpalkar/clarity/internal/api"
 	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
 	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
 	"github.com/Aditya-Pimpalkar/clarity/internal/kafka"
 	"github.com/Aditya-Pimpalkar/clarity/internal/services"
@@ -75,6 +78,7 @@ func main() {
 		ReadTimeout:           time.Duration(config.ReadTimeout) * time.Second,
 		WriteTimeout:          time.Duration(config.WriteTimeout) * time.Second,
 		IdleTimeout:           time.Duration(config.IdleTimeout) * time.Second,
+		BodyLimit:             config.BodyLimitMB * 1024 * 1024,
 	})
 
 	// Setup middleware
@@ -84,7 +88,7 @@ func main() {
 
 	// Setup routes
 	log.Println("🛣️  Setting up routes...")
-	setupRoutes(app, repo, kafkaProducer, config)
+	flushUsage := setupRoutes(app, repo, kafkaProducer, config)
 	log.Println("✅ Routes configured")
 
 	// Start server in goroutine
@@ -114,6 +118,7 @@ func main() {
 	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
 		log.Fatal("❌ Server forced to shutdown:", err)
 	}
+	flushUsage(shutdownCtx)
 
 	log.Println("✅ Server gracefully stopped")
 }
@@ -129,6 +134,37 @@ type Config struct {
 	ReadTimeout   int
 	WriteTimeout  int
 	IdleTimeout   int
+	ExportDir     string
+	BodyLimitMB   int
+
+	// AnomalyIntervalMinutes is how often anomaly detection runs; 0 disables it
+	AnomalyIntervalMinutes int
+	// PricingCatalogPath overrides the embedded model price catalog
+	PricingCatalogPath string
+	// PricingRefreshMinutes is how often prices are reloaded from the database
+	PricingRefreshMinutes int
+	// BudgetSyncMinutes is how often budget spend is recounted from the database
+	BudgetSyncMinutes int
+	// UsageFlushSeconds is how often metered usage is written to the database
+	UsageFlushSeconds int
+	// DefaultPlan is the plan of organizations without one: legacy (no quotas) or a metered plan
+	DefaultPlan string
+	// ExchangeRatesPath is a CSV or JSON file of daily exchange rates loaded on startup
+	ExchangeRatesPath string
+	// ExchangeRateRefreshMinutes is how often exchange rates are reloaded from the database
+	ExchangeRateRefreshMinutes int
+	// AlertEvaluationSeconds is how often alert rules are evaluated
+	AlertEvaluationSeconds int
+	// AlertEvaluator makes this instance evaluate alert rules. Exactly one instance sharing a
+	// database should; the others only follow the states it records.
+	AlertEvaluator bool
+	// JobRunner makes this instance resume the cost backfills and erasures a previous process
+	// left unfinished, and rebuild the rollups they touched once closed. Exactly one instance
+	// sharing a database should, or each would run them.
+	JobRunner bool
+	// NotificationAllowedHosts are comma-separated hosts that notification channels may
+	// reach even though they are internal addresses, such as localhost for cmd/notifystub
+	NotificationAllowedHosts string
 }
 
 // loadConfig loads configuration from environment
@@ -143,6 +179,22 @@ func loadConfig() Config {
 		ReadTimeout:   getEnvInt("READ_TIMEOUT", 10),
 		WriteTimeout:  getEnvInt("WRITE_TIMEOUT", 10),
 		IdleTimeout:   getEnvInt("IDLE_TIMEOUT", 120),
+		ExportDir:     getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "clarity-exports")),
+		BodyLimitMB:   getEnvInt("BODY_LIMIT_MB", 100),
+
+		AnomalyIntervalMinutes: getEnvInt("ANOMALY_DETECTION_INTERVAL_MINUTES", 60),
+		PricingCatalogPath:     getEnv("PRICING_CATALOG_PATH", ""),
+		PricingRefreshMinutes:  getEnvInt("PRICING_REFRESH_MINUTES", 5),
+		BudgetSyncMinutes:      getEnvInt("BUDGET_SYNC_MINUTES", 1),
+		UsageFlushSeconds:      getEnvInt("USAGE_FLUSH_SECONDS", 30),
+		DefaultPlan:            getEnv("DEFAULT_PLAN", models.PlanLegacy),
+
+		ExchangeRatesPath:          getEnv("EXCHANGE_RATES_PATH", ""),
+		ExchangeRateRefreshMinutes: getEnvInt("EXCHANGE_RATE_REFRESH_MINUTES", 60),
+		AlertEvaluationSeconds:     getEnvInt("ALERT_EVALUATION_SECONDS", 60),
+		AlertEvaluator:             getEnvBool("ALERT_EVALUATOR", true),
+		JobRunner:                  getEnvBool("JOB_RUNNER", true),
+		NotificationAllowedHosts:   getEnv("NOTIFICATION_ALLOWED_HOSTS", ""),
 	}
 }
 
@@ -168,35 +220,172 @@ func setupMiddleware(app *fiber.App, config Config) {
 	app.Use(middleware.GlobalRateLimiter())
 }
 
-// setupRoutes configures all routes with appropriate middleware
-func setupRoutes(app *fiber.App, repo repository.Repository, kafkaProducer *kafka.Producer, config Config) {
+// routeHandlers groups the HTTP handlers shared by the route groups
+type routeHandlers struct {
+	health        *api.HealthHandler
+	auth          *api.AuthHandler
+	trace         *api.TraceHandler
+	analytics     *api.AnalyticsHandler
+	userAnalytics *api.UserAnalyticsHandler
+	export        *api.ExportHandler
+	imports       *api.ImportHandler
+	erasure       *api.ErasureHandler
+	metric        *api.MetricHandler
+	forecast      *api.ForecastHandler
+	anomaly       *api.AnomalyHandler
+	pricing       *api.PricingHandler
+	backfill      *api.BackfillHandler
+	budget        *api.BudgetHandler
+	usage         *api.UsageHandler
+	currency      *api.CurrencyHandler
+	chargeback    *api.ChargebackHandler
+	alert         *api.AlertHandler
+	notification  *api.NotificationHandler
+}
+
+// setupRoutes configures all routes with appropriate middleware and returns a function to
+// call on shutdown, which writes out the usage metered since the last flush and waits for
+// notifications still being delivered
+func setupRoutes(app *fiber.App, repo repository.Repository, kafkaProducer *kafka.Producer, config Config) func(context.Context) {
+	// Prices are stored in the database; a newer catalog file is synced on startup
+	pricingService := services.NewPricingService(repo)
+	if err := pricingService.LoadCatalog(context.Background(), config.PricingCatalogPath); err != nil {
+		log.Printf("❌ Failed to load price catalog, using built-in prices: %v", err)
+	}
+	if config.PricingRefreshMinutes > 0 {
+		pricingService.Start(context.Background(), time.Duration(config.PricingRefreshMinutes)*time.Minute)
+	}
+
+	// Alerts and crossed budget thresholds are sent to each organization's notification channels
+	notificationService := services.NewNotificationService(repo, strings.Split(config.NotificationAllowedHosts, ","))
+
+	// Spend is counted in memory as traces arrive and recounted from the database periodically
+	budgetService := services.NewBudgetService(repo, notificationService)
+	if err := budgetService.Refresh(context.Background()); err != nil {
+		log.Printf("❌ Failed to load budgets: %v", err)
+	}
+	if config.BudgetSyncMinutes > 0 {
+		budgetService.Start(context.Background(), time.Duration(config.BudgetSyncMinutes)*time.Minute)
+	}
+
+	// Usage against plan quotas is counted the same way and flushed to the database periodically
+	meteringService := services.NewMeteringService(repo, config.DefaultPlan)
+	if err := meteringService.Refresh(context.Background()); err != nil {
+		log.Printf("❌ Failed to load usage: %v", err)
+	}
+	if config.UsageFlushSeconds > 0 {
+		meteringService.Start(context.Background(), time.Duration(config.UsageFlushSeconds)*time.Second)
+	}
+
+	// Costs are stored in USD and converted into display currencies on read
+	currencyService := services.NewCurrencyService(repo)
+	if _, err := currencyService.LoadRatesFile(context.Background(), config.ExchangeRatesPath); err != nil {
+		log.Printf("❌ Failed to load exchange rates file: %v", err)
+	}
+	if err := currencyService.Refresh(context.Background()); err != nil {
+		log.Printf("❌ Failed to load exchange rates: %v", err)
+	}
+	if config.ExchangeRateRefreshMinutes > 0 {
+		currencyService.Start(context.Background(), time.Duration(config.ExchangeRateRefreshMinutes)*time.Minute)
+	}
+
 	// Create services
-	traceService := services.NewTraceService(repo, kafkaProducer)
+	traceService := services.NewTraceService(repo, kafkaProducer, pricingService, budgetService, meteringService)
 	analyticsService := services.NewAnalyticsService(repo)
+	userAnalyticsService := services.NewUserAnalyticsService(repo)
 	userService := services.NewUserService(repo)
+	metricService := services.NewMetricService(repo)
+	forecastService := services.NewForecastService(repo, budgetService)
+	anomalyService := services.NewAnomalyService(repo)
+	chargebackService := services.NewChargebackService(repo)
+
+	// Alert rules are evaluated in the background; firing states survive restarts through their events
+	alertService := services.NewAlertService(repo, notificationService)
+	if err := alertService.Refresh(context.Background()); err != nil {
+		log.Printf("❌ Failed to load alert rules: %v", err)
+	}
+	if config.AlertEvaluationSeconds > 0 {
+		interval := time.Duration(config.AlertEvaluationSeconds) * time.Second
+		if config.AlertEvaluator {
+			alertService.Start(context.Background(), interval)
+		} else {
+			alertService.Follow(context.Background(), interval)
+		}
+	}
+
+	// Anomaly detection runs in the background for the lifetime of the process
+	if config.AnomalyIntervalMinutes > 0 {
+		anomalyService.Start(context.Background(), time.Duration(config.AnomalyIntervalMinutes)*time.Minute)
+	}
+
+	// Background jobs are kept for a day after they finish
+	jobs := services.NewJobManager(24 * time.Hour)
+	exportService := services.NewExportService(repo, jobs, config.ExportDir, currencyService)
+	importService := services.NewImportService(repo, jobs, traceService)
+	erasureService := services.NewErasureService(repo, jobs)
+	backfillService := services.NewCostBackfillService(repo, jobs, pricingService)
+
+	if config.JobRunner {
+		// Backfills checkpoint as they go; pick up any a previous process left unfinished
+		if err := backfillService.ResumeUnfinished(context.Background()); err != nil {
+			log.Printf("❌ Failed to resume cost backfills: %v", err)
+		}
+
+		// Erasures must run to completion, so restart any a previous process left unfinished
+		if err := erasureService.ResumeUnfinished(context.Background()); err != nil {
+			log.Printf("❌ Failed to resume erasures: %v", err)
+		}
+
+		// Rollup buckets still open during an erasure or backfill are rebuilt once they close
+		erasureService.Start(context.Background(), 10*time.Minute)
+	}
 
 	// Create handlers
-	healthHandler := api.NewHealthHandler(repo)
-	traceHandler := api.NewTraceHandler(traceService)
-	analyticsHandler := api.NewAnalyticsHandler(analyticsService)
-	authHandler := api.NewAuthHandler(userService)
+	handlers := &routeHandlers{
+		health:        api.NewHealthHandler(repo),
+		auth:          api.NewAuthHandler(userService),
+		trace:         api.NewTraceHandler(traceService),
+		analytics:     api.NewAnalyticsHandler(analyticsService, currencyService),
+		userAnalytics: api.NewUserAnalyticsHandler(userAnalyticsService, currencyService),
+		export:        api.NewExportHandler(exportService),
+		imports:       api.NewImportHandler(importService),
+		erasure:       api.NewErasureHandler(erasureService),
+		metric:        api.NewMetricHandler(metricService),
+		forecast:      api.NewForecastHandler(forecastService, currencyService),
+		anomaly:       api.NewAnomalyHandler(anomalyService),
+		pricing:       api.NewPricingHandler(pricingService),
+		backfill:      api.NewBackfillHandler(backfillService),
+		budget:        api.NewBudgetHandler(budgetService),
+		usage:         api.NewUsageHandler(meteringService),
+		currency:      api.NewCurrencyHandler(currencyService),
+		chargeback:    api.NewChargebackHandler(chargebackService, currencyService),
+		alert:         api.NewAlertHandler(alertService),
+		notification:  api.NewNotificationHandler(notificationService),
+	}
 
 	// Public routes (no authentication)
-	setupPublicRoutes(app, healthHandler, authHandler)
+	setupPublicRoutes(app, handlers)
 
-	// API key routes (SDK ingestion + analytics)
-	setupAPIKeyRoutes(app, traceHandler, analyticsHandler)
+	// API key routes (SDK ingestion + analytics), rate limited by plan
+	setupAPIKeyRoutes(app, handlers, middleware.PlanRateLimiter(meteringService.RequestsPerMinute))
 
 	// JWT routes (dashboard)
-	setupAuthenticatedRoutes(app, traceHandler, analyticsHandler, userService)
+	setupAuthenticatedRoutes(app, handlers)
+
+	return func(ctx context.Context) {
+		if err := meteringService.Flush(ctx); err != nil {
+			log.Printf("❌ Failed to flush usage: %v", err)
+		}
+		notificationService.Wait()
+	}
 }
 
 // setupPublicRoutes configures public endpoints
-func setupPublicRoutes(app *fiber.App, healthHandler *api.HealthHandler, authHandler *api.AuthHandler) {
+func setupPublicRoutes(app *fiber.App, handlers *routeHandlers) {
 	// Health checks
-	app.Get("/health", healthHandler.GetHealth)
-	app.Get("/ready", healthHandler.GetReadiness)
-	app.Get("/live", healthHandler.GetLiveness)
+	app.Get("/health", handlers.health.GetHealth)
+	app.Get("/ready", handlers.health.GetReadiness)
+	app.Get("/live", handlers.health.GetLiveness)
 
 	// API info
 	app.Get("/api/v1", func(c *fiber.Ctx) error {
@@ -213,6 +402,7 @@ func setupPublicRoutes(app *fiber.App, healthHandler *api.HealthHandler, authHan
 				"health":    "/health",
 				"traces":    "/api/v1/traces",
 				"analytics": "/api/v1/analytics",
+				"metrics":   "/api/v1/metrics",
 				"auth":      "/api/v1/auth",
 			},
 		})
@@ -220,62 +410,198 @@ func setupPublicRoutes(app *fiber.App, healthHandler *api.HealthHandler, authHan
 
 	// Authentication endpoints
 	auth := app.Group("/api/v1/auth")
-	auth.Post("/login", authHandler.Login)
+	auth.Post("/login", handlers.auth.Login)
+}
+
+// setupAnalyticsRoutes registers the analytics endpoints on a route group
+func setupAnalyticsRoutes(analytics fiber.Router, handlers *routeHandlers) {
+	analytics.Get("/dashboard", handlers.analytics.GetDashboard)
+	analytics.Get("/costs", handlers.analytics.GetCostAnalysis)
+	analytics.Get("/performance", handlers.analytics.GetPerformanceMetrics)
+	analytics.Get("/models", handlers.analytics.GetModelComparison)
+	analytics.Get("/compare", handlers.analytics.CompareCohorts)
+	analytics.Get("/timeseries", handlers.analytics.GetTimeSeries)
+	analytics.Get("/forecast", handlers.forecast.GetCostForecast)
+	analytics.Get("/anomalies", handlers.anomaly.ListAnomalies)
+
+	// End-user analytics
+	analytics.Get("/users", handlers.userAnalytics.ListTopUsers)
+	analytics.Get("/users/:user_id", handlers.userAnalytics.GetUser)
+	analytics.Get("/users/:user_id/traces", handlers.userAnalytics.ListUserTraces)
 }
 
 // setupAPIKeyRoutes configures API key protected routes
-func setupAPIKeyRoutes(app *fiber.App, traceHandler *api.TraceHandler, analyticsHandler *api.AnalyticsHandler) {
+func setupAPIKeyRoutes(app *fiber.App, handlers *routeHandlers, rateLimiter fiber.Handler) {
 	apiKey := app.Group("/api/v1",
 		middleware.APIKeyAuth(),
-		middleware.APIKeyRateLimiter(),
+		rateLimiter,
 	)
 
 	// Trace ingestion (SDK usage)
-	apiKey.Post("/traces", traceHandler.CreateTrace)
-	apiKey.Post("/traces/batch", traceHandler.CreateTraceBatch)
+	apiKey.Post("/traces", handlers.trace.CreateTrace)
+	apiKey.Post("/traces/batch", handlers.trace.CreateTraceBatch)
+
+	// Custom application metrics
+	apiKey.Post("/metrics", handlers.metric.CreateMetric)
+	apiKey.Post("/metrics/batch", handlers.metric.CreateMetricBatch)
+	apiKey.Get("/metrics/query", handlers.metric.QueryMetrics)
 
 	// Analytics (also accessible via API key for programmatic access)
-	analytics := apiKey.Group("/analytics")
-	analytics.Get("/dashboard", analyticsHandler.GetDashboard)
-	analytics.Get("/costs", analyticsHandler.GetCostAnalysis)
-	analytics.Get("/performance", analyticsHandler.GetPerformanceMetrics)
-	analytics.Get("/models", analyticsHandler.GetModelComparison)
+	setupAnalyticsRoutes(apiKey.Group("/analytics"), handlers)
 
 	// ADD THESE LINES - Trace reading (for frontend)
-	apiKey.Get("/traces", traceHandler.ListTraces)
-	apiKey.Get("/traces/:id", traceHandler.GetTrace)
+	apiKey.Get("/traces", handlers.trace.ListTraces)
+	apiKey.Get("/traces/diff", handlers.trace.DiffTraces)
+	apiKey.Get("/traces/export", handlers.export.ExportTraces)
+	apiKey.Get("/traces/:id", handlers.trace.GetTrace)
+	apiKey.Delete("/traces/:id", handlers.erasure.DeleteTrace)
+
+	// Export and import jobs
+	setupExportRoutes(apiKey.Group("/exports"), handlers)
+	setupImportRoutes(apiKey.Group("/imports"), handlers)
+
+	// Right-to-erasure requests
+	setupErasureRoutes(apiKey.Group("/erasures"), handlers)
+
+	// Budget status, and the pre-call check for gateways
+	apiKey.Get("/budgets", handlers.budget.ListBudgets)
+	apiKey.Get("/budgets/:id", handlers.budget.GetBudget)
+	apiKey.Post("/budgets/check", handlers.budget.CheckBudget)
+
+	// Usage against the plan's quotas, and the daily export for billing systems
+	apiKey.Get("/usage", handlers.usage.GetUsage)
+	apiKey.Get("/usage/export", handlers.usage.ExportUsage)
+
+	// Display currency and the exchange rates costs are converted at
+	apiKey.Get("/currency", handlers.currency.GetCurrency)
+	apiKey.Get("/currency/rates", handlers.currency.ListRates)
+
+	// Spend attributed to teams, and their monthly statements for finance systems
+	apiKey.Get("/chargeback", handlers.chargeback.GetReport)
+	apiKey.Get("/chargeback/statements", handlers.chargeback.GetStatements)
+
+	// Alert rules, for managing them as code, and their history
+	setupAlertRoutes(apiKey.Group("/alerts"), handlers)
+
+	// The notification delivery log; channels are only readable from the dashboard
+	apiKey.Get("/notifications/deliveries", handlers.notification.ListDeliveries)
 }
 
-// setupAuthenticatedRoutes configures JWT protected routes
-func setupAuthenticatedRoutes(app *fiber.App, traceHandler *api.TraceHandler,
-	analyticsHandler *api.AnalyticsHandler, userService *services.UserService) {
+// setupExportRoutes registers the background export job endpoints on a route group
+func setupExportRoutes(exports fiber.Router, handlers *routeHandlers) {
+	exports.Post("/", handlers.export.CreateExportJob)
+	exports.Get("/", handlers.export.ListExportJobs)
+	exports.Get("/:id", handlers.export.GetExportJob)
+	exports.Get("/:id/download", handlers.export.DownloadExport)
+}
 
+// setupAlertRoutes registers the alert rule and history endpoints on a route group
+func setupAlertRoutes(alerts fiber.Router, handlers *routeHandlers) {
+	alerts.Get("/rules", handlers.alert.ListRules)
+	alerts.Post("/rules", handlers.alert.CreateRule)
+	alerts.Get("/rules/:id", handlers.alert.GetRule)
+	alerts.Put("/rules/:id", handlers.alert.UpdateRule)
+	alerts.Delete("/rules/:id", handlers.alert.DeleteRule)
+	alerts.Get("/history", handlers.alert.ListEvents)
+}
+
+// setupImportRoutes registers the bulk import job endpoints on a route group
+func setupImportRoutes(imports fiber.Router, handlers *routeHandlers) {
+	imports.Post("/", handlers.imports.CreateImportJob)
+	imports.Get("/", handlers.imports.ListImportJobs)
+	imports.Get("/:id", handlers.imports.GetImportJob)
+}
+
+// setupErasureRoutes registers the end-user erasure endpoints on a route group
+func setupErasureRoutes(erasures fiber.Router, handlers *routeHandlers) {
+	erasures.Post("/", handlers.erasure.CreateUserErasure)
+	erasures.Get("/", handlers.erasure.ListErasures)
+	erasures.Get("/:id", handlers.erasure.GetErasure)
+}
+
+// setupAuthenticatedRoutes configures JWT protected routes
+func setupAuthenticatedRoutes(app *fiber.App, handlers *routeHandlers) {
 	auth := app.Group("/api/v1",
 		middleware.AuthMiddleware(),
 		middleware.StrictRateLimiter(),
 	)
 
 	// Traces (read operations)
-	auth.Get("/traces", traceHandler.ListTraces)
-	auth.Get("/traces/:id", traceHandler.GetTrace)
+	auth.Get("/traces", handlers.trace.ListTraces)
+	auth.Get("/traces/diff", handlers.trace.DiffTraces)
+	auth.Get("/traces/export", handlers.export.ExportTraces)
+	auth.Get("/traces/:id", handlers.trace.GetTrace)
+
+	// Export and import jobs
+	setupExportRoutes(auth.Group("/exports"), handlers)
+	setupImportRoutes(auth.Group("/imports"), handlers)
 
 	// Analytics
-	analytics := auth.Group("/analytics")
-	analytics.Get("/dashboard", analyticsHandler.GetDashboard)
-	analytics.Get("/costs", analyticsHandler.GetCostAnalysis)
-	analytics.Get("/performance", analyticsHandler.GetPerformanceMetrics)
-	analytics.Get("/models", analyticsHandler.GetModelComparison)
+	setupAnalyticsRoutes(auth.Group("/analytics"), handlers)
+	auth.Get("/metrics/query", handlers.metric.QueryMetrics)
 
 	// User endpoints
-	authHandler := api.NewAuthHandler(userService)
-	auth.Get("/auth/me", authHandler.GetCurrentUser)
-	auth.Post("/auth/api-keys", authHandler.GenerateAPIKey)
+	auth.Get("/auth/me", handlers.auth.GetCurrentUser)
+	auth.Post("/auth/api-keys", handlers.auth.GenerateAPIKey)
+
+	// Negotiated rates and cost backfills change the organization's reported spend, so they need the admin role
+	overrides := auth.Group("/pricing/overrides", middleware.RequireRole("admin"))
+	overrides.Get("/", handlers.pricing.ListOverrides)
+	overrides.Post("/", handlers.pricing.UpsertOverride)
+
+	backfills := auth.Group("/pricing/backfills", middleware.RequireRole("admin"))
+	backfills.Post("/", handlers.backfill.CreateBackfill)
+	backfills.Get("/", handlers.backfill.ListBackfills)
+	backfills.Get("/:id", handlers.backfill.GetBackfill)
+	backfills.Post("/:id/resume", handlers.backfill.ResumeBackfill)
+	backfills.Post("/:id/cancel", handlers.backfill.CancelBackfill)
+
+	// Budgets; changing them needs the admin role
+	auth.Get("/budgets", handlers.budget.ListBudgets)
+	auth.Get("/budgets/:id", handlers.budget.GetBudget)
+	auth.Post("/budgets", middleware.RequireRole("admin"), handlers.budget.UpsertBudget)
+	auth.Delete("/budgets/:id", middleware.RequireRole("admin"), handlers.budget.DeleteBudget)
+
+	// Usage against the plan's quotas; the billing export needs the admin role
+	auth.Get("/usage", handlers.usage.GetUsage)
+	auth.Get("/usage/export", middleware.RequireRole("admin"), handlers.usage.ExportUsage)
+
+	// Display currency; changing it needs the admin role
+	auth.Get("/currency", handlers.currency.GetCurrency)
+	auth.Put("/currency", middleware.RequireRole("admin"), handlers.currency.SetCurrency)
+	auth.Get("/currency/rates", handlers.currency.ListRates)
+
+	// Chargeback to teams; changing how spend is attributed needs the admin role
+	auth.Get("/chargeback", handlers.chargeback.GetReport)
+	auth.Get("/chargeback/statements", handlers.chargeback.GetStatements)
+	auth.Get("/chargeback/config", handlers.chargeback.GetConfig)
+	auth.Put("/chargeback/config", middleware.RequireRole("admin"), handlers.chargeback.UpdateConfig)
+
+	// Alert rules and their history
+	setupAlertRoutes(auth.Group("/alerts"), handlers)
+
+	// Notification channels; changing or test-sending them needs the admin role
+	auth.Get("/notifications/channels", handlers.notification.ListChannels)
+	auth.Get("/notifications/channels/:id", handlers.notification.GetChannel)
+	auth.Post("/notifications/channels", middleware.RequireRole("admin"), handlers.notification.CreateChannel)
+	auth.Put("/notifications/channels/:id", middleware.RequireRole("admin"), handlers.notification.UpdateChannel)
+	auth.Delete("/notifications/channels/:id", middleware.RequireRole("admin"), handlers.notification.DeleteChannel)
+	auth.Post("/notifications/channels/:id/test", middleware.RequireRole("admin"), handlers.notification.TestChannel)
+	auth.Get("/notifications/deliveries", handlers.notification.ListDeliveries)
+
+	// Erasure is destructive, so dashboard users need the admin role
+	auth.Delete("/traces/:id", middleware.RequireRole("admin"), handlers.erasure.DeleteTrace)
+	setupErasureRoutes(auth.Group("/erasures", middleware.RequireRole("admin")), handlers)
 
 	// Admin routes
 	admin := auth.Group("/admin", middleware.RequireRole("admin"))
 	admin.Get("/stats", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"message": "Admin statistics"})
 	})
+	admin.Get("/pricing", handlers.pricing.ListPrices)
+	admin.Post("/pricing", handlers.pricing.UpsertPrice)
+	admin.Post("/exchange-rates", handlers.currency.UpsertRates)
+	admin.Put("/organizations/:id/plan", handlers.usage.SetPlan)
 }
 
 // buildClickHouseDSN builds the ClickHouse connection string
@@ -352,5 +678,15 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvBool gets boolean environment variable with default
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
 // CI test
 // CI test
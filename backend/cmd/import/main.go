@@ -0,0 +1,137 @@
+// Command import bulk-loads historical traces from JSONL files into ClickHouse.
+//
+// Usage:
+//
+//	import -org <organization_id> [-project <project_id>] [-format auto|clarity|otlp|langfuse|langsmith] file.jsonl...
+//
+// Use "-" as the file name to read from standard input. Traces whose ID the organization
+// already stores are skipped, so a file can be imported again.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+func main() {
+	orgID := flag.String("org", "", "organization ID the traces are imported into (required)")
+	projectID := flag.String("project", "", "project ID for traces that do not carry one")
+	format := flag.String("format", services.ImportFormatAuto, "input format: auto, clarity, otlp, langfuse or langsmith")
+	flag.Parse()
+
+	if *orgID == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using environment variables")
+	}
+
+	addr := getEnv("CLICKHOUSE_HOST", "localhost") + ":" + getEnv("CLICKHOUSE_PORT", "9000")
+	repo, err := repository.NewClickHouseRepository(addr)
+	if err != nil {
+		log.Fatal("❌ Failed to connect to ClickHouse:", err)
+	}
+	defer repo.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pricingService := services.NewPricingService(repo)
+	if err := pricingService.Refresh(ctx); err != nil {
+		log.Printf("❌ Failed to load prices, using built-in prices: %v", err)
+	}
+
+	importService := services.NewImportService(repo, services.NewJobManager(0), services.NewTraceService(repo, nil, pricingService, nil, nil))
+	opts := services.ImportOptions{
+		OrganizationID: *orgID,
+		ProjectID:      *projectID,
+		Format:         *format,
+	}
+
+	failed := false
+	for _, name := range flag.Args() {
+		if !importFile(ctx, importService, name, opts) {
+			failed = true
+		}
+		if ctx.Err() != nil {
+			log.Println("🛑 Import interrupted")
+			os.Exit(1)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// importFile imports a single file and reports whether every line succeeded
+func importFile(ctx context.Context, importService *services.ImportService, name string, opts services.ImportOptions) bool {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			log.Printf("❌ %s: %v", name, err)
+			return false
+		}
+		defer file.Close()
+		r = file
+	}
+
+	log.Printf("📥 Importing %s...", name)
+	result, err := importService.Import(ctx, r, opts, &progressLogger{name: name})
+	if result != nil {
+		for _, lineErr := range result.Errors {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", name, lineErr.Line, lineErr.Error)
+		}
+		log.Printf("✅ %s: %d lines, %d traces imported, %d skipped (%d existing, %d past the 90 day retention), %d lines failed",
+			name, result.Lines, result.Imported, result.Skipped,
+			result.SkippedBy[services.ImportSkipExisting], result.SkippedBy[services.ImportSkipExpired], result.FailedLines)
+	}
+	if err != nil {
+		log.Printf("❌ %s: %v", name, err)
+		return false
+	}
+	return result.FailedLines == 0
+}
+
+// progressLogger logs import progress every progressInterval lines
+type progressLogger struct {
+	name   string
+	lines  int64
+	failed int64
+}
+
+const progressInterval = 10000
+
+func (p *progressLogger) SetTotal(total int64) {}
+
+func (p *progressLogger) Add(processed, failed int64) {
+	p.failed += failed
+	p.lines += processed
+	if p.lines%progressInterval == 0 {
+		log.Printf("⏳ %s: %d lines processed (%d failed)", p.name, p.lines, p.failed)
+	}
+}
+
+func (p *progressLogger) SetResult(key string, value interface{}) {}
+
+// getEnv gets environment variable with default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
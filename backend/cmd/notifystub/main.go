@@ -0,0 +1,55 @@
+// Command notifystub receives notifications locally, to try out notification channels
+// without real Slack, PagerDuty or mail accounts. Point webhook, Slack and events channels
+// at http://localhost:8025/<anything> and email channels at localhost port 2525; every
+// message received is printed to standard output. The API refuses to notify internal
+// addresses, so run it with NOTIFICATION_ALLOWED_HOSTS=localhost.
+//
+// Usage:
+//
+//	notifystub [-http :8025] [-smtp :2525] [-secret <webhook secret>]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/notifystub"
+)
+
+func main() {
+	httpAddr := flag.String("http", ":8025", "address of the HTTP receiver")
+	smtpAddr := flag.String("smtp", ":2525", "address of the SMTP receiver")
+	secret := flag.String("secret", "", "webhook secret to verify X-Clarity-Signature with")
+	flag.Parse()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	var mu sync.Mutex
+
+	stub := notifystub.NewServer(*secret)
+	stub.OnMessage = func(msg notifystub.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := encoder.Encode(msg); err != nil {
+			log.Printf("❌ Failed to print message: %v", err)
+		}
+	}
+
+	smtpListener, err := net.Listen("tcp", *smtpAddr)
+	if err != nil {
+		log.Fatal("❌ Failed to listen for SMTP:", err)
+	}
+	go func() {
+		if err := stub.ServeSMTP(smtpListener); err != nil {
+			log.Fatal("❌ SMTP receiver stopped:", err)
+		}
+	}()
+
+	log.Printf("📬 Receiving webhooks on %s and email on %s", *httpAddr, *smtpAddr)
+	log.Fatal(http.ListenAndServe(*httpAddr, stub))
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// AlertHandler handles alert rule and alert history requests
+type AlertHandler struct {
+	alertService *services.AlertService
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertService *services.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// ListRules handles GET /api/v1/alerts/rules?project_id=
+func (h *AlertHandler) ListRules(c *fiber.Ctx) error {
+	rules, err := h.alertService.ListRules(c.Context(), resolveOrgID(c), c.Query("project_id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list alert rules")
+	}
+
+	return SuccessResponse(c, rules)
+}
+
+// GetRule handles GET /api/v1/alerts/rules/:id
+func (h *AlertHandler) GetRule(c *fiber.Ctx) error {
+	rule, err := h.alertService.GetRule(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Alert rule")
+	}
+
+	return SuccessResponse(c, rule)
+}
+
+// CreateRule handles POST /api/v1/alerts/rules
+func (h *AlertHandler) CreateRule(c *fiber.Ctx) error {
+	var req models.AlertRuleRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+	req.ID = ""
+
+	rule, err := h.alertService.UpsertRule(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to create alert rule")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// UpdateRule handles PUT /api/v1/alerts/rules/:id
+func (h *AlertHandler) UpdateRule(c *fiber.Ctx) error {
+	var req models.AlertRuleRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+	req.ID = c.Params("id")
+
+	rule, err := h.alertService.UpsertRule(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Alert rule")
+	}
+
+	return SuccessResponse(c, rule)
+}
+
+// DeleteRule handles DELETE /api/v1/alerts/rules/:id
+func (h *AlertHandler) DeleteRule(c *fiber.Ctx) error {
+	if err := h.alertService.DeleteRule(c.Context(), resolveOrgID(c), c.Params("id")); err != nil {
+		return ServiceErrorResponse(c, err, "Alert rule")
+	}
+
+	return SuccessResponse(c, fiber.Map{"id": c.Params("id"), "deleted": true})
+}
+
+// ListEvents handles GET /api/v1/alerts/history?project_id=&rule_id=&state=firing,resolved,
+// the state changes of an organization's alert rules
+func (h *AlertHandler) ListEvents(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 7*24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	events, err := h.alertService.ListEvents(c.Context(), &models.AlertEventQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		RuleID:         c.Query("rule_id"),
+		States:         splitList(c.Query("state")),
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Limit:          parseLimit(c, "limit", 100, 1000),
+	})
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list alert history")
+	}
+
+	return SuccessResponse(c, events)
+}
@@ -1,24 +1,36 @@
 package api
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
 	"github.com/Aditya-Pimpalkar/clarity/internal/services"
 )
 
 type AnalyticsHandler struct {
 	analyticsService *services.AnalyticsService
+	currencyService  *services.CurrencyService
 }
 
-func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService, currencyService *services.CurrencyService) *AnalyticsHandler {
 	return &AnalyticsHandler{
 		analyticsService: analyticsService,
+		currencyService:  currencyService,
 	}
 }
 
-// GetDashboard handles GET /api/v1/analytics/dashboard
+// GetDashboard handles GET /api/v1/analytics/dashboard.
+// time_range takes trailing windows (24h, 7d) and calendar periods (today, last_week, month_to_date);
+// start_time/end_time take absolute times or offsets such as now-6h; tz sets the IANA time zone;
+// currency overrides the organization's display currency.
 func (h *AnalyticsHandler) GetDashboard(c *fiber.Ctx) error {
-	timeRange := c.Query("time_range", "24h")
+	timeRange := timeRangeSpec(c)
+	if timeRange.Range == "" && timeRange.Start == "" && timeRange.End == "" {
+		timeRange.Range = "24h"
+	}
 
 	// Get org ID from context (set by API key or JWT middleware)
 	orgID := middleware.GetOrgID(c)
@@ -28,31 +40,253 @@ func (h *AnalyticsHandler) GetDashboard(c *fiber.Ctx) error {
 		orgID = c.Query("organization_id", "org-test-123")
 	}
 
-	stats, err := h.analyticsService.GetDashboard(c.Context(), timeRange, orgID)
+	converter, err := displayConverter(c, h.currencyService, orgID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	stats, err := h.analyticsService.GetDashboard(c.Context(), orgID, timeRange)
 	if err != nil {
-		return InternalErrorResponse(c, "Failed to get dashboard stats")
+		return ServiceErrorResponse(c, err, "Failed to get dashboard stats")
+	}
+	if converter != nil {
+		converter.ConvertDashboard(stats)
 	}
 
 	return SuccessResponse(c, stats)
 }
 
-// GetCostAnalysis handles GET /api/v1/analytics/costs
+// GetCostAnalysis handles GET /api/v1/analytics/costs; tz sets the IANA time zone of the
+// daily costs, UTC by default
 func (h *AnalyticsHandler) GetCostAnalysis(c *fiber.Ctx) error {
-	return SuccessResponse(c, fiber.Map{
-		"message": "Cost analysis endpoint - coming soon!",
-	})
+	startTime, endTime, err := parseTimeWindow(c, 30*24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.CostQuery{
+		AnalyticsQuery: models.AnalyticsQuery{
+			OrganizationID: resolveOrgID(c),
+			ProjectID:      c.Query("project_id"),
+			StartTime:      startTime,
+			EndTime:        endTime,
+			TimeZone:       c.Query("tz"),
+		},
+		Limit: parseLimit(c, "limit", 10, 100),
+	}
+
+	// group_by_tags=feature,customer_tier
+	query.TagKeys = splitList(c.Query("group_by_tags"))
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	analysis, err := h.analyticsService.GetCostAnalysis(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get cost analysis")
+	}
+	if converter != nil {
+		converter.ConvertCostAnalysis(analysis)
+	}
+
+	return SuccessResponse(c, analysis)
 }
 
 // GetPerformanceMetrics handles GET /api/v1/analytics/performance
 func (h *AnalyticsHandler) GetPerformanceMetrics(c *fiber.Ctx) error {
-	return SuccessResponse(c, fiber.Map{
-		"message": "Performance metrics endpoint - coming soon!",
-	})
+	startTime, endTime, err := parseTimeWindow(c, 24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.LatencyQuery{
+		MetricQuery: models.MetricQuery{
+			OrganizationID: resolveOrgID(c),
+			ProjectID:      c.Query("project_id"),
+			Model:          c.Query("model"),
+			Provider:       c.Query("provider"),
+			StartTime:      startTime,
+			EndTime:        endTime,
+			Granularity:    c.Query("granularity"),
+			Limit:          parseLimit(c, "limit", 10, 50),
+		},
+		Level:   c.Query("level"),
+		GroupBy: c.Query("group_by"),
+	}
+
+	// buckets=100,500,1000 sets the histogram upper bounds in ms
+	for _, value := range splitList(c.Query("buckets")) {
+		bound, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return BadRequestResponse(c, "Invalid bucket bound: "+value)
+		}
+		query.Buckets = append(query.Buckets, bound)
+	}
+
+	metrics, err := h.analyticsService.GetPerformanceMetrics(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get performance metrics")
+	}
+
+	return SuccessResponse(c, metrics)
 }
 
 // GetModelComparison handles GET /api/v1/analytics/models
 func (h *AnalyticsHandler) GetModelComparison(c *fiber.Ctx) error {
-	return SuccessResponse(c, fiber.Map{
-		"message": "Model comparison endpoint - coming soon!",
+	startTime, endTime, err := parseTimeWindow(c, 7*24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.AnalyticsQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		StartTime:      startTime,
+		EndTime:        endTime,
+	}
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	// compare=gpt-4,claude-3-sonnet adds a pairwise significance test
+	var modelA, modelB string
+	if compare := splitList(c.Query("compare")); len(compare) > 0 {
+		if len(compare) != 2 {
+			return BadRequestResponse(c, "compare takes exactly two models")
+		}
+		modelA, modelB = compare[0], compare[1]
+	}
+
+	report, err := h.analyticsService.GetModelComparison(c.Context(), query, modelA, modelB)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to compare models")
+	}
+	if converter != nil {
+		converter.ConvertModelComparison(report, startTime, endTime)
+	}
+
+	return SuccessResponse(c, report)
+}
+
+// CompareCohorts handles GET /api/v1/analytics/compare. The unprefixed filters and time range
+// select the comparison cohort; baseline_ parameters override them for the baseline, e.g.
+// time_range=this_week&baseline_time_range=last_week or tags=prompt_version:v2&baseline_tags=prompt_version:v1.
+func (h *AnalyticsHandler) CompareCohorts(c *fiber.Ctx) error {
+	now := time.Now()
+	window, err := services.ResolveTimeRange(timeRangeSpec(c), 24*time.Hour, now)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	comparison, err := cohortParams(c, "", models.Cohort{})
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid comparison filters")
+	}
+	comparison.StartTime, comparison.EndTime = window.Start, window.End
+
+	// The baseline inherits every filter it does not override
+	baseline, err := cohortParams(c, "baseline_", comparison)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid baseline filters")
+	}
+	baseline.StartTime, baseline.EndTime = time.Time{}, time.Time{}
+
+	baselineSpec := services.TimeRangeSpec{
+		Range:    c.Query("baseline_time_range"),
+		Start:    c.Query("baseline_start_time"),
+		End:      c.Query("baseline_end_time"),
+		TimeZone: c.Query("tz"),
+	}
+	if baselineSpec.Range != "" || baselineSpec.Start != "" || baselineSpec.End != "" {
+		baselineWindow, err := services.ResolveTimeRange(baselineSpec, window.End.Sub(window.Start), now)
+		if err != nil {
+			return ServiceErrorResponse(c, err, "Invalid baseline time range")
+		}
+		baseline.StartTime, baseline.EndTime = baselineWindow.Start, baselineWindow.End
+	}
+
+	report, err := h.analyticsService.CompareCohorts(c.Context(), &models.ComparisonQuery{
+		OrganizationID: resolveOrgID(c),
+		Baseline:       baseline,
+		Comparison:     comparison,
+		Limit:          parseLimit(c, "limit", 10, 50),
 	})
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to compare cohorts")
+	}
+
+	return SuccessResponse(c, report)
+}
+
+// cohortParams reads the cohort filters with the given parameter prefix, keeping the
+// defaults for filters that are not given
+func cohortParams(c *fiber.Ctx, prefix string, defaults models.Cohort) (models.Cohort, error) {
+	cohort := defaults
+	for _, filter := range []struct {
+		key   string
+		value *string
+	}{
+		{"project_id", &cohort.ProjectID},
+		{"model", &cohort.Model},
+		{"provider", &cohort.Provider},
+		{"user_id", &cohort.UserID},
+	} {
+		if value := c.Query(prefix + filter.key); value != "" {
+			*filter.value = value
+		}
+	}
+
+	if value := c.Query(prefix + "tags"); value != "" {
+		tags, err := parseTagFilters(value)
+		if err != nil {
+			return models.Cohort{}, err
+		}
+		cohort.Tags = tags
+	}
+
+	return cohort, nil
+}
+
+// GetTimeSeries handles GET /api/v1/analytics/timeseries
+func (h *AnalyticsHandler) GetTimeSeries(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.TimeSeriesQuery{
+		MetricQuery: models.MetricQuery{
+			OrganizationID: resolveOrgID(c),
+			ProjectID:      c.Query("project_id"),
+			MetricName:     c.Query("metric"),
+			Model:          c.Query("model"),
+			Provider:       c.Query("provider"),
+			StartTime:      startTime,
+			EndTime:        endTime,
+			Granularity:    c.Query("interval"),
+			Limit:          parseLimit(c, "limit", 10, 50),
+		},
+		UserID:  c.Query("user_id"),
+		Status:  c.Query("status"),
+		GroupBy: c.Query("group_by"),
+	}
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	report, err := h.analyticsService.GetTimeSeries(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get time series")
+	}
+	if converter != nil {
+		converter.ConvertTimeSeries(report)
+	}
+
+	return SuccessResponse(c, report)
 }
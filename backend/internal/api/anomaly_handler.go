@@ -0,0 +1,47 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// AnomalyHandler handles anomaly requests
+type AnomalyHandler struct {
+	anomalyService *services.AnomalyService
+}
+
+// NewAnomalyHandler creates a new anomaly handler
+func NewAnomalyHandler(anomalyService *services.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{
+		anomalyService: anomalyService,
+	}
+}
+
+// ListAnomalies handles GET /api/v1/analytics/anomalies
+func (h *AnomalyHandler) ListAnomalies(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.AnomalyQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		Model:          c.Query("model"),
+		Metric:         c.Query("metric"),
+		Severities:     splitList(c.Query("severity")),
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Limit:          parseLimit(c, "limit", 100, 1000),
+	}
+
+	anomalies, err := h.anomalyService.ListAnomalies(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list anomalies")
+	}
+
+	return SuccessResponse(c, anomalies)
+}
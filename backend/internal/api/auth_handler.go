@@ -123,6 +123,7 @@ func (h *AuthHandler) GenerateAPIKey(c *fiber.Ctx) error {
 	// Return response
 	return CreatedResponse(c, fiber.Map{
 		"api_key":    apiKey,
+		"key_id":     middleware.APIKeyID(apiKey),
 		"name":       req.Name,
 		"project_id": req.ProjectID,
 		"created_at": time.Now().Format(time.RFC3339),
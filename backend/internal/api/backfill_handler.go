@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// BackfillHandler handles cost recomputation backfills
+type BackfillHandler struct {
+	backfillService *services.CostBackfillService
+}
+
+// NewBackfillHandler creates a new backfill handler
+func NewBackfillHandler(backfillService *services.CostBackfillService) *BackfillHandler {
+	return &BackfillHandler{
+		backfillService: backfillService,
+	}
+}
+
+// CreateBackfill handles POST /api/v1/pricing/backfills
+func (h *BackfillHandler) CreateBackfill(c *fiber.Ctx) error {
+	var req models.CostBackfillRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	backfill, err := h.backfillService.StartBackfill(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start cost backfill")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    backfill,
+	})
+}
+
+// ListBackfills handles GET /api/v1/pricing/backfills
+func (h *BackfillHandler) ListBackfills(c *fiber.Ctx) error {
+	backfills, err := h.backfillService.ListBackfills(c.Context(), resolveOrgID(c), parseLimit(c, "limit", 50, 500))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list cost backfills")
+	}
+
+	return SuccessResponse(c, backfills)
+}
+
+// GetBackfill handles GET /api/v1/pricing/backfills/:id
+func (h *BackfillHandler) GetBackfill(c *fiber.Ctx) error {
+	backfill, err := h.backfillService.GetBackfill(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Cost backfill")
+	}
+
+	return SuccessResponse(c, backfill)
+}
+
+// ResumeBackfill handles POST /api/v1/pricing/backfills/:id/resume
+func (h *BackfillHandler) ResumeBackfill(c *fiber.Ctx) error {
+	backfill, err := h.backfillService.ResumeBackfill(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to resume cost backfill")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    backfill,
+	})
+}
+
+// CancelBackfill handles POST /api/v1/pricing/backfills/:id/cancel
+func (h *BackfillHandler) CancelBackfill(c *fiber.Ctx) error {
+	backfill, err := h.backfillService.CancelBackfill(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to cancel cost backfill")
+	}
+
+	return SuccessResponse(c, backfill)
+}
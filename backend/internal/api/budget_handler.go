@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// BudgetHandler handles budget requests
+type BudgetHandler struct {
+	budgetService *services.BudgetService
+}
+
+// NewBudgetHandler creates a new budget handler
+func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+	}
+}
+
+// ListBudgets handles GET /api/v1/budgets
+func (h *BudgetHandler) ListBudgets(c *fiber.Ctx) error {
+	statuses, err := h.budgetService.ListStatus(c.Context(), resolveOrgID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list budgets")
+	}
+
+	return SuccessResponse(c, statuses)
+}
+
+// GetBudget handles GET /api/v1/budgets/:id
+func (h *BudgetHandler) GetBudget(c *fiber.Ctx) error {
+	status, err := h.budgetService.GetStatus(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Budget")
+	}
+
+	return SuccessResponse(c, status)
+}
+
+// UpsertBudget handles POST /api/v1/budgets
+func (h *BudgetHandler) UpsertBudget(c *fiber.Ctx) error {
+	var req models.BudgetRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	budget, err := h.budgetService.UpsertBudget(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to save budget")
+	}
+
+	return SuccessResponse(c, budget)
+}
+
+// DeleteBudget handles DELETE /api/v1/budgets/:id
+func (h *BudgetHandler) DeleteBudget(c *fiber.Ctx) error {
+	if err := h.budgetService.DeleteBudget(c.Context(), resolveOrgID(c), c.Params("id")); err != nil {
+		return ServiceErrorResponse(c, err, "Budget")
+	}
+
+	return SuccessResponse(c, fiber.Map{"id": c.Params("id"), "deleted": true})
+}
+
+// CheckBudget handles POST /api/v1/budgets/check, asked by gateways before they make a call
+func (h *BudgetHandler) CheckBudget(c *fiber.Ctx) error {
+	var req models.BudgetCheckRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+	if req.ProjectID == "" {
+		req.ProjectID = middleware.GetProjectID(c)
+	}
+
+	check, err := h.budgetService.Check(resolveOrgID(c), middleware.GetAPIKeyID(c), &req)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to check budgets")
+	}
+
+	setBudgetHeaders(c, check)
+	return SuccessResponse(c, check)
+}
+
+// setBudgetHeaders reports the state of the budgets a call is charged to
+func setBudgetHeaders(c *fiber.Ctx, check *models.BudgetCheck) {
+	if check == nil || len(check.Budgets) == 0 {
+		return
+	}
+
+	c.Set("X-Budget-State", check.State)
+	c.Set("X-Budget-Allowed", strconv.FormatBool(check.Allowed))
+	c.Set("X-Budget-Percent-Used", strconv.FormatFloat(check.PercentUsed, 'f', 1, 64))
+	if check.RemainingUSD != nil {
+		c.Set("X-Budget-Remaining-USD", strconv.FormatFloat(*check.RemainingUSD, 'f', 4, 64))
+	}
+	if check.BlockedBy != "" {
+		c.Set("X-Budget-Blocked-By", check.BlockedBy)
+	}
+}
+
+// budgetRejection returns the budget check of an error from a hard budget, if it is one
+func budgetRejection(err error) *models.BudgetCheck {
+	var exceeded *services.BudgetExceededError
+	if errors.As(err, &exceeded) {
+		return exceeded.Check
+	}
+	return nil
+}
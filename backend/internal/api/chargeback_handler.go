@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// ChargebackHandler handles chargeback requests
+type ChargebackHandler struct {
+	chargebackService *services.ChargebackService
+	currencyService   *services.CurrencyService
+}
+
+// NewChargebackHandler creates a new chargeback handler
+func NewChargebackHandler(chargebackService *services.ChargebackService, currencyService *services.CurrencyService) *ChargebackHandler {
+	return &ChargebackHandler{
+		chargebackService: chargebackService,
+		currencyService:   currencyService,
+	}
+}
+
+// GetConfig handles GET /api/v1/chargeback/config
+func (h *ChargebackHandler) GetConfig(c *fiber.Ctx) error {
+	config, err := h.chargebackService.GetConfig(c.Context(), resolveOrgID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get chargeback config")
+	}
+
+	return SuccessResponse(c, config)
+}
+
+// UpdateConfig handles PUT /api/v1/chargeback/config
+func (h *ChargebackHandler) UpdateConfig(c *fiber.Ctx) error {
+	var req models.ChargebackConfigRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	config, err := h.chargebackService.UpdateConfig(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to save chargeback config")
+	}
+
+	return SuccessResponse(c, config)
+}
+
+// GetReport handles GET /api/v1/chargeback?period=YYYY-MM&project_id=&currency=
+func (h *ChargebackHandler) GetReport(c *fiber.Ctx) error {
+	orgID := resolveOrgID(c)
+	converter, err := displayConverter(c, h.currencyService, orgID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	report, err := h.chargebackService.GetReport(c.Context(), orgID, c.Query("project_id"), c.Query("period"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get chargeback report")
+	}
+	if converter != nil {
+		converter.ConvertChargeback(report)
+	}
+
+	return SuccessResponse(c, report)
+}
+
+// GetStatements handles GET /api/v1/chargeback/statements?period=YYYY-MM&team=&currency=&format=json|csv,
+// the monthly statements issued to teams
+func (h *ChargebackHandler) GetStatements(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return BadRequestResponse(c, "format must be json or csv")
+	}
+
+	orgID := resolveOrgID(c)
+	converter, err := displayConverter(c, h.currencyService, orgID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	report, err := h.chargebackService.GetStatements(c.Context(), orgID, c.Query("period"), c.Query("team"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Chargeback statement")
+	}
+	if converter != nil {
+		converter.ConvertChargeback(report)
+	}
+
+	if format == "json" {
+		return c.JSON(report.Statements)
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteChargebackCSV(&buf, report); err != nil {
+		return InternalErrorResponse(c, "Failed to export chargeback statements: "+err.Error())
+	}
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="chargeback-`+report.Period+`.csv"`)
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// CurrencyHandler handles display currency and exchange rate requests
+type CurrencyHandler struct {
+	currencyService *services.CurrencyService
+}
+
+// NewCurrencyHandler creates a new currency handler
+func NewCurrencyHandler(currencyService *services.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{
+		currencyService: currencyService,
+	}
+}
+
+// CurrencyRequest is the body of PUT /api/v1/currency
+type CurrencyRequest struct {
+	Currency string `json:"currency"`
+}
+
+// ExchangeRatesRequest is the body of POST /api/v1/admin/exchange-rates
+type ExchangeRatesRequest struct {
+	Rates []models.ExchangeRate `json:"rates"`
+}
+
+// GetCurrency handles GET /api/v1/currency
+func (h *CurrencyHandler) GetCurrency(c *fiber.Ctx) error {
+	setting, err := h.currencyService.GetDisplayCurrency(c.Context(), resolveOrgID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get display currency")
+	}
+
+	return SuccessResponse(c, setting)
+}
+
+// SetCurrency handles PUT /api/v1/currency
+func (h *CurrencyHandler) SetCurrency(c *fiber.Ctx) error {
+	var req CurrencyRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	setting, err := h.currencyService.SetDisplayCurrency(c.Context(), resolveOrgID(c), req.Currency, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to set display currency")
+	}
+
+	return SuccessResponse(c, setting)
+}
+
+// ListRates handles GET /api/v1/currency/rates?currency=EUR&start_date=2025-01-01&end_date=2025-01-31
+func (h *CurrencyHandler) ListRates(c *fiber.Ctx) error {
+	rates, err := h.currencyService.ListRates(c.Context(), c.Query("currency"), c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list exchange rates")
+	}
+
+	return SuccessResponse(c, rates)
+}
+
+// UpsertRates handles POST /api/v1/admin/exchange-rates
+func (h *CurrencyHandler) UpsertRates(c *fiber.Ctx) error {
+	var req ExchangeRatesRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	count, err := h.currencyService.UpsertRates(c.Context(), req.Rates, "api", requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to save exchange rates")
+	}
+
+	return SuccessResponse(c, fiber.Map{"rates": count})
+}
+
+// displayConverter returns the converter into the ?currency= display currency, or the
+// organization's; nil when costs are shown in USD only
+func displayConverter(c *fiber.Ctx, currencyService *services.CurrencyService, orgID string) (*services.CurrencyConverter, error) {
+	if currencyService == nil {
+		return nil, nil
+	}
+	return currencyService.DisplayConverter(c.Context(), orgID, c.Query("currency"))
+}
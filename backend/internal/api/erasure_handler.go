@@ -0,0 +1,86 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// ErasureHandler handles right-to-erasure requests
+type ErasureHandler struct {
+	erasureService *services.ErasureService
+}
+
+// NewErasureHandler creates a new erasure handler
+func NewErasureHandler(erasureService *services.ErasureService) *ErasureHandler {
+	return &ErasureHandler{
+		erasureService: erasureService,
+	}
+}
+
+// UserErasureRequest is the body of POST /api/v1/erasures
+type UserErasureRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// DeleteTrace handles DELETE /api/v1/traces/:id
+func (h *ErasureHandler) DeleteTrace(c *fiber.Ctx) error {
+	record, err := h.erasureService.DeleteTrace(c.Context(), resolveOrgID(c), c.Params("id"), requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to delete trace")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    record,
+	})
+}
+
+// CreateUserErasure handles POST /api/v1/erasures
+func (h *ErasureHandler) CreateUserErasure(c *fiber.Ctx) error {
+	var req UserErasureRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	record, err := h.erasureService.StartUserErasure(c.Context(), resolveOrgID(c), req.UserID, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start erasure")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    record,
+	})
+}
+
+// ListErasures handles GET /api/v1/erasures
+func (h *ErasureHandler) ListErasures(c *fiber.Ctx) error {
+	records, err := h.erasureService.ListErasures(c.Context(), resolveOrgID(c), parseLimit(c, "limit", 50, 500))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list erasures")
+	}
+
+	return SuccessResponse(c, records)
+}
+
+// GetErasure handles GET /api/v1/erasures/:id
+func (h *ErasureHandler) GetErasure(c *fiber.Ctx) error {
+	record, err := h.erasureService.GetErasure(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Erasure")
+	}
+
+	return SuccessResponse(c, record)
+}
+
+// requestedBy identifies the caller for the erasure audit log
+func requestedBy(c *fiber.Ctx) string {
+	if userID := middleware.GetUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	if authenticatedBy, ok := c.Locals("authenticated_by").(string); ok {
+		return authenticatedBy
+	}
+	return "anonymous"
+}
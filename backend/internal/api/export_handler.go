@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// ExportHandler handles trace export requests
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// ExportJobRequest is the body of POST /api/v1/exports
+type ExportJobRequest struct {
+	Format    string `json:"format"`
+	ProjectID string `json:"project_id"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Model     string `json:"model"`
+	Provider  string `json:"provider"`
+	Status    string `json:"status"`
+	UserID    string `json:"user_id"`
+	Limit     int    `json:"limit"`
+	Currency  string `json:"currency"` // display currency; the organization's when empty
+}
+
+// ExportTraces handles GET /api/v1/traces/export and streams the result
+func (h *ExportHandler) ExportTraces(c *fiber.Ctx) error {
+	format := c.Query("format", services.ExportFormatJSONL)
+
+	contentType, filename, err := h.exportService.ContentType(format)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to export traces")
+	}
+
+	query := &models.TraceQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		UserID:         c.Query("user_id"),
+		Model:          c.Query("model"),
+		Provider:       c.Query("provider"),
+		Status:         c.Query("status"),
+		StartTime:      parseTime(c.Query("start_time")),
+		EndTime:        parseTime(c.Query("end_time")),
+		Currency:       c.Query("currency"),
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		query.Limit = limit
+	}
+
+	if query.OrganizationID == "" {
+		return BadRequestResponse(c, "organization_id is required")
+	}
+	for _, key := range []string{"start_time", "end_time"} {
+		if c.Query(key) != "" && parseTime(c.Query(key)).IsZero() {
+			return BadRequestResponse(c, "Invalid "+key+": "+c.Query(key))
+		}
+	}
+	// Once streaming starts the status is sent, so bad input must be rejected here
+	if err := h.exportService.ValidateExport(query, format); err != nil {
+		return ServiceErrorResponse(c, err, "Failed to export traces")
+	}
+	if err := h.exportService.ResolveCurrency(c.Context(), query); err != nil {
+		return ServiceErrorResponse(c, err, "Failed to export traces")
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	// The request context is recycled once the handler returns, so stream with a detached one
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := h.exportService.Export(context.Background(), query, format, w); err != nil {
+			log.Printf("❌ Trace export (%s) failed mid-stream: %v", format, err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+// CreateExportJob handles POST /api/v1/exports
+func (h *ExportHandler) CreateExportJob(c *fiber.Ctx) error {
+	var req ExportJobRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	if req.Format == "" {
+		req.Format = services.ExportFormatJSONL
+	}
+
+	query := &models.TraceQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      req.ProjectID,
+		UserID:         req.UserID,
+		Model:          req.Model,
+		Provider:       req.Provider,
+		Status:         req.Status,
+		StartTime:      parseTime(req.StartTime),
+		EndTime:        parseTime(req.EndTime),
+		Limit:          req.Limit,
+		Currency:       req.Currency,
+	}
+	for _, field := range [][2]string{{"start_time", req.StartTime}, {"end_time", req.EndTime}} {
+		if field[1] != "" && parseTime(field[1]).IsZero() {
+			return BadRequestResponse(c, "Invalid "+field[0]+": "+field[1])
+		}
+	}
+	if err := h.exportService.ResolveCurrency(c.Context(), query); err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start export")
+	}
+
+	job, err := h.exportService.StartExportJob(query, req.Format)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start export")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// ListExportJobs handles GET /api/v1/exports
+func (h *ExportHandler) ListExportJobs(c *fiber.Ctx) error {
+	orgID := resolveOrgID(c)
+	if orgID == "" {
+		return BadRequestResponse(c, "organization_id is required")
+	}
+
+	return SuccessResponse(c, h.exportService.ListExportJobs(orgID))
+}
+
+// GetExportJob handles GET /api/v1/exports/:id
+func (h *ExportHandler) GetExportJob(c *fiber.Ctx) error {
+	job, err := h.exportService.GetExportJob(resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Export job")
+	}
+
+	return SuccessResponse(c, job)
+}
+
+// DownloadExport handles GET /api/v1/exports/:id/download
+func (h *ExportHandler) DownloadExport(c *fiber.Ctx) error {
+	path, filename, err := h.exportService.ExportFile(resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Export job")
+	}
+
+	return c.Download(path, filename)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// ForecastHandler handles cost forecast requests
+type ForecastHandler struct {
+	forecastService *services.ForecastService
+	currencyService *services.CurrencyService
+}
+
+// NewForecastHandler creates a new forecast handler
+func NewForecastHandler(forecastService *services.ForecastService, currencyService *services.CurrencyService) *ForecastHandler {
+	return &ForecastHandler{
+		forecastService: forecastService,
+		currencyService: currencyService,
+	}
+}
+
+// GetCostForecast handles GET /api/v1/analytics/forecast. Without ?budget= the forecast is
+// compared against the organization's, or the project's, monthly budget. currency adds the
+// projections in a display currency other than the organization's.
+func (h *ForecastHandler) GetCostForecast(c *fiber.Ctx) error {
+	query := &models.ForecastQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		GroupBy:        c.Query("group_by"),
+		HistoryDays:    c.QueryInt("history_days", 0),
+		Limit:          parseLimit(c, "limit", 10, 50),
+	}
+
+	if value := c.Query("budget"); value != "" {
+		budget, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return BadRequestResponse(c, "Invalid budget: "+value)
+		}
+		query.Budget = budget
+	}
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	forecast, err := h.forecastService.ForecastCosts(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to forecast costs")
+	}
+	if converter != nil {
+		converter.ConvertForecast(forecast)
+	}
+
+	return SuccessResponse(c, forecast)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// ImportHandler handles bulk trace import requests
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// CreateImportJob handles POST /api/v1/imports.
+// The JSONL data is either the raw request body or a multipart "file" field.
+func (h *ImportHandler) CreateImportJob(c *fiber.Ctx) error {
+	opts := services.ImportOptions{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		Format:         c.Query("format", services.ImportFormatAuto),
+	}
+
+	if err := h.importService.ValidateOptions(&opts); err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start import")
+	}
+
+	// Spool the upload to disk; the request body is gone once the handler returns
+	path, err := h.spoolUpload(c)
+	if err != nil {
+		return BadRequestResponse(c, err.Error())
+	}
+
+	job, err := h.importService.StartImportJob(path, opts)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to start import")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// ListImportJobs handles GET /api/v1/imports
+func (h *ImportHandler) ListImportJobs(c *fiber.Ctx) error {
+	orgID := resolveOrgID(c)
+	if orgID == "" {
+		return BadRequestResponse(c, "organization_id is required")
+	}
+
+	return SuccessResponse(c, h.importService.ListImportJobs(orgID))
+}
+
+// GetImportJob handles GET /api/v1/imports/:id
+func (h *ImportHandler) GetImportJob(c *fiber.Ctx) error {
+	job, err := h.importService.GetImportJob(resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Import job")
+	}
+
+	return SuccessResponse(c, job)
+}
+
+// spoolUpload copies the uploaded JSONL to a temporary file and returns its path
+func (h *ImportHandler) spoolUpload(c *fiber.Ctx) (string, error) {
+	var src io.Reader
+
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		src = file
+	} else {
+		if len(c.Body()) == 0 {
+			return "", fiber.NewError(fiber.StatusBadRequest, "request body must contain JSONL data or a multipart file field")
+		}
+		src = bytes.NewReader(c.Body())
+	}
+
+	tmp, err := os.CreateTemp("", "clarity-import-*.jsonl")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// MetricHandler handles custom application metric requests
+type MetricHandler struct {
+	metricService *services.MetricService
+}
+
+// NewMetricHandler creates a new metric handler
+func NewMetricHandler(metricService *services.MetricService) *MetricHandler {
+	return &MetricHandler{
+		metricService: metricService,
+	}
+}
+
+// CreateMetric handles POST /api/v1/metrics
+func (h *MetricHandler) CreateMetric(c *fiber.Ctx) error {
+	var req models.MetricRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	// The authenticated organization wins over the one in the body
+	if orgID := middleware.GetOrgID(c); orgID != "" {
+		req.OrganizationID = orgID
+	}
+
+	if err := h.metricService.RecordMetric(c.Context(), &req); err != nil {
+		return ServiceErrorResponse(c, err, "Failed to record metric")
+	}
+
+	return CreatedResponse(c, &models.BatchMetricResponse{Accepted: 1})
+}
+
+// CreateMetricBatch handles POST /api/v1/metrics/batch
+func (h *MetricHandler) CreateMetricBatch(c *fiber.Ctx) error {
+	var req models.BatchMetricRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	if orgID := middleware.GetOrgID(c); orgID != "" {
+		for i := range req.Metrics {
+			req.Metrics[i].OrganizationID = orgID
+		}
+	}
+
+	response, err := h.metricService.RecordMetrics(c.Context(), req.Metrics)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to record metrics")
+	}
+
+	return CreatedResponse(c, response)
+}
+
+// QueryMetrics handles GET /api/v1/metrics/query
+func (h *MetricHandler) QueryMetrics(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	query := &models.CustomMetricQuery{
+		MetricQuery: models.MetricQuery{
+			OrganizationID: resolveOrgID(c),
+			ProjectID:      c.Query("project_id"),
+			MetricName:     c.Query("name"),
+			StartTime:      startTime,
+			EndTime:        endTime,
+			Granularity:    c.Query("interval"),
+			Limit:          parseLimit(c, "limit", 10, 50),
+		},
+		Aggregation: c.Query("aggregation"),
+		GroupBy:     c.Query("group_by"),
+	}
+
+	// tags=feature:search,env:prod filters on exact tag values
+	tags, err := parseTagFilters(c.Query("tags"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid tag filter")
+	}
+	query.Tags = tags
+
+	report, err := h.metricService.QueryMetrics(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to query metrics")
+	}
+
+	return SuccessResponse(c, report)
+}
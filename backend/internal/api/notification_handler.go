@@ -0,0 +1,119 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// NotificationHandler handles notification channel and delivery log requests
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// ListChannels handles GET /api/v1/notifications/channels
+func (h *NotificationHandler) ListChannels(c *fiber.Ctx) error {
+	channels, err := h.notificationService.ListChannels(c.Context(), resolveOrgID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list notification channels")
+	}
+
+	return SuccessResponse(c, channels)
+}
+
+// GetChannel handles GET /api/v1/notifications/channels/:id
+func (h *NotificationHandler) GetChannel(c *fiber.Ctx) error {
+	channel, err := h.notificationService.GetChannel(c.Context(), resolveOrgID(c), c.Params("id"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Notification channel")
+	}
+
+	return SuccessResponse(c, channel)
+}
+
+// CreateChannel handles POST /api/v1/notifications/channels
+func (h *NotificationHandler) CreateChannel(c *fiber.Ctx) error {
+	var req models.NotificationChannelRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+	req.ID = ""
+
+	channel, err := h.notificationService.UpsertChannel(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to create notification channel")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    channel,
+	})
+}
+
+// UpdateChannel handles PUT /api/v1/notifications/channels/:id; secrets sent back
+// masked keep their stored value
+func (h *NotificationHandler) UpdateChannel(c *fiber.Ctx) error {
+	var req models.NotificationChannelRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+	req.ID = c.Params("id")
+
+	channel, err := h.notificationService.UpsertChannel(c.Context(), resolveOrgID(c), &req, requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Notification channel")
+	}
+
+	return SuccessResponse(c, channel)
+}
+
+// DeleteChannel handles DELETE /api/v1/notifications/channels/:id
+func (h *NotificationHandler) DeleteChannel(c *fiber.Ctx) error {
+	if err := h.notificationService.DeleteChannel(c.Context(), resolveOrgID(c), c.Params("id")); err != nil {
+		return ServiceErrorResponse(c, err, "Notification channel")
+	}
+
+	return SuccessResponse(c, fiber.Map{"id": c.Params("id"), "deleted": true})
+}
+
+// TestChannel handles POST /api/v1/notifications/channels/:id/test, which sends a test
+// notification and returns its delivery
+func (h *NotificationHandler) TestChannel(c *fiber.Ctx) error {
+	delivery, err := h.notificationService.TestChannel(c.Context(), resolveOrgID(c), c.Params("id"), requestedBy(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Notification channel")
+	}
+
+	return SuccessResponse(c, delivery)
+}
+
+// ListDeliveries handles GET /api/v1/notifications/deliveries?channel_id=&status=failed
+func (h *NotificationHandler) ListDeliveries(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 7*24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	deliveries, err := h.notificationService.ListDeliveries(c.Context(), &models.NotificationDeliveryQuery{
+		OrganizationID: resolveOrgID(c),
+		ChannelID:      c.Query("channel_id"),
+		Status:         c.Query("status"),
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Limit:          parseLimit(c, "limit", 100, 1000),
+	})
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list notification deliveries")
+	}
+
+	return SuccessResponse(c, deliveries)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// resolveOrgID returns the organization from auth context, falling back to the query string
+func resolveOrgID(c *fiber.Ctx) string {
+	if orgID := middleware.GetOrgID(c); orgID != "" {
+		return orgID
+	}
+	return c.Query("organization_id")
+}
+
+// timeRangeSpec reads time_range, start_time, end_time and tz from the query string
+func timeRangeSpec(c *fiber.Ctx) services.TimeRangeSpec {
+	return services.TimeRangeSpec{
+		Range:    c.Query("time_range"),
+		Start:    c.Query("start_time"),
+		End:      c.Query("end_time"),
+		TimeZone: c.Query("tz"),
+	}
+}
+
+// parseTimeWindow resolves the requested time range, defaulting to the trailing window ending now
+func parseTimeWindow(c *fiber.Ctx, defaultWindow time.Duration) (time.Time, time.Time, error) {
+	window, err := services.ResolveTimeRange(timeRangeSpec(c), defaultWindow, time.Now())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return window.Start, window.End, nil
+}
+
+// parseLimit reads an integer query parameter clamped to [1, max]
+func parseLimit(c *fiber.Ctx, key string, defaultValue, max int) int {
+	value, err := strconv.Atoi(c.Query(key, strconv.Itoa(defaultValue)))
+	if err != nil || value < 1 {
+		return defaultValue
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// splitList splits a comma-separated query parameter, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTagFilters reads exact tag filters written as key:value pairs, e.g. feature:search,env:prod
+func parseTagFilters(value string) (map[string]string, error) {
+	var tags map[string]string
+	for _, filter := range splitList(value) {
+		key, value, ok := strings.Cut(filter, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag filter %q: %w", filter, services.ErrInvalidArgument)
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// ServiceErrorResponse maps service and repository errors onto HTTP status codes
+func ServiceErrorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidArgument), errors.Is(err, repository.ErrInvalidInput):
+		return BadRequestResponse(c, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return NotFoundResponse(c, message+": not found")
+	case errors.Is(err, services.ErrNotReady):
+		return ErrorResponse(c, fiber.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrBudgetExceeded):
+		return ErrorResponse(c, fiber.StatusTooManyRequests, err.Error(), nil)
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return ErrorResponse(c, fiber.StatusPaymentRequired, err.Error(), nil)
+	default:
+		return InternalErrorResponse(c, message+": "+err.Error())
+	}
+}
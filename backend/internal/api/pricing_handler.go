@@ -0,0 +1,79 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// PricingHandler handles model price requests
+type PricingHandler struct {
+	pricingService *services.PricingService
+}
+
+// NewPricingHandler creates a new pricing handler
+func NewPricingHandler(pricingService *services.PricingService) *PricingHandler {
+	return &PricingHandler{
+		pricingService: pricingService,
+	}
+}
+
+// ListPrices handles GET /api/v1/admin/pricing
+func (h *PricingHandler) ListPrices(c *fiber.Ctx) error {
+	prices, err := h.pricingService.ListPrices(c.Context())
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list prices")
+	}
+
+	return SuccessResponse(c, prices)
+}
+
+// UpsertPrice handles POST /api/v1/admin/pricing
+func (h *PricingHandler) UpsertPrice(c *fiber.Ctx) error {
+	var req models.ModelPriceRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	// Global prices only; organizations set their rates through the overrides endpoint
+	req.OrganizationID, req.ProjectID = "", ""
+
+	price, err := h.pricingService.UpsertPrice(c.Context(), &req, middleware.GetUserID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to save price")
+	}
+
+	return CreatedResponse(c, price)
+}
+
+// ListOverrides handles GET /api/v1/pricing/overrides
+func (h *PricingHandler) ListOverrides(c *fiber.Ctx) error {
+	prices, err := h.pricingService.ListOverrides(c.Context(), resolveOrgID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to list price overrides")
+	}
+
+	return SuccessResponse(c, prices)
+}
+
+// UpsertOverride handles POST /api/v1/pricing/overrides
+func (h *PricingHandler) UpsertOverride(c *fiber.Ctx) error {
+	var req models.ModelPriceRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	// Overrides always belong to the caller's organization
+	req.OrganizationID = resolveOrgID(c)
+	if req.OrganizationID == "" {
+		return BadRequestResponse(c, "organization_id is required")
+	}
+
+	price, err := h.pricingService.UpsertPrice(c.Context(), &req, middleware.GetUserID(c))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to save price override")
+	}
+
+	return CreatedResponse(c, price)
+}
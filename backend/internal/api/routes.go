@@ -1,7 +1,12 @@
 package api
 
 import (
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
 	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
 	"github.com/Aditya-Pimpalkar/clarity/internal/services"
 )
@@ -9,12 +14,43 @@ import (
 // SetupRoutes configures all API routes
 func SetupRoutes(app *fiber.App, repo repository.Repository) {
 	// Create services
-	traceService := services.NewTraceService(repo, nil)
+	pricingService := services.NewPricingService(repo)
+	notificationService := services.NewNotificationService(repo, nil)
+	budgetService := services.NewBudgetService(repo, notificationService)
+	meteringService := services.NewMeteringService(repo, models.PlanLegacy)
+	currencyService := services.NewCurrencyService(repo)
+	traceService := services.NewTraceService(repo, nil, pricingService, budgetService, meteringService)
 	analyticsService := services.NewAnalyticsService(repo)
+	userAnalyticsService := services.NewUserAnalyticsService(repo)
+	jobs := services.NewJobManager(24 * time.Hour)
+	exportService := services.NewExportService(repo, jobs, filepath.Join(os.TempDir(), "clarity-exports"), currencyService)
+	importService := services.NewImportService(repo, jobs, traceService)
+	erasureService := services.NewErasureService(repo, jobs)
+	backfillService := services.NewCostBackfillService(repo, jobs, pricingService)
+	metricService := services.NewMetricService(repo)
+	forecastService := services.NewForecastService(repo, budgetService)
+	anomalyService := services.NewAnomalyService(repo)
+	chargebackService := services.NewChargebackService(repo)
+	alertService := services.NewAlertService(repo, notificationService)
 
 	// Create handlers
 	traceHandler := NewTraceHandler(traceService)
-	analyticsHandler := NewAnalyticsHandler(analyticsService)
+	analyticsHandler := NewAnalyticsHandler(analyticsService, currencyService)
+	userAnalyticsHandler := NewUserAnalyticsHandler(userAnalyticsService, currencyService)
+	exportHandler := NewExportHandler(exportService)
+	importHandler := NewImportHandler(importService)
+	erasureHandler := NewErasureHandler(erasureService)
+	metricHandler := NewMetricHandler(metricService)
+	forecastHandler := NewForecastHandler(forecastService, currencyService)
+	anomalyHandler := NewAnomalyHandler(anomalyService)
+	pricingHandler := NewPricingHandler(pricingService)
+	backfillHandler := NewBackfillHandler(backfillService)
+	budgetHandler := NewBudgetHandler(budgetService)
+	usageHandler := NewUsageHandler(meteringService)
+	currencyHandler := NewCurrencyHandler(currencyService)
+	chargebackHandler := NewChargebackHandler(chargebackService, currencyService)
+	alertHandler := NewAlertHandler(alertService)
+	notificationHandler := NewNotificationHandler(notificationService)
 	healthHandler := NewHealthHandler(repo)
 
 	// Health check routes
@@ -33,6 +69,7 @@ func SetupRoutes(app *fiber.App, repo repository.Repository) {
 			"endpoints": fiber.Map{
 				"traces":    "/api/v1/traces",
 				"analytics": "/api/v1/analytics",
+				"metrics":   "/api/v1/metrics",
 				"health":    "/health",
 			},
 		})
@@ -43,7 +80,35 @@ func SetupRoutes(app *fiber.App, repo repository.Repository) {
 	traces.Post("/", traceHandler.CreateTrace)
 	traces.Post("/batch", traceHandler.CreateTraceBatch)
 	traces.Get("/", traceHandler.ListTraces)
+	traces.Get("/diff", traceHandler.DiffTraces)
+	traces.Get("/export", exportHandler.ExportTraces)
 	traces.Get("/:id", traceHandler.GetTrace)
+	traces.Delete("/:id", erasureHandler.DeleteTrace)
+
+	// Custom metric routes
+	metrics := v1.Group("/metrics")
+	metrics.Post("/", metricHandler.CreateMetric)
+	metrics.Post("/batch", metricHandler.CreateMetricBatch)
+	metrics.Get("/query", metricHandler.QueryMetrics)
+
+	// Export job routes
+	exports := v1.Group("/exports")
+	exports.Post("/", exportHandler.CreateExportJob)
+	exports.Get("/", exportHandler.ListExportJobs)
+	exports.Get("/:id", exportHandler.GetExportJob)
+	exports.Get("/:id/download", exportHandler.DownloadExport)
+
+	// Import job routes
+	imports := v1.Group("/imports")
+	imports.Post("/", importHandler.CreateImportJob)
+	imports.Get("/", importHandler.ListImportJobs)
+	imports.Get("/:id", importHandler.GetImportJob)
+
+	// Erasure routes
+	erasures := v1.Group("/erasures")
+	erasures.Post("/", erasureHandler.CreateUserErasure)
+	erasures.Get("/", erasureHandler.ListErasures)
+	erasures.Get("/:id", erasureHandler.GetErasure)
 
 	// Analytics routes
 	analytics := v1.Group("/analytics")
@@ -51,4 +116,76 @@ func SetupRoutes(app *fiber.App, repo repository.Repository) {
 	analytics.Get("/costs", analyticsHandler.GetCostAnalysis)
 	analytics.Get("/performance", analyticsHandler.GetPerformanceMetrics)
 	analytics.Get("/models", analyticsHandler.GetModelComparison)
+	analytics.Get("/compare", analyticsHandler.CompareCohorts)
+	analytics.Get("/timeseries", analyticsHandler.GetTimeSeries)
+	analytics.Get("/forecast", forecastHandler.GetCostForecast)
+	analytics.Get("/anomalies", anomalyHandler.ListAnomalies)
+	analytics.Get("/users", userAnalyticsHandler.ListTopUsers)
+	analytics.Get("/users/:user_id", userAnalyticsHandler.GetUser)
+	analytics.Get("/users/:user_id/traces", userAnalyticsHandler.ListUserTraces)
+
+	// Model price routes
+	pricing := v1.Group("/admin/pricing")
+	pricing.Get("/", pricingHandler.ListPrices)
+	pricing.Post("/", pricingHandler.UpsertPrice)
+
+	// Organization price override routes
+	overrides := v1.Group("/pricing/overrides")
+	overrides.Get("/", pricingHandler.ListOverrides)
+	overrides.Post("/", pricingHandler.UpsertOverride)
+
+	// Cost backfill routes
+	backfills := v1.Group("/pricing/backfills")
+	backfills.Post("/", backfillHandler.CreateBackfill)
+	backfills.Get("/", backfillHandler.ListBackfills)
+	backfills.Get("/:id", backfillHandler.GetBackfill)
+	backfills.Post("/:id/resume", backfillHandler.ResumeBackfill)
+	backfills.Post("/:id/cancel", backfillHandler.CancelBackfill)
+
+	// Budget routes
+	budgets := v1.Group("/budgets")
+	budgets.Get("/", budgetHandler.ListBudgets)
+	budgets.Post("/", budgetHandler.UpsertBudget)
+	budgets.Post("/check", budgetHandler.CheckBudget)
+	budgets.Get("/:id", budgetHandler.GetBudget)
+	budgets.Delete("/:id", budgetHandler.DeleteBudget)
+
+	// Usage metering routes
+	usage := v1.Group("/usage")
+	usage.Get("/", usageHandler.GetUsage)
+	usage.Get("/export", usageHandler.ExportUsage)
+	v1.Put("/admin/organizations/:id/plan", usageHandler.SetPlan)
+
+	// Display currency and exchange rate routes
+	currency := v1.Group("/currency")
+	currency.Get("/", currencyHandler.GetCurrency)
+	currency.Put("/", currencyHandler.SetCurrency)
+	currency.Get("/rates", currencyHandler.ListRates)
+	currency.Post("/rates", currencyHandler.UpsertRates)
+
+	// Chargeback routes
+	chargeback := v1.Group("/chargeback")
+	chargeback.Get("/", chargebackHandler.GetReport)
+	chargeback.Get("/statements", chargebackHandler.GetStatements)
+	chargeback.Get("/config", chargebackHandler.GetConfig)
+	chargeback.Put("/config", chargebackHandler.UpdateConfig)
+
+	// Alert routes
+	alerts := v1.Group("/alerts")
+	alerts.Get("/rules", alertHandler.ListRules)
+	alerts.Post("/rules", alertHandler.CreateRule)
+	alerts.Get("/rules/:id", alertHandler.GetRule)
+	alerts.Put("/rules/:id", alertHandler.UpdateRule)
+	alerts.Delete("/rules/:id", alertHandler.DeleteRule)
+	alerts.Get("/history", alertHandler.ListEvents)
+
+	// Notification routes
+	notifications := v1.Group("/notifications")
+	notifications.Get("/channels", notificationHandler.ListChannels)
+	notifications.Post("/channels", notificationHandler.CreateChannel)
+	notifications.Get("/channels/:id", notificationHandler.GetChannel)
+	notifications.Put("/channels/:id", notificationHandler.UpdateChannel)
+	notifications.Delete("/channels/:id", notificationHandler.DeleteChannel)
+	notifications.Post("/channels/:id/test", notificationHandler.TestChannel)
+	notifications.Get("/deliveries", notificationHandler.ListDeliveries)
 }
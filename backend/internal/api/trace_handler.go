@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/middleware"
 	"github.com/Aditya-Pimpalkar/clarity/internal/models"
 	"github.com/Aditya-Pimpalkar/clarity/internal/services"
 )
@@ -29,13 +30,26 @@ func (h *TraceHandler) CreateTrace(c *fiber.Ctx) error {
 		return BadRequestResponse(c, "Invalid request body: "+err.Error())
 	}
 
+	req.APIKeyID = middleware.GetAPIKeyID(c)
+
 	// Call service
 	resp, err := h.traceService.CreateTrace(c.Context(), &req)
 	if err != nil {
+		if quota := quotaRejection(err); quota != nil {
+			return ErrorResponse(c, fiber.StatusPaymentRequired, err.Error(), map[string]interface{}{
+				"metric": quota.Metric,
+				"usage":  quota.Usage,
+			})
+		}
+		if check := budgetRejection(err); check != nil {
+			setBudgetHeaders(c, check)
+			return ErrorResponse(c, fiber.StatusTooManyRequests, err.Error(), nil)
+		}
 		return InternalErrorResponse(c, "Failed to create trace: "+err.Error())
 	}
 
 	// Return response
+	setBudgetHeaders(c, resp.Budget)
 	return CreatedResponse(c, resp)
 }
 
@@ -60,16 +74,23 @@ func (h *TraceHandler) CreateTraceBatch(c *fiber.Ctx) error {
 	accepted := 0
 	rejected := 0
 	var errors []string
+	var budget *models.BudgetCheck // state after the last trace
 
 	for i, traceReq := range req.Traces {
-		_, err := h.traceService.CreateTrace(c.Context(), &traceReq)
+		traceReq.APIKeyID = middleware.GetAPIKeyID(c)
+		resp, err := h.traceService.CreateTrace(c.Context(), &traceReq)
 		if err != nil {
 			rejected++
 			errors = append(errors, "Trace "+strconv.Itoa(i)+": "+err.Error())
+			if check := budgetRejection(err); check != nil {
+				budget = check
+			}
 		} else {
 			accepted++
+			budget = resp.Budget
 		}
 	}
+	setBudgetHeaders(c, budget)
 
 	// Return batch response
 	response := models.BatchTraceResponse{
@@ -99,6 +120,16 @@ func (h *TraceHandler) GetTrace(c *fiber.Ctx) error {
 	return SuccessResponse(c, trace)
 }
 
+// DiffTraces handles GET /api/v1/traces/diff?a=..&b=..
+func (h *TraceHandler) DiffTraces(c *fiber.Ctx) error {
+	diff, err := h.traceService.DiffTraces(c.Context(), middleware.GetOrgID(c), c.Query("a"), c.Query("b"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to diff traces")
+	}
+
+	return SuccessResponse(c, diff)
+}
+
 // ListTraces handles GET /api/v1/traces
 func (h *TraceHandler) ListTraces(c *fiber.Ctx) error {
 	// Parse query parameters
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// UsageHandler handles usage metering requests
+type UsageHandler struct {
+	meteringService *services.MeteringService
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(meteringService *services.MeteringService) *UsageHandler {
+	return &UsageHandler{
+		meteringService: meteringService,
+	}
+}
+
+// GetUsage handles GET /api/v1/usage?period=YYYY-MM
+func (h *UsageHandler) GetUsage(c *fiber.Ctx) error {
+	usage, err := h.meteringService.GetUsage(c.Context(), resolveOrgID(c), c.Query("period"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get usage")
+	}
+
+	return SuccessResponse(c, usage)
+}
+
+// ExportUsage handles GET /api/v1/usage/export?period=YYYY-MM&format=json|csv, one record
+// per day for billing systems
+func (h *UsageHandler) ExportUsage(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return BadRequestResponse(c, "format must be json or csv")
+	}
+
+	records, err := h.meteringService.ExportUsage(c.Context(), resolveOrgID(c), c.Query("period"))
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to export usage")
+	}
+
+	if format == "json" {
+		return c.JSON(records)
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteUsageCSV(&buf, records); err != nil {
+		return InternalErrorResponse(c, "Failed to export usage: "+err.Error())
+	}
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="usage.csv"`)
+	return c.Send(buf.Bytes())
+}
+
+// SetPlan handles PUT /api/v1/admin/organizations/:id/plan
+func (h *UsageHandler) SetPlan(c *fiber.Ctx) error {
+	var req models.PlanRequest
+	if err := BindJSON(c, &req); err != nil {
+		return BadRequestResponse(c, "Invalid request body: "+err.Error())
+	}
+
+	limits, err := h.meteringService.SetPlan(c.Context(), c.Params("id"), req.Plan)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to set plan")
+	}
+
+	return SuccessResponse(c, limits)
+}
+
+// quotaRejection returns the error of a used-up plan quota, if err is one
+func quotaRejection(err error) *services.QuotaExceededError {
+	var exceeded *services.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		return exceeded
+	}
+	return nil
+}
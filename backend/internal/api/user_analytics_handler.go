@@ -0,0 +1,124 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/services"
+)
+
+// UserAnalyticsHandler handles end-user analytics requests
+type UserAnalyticsHandler struct {
+	userAnalyticsService *services.UserAnalyticsService
+	currencyService      *services.CurrencyService
+}
+
+// NewUserAnalyticsHandler creates a new end-user analytics handler
+func NewUserAnalyticsHandler(userAnalyticsService *services.UserAnalyticsService, currencyService *services.CurrencyService) *UserAnalyticsHandler {
+	return &UserAnalyticsHandler{
+		userAnalyticsService: userAnalyticsService,
+		currencyService:      currencyService,
+	}
+}
+
+// ListTopUsers handles GET /api/v1/analytics/users
+func (h *UserAnalyticsHandler) ListTopUsers(c *fiber.Ctx) error {
+	query, err := h.parseQuery(c)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+	query.SortBy = c.Query("sort_by", "cost")
+	query.Limit = parseLimit(c, "limit", 50, 1000)
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	users, err := h.userAnalyticsService.GetTopUsers(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get top users")
+	}
+	if converter != nil {
+		for _, user := range users {
+			converter.ConvertUserStats(user, query.StartTime, query.EndTime)
+		}
+	}
+
+	return SuccessResponse(c, users)
+}
+
+// GetUser handles GET /api/v1/analytics/users/:user_id
+func (h *UserAnalyticsHandler) GetUser(c *fiber.Ctx) error {
+	query, err := h.parseQuery(c)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+	query.UserID = c.Params("user_id")
+
+	converter, err := displayConverter(c, h.currencyService, query.OrganizationID)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid currency")
+	}
+
+	detail, err := h.userAnalyticsService.GetUserDetail(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get user")
+	}
+	if converter != nil {
+		converter.ConvertUserDetail(detail, query.StartTime, query.EndTime)
+	}
+
+	return SuccessResponse(c, detail)
+}
+
+// ListUserTraces handles GET /api/v1/analytics/users/:user_id/traces
+func (h *UserAnalyticsHandler) ListUserTraces(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeWindow(c, 7*24*time.Hour)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Invalid time range")
+	}
+
+	limit := parseLimit(c, "limit", 50, 1000)
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := &models.TraceQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		UserID:         c.Params("user_id"),
+		Model:          c.Query("model"),
+		Status:         c.Query("status"),
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Limit:          limit,
+		Offset:         offset,
+	}
+
+	traces, total, err := h.userAnalyticsService.GetUserTraces(c.Context(), query)
+	if err != nil {
+		return ServiceErrorResponse(c, err, "Failed to get user traces")
+	}
+
+	return PaginatedResponse(c, traces, total, (offset/limit)+1, limit)
+}
+
+// parseQuery reads the filters shared by the end-user endpoints
+func (h *UserAnalyticsHandler) parseQuery(c *fiber.Ctx) (*models.UserAnalyticsQuery, error) {
+	startTime, endTime, err := parseTimeWindow(c, 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserAnalyticsQuery{
+		OrganizationID: resolveOrgID(c),
+		ProjectID:      c.Query("project_id"),
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Granularity:    c.Query("granularity"),
+	}, nil
+}
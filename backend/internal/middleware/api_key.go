@@ -42,12 +42,20 @@ func APIKeyAuth() fiber.Handler {
 		// Store in context for later use
 		c.Locals(string(OrgIDKey), orgID)
 		c.Locals(string(ProjectIDKey), projectID)
+		c.Locals(string(APIKeyIDKey), APIKeyID(apiKey))
 		c.Locals("authenticated_by", "api_key")
 
 		return c.Next()
 	}
 }
 
+// APIKeyID derives the public identifier of an API key, which budgets and traces refer to
+// without storing the key itself
+func APIKeyID(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return "key_" + hex.EncodeToString(hash[:8])
+}
+
 // validateAPIKey validates an API key and returns org/project IDs
 func validateAPIKey(apiKey string) (orgID, projectID string, valid bool) {
 	// Get test keys
@@ -17,6 +17,7 @@ const (
 	OrgIDKey     contextKey = "org_id"
 	RoleKey      contextKey = "role"
 	ProjectIDKey contextKey = "project_id"
+	APIKeyIDKey  contextKey = "api_key_id"
 )
 
 type Claims struct {
@@ -121,6 +122,18 @@ func GetOrgID(c *fiber.Ctx) string {
 	return orgID
 }
 
+// GetProjectID returns the project of the API key that authenticated the request, if any
+func GetProjectID(c *fiber.Ctx) string {
+	projectID, _ := c.Locals(string(ProjectIDKey)).(string)
+	return projectID
+}
+
+// GetAPIKeyID returns the ID of the API key that authenticated the request, if any
+func GetAPIKeyID(c *fiber.Ctx) string {
+	keyID, _ := c.Locals(string(APIKeyIDKey)).(string)
+	return keyID
+}
+
 func GetRole(c *fiber.Ctx) string {
 	role, _ := c.Locals(string(RoleKey)).(string)
 	return role
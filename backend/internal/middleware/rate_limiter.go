@@ -14,6 +14,9 @@ type RateLimiter struct {
 	mu       sync.RWMutex
 	limit    int
 	window   time.Duration
+
+	// limitFor, when set, gives each organization its own limit
+	limitFor func(orgID string) int
 }
 
 type clientInfo struct {
@@ -35,21 +38,36 @@ func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
 	return rl
 }
 
+// NewPlanRateLimiter creates a rate limiter that counts requests per organization against
+// the limit limitFor returns for it; it must run after authentication
+func NewPlanRateLimiter(window time.Duration, limitFor func(orgID string) int) *RateLimiter {
+	rl := NewRateLimiter(0, window)
+	rl.limitFor = limitFor
+	return rl
+}
+
 // Middleware returns the rate limiting middleware
 func (rl *RateLimiter) Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get client identifier (IP address or user ID)
 		clientID := c.IP()
+		limit := rl.limit
 
 		// If authenticated, use user ID for more accurate limiting
 		if userID := GetUserID(c); userID != "" {
 			clientID = userID
 		}
 
+		// Per-organization limits count all of the organization's requests together
+		if orgID := GetOrgID(c); rl.limitFor != nil && orgID != "" {
+			clientID = "org:" + orgID
+			limit = rl.limitFor(orgID)
+		}
+
 		// Check rate limit
-		allowed, resetTime := rl.allow(clientID)
+		allowed, resetTime := rl.allow(clientID, limit)
 		if !allowed {
-			c.Set("X-RateLimit-Limit", fmt.Sprintf("%d", rl.limit))
+			c.Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 			c.Set("X-RateLimit-Remaining", "0")
 			c.Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetTime.Unix()))
 
@@ -61,8 +79,8 @@ func (rl *RateLimiter) Middleware() fiber.Handler {
 		}
 
 		// Set rate limit headers
-		remaining := rl.getRemaining(clientID)
-		c.Set("X-RateLimit-Limit", fmt.Sprintf("%d", rl.limit))
+		remaining := rl.getRemaining(clientID, limit)
+		c.Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 		c.Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
 		c.Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetTime.Unix()))
 
@@ -71,7 +89,7 @@ func (rl *RateLimiter) Middleware() fiber.Handler {
 }
 
 // allow checks if a request is allowed
-func (rl *RateLimiter) allow(clientID string) (bool, time.Time) {
+func (rl *RateLimiter) allow(clientID string, limit int) (bool, time.Time) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
@@ -94,7 +112,7 @@ func (rl *RateLimiter) allow(clientID string) (bool, time.Time) {
 	}
 
 	// Check if limit exceeded
-	if info.count >= rl.limit {
+	if info.count >= limit {
 		return false, info.resetTime
 	}
 
@@ -103,16 +121,16 @@ func (rl *RateLimiter) allow(clientID string) (bool, time.Time) {
 }
 
 // getRemaining returns the number of remaining requests
-func (rl *RateLimiter) getRemaining(clientID string) int {
+func (rl *RateLimiter) getRemaining(clientID string, limit int) int {
 	rl.mu.RLock()
 	defer rl.mu.RUnlock()
 
 	info, exists := rl.requests[clientID]
 	if !exists {
-		return rl.limit
+		return limit
 	}
 
-	remaining := rl.limit - info.count
+	remaining := limit - info.count
 	if remaining < 0 {
 		return 0
 	}
@@ -156,3 +174,10 @@ func APIKeyRateLimiter() fiber.Handler {
 	limiter := NewRateLimiter(10000, time.Hour)
 	return limiter.Middleware()
 }
+
+// PlanRateLimiter creates a rate limiter for API keys whose limit per minute depends on
+// the organization's plan
+func PlanRateLimiter(requestsPerMinute func(orgID string) int) fiber.Handler {
+	limiter := NewPlanRateLimiter(time.Minute, requestsPerMinute)
+	return limiter.Middleware()
+}
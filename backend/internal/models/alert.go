@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// Alert metrics, measured over a rule's evaluation window
+const (
+	AlertMetricErrorRate   = "error_rate"     // percent of traces that failed
+	AlertMetricP95Latency  = "p95_latency_ms" // 95th percentile trace duration
+	AlertMetricCostPerHour = "cost_per_hour"  // USD spent per hour
+	AlertMetricVolumeDrop  = "volume_drop"    // percent fewer traces than in the window before
+)
+
+// Alert comparators: how a metric's value is compared with the threshold
+const (
+	AlertComparatorGT  = "gt"
+	AlertComparatorGTE = "gte"
+	AlertComparatorLT  = "lt"
+	AlertComparatorLTE = "lte"
+)
+
+// Alert severities
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// Alert states. A rule whose condition holds is pending until it has held for the rule's
+// for duration, then firing until the condition clears, when it is resolved.
+const (
+	AlertStateInactive = "inactive"
+	AlertStatePending  = "pending"
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+)
+
+// AlertRule is a condition on a project's traces, evaluated on a schedule
+type AlertRule struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id"`
+	Name           string    `json:"name"`
+	Metric         string    `json:"metric"`
+	Comparator     string    `json:"comparator"`
+	Threshold      float64   `json:"threshold"`
+	WindowMinutes  int       `json:"window_minutes"` // how far back the metric is measured
+	ForMinutes     int       `json:"for_minutes"`    // how long the condition must hold before firing
+	Severity       string    `json:"severity"`
+	Enabled        bool      `json:"enabled"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Deleted        bool      `json:"-"`
+}
+
+// AlertRuleRequest creates an alert rule, or updates the one with the given ID
+type AlertRuleRequest struct {
+	ID            string  `json:"id,omitempty"`
+	ProjectID     string  `json:"project_id"`
+	Name          string  `json:"name"`
+	Metric        string  `json:"metric"`
+	Comparator    string  `json:"comparator"`
+	Threshold     float64 `json:"threshold"`
+	WindowMinutes int     `json:"window_minutes,omitempty"` // defaults to 5
+	ForMinutes    int     `json:"for_minutes,omitempty"`
+	Severity      string  `json:"severity,omitempty"` // defaults to warning
+	Enabled       *bool   `json:"enabled,omitempty"`  // defaults to true
+}
+
+// AlertStatus is an alert rule with the outcome of its latest evaluation
+type AlertStatus struct {
+	AlertRule
+	State           string     `json:"state"`
+	Value           *float64   `json:"value,omitempty"`
+	StateSince      *time.Time `json:"state_since,omitempty"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+}
+
+// AlertEvent records a rule changing state
+type AlertEvent struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id"`
+	RuleID         string    `json:"rule_id"`
+	RuleName       string    `json:"rule_name"`
+	Metric         string    `json:"metric"`
+	Comparator     string    `json:"comparator"`
+	Threshold      float64   `json:"threshold"`
+	Severity       string    `json:"severity"`
+	State          string    `json:"state"`
+	Value          float64   `json:"value"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// AlertEventQuery filters an organization's alert history
+type AlertEventQuery struct {
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	RuleID         string    `json:"rule_id,omitempty"`
+	States         []string  `json:"states,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	Limit          int       `json:"limit"`
+}
@@ -0,0 +1,164 @@
+package models
+
+import "time"
+
+// AnalyticsQuery scopes an aggregate query over traces
+type AnalyticsQuery struct {
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	TimeZone       string    `json:"time_zone,omitempty"` // IANA name for calendar-day buckets; UTC when empty
+}
+
+// TimeWindow is a half-open time interval [Start, End)
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// TraceTotals are aggregate totals over the traces in a time window
+type TraceTotals struct {
+	TraceCount   int64   `json:"trace_count"`
+	ErrorCount   int64   `json:"error_count"`
+	SuccessCount int64   `json:"success_count"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCost    float64 `json:"total_cost"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// GroupTotal is the trace count and cost of one group in a breakdown
+type GroupTotal struct {
+	Key   string  `json:"key"`
+	Count int64   `json:"count"`
+	Cost  float64 `json:"cost"`
+}
+
+// DashboardBreakdowns holds the grouped widgets of the dashboard
+type DashboardBreakdowns struct {
+	TopModels []GroupTotal `json:"top_models"`  // by trace count, descending
+	CostByDay []GroupTotal `json:"cost_by_day"` // keyed by YYYY-MM-DD, ascending
+	ByStatus  []GroupTotal `json:"by_status"`   // by trace count, descending
+}
+
+// CostQuery selects the traces and groupings of a cost breakdown
+type CostQuery struct {
+	AnalyticsQuery
+	TagKeys []string `json:"tag_keys,omitempty"` // metadata keys to group by
+	Limit   int      `json:"limit"`              // rows per grouping
+}
+
+// LatencyQuery selects the durations summarized by a latency report
+type LatencyQuery struct {
+	MetricQuery
+	Level   string    `json:"level"`              // trace, span
+	GroupBy string    `json:"group_by,omitempty"` // model, provider, span_name; empty for a single series
+	Buckets []float64 `json:"buckets"`            // ascending histogram upper bounds in ms
+}
+
+// LatencyStats summarizes a set of durations
+type LatencyStats struct {
+	Count        int64   `json:"count"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P90LatencyMs float64 `json:"p90_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// LatencyBucket counts the durations in (MinMs, MaxMs]; the last bucket has no MaxMs
+type LatencyBucket struct {
+	MinMs float64  `json:"min_ms"`
+	MaxMs *float64 `json:"max_ms"`
+	Count int64    `json:"count"`
+}
+
+// LatencyPoint is one time bucket of a latency time series
+type LatencyPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	LatencyStats
+}
+
+// LatencySeries is the latency distribution of one group
+type LatencySeries struct {
+	Group      string          `json:"group"`
+	Stats      LatencyStats    `json:"stats"`
+	Histogram  []LatencyBucket `json:"histogram"`
+	TimeSeries []LatencyPoint  `json:"time_series"`
+}
+
+// TimeSeriesQuery selects one metric over time. MetricName and Granularity of the
+// embedded MetricQuery choose the metric and the bucket interval.
+type TimeSeriesQuery struct {
+	MetricQuery
+	UserID  string `json:"user_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	GroupBy string `json:"group_by,omitempty"` // model, provider, project, user, status
+	Source  string `json:"source"`             // traces, metrics_hourly
+}
+
+// TimeSeriesValue is the value of a metric in one time bucket
+type TimeSeriesValue struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Value          float64   `json:"value"`
+	ValueConverted *float64  `json:"value_converted,omitempty"` // cost in the display currency
+}
+
+// TimeSeries is one group's values of a metric over time
+type TimeSeries struct {
+	Group  string            `json:"group"`
+	Points []TimeSeriesValue `json:"points"`
+}
+
+// ForecastQuery selects the daily cost history a forecast is fitted to
+type ForecastQuery struct {
+	OrganizationID string  `json:"organization_id"`
+	ProjectID      string  `json:"project_id,omitempty"`
+	GroupBy        string  `json:"group_by,omitempty"` // model, project
+	HistoryDays    int     `json:"history_days"`
+	Budget         float64 `json:"budget,omitempty"` // monthly budget in USD
+	Limit          int     `json:"limit"`
+}
+
+// Cohort selects one side of a comparison: the traces in a time window matching every filter set
+type Cohort struct {
+	ProjectID string            `json:"project_id,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	Provider  string            `json:"provider,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"` // metadata values that must match exactly
+	StartTime time.Time         `json:"start_time"`
+	EndTime   time.Time         `json:"end_time"`
+}
+
+// ComparisonQuery compares a cohort of an organization's traces against a baseline cohort
+type ComparisonQuery struct {
+	OrganizationID string `json:"organization_id"`
+	Baseline       Cohort `json:"baseline"`
+	Comparison     Cohort `json:"comparison"`
+	Limit          int    `json:"limit"` // models in the breakdown
+}
+
+// CohortGroupStats aggregates the traces of a cohort, or of one model within it
+type CohortGroupStats struct {
+	Model         string  `json:"model,omitempty"`
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	TotalCost     float64 `json:"total_cost"`
+	TotalTokens   int64   `json:"total_tokens"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	LatencyStdDev float64 `json:"latency_std_dev"`
+	P50LatencyMs  float64 `json:"p50_latency_ms"`
+	P90LatencyMs  float64 `json:"p90_latency_ms"`
+	P95LatencyMs  float64 `json:"p95_latency_ms"`
+	P99LatencyMs  float64 `json:"p99_latency_ms"`
+}
+
+// CohortStats holds the totals of a cohort and its busiest models
+type CohortStats struct {
+	Total   CohortGroupStats    `json:"total"`
+	ByModel []*CohortGroupStats `json:"by_model"`
+}
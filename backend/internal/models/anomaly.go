@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Anomaly severities, from least to most severe
+const (
+	AnomalySeverityLow    = "low"
+	AnomalySeverityMedium = "medium"
+	AnomalySeverityHigh   = "high"
+)
+
+// Anomaly directions
+const (
+	AnomalyAbove = "above"
+	AnomalyBelow = "below"
+)
+
+// Anomaly is a run of consecutive hours in which one metric of a project and model
+// deviated from its baseline. Re-detecting the same run updates the record in place.
+type Anomaly struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id"`
+	Model          string    `json:"model"`
+	Metric         string    `json:"metric"`    // cost, tokens, latency_p95, error_rate
+	Direction      string    `json:"direction"` // above, below
+	Severity       string    `json:"severity"`  // low, medium, high
+	Score          float64   `json:"score"`     // peak robust z-score, in standard deviations
+	Value          float64   `json:"value"`     // observed value at the peak hour
+	Baseline       float64   `json:"baseline"`  // expected value at the peak hour
+	StartedAt      time.Time `json:"started_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"` // end of the last anomalous hour
+	Ongoing        bool      `json:"ongoing"`
+	Explanation    string    `json:"explanation"`
+	DetectedAt     time.Time `json:"detected_at"`
+}
+
+// AnomalyQuery selects stored anomalies overlapping a time window
+type AnomalyQuery struct {
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Metric         string    `json:"metric,omitempty"`
+	Severities     []string  `json:"severities,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	Limit          int       `json:"limit"`
+}
+
+// HourlyModelStats holds one hour of traffic for a project and model
+type HourlyModelStats struct {
+	ProjectID    string    `json:"project_id"`
+	Model        string    `json:"model"`
+	Hour         time.Time `json:"hour"`
+	Requests     int64     `json:"requests"`
+	Errors       int64     `json:"errors"`
+	CostUSD      float64   `json:"cost_usd"`
+	Tokens       int64     `json:"tokens"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
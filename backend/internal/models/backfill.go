@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// CostBackfillRequest selects the traces whose costs a backfill recomputes
+type CostBackfillRequest struct {
+	Model     string    `json:"model,omitempty"` // reprice only spans of this model; all models when empty
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"` // exclusive; at most the start of the current day
+}
+
+// CostBackfill recomputes the stored costs of an organization's traces with the current
+// prices. Traces are processed in (timestamp, trace_id) order and the cursor is the last
+// trace written, so an interrupted backfill resumes where it stopped.
+type CostBackfill struct {
+	ID              string     `json:"id"`
+	OrganizationID  string     `json:"organization_id"`
+	Model           string     `json:"model,omitempty"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	RequestedBy     string     `json:"requested_by"`
+	Status          string     `json:"status"`
+	JobID           string     `json:"job_id,omitempty"` // job of the current run; not persisted
+	TracesTotal     int64      `json:"traces_total"`
+	TracesProcessed int64      `json:"traces_processed"`
+	TracesUpdated   int64      `json:"traces_updated"`
+	SpansRepriced   int64      `json:"spans_repriced"`
+	CostBefore      float64    `json:"cost_before"` // of the processed traces
+	CostAfter       float64    `json:"cost_after"`
+	CursorTimestamp time.Time  `json:"cursor_timestamp"`
+	CursorTraceID   string     `json:"cursor_trace_id,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	RequestedAt     time.Time  `json:"requested_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
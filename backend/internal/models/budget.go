@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Budget periods; periods start at midnight UTC and on the first of the month
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// Budget scopes: what a budget's spend is counted over
+const (
+	BudgetScopeOrganization = "organization"
+	BudgetScopeProject      = "project"
+	BudgetScopeModel        = "model"
+	BudgetScopeAPIKey       = "api_key"
+)
+
+// Budget enforcement: a soft budget only reports its state, a hard budget also rejects
+// traces and gateway checks once it is used up
+const (
+	BudgetEnforcementSoft = "soft"
+	BudgetEnforcementHard = "hard"
+)
+
+// Budget states, from the thresholds crossed
+const (
+	BudgetStateOK       = "ok"
+	BudgetStateWarning  = "warning"
+	BudgetStateExceeded = "exceeded"
+)
+
+// Budget is a spending limit for an organization, or a project, model or API key of it
+type Budget struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	Scope          string    `json:"scope"`
+	ScopeValue     string    `json:"scope_value,omitempty"` // project ID, model or API key ID
+	Period         string    `json:"period"`
+	LimitUSD       float64   `json:"limit_usd"`
+	Thresholds     []float64 `json:"thresholds"` // warning levels in percent of the limit
+	Enforcement    string    `json:"enforcement"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Deleted        bool      `json:"-"`
+}
+
+// BudgetRequest creates a budget, or updates the one with the given ID
+type BudgetRequest struct {
+	ID          string    `json:"id,omitempty"`
+	Name        string    `json:"name"`
+	Scope       string    `json:"scope"`
+	ScopeValue  string    `json:"scope_value,omitempty"`
+	Period      string    `json:"period"`
+	LimitUSD    float64   `json:"limit_usd"`
+	Thresholds  []float64 `json:"thresholds,omitempty"` // defaults to 50, 80 and 100
+	Enforcement string    `json:"enforcement,omitempty"` // defaults to soft
+}
+
+// BudgetStatus is a budget's consumption in its current period
+type BudgetStatus struct {
+	Budget
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	SpentUSD     float64   `json:"spent_usd"`
+	RemainingUSD float64   `json:"remaining_usd"`
+	PercentUsed  float64   `json:"percent_used"`
+	Threshold    float64   `json:"threshold,omitempty"` // highest threshold crossed
+	State        string    `json:"state"`
+}
+
+// BudgetCheckRequest asks whether a call may go ahead; the project defaults to the API key's
+type BudgetCheckRequest struct {
+	ProjectID        string  `json:"project_id,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// BudgetCheck is the outcome of a budget check or of charging a trace to its budgets.
+// State and remaining spend are those of the most used budget.
+type BudgetCheck struct {
+	Allowed      bool           `json:"allowed"`
+	State        string         `json:"state"`
+	RemainingUSD *float64       `json:"remaining_usd,omitempty"`
+	PercentUsed  float64        `json:"percent_used"`
+	BlockedBy    string         `json:"blocked_by,omitempty"` // ID of the hard budget that rejected the call
+	Budgets      []BudgetStatus `json:"budgets"`
+}
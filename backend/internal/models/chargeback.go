@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// How spend without the top-level tag of a chargeback hierarchy is charged
+const (
+	UntaggedPolicyBucket       = "bucket"       // charged to a default bucket
+	UntaggedPolicyProportional = "proportional" // spread over teams in proportion to their tagged spend
+)
+
+// DefaultChargebackBucket is the team untagged spend is charged to by default
+const DefaultChargebackBucket = "unallocated"
+
+// ChargebackConfig is how an organization's spend is attributed to internal teams.
+// Hierarchy lists the metadata tags spend is broken down by, such as team, feature;
+// the first names the team a statement is issued to.
+type ChargebackConfig struct {
+	OrganizationID string    `json:"organization_id"`
+	Hierarchy      []string  `json:"hierarchy"`
+	UntaggedPolicy string    `json:"untagged_policy"`
+	DefaultBucket  string    `json:"default_bucket"`
+	UpdatedBy      string    `json:"updated_by,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// ChargebackConfigRequest is the body of a chargeback configuration change
+type ChargebackConfigRequest struct {
+	Hierarchy      []string `json:"hierarchy"`
+	UntaggedPolicy string   `json:"untagged_policy,omitempty"`
+	DefaultBucket  string   `json:"default_bucket,omitempty"`
+}
+
+// TagPathCost is the spend of traces with the same values of a list of metadata tags;
+// a value is empty when the trace does not have the tag
+type TagPathCost struct {
+	Values       []string `json:"values"`
+	TotalCost    float64  `json:"total_cost"`
+	RequestCount int64    `json:"request_count"`
+	TotalTokens  int64    `json:"total_tokens"`
+}
+
+// ChargebackLine is the spend of one path through the hierarchy within a team
+type ChargebackLine struct {
+	Tags          map[string]string `json:"tags"`
+	DirectCost    float64           `json:"direct_cost"`
+	AllocatedCost float64           `json:"allocated_cost"` // share of untagged spend
+	TotalCost     float64           `json:"total_cost"`
+	RequestCount  int64             `json:"request_count"`
+	TotalTokens   int64             `json:"total_tokens"`
+
+	DirectCostConverted    *float64 `json:"direct_cost_converted,omitempty"`
+	AllocatedCostConverted *float64 `json:"allocated_cost_converted,omitempty"`
+	TotalCostConverted     *float64 `json:"total_cost_converted,omitempty"`
+}
+
+// ChargebackStatement is a team's monthly bill. IDs are stable for a team and period.
+type ChargebackStatement struct {
+	ID             string           `json:"id"`
+	OrganizationID string           `json:"organization_id"`
+	Period         string           `json:"period"` // YYYY-MM
+	TagKey         string           `json:"tag_key"`
+	Team           string           `json:"team"`
+	DirectCost     float64          `json:"direct_cost"`
+	AllocatedCost  float64          `json:"allocated_cost"`
+	TotalCost      float64          `json:"total_cost"`
+	Share          float64          `json:"share"` // percent of the organization's spend
+	RequestCount   int64            `json:"request_count"`
+	TotalTokens    int64            `json:"total_tokens"`
+	Lines          []ChargebackLine `json:"lines"`
+
+	// Costs in the display currency, at the effective rate over the period
+	Currency               string   `json:"currency,omitempty"`
+	ExchangeRate           float64  `json:"exchange_rate,omitempty"`
+	DirectCostConverted    *float64 `json:"direct_cost_converted,omitempty"`
+	AllocatedCostConverted *float64 `json:"allocated_cost_converted,omitempty"`
+	TotalCostConverted     *float64 `json:"total_cost_converted,omitempty"`
+}
+
+// ChargebackReport attributes an organization's spend in a month to its teams
+type ChargebackReport struct {
+	OrganizationID string                `json:"organization_id"`
+	Period         string                `json:"period"`
+	PeriodStart    time.Time             `json:"period_start"`
+	PeriodEnd      time.Time             `json:"period_end"`
+	Hierarchy      []string              `json:"hierarchy"`
+	UntaggedPolicy string                `json:"untagged_policy"`
+	TotalCost      float64               `json:"total_cost"`
+	TaggedCost     float64               `json:"tagged_cost"`
+	UntaggedCost   float64               `json:"untagged_cost"`
+	Statements     []ChargebackStatement `json:"statements"`
+	Converted      *CostConversion       `json:"converted,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// BaseCurrency is the currency costs are priced and stored in
+const BaseCurrency = "USD"
+
+// ExchangeRate is the value of one US dollar in a currency on a day
+type ExchangeRate struct {
+	Currency  string    `json:"currency"` // ISO 4217 code
+	Date      string    `json:"date"`     // YYYY-MM-DD
+	Rate      float64   `json:"rate"`     // units of the currency per USD
+	Source    string    `json:"source,omitempty"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CurrencySetting is the currency an organization's costs are displayed in
+type CurrencySetting struct {
+	OrganizationID string    `json:"organization_id"`
+	Currency       string    `json:"currency"`
+	UpdatedBy      string    `json:"updated_by,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// ConvertedDailyCost is one day's cost in USD and in the display currency
+type ConvertedDailyCost struct {
+	Date    string  `json:"date"`
+	CostUSD float64 `json:"cost_usd"`
+	Rate    float64 `json:"rate"`
+	Cost    float64 `json:"cost"`
+}
+
+// CostConversion reports a report's costs in a display currency, converted at each day's
+// rate. Rate is the effective rate over the range, the converted total over the USD total,
+// and converts the report's other cost figures.
+type CostConversion struct {
+	Currency   string               `json:"currency"`
+	Rate       float64              `json:"rate"`
+	TotalCost  float64              `json:"total_cost"`
+	DailyCosts []ConvertedDailyCost `json:"daily_costs"`
+}
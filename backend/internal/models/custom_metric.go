@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// MetricRequest records one application-level metric data point
+type MetricRequest struct {
+	OrganizationID string                 `json:"organization_id"`
+	ProjectID      string                 `json:"project_id,omitempty"`
+	Name           string                 `json:"name"`
+	Value          float64                `json:"value"`
+	Timestamp      *time.Time             `json:"timestamp,omitempty"` // defaults to now
+	Tags           map[string]interface{} `json:"tags,omitempty"`
+}
+
+// BatchMetricRequest records several metric data points at once
+type BatchMetricRequest struct {
+	Metrics []MetricRequest `json:"metrics"`
+}
+
+// BatchMetricResponse reports which data points of a batch were stored
+type BatchMetricResponse struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// CustomMetricQuery aggregates one custom metric over time. MetricName and Granularity
+// of the embedded MetricQuery choose the metric and the bucket interval.
+type CustomMetricQuery struct {
+	MetricQuery
+	Aggregation string            `json:"aggregation"`        // sum, avg, min, max, count, rate, p50, p90, p95, p99
+	GroupBy     string            `json:"group_by,omitempty"` // a tag key, or project
+	Tags        map[string]string `json:"tags,omitempty"`     // exact tag value filters
+	Source      string            `json:"source"`             // metrics, metrics_hourly
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Erasure subject types
+const (
+	ErasureSubjectUser  = "user"
+	ErasureSubjectTrace = "trace"
+)
+
+// ErasureFilter selects the traces removed by an erasure; exactly one of UserID, UserIDHash
+// or TraceID is set. UserIDHash is the hashed subject of a user erasure's audit record, so
+// an unfinished erasure can be resumed without the raw user ID.
+type ErasureFilter struct {
+	OrganizationID string `json:"organization_id"`
+	UserID         string `json:"user_id,omitempty"`
+	UserIDHash     string `json:"user_id_hash,omitempty"`
+	TraceID        string `json:"trace_id,omitempty"`
+}
+
+// ErasureCounts reports how many rows an erasure affects
+type ErasureCounts struct {
+	Traces int64 `json:"traces"`
+	Spans  int64 `json:"spans"`
+}
+
+// ErasureRecord is the audit record of a right-to-erasure request.
+// User IDs are stored as a SHA-256 hash so the audit log holds no personal data.
+type ErasureRecord struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	SubjectType    string     `json:"subject_type"` // user, trace
+	SubjectID      string     `json:"subject_id"`
+	RequestedBy    string     `json:"requested_by"`
+	Status         string     `json:"status"`
+	TracesDeleted  int64      `json:"traces_deleted"`
+	SpansDeleted   int64      `json:"spans_deleted"`
+	Error          string     `json:"error,omitempty"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
@@ -17,14 +17,21 @@ type MetricsSummary struct {
 }
 
 type ModelUsage struct {
-    Model        string  `json:"model"`
-    Provider     string  `json:"provider"`
-    Count        int64   `json:"count"`
-    CallCount    int64   `json:"call_count"`
-    TotalCost    float64 `json:"total_cost"`
-    AvgTokens    float64 `json:"avg_tokens"`
-    TotalTokens  int64   `json:"total_tokens"`
-    AvgLatency   float64 `json:"avg_latency"`
+    Model            string  `json:"model"`
+    Provider         string  `json:"provider"`
+    Count            int64   `json:"count"`
+    CallCount        int64   `json:"call_count"`
+    ErrorCount       int64   `json:"error_count"`
+    TotalCost        float64 `json:"total_cost"`
+    AvgTokens        float64 `json:"avg_tokens"`
+    TotalTokens      int64   `json:"total_tokens"`
+    PromptTokens     int64   `json:"prompt_tokens"`
+    CompletionTokens int64   `json:"completion_tokens"`
+    AvgLatency       float64 `json:"avg_latency"`
+    LatencyStdDev    float64 `json:"latency_stddev"`
+    P50LatencyMs     float64 `json:"p50_latency_ms"`
+    P95LatencyMs     float64 `json:"p95_latency_ms"`
+    P99LatencyMs     float64 `json:"p99_latency_ms"`
 }
 
 type ProviderCost struct {
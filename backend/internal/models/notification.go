@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Notification channel types
+const (
+	ChannelTypeWebhook = "webhook" // JSON POST signed with HMAC-SHA256
+	ChannelTypeSlack   = "slack"   // Slack-format incoming webhook
+	ChannelTypeEmail   = "email"   // SMTP
+	ChannelTypeEvents  = "events"  // PagerDuty Events API v2 style incident events
+)
+
+// Notification kinds: what a notification is about
+const (
+	NotificationKindAlert  = "alert"
+	NotificationKindBudget = "budget"
+	NotificationKindTest   = "test"
+)
+
+// Notification delivery outcomes
+const (
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// NotificationChannelConfig holds the settings of every channel type; each type uses
+// only some of them
+type NotificationChannelConfig struct {
+	URL        string   `json:"url,omitempty"`         // webhook, slack and events
+	Secret     string   `json:"secret,omitempty"`      // webhook signing key
+	RoutingKey string   `json:"routing_key,omitempty"` // events integration key
+	SMTPHost   string   `json:"smtp_host,omitempty"`
+	SMTPPort   int      `json:"smtp_port,omitempty"`
+	Username   string   `json:"username,omitempty"`
+	Password   string   `json:"password,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         []string `json:"to,omitempty"`
+}
+
+// NotificationChannel is somewhere an organization's alerts and budget warnings are sent.
+// Template is a text/template over the Notification that replaces the type's default message.
+type NotificationChannel struct {
+	ID             string                    `json:"id"`
+	OrganizationID string                    `json:"organization_id"`
+	Name           string                    `json:"name"`
+	Type           string                    `json:"type"`
+	Config         NotificationChannelConfig `json:"config"`
+	Template       string                    `json:"template,omitempty"`
+	Kinds          []string                  `json:"kinds"` // notification kinds sent; all when empty
+	Enabled        bool                      `json:"enabled"`
+	CreatedBy      string                    `json:"created_by,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	UpdatedAt      time.Time                 `json:"updated_at"`
+	Deleted        bool                      `json:"-"`
+}
+
+// NotificationChannelRequest creates a channel, or updates the one with the given ID.
+// Secrets left masked keep their stored value.
+type NotificationChannelRequest struct {
+	ID       string                    `json:"id,omitempty"`
+	Name     string                    `json:"name"`
+	Type     string                    `json:"type"`
+	Config   NotificationChannelConfig `json:"config"`
+	Template string                    `json:"template,omitempty"`
+	Kinds    []string                  `json:"kinds,omitempty"`
+	Enabled  *bool                     `json:"enabled,omitempty"` // defaults to true
+}
+
+// Notification is a message about an alert or budget sent to an organization's channels
+type Notification struct {
+	Kind           string            `json:"kind"`
+	OrganizationID string            `json:"organization_id"`
+	ProjectID      string            `json:"project_id,omitempty"`
+	Title          string            `json:"title"`
+	Message        string            `json:"message"`
+	Severity       string            `json:"severity"` // info, warning or critical
+	State          string            `json:"state"`    // firing or resolved for alerts; the budget state for budgets
+	DedupKey       string            `json:"dedup_key"`
+	Fields         map[string]string `json:"fields,omitempty"`
+	Timestamp      time.Time         `json:"timestamp"`
+}
+
+// NotificationDelivery records sending one notification to one channel
+type NotificationDelivery struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	ChannelID      string    `json:"channel_id"`
+	ChannelType    string    `json:"channel_type"`
+	Kind           string    `json:"kind"`
+	Title          string    `json:"title"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	CompletedAt    time.Time `json:"completed_at"`
+}
+
+// NotificationDeliveryQuery filters an organization's delivery log
+type NotificationDeliveryQuery struct {
+	OrganizationID string    `json:"organization_id"`
+	ChannelID      string    `json:"channel_id,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	Limit          int       `json:"limit"`
+}
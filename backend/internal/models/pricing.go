@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Price sources. Price entries come from the catalog or an admin; spans and traces also
+// record the scope of the price, or that the client sent its own cost.
+const (
+	PriceSourceCatalog      = "catalog"
+	PriceSourceAdmin        = "admin"
+	PriceSourceOrganization = "organization"
+	PriceSourceProject      = "project"
+	PriceSourceClient       = "client"
+	PriceSourceMixed        = "mixed" // a trace whose spans were priced from different sources
+)
+
+// ModelPrice is the list price of a model from a given date, in USD per million tokens.
+// A price applies until the next entry for the same provider and model takes effect.
+// Entries with an organization or project override the global entries for its calls.
+type ModelPrice struct {
+	OrganizationID   string   `json:"organization_id,omitempty"`
+	ProjectID        string   `json:"project_id,omitempty"`
+	Provider         string   `json:"provider"`
+	Model            string   `json:"model"`
+	Aliases          []string `json:"aliases,omitempty"` // other names billed at this price
+	MatchPrefix      bool     `json:"match_prefix"`      // also price dated snapshots such as gpt-4o-2024-08-06
+	InputPerMillion  float64  `json:"input_per_million"`
+	OutputPerMillion float64  `json:"output_per_million"`
+	// Rates of usage billed differently; 0 bills cached and image tokens at the input
+	// rate and reasoning tokens at the output rate
+	CachedInputPerMillion float64   `json:"cached_input_per_million,omitempty"`
+	ReasoningPerMillion   float64   `json:"reasoning_per_million,omitempty"`
+	ImageInputPerMillion  float64   `json:"image_input_per_million,omitempty"`
+	AudioPerMinute        float64   `json:"audio_per_minute,omitempty"`
+	BatchDiscount         float64   `json:"batch_discount,omitempty"` // fraction taken off batch API calls
+	EffectiveFrom         time.Time `json:"effective_from"`
+	Source                string    `json:"source"` // catalog, admin
+	CatalogVersion        int       `json:"catalog_version,omitempty"`
+	UpdatedBy             string    `json:"updated_by,omitempty"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// ModelPriceRequest adds or updates a price; an existing entry with the same scope,
+// provider, model and effective date is replaced
+type ModelPriceRequest struct {
+	OrganizationID        string     `json:"organization_id,omitempty"`
+	ProjectID             string     `json:"project_id,omitempty"`
+	Provider              string     `json:"provider"`
+	Model                 string     `json:"model"`
+	Aliases               []string   `json:"aliases,omitempty"`
+	MatchPrefix           bool       `json:"match_prefix"`
+	InputPerMillion       float64    `json:"input_per_million"`
+	OutputPerMillion      float64    `json:"output_per_million"`
+	CachedInputPerMillion float64    `json:"cached_input_per_million,omitempty"`
+	ReasoningPerMillion   float64    `json:"reasoning_per_million,omitempty"`
+	ImageInputPerMillion  float64    `json:"image_input_per_million,omitempty"`
+	AudioPerMinute        float64    `json:"audio_per_minute,omitempty"`
+	BatchDiscount         float64    `json:"batch_discount,omitempty"`
+	EffectiveFrom         *time.Time `json:"effective_from,omitempty"` // defaults to now
+}
+
+// TokenUsage is the billable usage of a call. Cached and image tokens are part of the
+// prompt tokens and reasoning tokens part of the completion tokens, as providers report them.
+type TokenUsage struct {
+	PromptTokens       int
+	CompletionTokens   int
+	CachedPromptTokens int
+	ReasoningTokens    int
+	ImageTokens        int
+	AudioSeconds       float64
+	Batch              bool
+}
+
+// CostComponents splits the cost of calls by usage category, in USD. The categories add up
+// to the total cost; the savings are what caching and batching took off list prices.
+type CostComponents struct {
+	Input        float64 `json:"input"`
+	CachedInput  float64 `json:"cached_input"`
+	Output       float64 `json:"output"`
+	Reasoning    float64 `json:"reasoning"`
+	Image        float64 `json:"image"`
+	Audio        float64 `json:"audio"`
+	CacheSavings float64 `json:"cache_savings"`
+	BatchSavings float64 `json:"batch_savings"`
+}
@@ -14,6 +14,7 @@ type TraceQuery struct {
     EndTime        time.Time `json:"end_time"`
     Limit          int       `json:"limit"`
     Offset         int       `json:"offset"`
+    Currency       string    `json:"currency,omitempty"` // display currency for exports; USD when empty
 }
 
 // Metric represents a single metric data point
@@ -50,8 +51,13 @@ type CostBreakdown struct {
     ByModel        []ModelCost          `json:"by_model"`
     ByProvider     []ProviderCost       `json:"by_provider"`
     ByProject      []ProjectCost        `json:"by_project"`
+    ByUser         []UserCost           `json:"by_user"`
+    ByTag          []TagCost            `json:"by_tag"`
     DailyCosts     []DailyCost          `json:"daily_costs"`
     TopExpensive   []ExpensiveTrace     `json:"top_expensive"`
+    Components     CostComponents       `json:"components"`
+    UsageByModel   []ModelUsageCost     `json:"usage_by_model"`
+    ByPriceSource  []PriceSourceCost    `json:"by_price_source"`
 }
 
 // ModelCost represents cost breakdown by model
@@ -72,6 +78,44 @@ type ProjectCost struct {
     RequestCount int64   `json:"request_count"`
 }
 
+// ModelUsageCost represents the usage categories of a model's calls and their cost
+type ModelUsageCost struct {
+    Model              string         `json:"model"`
+    Provider           string         `json:"provider"`
+    PromptTokens       int64          `json:"prompt_tokens"`
+    CachedPromptTokens int64          `json:"cached_prompt_tokens"`
+    CompletionTokens   int64          `json:"completion_tokens"`
+    ReasoningTokens    int64          `json:"reasoning_tokens"`
+    ImageTokens        int64          `json:"image_tokens"`
+    AudioSeconds       float64        `json:"audio_seconds"`
+    BatchRequests      int64          `json:"batch_requests"`
+    CacheHitRate       float64        `json:"cache_hit_rate"` // % of prompt tokens read from a prompt cache
+    Costs              CostComponents `json:"costs"`
+}
+
+// PriceSourceCost represents the cost of the spans priced from one source
+type PriceSourceCost struct {
+    Source    string  `json:"source"` // catalog, admin, organization, project, client; empty when unpriced
+    TotalCost float64 `json:"total_cost"`
+    SpanCount int64   `json:"span_count"`
+}
+
+// UserCost represents cost breakdown by end user
+type UserCost struct {
+    UserID       string  `json:"user_id"`
+    TotalCost    float64 `json:"total_cost"`
+    RequestCount int64   `json:"request_count"`
+    TotalTokens  int64   `json:"total_tokens"`
+}
+
+// TagCost represents cost breakdown by the value of a metadata tag
+type TagCost struct {
+    Key          string  `json:"key"`
+    Value        string  `json:"value"`
+    TotalCost    float64 `json:"total_cost"`
+    RequestCount int64   `json:"request_count"`
+}
+
 // DailyCost represents daily cost data
 type DailyCost struct {
     Date         string  `json:"date"`
@@ -10,40 +10,56 @@ type Trace struct {
     Model          string                 `json:"model" ch:"model"`
     Provider       string                 `json:"provider" ch:"provider"`
     UserID         string                 `json:"user_id,omitempty" ch:"user_id"`
+    APIKeyID       string                 `json:"api_key_id,omitempty" ch:"api_key_id"`
     Status         string                 `json:"status" ch:"status"`
     TotalTokens    int                    `json:"total_tokens" ch:"total_tokens"`
     TotalCost      float64                `json:"total_cost" ch:"total_cost"`
     TotalCostUSD   float64                `json:"total_cost_usd" ch:"total_cost_usd"`
+    PriceSource    string                 `json:"price_source,omitempty" ch:"price_source"`
     DurationMs     int64                  `json:"duration_ms" ch:"duration_ms"`
     Metadata       map[string]string      `json:"metadata,omitempty"`
     Tags           map[string]string      `json:"tags,omitempty"`
     Timestamp      time.Time              `json:"timestamp" ch:"timestamp"`
     CreatedAt      time.Time              `json:"created_at" ch:"created_at"`
     Spans          []Span                 `json:"spans,omitempty"`
+
+    // Set when costs are converted into a display currency on read
+    Currency           string   `json:"currency,omitempty"`
+    ExchangeRate       float64  `json:"exchange_rate,omitempty"` // units of currency per USD on the trace's day
+    TotalCostConverted *float64 `json:"total_cost_converted,omitempty"`
 }
 
 type Span struct {
-    SpanID           string            `json:"span_id" ch:"span_id"`
-    TraceID          string            `json:"trace_id" ch:"trace_id"`
-    ParentSpanID     string            `json:"parent_span_id,omitempty" ch:"parent_span_id"`
-    Name             string            `json:"name" ch:"name"`
-    Model            string            `json:"model" ch:"model"`
-    Provider         string            `json:"provider" ch:"provider"`
-    Input            string            `json:"input" ch:"input"`
-    Output           string            `json:"output" ch:"output"`
-    PromptTokens     int               `json:"prompt_tokens" ch:"prompt_tokens"`
-    CompletionTokens int               `json:"completion_tokens" ch:"completion_tokens"`
-    TotalTokens      int               `json:"total_tokens" ch:"total_tokens"`
-    Cost             float64           `json:"cost" ch:"cost"`
-    CostUSD          float64           `json:"cost_usd" ch:"cost_usd"`
-    DurationMs       int64             `json:"duration_ms" ch:"duration_ms"`
-    StartTime        time.Time         `json:"start_time" ch:"start_time"`
-    EndTime          time.Time         `json:"end_time" ch:"end_time"`
-    Status           string            `json:"status" ch:"status"`
-    ErrorMessage     string            `json:"error_message,omitempty" ch:"error_message"`
-    Metadata         map[string]string `json:"metadata,omitempty"`
-    Tags             map[string]string `json:"tags,omitempty"`
-    CreatedAt        time.Time         `json:"created_at" ch:"created_at"`
+    SpanID             string            `json:"span_id" ch:"span_id"`
+    TraceID            string            `json:"trace_id" ch:"trace_id"`
+    ParentSpanID       string            `json:"parent_span_id,omitempty" ch:"parent_span_id"`
+    Name               string            `json:"name" ch:"name"`
+    Model              string            `json:"model" ch:"model"`
+    Provider           string            `json:"provider" ch:"provider"`
+    Input              string            `json:"input" ch:"input"`
+    Output             string            `json:"output" ch:"output"`
+    PromptTokens       int               `json:"prompt_tokens" ch:"prompt_tokens"`
+    CompletionTokens   int               `json:"completion_tokens" ch:"completion_tokens"`
+    TotalTokens        int               `json:"total_tokens" ch:"total_tokens"`
+    CachedPromptTokens int               `json:"cached_prompt_tokens,omitempty" ch:"cached_prompt_tokens"` // part of prompt_tokens read from a prompt cache
+    ReasoningTokens    int               `json:"reasoning_tokens,omitempty" ch:"reasoning_tokens"`         // part of completion_tokens
+    ImageTokens        int               `json:"image_tokens,omitempty" ch:"image_tokens"`                 // part of prompt_tokens
+    AudioSeconds       float64           `json:"audio_seconds,omitempty" ch:"audio_seconds"`
+    Batch              bool              `json:"batch,omitempty" ch:"batch"` // sent through a batch API
+    Cost               float64           `json:"cost" ch:"cost"`
+    CostUSD            float64           `json:"cost_usd" ch:"cost_usd"`
+    CostConverted      *float64          `json:"cost_converted,omitempty"` // cost_usd in the trace's display currency
+    CostComponents     CostComponents    `json:"cost_components"`
+    DurationMs         int64             `json:"duration_ms" ch:"duration_ms"`
+    StartTime          time.Time         `json:"start_time" ch:"start_time"`
+    EndTime            time.Time         `json:"end_time" ch:"end_time"`
+    Status             string            `json:"status" ch:"status"`
+    ErrorMessage       string            `json:"error_message,omitempty" ch:"error_message"`
+    Unpriced           bool              `json:"unpriced,omitempty" ch:"unpriced"` // no price was known for the model
+    PriceSource        string            `json:"price_source,omitempty" ch:"price_source"`
+    Metadata           map[string]string `json:"metadata,omitempty"`
+    Tags               map[string]string `json:"tags,omitempty"`
+    CreatedAt          time.Time         `json:"created_at" ch:"created_at"`
 }
 
 type CreateTraceRequest struct {
@@ -90,37 +106,45 @@ type TraceRequest struct {
     Tags             map[string]string `json:"tags,omitempty"`
     Metadata         map[string]string `json:"metadata,omitempty"`
     Spans            []SpanRequest     `json:"spans,omitempty"`
+    APIKeyID         string            `json:"-"` // set from the authenticated API key
 }
 
 // SpanRequest represents a span in TraceRequest
 type SpanRequest struct {
-    Name             string            `json:"name" validate:"required"`
-    ParentSpanID     string            `json:"parent_span_id,omitempty"`
-    Model            string            `json:"model" validate:"required"`
-    Provider         string            `json:"provider" validate:"required"`
-    Input            string            `json:"input" validate:"required"`
-    Output           string            `json:"output" validate:"required"`
-    PromptTokens     int               `json:"prompt_tokens" validate:"min=0"`
-    CompletionTokens int               `json:"completion_tokens" validate:"min=0"`
-    DurationMs       int64             `json:"duration_ms" validate:"min=0"`
-    Status           string            `json:"status" validate:"required"`
-    ErrorMessage     string            `json:"error_message,omitempty"`
-    Tags             map[string]string `json:"tags,omitempty"`
-    Metadata         map[string]string `json:"metadata,omitempty"`
+    Name               string            `json:"name" validate:"required"`
+    ParentSpanID       string            `json:"parent_span_id,omitempty"`
+    Model              string            `json:"model" validate:"required"`
+    Provider           string            `json:"provider" validate:"required"`
+    Input              string            `json:"input" validate:"required"`
+    Output             string            `json:"output" validate:"required"`
+    PromptTokens       int               `json:"prompt_tokens" validate:"min=0"`
+    CompletionTokens   int               `json:"completion_tokens" validate:"min=0"`
+    CachedPromptTokens int               `json:"cached_prompt_tokens,omitempty" validate:"min=0"`
+    ReasoningTokens    int               `json:"reasoning_tokens,omitempty" validate:"min=0"`
+    ImageTokens        int               `json:"image_tokens,omitempty" validate:"min=0"`
+    AudioSeconds       float64           `json:"audio_seconds,omitempty" validate:"min=0"`
+    Batch              bool              `json:"batch,omitempty"`
+    CostUSD            *float64          `json:"cost_usd,omitempty"` // the call's actual cost; takes precedence over prices
+    DurationMs         int64             `json:"duration_ms" validate:"min=0"`
+    Status             string            `json:"status" validate:"required"`
+    ErrorMessage       string            `json:"error_message,omitempty"`
+    Tags               map[string]string `json:"tags,omitempty"`
+    Metadata           map[string]string `json:"metadata,omitempty"`
 }
 
 // TraceResponse is returned after creating a trace
 type TraceResponse struct {
-    TraceID        string    `json:"trace_id"`
-    OrganizationID string    `json:"organization_id"`
-    ProjectID      string    `json:"project_id,omitempty"`
-    Model          string    `json:"model"`
-    Provider       string    `json:"provider"`
-    Status         string    `json:"status"`
-    TotalTokens    int       `json:"total_tokens"`
-    TotalCost      float64   `json:"total_cost"`
-    DurationMs     int64     `json:"duration_ms"`
-    Timestamp      string    `json:"timestamp"`
-    CreatedAt      string    `json:"created_at"`
-    Message        string    `json:"message,omitempty"`
+    TraceID        string       `json:"trace_id"`
+    OrganizationID string       `json:"organization_id"`
+    ProjectID      string       `json:"project_id,omitempty"`
+    Model          string       `json:"model"`
+    Provider       string       `json:"provider"`
+    Status         string       `json:"status"`
+    TotalTokens    int          `json:"total_tokens"`
+    TotalCost      float64      `json:"total_cost"`
+    DurationMs     int64        `json:"duration_ms"`
+    Timestamp      string       `json:"timestamp"`
+    CreatedAt      string       `json:"created_at"`
+    Message        string       `json:"message,omitempty"`
+    Budget         *BudgetCheck `json:"budget,omitempty"`
 }
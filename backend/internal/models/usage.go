@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Organization plans. Legacy is for organizations set up before plans were metered: no
+// quotas, and the API rate limit keys had before.
+const (
+	PlanLegacy     = "legacy"
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+// Metered quantities
+const (
+	UsageMetricTraces = "traces"
+	UsageMetricSpans  = "spans"
+	UsageMetricBytes  = "bytes"
+)
+
+// PlanLimits are the quotas of a plan; a zero quota is unlimited
+type PlanLimits struct {
+	Plan              string `json:"plan"`
+	TracesPerPeriod   int64  `json:"traces_per_period"`
+	SpansPerPeriod    int64  `json:"spans_per_period"`
+	BytesPerPeriod    int64  `json:"bytes_per_period"`
+	RequestsPerMinute int    `json:"requests_per_minute"`
+}
+
+// PlanRequest sets an organization's plan
+type PlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// UsageRecord is an organization's metered usage on one UTC day
+type UsageRecord struct {
+	OrganizationID string    `json:"organization_id"`
+	Date           time.Time `json:"date"`
+	Traces         int64     `json:"traces"`
+	Spans          int64     `json:"spans"`
+	Bytes          int64     `json:"bytes"`
+}
+
+// QuotaStatus is the consumption of one quota in the billing period
+type QuotaStatus struct {
+	Metric      string  `json:"metric"`
+	Used        int64   `json:"used"`
+	Limit       int64   `json:"limit,omitempty"` // zero when unlimited
+	Remaining   *int64  `json:"remaining,omitempty"`
+	PercentUsed float64 `json:"percent_used"`
+	Exceeded    bool    `json:"exceeded"`
+}
+
+// Usage is an organization's consumption against its plan in a billing period
+type Usage struct {
+	OrganizationID string        `json:"organization_id"`
+	Plan           string        `json:"plan"`
+	PeriodStart    time.Time     `json:"period_start"`
+	PeriodEnd      time.Time     `json:"period_end"`
+	Traces         int64         `json:"traces"`
+	Spans          int64         `json:"spans"`
+	Bytes          int64         `json:"bytes"`
+	Limits         PlanLimits    `json:"limits"`
+	Quotas         []QuotaStatus `json:"quotas"`
+}
+
+// UsageExportRecord is one line of the usage export fed to billing systems. ID is stable
+// across exports so a record can be upserted when a day is exported again.
+type UsageExportRecord struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id"`
+	Plan           string `json:"plan"`
+	Period         string `json:"period"` // YYYY-MM
+	Date           string `json:"date"`   // YYYY-MM-DD
+	Traces         int64  `json:"traces"`
+	Spans          int64  `json:"spans"`
+	Bytes          int64  `json:"bytes"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// UserAnalyticsQuery represents query parameters for end-user analytics
+type UserAnalyticsQuery struct {
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	UserID         string    `json:"user_id,omitempty"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	SortBy         string    `json:"sort_by,omitempty"`     // cost, tokens, requests, errors, latency
+	Granularity    string    `json:"granularity,omitempty"` // hour, day
+	Limit          int       `json:"limit"`
+}
+
+// UserStats represents aggregated usage for a single end user
+type UserStats struct {
+	UserID          string    `json:"user_id"`
+	RequestCount    int64     `json:"request_count"`
+	ErrorCount      int64     `json:"error_count"`
+	ErrorRate       float64   `json:"error_rate"`
+	TotalCost       float64   `json:"total_cost"`
+	TotalTokens     int64     `json:"total_tokens"`
+	AvgCostPerReq   float64   `json:"avg_cost_per_request"`
+	AvgLatencyMs    float64   `json:"avg_latency_ms"`
+	P50LatencyMs    float64   `json:"p50_latency_ms"`
+	P95LatencyMs    float64   `json:"p95_latency_ms"`
+	P99LatencyMs    float64   `json:"p99_latency_ms"`
+	RequestsPerHour float64   `json:"requests_per_hour"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+
+	// Costs in the display currency, at the effective rate over the query's range
+	Currency               string   `json:"currency,omitempty"`
+	ExchangeRate           float64  `json:"exchange_rate,omitempty"`
+	TotalCostConverted     *float64 `json:"total_cost_converted,omitempty"`
+	AvgCostPerReqConverted *float64 `json:"avg_cost_per_request_converted,omitempty"`
+}
+
+// UserTimeSeriesPoint represents a single end-user bucket over time
+type UserTimeSeriesPoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	RequestCount int64     `json:"request_count"`
+	ErrorCount   int64     `json:"error_count"`
+	ErrorRate    float64   `json:"error_rate"`
+	TotalCost    float64   `json:"total_cost"`
+	TotalTokens  int64     `json:"total_tokens"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	P50LatencyMs float64   `json:"p50_latency_ms"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+
+	TotalCostConverted *float64 `json:"total_cost_converted,omitempty"` // in the display currency
+}
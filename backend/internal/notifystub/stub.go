@@ -0,0 +1,186 @@
+// Package notifystub is a local receiver for notification channels: an HTTP endpoint for
+// webhook, Slack and events channels and a minimal SMTP server for email channels. It
+// records every message it receives, for tests and for trying channels out locally.
+package notifystub
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a notification the stub received
+type Message struct {
+	Protocol   string            `json:"protocol"` // http or smtp
+	Path       string            `json:"path,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	From       string            `json:"from,omitempty"`
+	To         []string          `json:"to,omitempty"`
+	Body       string            `json:"body"`
+	Signed     bool              `json:"signed"` // carried a valid webhook signature
+	ReceivedAt time.Time         `json:"received_at"`
+}
+
+// Server records the messages sent to it
+type Server struct {
+	// Secret verifies the X-Clarity-Signature of webhooks; unsigned requests are accepted too
+	Secret string
+	// OnMessage, if set, is called with every message received
+	OnMessage func(Message)
+
+	mu       sync.Mutex
+	messages []Message
+	failures []int // statuses to answer the next HTTP requests with
+}
+
+// NewServer creates a stub that verifies webhook signatures with secret
+func NewServer(secret string) *Server {
+	return &Server{Secret: secret}
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// FailNext makes the next HTTP requests fail with the given statuses, one each
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// ServeHTTP records a request. Requests are answered 200 unless a failure is queued.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	s.mu.Unlock()
+
+	headers := make(map[string]string, len(r.Header))
+	for name := range r.Header {
+		headers[name] = r.Header.Get(name)
+	}
+	s.record(Message{
+		Protocol: "http",
+		Path:     r.URL.Path,
+		Headers:  headers,
+		Body:     string(body),
+		Signed:   s.verify(r.Header.Get("X-Clarity-Timestamp"), r.Header.Get("X-Clarity-Signature"), body),
+	})
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "ok")
+}
+
+// ServeSMTP accepts SMTP connections on l until it is closed
+func (s *Server) ServeSMTP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleSMTP(conn)
+	}
+}
+
+// handleSMTP speaks just enough SMTP for net/smtp.SendMail, accepting any credentials
+func (s *Server) handleSMTP(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 notifystub ESMTP")
+
+	var msg Message
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			text.PrintfLine("250-notifystub")
+			text.PrintfLine("250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			text.PrintfLine("250 OK")
+		case "AUTH":
+			text.PrintfLine("235 Authenticated")
+		case "RSET":
+			msg = Message{}
+			text.PrintfLine("250 OK")
+		case "MAIL":
+			msg = Message{From: smtpAddress(arg)}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, smtpAddress(arg))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(bufio.NewReader(text.DotReader()))
+			if err != nil {
+				return
+			}
+			msg.Protocol = "smtp"
+			msg.Body = string(data)
+			s.record(msg)
+			msg = Message{}
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *Server) record(msg Message) {
+	msg.ReceivedAt = time.Now()
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	if s.OnMessage != nil {
+		s.OnMessage(msg)
+	}
+}
+
+// verify checks a webhook signature: the hex HMAC-SHA256 of "<timestamp>.<body>"
+func (s *Server) verify(timestamp, signature string, body []byte) bool {
+	if s.Secret == "" || timestamp == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal([]byte(signature), []byte("sha256="+hex.EncodeToString(mac.Sum(nil))))
+}
+
+// smtpAddress extracts the address of a MAIL FROM:<...> or RCPT TO:<...> argument
+func smtpAddress(arg string) string {
+	if start, end := strings.Index(arg, "<"), strings.Index(arg, ">"); start >= 0 && end > start {
+		return arg[start+1 : end]
+	}
+	_, addr, _ := strings.Cut(arg, ":")
+	return strings.TrimSpace(addr)
+}
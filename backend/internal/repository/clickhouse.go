@@ -51,8 +51,9 @@ func (r *ClickHouseRepository) SaveTrace(ctx context.Context, trace *models.Trac
         INSERT INTO traces (
             trace_id, organization_id, project_id, timestamp, 
             trace_type, duration_ms, status, total_cost_usd, 
-            total_tokens, model, provider, user_id, metadata
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            total_tokens, model, provider, user_id, metadata, price_source,
+            api_key_id
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
     err := r.conn.Exec(ctx, query,
@@ -69,6 +70,8 @@ func (r *ClickHouseRepository) SaveTrace(ctx context.Context, trace *models.Trac
         trace.Provider,
         trace.UserID,
         metadataJSON,
+        trace.PriceSource,
+        trace.APIKeyID,
     )
 
     if err != nil {
@@ -76,21 +79,17 @@ func (r *ClickHouseRepository) SaveTrace(ctx context.Context, trace *models.Trac
     }
 
     // Save spans
-    return r.SaveSpans(ctx, trace.Spans)
+    return r.SaveSpans(ctx, trace.OrganizationID, trace.Spans)
 }
 
-// SaveSpans stores spans in ClickHouse
-func (r *ClickHouseRepository) SaveSpans(ctx context.Context, spans []models.Span) error {
+// SaveSpans stores spans of the organization's traces in ClickHouse
+func (r *ClickHouseRepository) SaveSpans(ctx context.Context, orgID string, spans []models.Span) error {
     if len(spans) == 0 {
         return nil
     }
 
     batch, err := r.conn.PrepareBatch(ctx, `
-        INSERT INTO spans (
-            span_id, trace_id, parent_span_id, name, start_time, end_time,
-            duration_ms, model, provider, input, output, prompt_tokens,
-            completion_tokens, total_tokens, cost_usd, metadata
-        )
+        INSERT INTO spans (organization_id, ` + spanColumns + `)
     `)
     if err != nil {
         return fmt.Errorf("failed to prepare batch: %w", err)
@@ -104,7 +103,16 @@ func (r *ClickHouseRepository) SaveSpans(ctx context.Context, spans []models.Spa
             }
         }
 
+        var unpriced, batchCall uint8
+        if span.Unpriced {
+            unpriced = 1
+        }
+        if span.Batch {
+            batchCall = 1
+        }
+
         err := batch.Append(
+            orgID,
             span.SpanID,
             span.TraceID,
             span.ParentSpanID,
@@ -120,7 +128,24 @@ func (r *ClickHouseRepository) SaveSpans(ctx context.Context, spans []models.Spa
             span.CompletionTokens,
             span.TotalTokens,
             span.CostUSD,
+            span.Status,
+            span.ErrorMessage,
             metadataJSON,
+            unpriced,
+            uint32(span.CachedPromptTokens),
+            uint32(span.ReasoningTokens),
+            uint32(span.ImageTokens),
+            span.AudioSeconds,
+            batchCall,
+            span.CostComponents.Input,
+            span.CostComponents.CachedInput,
+            span.CostComponents.Output,
+            span.CostComponents.Reasoning,
+            span.CostComponents.Image,
+            span.CostComponents.Audio,
+            span.CostComponents.CacheSavings,
+            span.CostComponents.BatchSavings,
+            span.PriceSource,
         )
         if err != nil {
             return fmt.Errorf("failed to append span: %w", err)
@@ -131,19 +156,16 @@ func (r *ClickHouseRepository) SaveSpans(ctx context.Context, spans []models.Spa
 }
 
 
-// GetSpansByTraceID retrieves all spans for a trace
-func (r *ClickHouseRepository) GetSpansByTraceID(ctx context.Context, traceID string) ([]models.Span, error) {
+// GetSpansByTraceID retrieves all spans for a trace of the organization
+func (r *ClickHouseRepository) GetSpansByTraceID(ctx context.Context, orgID, traceID string) ([]models.Span, error) {
     query := `
-        SELECT 
-            span_id, trace_id, parent_span_id, name, start_time, end_time,
-            duration_ms, model, provider, input, output, prompt_tokens,
-            completion_tokens, total_tokens, cost_usd, metadata
+        SELECT ` + spanColumns + `
         FROM spans
-        WHERE trace_id = ?
+        WHERE organization_id = ? AND trace_id = ?
         ORDER BY start_time ASC
     `
 
-    rows, err := r.conn.Query(ctx, query, traceID)
+    rows, err := r.conn.Query(ctx, query, orgID, traceID)
     if err != nil {
         return nil, fmt.Errorf("failed to query spans: %w", err)
     }
@@ -151,41 +173,91 @@ func (r *ClickHouseRepository) GetSpansByTraceID(ctx context.Context, traceID st
 
     var spans []models.Span
     for rows.Next() {
-        var span models.Span
-        var metadataJSON string
-
-        err := rows.Scan(
-            &span.SpanID,
-            &span.TraceID,
-            &span.ParentSpanID,
-            &span.Name,
-            &span.StartTime,
-            &span.EndTime,
-            &span.DurationMs,
-            &span.Model,
-            &span.Provider,
-            &span.Input,
-            &span.Output,
-            &span.PromptTokens,
-            &span.CompletionTokens,
-            &span.TotalTokens,
-            &span.CostUSD,
-            &metadataJSON,
-        )
+        span, err := scanSpan(rows)
         if err != nil {
-            return nil, fmt.Errorf("failed to scan span: %w", err)
-        }
-
-        if metadataJSON != "" && metadataJSON != "{}" {
-            json.Unmarshal([]byte(metadataJSON), &span.Metadata)
+            return nil, err
         }
-
         spans = append(spans, span)
     }
 
     return spans, nil
 }
 
+// spanColumns lists the spans columns in the order SaveSpans writes and scanSpan reads them
+const spanColumns = `
+            span_id, trace_id, parent_span_id, name, start_time, end_time,
+            duration_ms, model, provider, input, output, prompt_tokens,
+            completion_tokens, total_tokens, cost_usd, status, error_message,
+            metadata, unpriced, cached_prompt_tokens, reasoning_tokens,
+            image_tokens, audio_seconds, batch, input_cost_usd, cached_input_cost_usd,
+            output_cost_usd, reasoning_cost_usd, image_cost_usd, audio_cost_usd,
+            cache_savings_usd, batch_savings_usd, price_source`
+
+// scanSpan scans a spans row selected with spanColumns
+func scanSpan(rows rowScanner) (models.Span, error) {
+    var span models.Span
+    var metadataJSON string
+    var durationMs, promptTokens, completionTokens, totalTokens uint32
+    var cachedPromptTokens, reasoningTokens, imageTokens uint32
+    var unpriced, batchCall uint8
+
+    err := rows.Scan(
+        &span.SpanID,
+        &span.TraceID,
+        &span.ParentSpanID,
+        &span.Name,
+        &span.StartTime,
+        &span.EndTime,
+        &durationMs,
+        &span.Model,
+        &span.Provider,
+        &span.Input,
+        &span.Output,
+        &promptTokens,
+        &completionTokens,
+        &totalTokens,
+        &span.CostUSD,
+        &span.Status,
+        &span.ErrorMessage,
+        &metadataJSON,
+        &unpriced,
+        &cachedPromptTokens,
+        &reasoningTokens,
+        &imageTokens,
+        &span.AudioSeconds,
+        &batchCall,
+        &span.CostComponents.Input,
+        &span.CostComponents.CachedInput,
+        &span.CostComponents.Output,
+        &span.CostComponents.Reasoning,
+        &span.CostComponents.Image,
+        &span.CostComponents.Audio,
+        &span.CostComponents.CacheSavings,
+        &span.CostComponents.BatchSavings,
+        &span.PriceSource,
+    )
+    if err != nil {
+        return span, fmt.Errorf("failed to scan span: %w", err)
+    }
+
+    // Convert types
+    span.DurationMs = int64(durationMs)
+    span.PromptTokens = int(promptTokens)
+    span.CompletionTokens = int(completionTokens)
+    span.TotalTokens = int(totalTokens)
+    span.CachedPromptTokens = int(cachedPromptTokens)
+    span.ReasoningTokens = int(reasoningTokens)
+    span.ImageTokens = int(imageTokens)
+    span.Unpriced = unpriced == 1
+    span.Batch = batchCall == 1
+
+    if metadataJSON != "" && metadataJSON != "{}" {
+        json.Unmarshal([]byte(metadataJSON), &span.Metadata)
+    }
+
+    return span, nil
+}
+
 // GetTraces retrieves traces with filtering
 
 
@@ -230,14 +302,35 @@ func (r *ClickHouseRepository) Ping(ctx context.Context) error {
     return r.conn.Ping(ctx)
 }
 
-// CreateOrganization - stub for now (Phase 2)
+// CreateOrganization stores an organization; saving it again records a new version
 func (r *ClickHouseRepository) CreateOrganization(ctx context.Context, org *models.Organization) error {
-    return fmt.Errorf("not implemented yet - Phase 2 feature")
+    err := r.conn.Exec(ctx, `
+        INSERT INTO organizations (id, name, plan, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, org.ID, org.Name, org.Plan, org.CreatedAt, org.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to create organization: %w", err)
+    }
+    return nil
 }
 
-// GetOrganization - stub for now (Phase 2)
+// GetOrganization retrieves the latest version of an organization
 func (r *ClickHouseRepository) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 2 feature")
+    var org models.Organization
+    err := r.conn.QueryRow(ctx, `
+        SELECT id, name, plan, created_at, updated_at
+        FROM organizations
+        WHERE id = ?
+        ORDER BY updated_at DESC
+        LIMIT 1
+    `, id).Scan(&org.ID, &org.Name, &org.Plan, &org.CreatedAt, &org.UpdatedAt)
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to get organization: %w", err)
+    }
+    return &org, nil
 }
 
 // CreateProject - stub for now (Phase 2)
@@ -268,89 +361,41 @@ func (r *ClickHouseRepository) CreateAPIKey(ctx context.Context, apiKey *models.
 // GetAPIKey - stub for now (Phase 2)
 
 
-// GetCostBreakdown - stub for now (Phase 4)
-func (r *ClickHouseRepository) GetCostBreakdown(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.CostBreakdown, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 4 feature")
-}
-
 // GetMetricSummary retrieves aggregated metrics
 func (r *ClickHouseRepository) GetMetricSummary(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) (*models.MetricSummary, error) {
     period := fmt.Sprintf("%s to %s", startTime.Format("2006-01-02"), endTime.Format("2006-01-02"))
 
-    var totalRequests int64
-    err := r.conn.QueryRow(ctx, `
-        SELECT count() FROM traces 
-        WHERE organization_id = ? 
-        AND timestamp >= ? AND timestamp <= ?
-    `, orgID, startTime, endTime).Scan(&totalRequests)
-    
-    if err != nil {
-        totalRequests = 0
-    }
-
-    var totalTokens int64
-    var totalCost float64
-    err = r.conn.QueryRow(ctx, `
-        SELECT 
-            sum(total_tokens) as total_tokens,
-            sum(total_cost_usd) as total_cost
-        FROM traces 
-        WHERE organization_id = ? 
-        AND timestamp >= ? AND timestamp <= ?
-    `, orgID, startTime, endTime).Scan(&totalTokens, &totalCost)
-    
-    if err != nil {
-        totalTokens = 0
-        totalCost = 0
-    }
-
-    var avgLatency, p50, p95, p99 float64
-    err = r.conn.QueryRow(ctx, `
-        SELECT 
-            avg(duration_ms),
-            quantile(0.50)(duration_ms),
-            quantile(0.95)(duration_ms),
-            quantile(0.99)(duration_ms)
-        FROM traces 
-        WHERE organization_id = ? 
-        AND timestamp >= ? AND timestamp <= ?
-    `, orgID, startTime, endTime).Scan(&avgLatency, &p50, &p95, &p99)
-    
+    totals, err := r.GetTraceTotals(ctx, &models.AnalyticsQuery{
+        OrganizationID: orgID,
+        ProjectID:      projectID,
+        StartTime:      startTime,
+        EndTime:        endTime,
+    })
     if err != nil {
-        avgLatency, p50, p95, p99 = 0, 0, 0, 0
+        return nil, err
     }
-
-    var successCount int64
-    r.conn.QueryRow(ctx, `
-        SELECT count() FROM traces 
-        WHERE organization_id = ? 
-        AND timestamp >= ? AND timestamp <= ?
-        AND status = 'success'
-    `, orgID, startTime, endTime).Scan(&successCount)
+    t := totals[0]
 
     successRate := float64(0)
     errorRate := float64(0)
-    if totalRequests > 0 {
-        successRate = (float64(successCount) / float64(totalRequests)) * 100
-        errorRate = 100 - successRate
-    }
-
     avgCostPerReq := float64(0)
-    if totalRequests > 0 {
-        avgCostPerReq = totalCost / float64(totalRequests)
+    if t.TraceCount > 0 {
+        successRate = (float64(t.SuccessCount) / float64(t.TraceCount)) * 100
+        errorRate = 100 - successRate
+        avgCostPerReq = t.TotalCost / float64(t.TraceCount)
     }
 
     return &models.MetricSummary{
         Period:            period,
-        TotalRequests:     totalRequests,
-        TotalTokens:       totalTokens,
-        TotalCost:         totalCost,
-        TotalCostUSD:      totalCost,
+        TotalRequests:     t.TraceCount,
+        TotalTokens:       t.TotalTokens,
+        TotalCost:         t.TotalCost,
+        TotalCostUSD:      t.TotalCost,
         AvgCostPerRequest: avgCostPerReq,
-        AvgLatencyMs:      avgLatency,
-        P50LatencyMs:      p50,
-        P95LatencyMs:      p95,
-        P99LatencyMs:      p99,
+        AvgLatencyMs:      t.AvgLatencyMs,
+        P50LatencyMs:      t.P50LatencyMs,
+        P95LatencyMs:      t.P95LatencyMs,
+        P99LatencyMs:      t.P99LatencyMs,
         ErrorRate:         errorRate,
         SuccessRate:       successRate,
         TopModels:         []models.ModelUsage{},
@@ -359,29 +404,9 @@ func (r *ClickHouseRepository) GetMetricSummary(ctx context.Context, orgID, proj
     }, nil
 }
 
-// GetMetrics - stub for now (Phase 4)
-func (r *ClickHouseRepository) GetMetrics(ctx context.Context, query *models.MetricQuery) ([]*models.Metric, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 4 feature")
-}
-
-// GetModelUsage - stub for now (Phase 4)
-func (r *ClickHouseRepository) GetModelUsage(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ModelUsage, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 4 feature")
-}
-
-// GetProviderMetrics - stub for now (Phase 4)
-func (r *ClickHouseRepository) GetProviderMetrics(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ProviderMetrics, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 4 feature")
-}
-
-// GetTimeSeries - stub for now (Phase 4)
-func (r *ClickHouseRepository) GetTimeSeries(ctx context.Context, orgID, projectID string, startTime, endTime time.Time, interval string) ([]*models.TimeSeriesPoint, error) {
-    return nil, fmt.Errorf("not implemented yet - Phase 4 feature")
-}
-
 // SaveSpan - single span save (we have SaveSpans for batch)
-func (r *ClickHouseRepository) SaveSpan(ctx context.Context, span *models.Span) error {
-    return r.SaveSpans(ctx, []models.Span{*span})
+func (r *ClickHouseRepository) SaveSpan(ctx context.Context, orgID string, span *models.Span) error {
+    return r.SaveSpans(ctx, orgID, []models.Span{*span})
 }
 
 // GetUserByID - stub for now (Phase 2)
@@ -394,10 +419,6 @@ func (r *ClickHouseRepository) GetProjectsByOrg(ctx context.Context, orgID strin
     return nil, fmt.Errorf("not implemented yet - Phase 2 feature")
 }
 
-// Query - raw query execution
-func (r *ClickHouseRepository) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-    return nil, fmt.Errorf("raw queries not supported - use specific methods")
-}
 // GetAPIKey - simple demo implementation (Phase 2 will add real DB)
 func (r *ClickHouseRepository) GetAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
     // For development - accept "demo-key"
@@ -416,36 +437,13 @@ func (r *ClickHouseRepository) GetAPIKey(ctx context.Context, key string) (*mode
 }
 // GetTraces retrieves traces with filtering
 func (r *ClickHouseRepository) GetTraces(ctx context.Context, query *models.TraceQuery) ([]*models.Trace, error) {
+    where, args := traceFilter(query)
     sql := `
         SELECT 
             trace_id, organization_id, project_id, timestamp,
             trace_type, duration_ms, status, total_cost_usd,
-            total_tokens, model, provider, user_id
-        FROM traces
-        WHERE organization_id = ?
-    `
-
-    args := []interface{}{query.OrganizationID}
-
-    if query.ProjectID != "" {
-        sql += " AND project_id = ?"
-        args = append(args, query.ProjectID)
-    }
-
-    if !query.StartTime.IsZero() {
-        sql += " AND timestamp >= ?"
-        args = append(args, query.StartTime)
-    }
-
-    if !query.EndTime.IsZero() {
-        sql += " AND timestamp <= ?"
-        args = append(args, query.EndTime)
-    }
-
-    if query.Status != "" {
-        sql += " AND status = ?"
-        args = append(args, query.Status)
-    }
+            total_tokens, model, provider, user_id, price_source
+        FROM traces` + where
 
     sql += " ORDER BY timestamp DESC LIMIT ? OFFSET ?"
     args = append(args, query.Limit, query.Offset)
@@ -475,6 +473,7 @@ func (r *ClickHouseRepository) GetTraces(ctx context.Context, query *models.Trac
             &trace.Model,
             &trace.Provider,
             &trace.UserID,
+            &trace.PriceSource,
         )
         if err != nil {
             return nil, fmt.Errorf("failed to scan trace: %w", err)
@@ -501,7 +500,7 @@ func (r *ClickHouseRepository) GetTraceByID(ctx context.Context, traceID string)
         SELECT 
             trace_id, organization_id, project_id, timestamp,
             trace_type, duration_ms, status, total_cost_usd,
-            total_tokens, model, provider, user_id, metadata
+            total_tokens, model, provider, user_id, metadata, price_source
         FROM traces
         WHERE trace_id = ?
     `
@@ -520,10 +519,11 @@ func (r *ClickHouseRepository) GetTraceByID(ctx context.Context, traceID string)
         &trace.Provider,
         &trace.UserID,
         &metadataJSON,
+        &trace.PriceSource,
     )
 
     if err == sql.ErrNoRows {
-        return nil, fmt.Errorf("trace not found")
+        return nil, fmt.Errorf("trace not found: %w", ErrNotFound)
     }
     if err != nil {
         return nil, fmt.Errorf("failed to get trace: %w", err)
@@ -537,7 +537,7 @@ func (r *ClickHouseRepository) GetTraceByID(ctx context.Context, traceID string)
         json.Unmarshal([]byte(metadataJSON), &trace.Metadata)
     }
 
-    spans, err := r.GetSpansByTraceID(ctx, traceID)
+    spans, err := r.GetSpansByTraceID(ctx, trace.OrganizationID, traceID)
     if err == nil {
         trace.Spans = spans
     }
@@ -547,23 +547,8 @@ func (r *ClickHouseRepository) GetTraceByID(ctx context.Context, traceID string)
 
 // GetTraceCount returns total count for pagination
 func (r *ClickHouseRepository) GetTraceCount(ctx context.Context, query *models.TraceQuery) (int64, error) {
-    sql := "SELECT count() FROM traces WHERE organization_id = ?"
-    args := []interface{}{query.OrganizationID}
-
-    if query.ProjectID != "" {
-        sql += " AND project_id = ?"
-        args = append(args, query.ProjectID)
-    }
-
-    if !query.StartTime.IsZero() {
-        sql += " AND timestamp >= ?"
-        args = append(args, query.StartTime)
-    }
-
-    if !query.EndTime.IsZero() {
-        sql += " AND timestamp <= ?"
-        args = append(args, query.EndTime)
-    }
+    where, args := traceFilter(query)
+    sql := "SELECT count() FROM traces" + where
 
     var count uint64
     err := r.conn.QueryRow(ctx, sql, args...).Scan(&count)
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// SaveAlertRule writes a new version of an alert rule
+func (r *ClickHouseRepository) SaveAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO alert_rules (
+			id, organization_id, project_id, name, metric, comparator, threshold,
+			window_minutes, for_minutes, severity, enabled, created_by, created_at, updated_at, deleted
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		rule.ID,
+		rule.OrganizationID,
+		rule.ProjectID,
+		rule.Name,
+		rule.Metric,
+		rule.Comparator,
+		rule.Threshold,
+		rule.WindowMinutes,
+		rule.ForMinutes,
+		rule.Severity,
+		rule.Enabled,
+		rule.CreatedBy,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+		rule.Deleted,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save alert rule: %w", err)
+	}
+	return nil
+}
+
+// ListAlertRules returns the alert rules of an organization, or of every organization when orgID is empty
+func (r *ClickHouseRepository) ListAlertRules(ctx context.Context, orgID string) ([]*models.AlertRule, error) {
+	query := `
+		SELECT
+			id, organization_id, project_id, name, metric, comparator, threshold,
+			window_minutes, for_minutes, severity, enabled, created_by, created_at, updated_at
+		FROM alert_rules FINAL
+		WHERE deleted = 0`
+	var args []interface{}
+	if orgID != "" {
+		query += " AND organization_id = ?"
+		args = append(args, orgID)
+	}
+
+	rows, err := r.conn.Query(ctx, query+" ORDER BY organization_id, created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*models.AlertRule{}
+	for rows.Next() {
+		var (
+			rule               models.AlertRule
+			window, forMinutes uint32
+			enabled            uint8
+		)
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.OrganizationID,
+			&rule.ProjectID,
+			&rule.Name,
+			&rule.Metric,
+			&rule.Comparator,
+			&rule.Threshold,
+			&window,
+			&forMinutes,
+			&rule.Severity,
+			&enabled,
+			&rule.CreatedBy,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rule.WindowMinutes = int(window)
+		rule.ForMinutes = int(forMinutes)
+		rule.Enabled = enabled == 1
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// SaveAlertEvents stores alert state changes in a single batch
+func (r *ClickHouseRepository) SaveAlertEvents(ctx context.Context, events []*models.AlertEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO alert_events (
+			id, organization_id, project_id, rule_id, rule_name, metric, comparator,
+			threshold, severity, state, value, timestamp
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare alert events batch: %w", err)
+	}
+
+	for _, event := range events {
+		if err := batch.Append(
+			event.ID,
+			event.OrganizationID,
+			event.ProjectID,
+			event.RuleID,
+			event.RuleName,
+			event.Metric,
+			event.Comparator,
+			event.Threshold,
+			event.Severity,
+			event.State,
+			event.Value,
+			event.Timestamp,
+		); err != nil {
+			return fmt.Errorf("failed to append alert event: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save alert events: %w", err)
+	}
+	return nil
+}
+
+// ListAlertEvents returns an organization's alert history, newest first
+func (r *ClickHouseRepository) ListAlertEvents(ctx context.Context, query *models.AlertEventQuery) ([]*models.AlertEvent, error) {
+	if query.OrganizationID == "" {
+		return nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{query.OrganizationID, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", query.ProjectID},
+		{"rule_id", query.RuleID},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+	if len(query.States) > 0 {
+		where += " AND state IN ?"
+		args = append(args, query.States)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	return r.queryAlertEvents(ctx, where+" ORDER BY timestamp DESC LIMIT ?", args...)
+}
+
+// GetLatestAlertEvents returns the latest event of every alert rule that has one
+func (r *ClickHouseRepository) GetLatestAlertEvents(ctx context.Context) ([]*models.AlertEvent, error) {
+	return r.queryAlertEvents(ctx, " ORDER BY timestamp DESC LIMIT 1 BY rule_id")
+}
+
+// queryAlertEvents reads alert events with the given WHERE, ORDER BY and LIMIT clauses
+func (r *ClickHouseRepository) queryAlertEvents(ctx context.Context, clauses string, args ...interface{}) ([]*models.AlertEvent, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, project_id, rule_id, rule_name, metric, comparator,
+			threshold, severity, state, value, timestamp
+		FROM alert_events`+clauses, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AlertEvent{}
+	for rows.Next() {
+		var event models.AlertEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.OrganizationID,
+			&event.ProjectID,
+			&event.RuleID,
+			&event.RuleName,
+			&event.Metric,
+			&event.Comparator,
+			&event.Threshold,
+			&event.Severity,
+			&event.State,
+			&event.Value,
+			&event.Timestamp,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan alert event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
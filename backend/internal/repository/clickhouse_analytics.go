@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// analyticsFilter builds the organization/project part of an analytics WHERE clause
+func analyticsFilter(query *models.AnalyticsQuery) (string, []interface{}) {
+	where := " WHERE organization_id = ?"
+	args := []interface{}{query.OrganizationID}
+
+	if query.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, query.ProjectID)
+	}
+
+	return where, args
+}
+
+// queryTimeZone returns the IANA time zone calendar days of a query are bucketed in
+func queryTimeZone(query *models.AnalyticsQuery) string {
+	if query.TimeZone == "" {
+		return "UTC"
+	}
+	return query.TimeZone
+}
+
+// GetTraceTotals aggregates traces over each window in a single scan.
+// Results follow the order of windows; windows must not overlap. With no
+// windows the query's own time range is used.
+func (r *ClickHouseRepository) GetTraceTotals(ctx context.Context, query *models.AnalyticsQuery, windows ...models.TimeWindow) ([]*models.TraceTotals, error) {
+	if len(windows) == 0 {
+		windows = []models.TimeWindow{{Start: query.StartTime, End: query.EndTime}}
+	}
+
+	// Label each row with the index of the window it falls in
+	var (
+		cases     []string
+		caseArgs  []interface{}
+		rangeFrom = windows[0].Start
+		rangeTo   = windows[0].End
+	)
+	for i, window := range windows {
+		cases = append(cases, fmt.Sprintf("timestamp >= ? AND timestamp < ?, %d", i))
+		caseArgs = append(caseArgs, window.Start, window.End)
+		if window.Start.Before(rangeFrom) {
+			rangeFrom = window.Start
+		}
+		if window.End.After(rangeTo) {
+			rangeTo = window.End
+		}
+	}
+
+	where, args := analyticsFilter(query)
+	args = append(caseArgs, args...)
+	args = append(args, rangeFrom, rangeTo)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			toInt32(multiIf(`+strings.Join(cases, ", ")+`, -1)) AS window,
+			count() AS trace_count,
+			countIf(status = 'error') AS error_count,
+			countIf(status = 'success') AS success_count,
+			sum(total_tokens) AS total_tokens,
+			sum(total_cost_usd) AS total_cost,
+			avg(duration_ms) AS avg_latency,
+			quantiles(0.50, 0.95, 0.99)(duration_ms) AS latency
+		FROM traces`+where+`
+			AND timestamp >= ? AND timestamp < ?
+		GROUP BY window
+		HAVING window >= 0
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make([]*models.TraceTotals, len(windows))
+	for i := range totals {
+		totals[i] = &models.TraceTotals{}
+	}
+
+	for rows.Next() {
+		var (
+			window                                       int32
+			traceCount, errorCount, successCount, tokens uint64
+			latency                                      []float64
+			t                                            models.TraceTotals
+		)
+
+		if err := rows.Scan(&window, &traceCount, &errorCount, &successCount, &tokens, &t.TotalCost, &t.AvgLatencyMs, &latency); err != nil {
+			return nil, fmt.Errorf("failed to scan trace totals: %w", err)
+		}
+
+		t.TraceCount = int64(traceCount)
+		t.ErrorCount = int64(errorCount)
+		t.SuccessCount = int64(successCount)
+		t.TotalTokens = int64(tokens)
+		if len(latency) == 3 {
+			t.P50LatencyMs, t.P95LatencyMs, t.P99LatencyMs = latency[0], latency[1], latency[2]
+		}
+
+		totals[window] = &t
+	}
+
+	return totals, rows.Err()
+}
+
+// GetDashboardBreakdowns computes the top models, daily cost and status breakdowns in one round trip.
+// Days are calendar days in the query's time zone.
+func (r *ClickHouseRepository) GetDashboardBreakdowns(ctx context.Context, query *models.AnalyticsQuery, topModels int) (*models.DashboardBreakdowns, error) {
+	where, filterArgs := analyticsFilter(query)
+	where += " AND timestamp >= ? AND timestamp < ?"
+	filterArgs = append(filterArgs, query.StartTime, query.EndTime)
+
+	// The day grouping takes the time zone ahead of its filter
+	var args []interface{}
+	args = append(args, filterArgs...)
+	args = append(args, queryTimeZone(query))
+	args = append(args, filterArgs...)
+	args = append(args, filterArgs...)
+	args = append(args, topModels)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT kind, key, count, cost FROM (
+			SELECT 'status' AS kind, status AS key, count() AS count, sum(total_cost_usd) AS cost
+			FROM traces`+where+`
+			GROUP BY key
+			UNION ALL
+			SELECT 'day' AS kind, toString(toDate(timestamp, ?)) AS key, count() AS count, sum(total_cost_usd) AS cost
+			FROM traces`+where+`
+			GROUP BY key
+			UNION ALL
+			SELECT * FROM (
+				SELECT 'model' AS kind, model AS key, count() AS count, sum(total_cost_usd) AS cost
+				FROM traces`+where+`
+				GROUP BY key
+				ORDER BY count DESC
+				LIMIT ?
+			)
+		)
+		ORDER BY kind, count DESC, key
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboard breakdowns: %w", err)
+	}
+	defer rows.Close()
+
+	breakdowns := &models.DashboardBreakdowns{
+		TopModels: []models.GroupTotal{},
+		CostByDay: []models.GroupTotal{},
+		ByStatus:  []models.GroupTotal{},
+	}
+
+	for rows.Next() {
+		var kind string
+		var count uint64
+		var group models.GroupTotal
+
+		if err := rows.Scan(&kind, &group.Key, &count, &group.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard breakdown: %w", err)
+		}
+		group.Count = int64(count)
+
+		switch kind {
+		case "model":
+			breakdowns.TopModels = append(breakdowns.TopModels, group)
+		case "day":
+			breakdowns.CostByDay = append(breakdowns.CostByDay, group)
+		case "status":
+			breakdowns.ByStatus = append(breakdowns.ByStatus, group)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Days come back by count; the chart wants them in date order
+	sort.Slice(breakdowns.CostByDay, func(i, j int) bool {
+		return breakdowns.CostByDay[i].Key < breakdowns.CostByDay[j].Key
+	})
+
+	return breakdowns, nil
+}
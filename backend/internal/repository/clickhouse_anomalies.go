@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// GetHourlyModelStats returns hourly traffic per project and model from metrics_hourly.
+// Hours without requests are omitted.
+func (r *ClickHouseRepository) GetHourlyModelStats(ctx context.Context, orgID string, startTime, endTime time.Time) ([]*models.HourlyModelStats, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			project_id,
+			model,
+			hour,
+			sumMergeIf(total_value, metric_name = 'requests'),
+			sumMergeIf(total_value, metric_name = 'errors'),
+			sumMergeIf(total_value, metric_name = 'cost_usd'),
+			sumMergeIf(total_value, metric_name = 'tokens'),
+			ifNotFinite(quantilesMergeIf(0.50, 0.90, 0.95, 0.99)(quantiles, metric_name = 'latency_ms')[3], 0)
+		FROM metrics_hourly
+		WHERE organization_id = ?
+			AND metric_name IN ('requests', 'errors', 'cost_usd', 'tokens', 'latency_ms')
+			AND hour >= toStartOfHour(?) AND hour < ?
+		GROUP BY project_id, model, hour
+		HAVING sumMergeIf(total_value, metric_name = 'requests') > 0
+		ORDER BY project_id, model, hour
+	`, orgID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hourly model stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*models.HourlyModelStats{}
+	for rows.Next() {
+		var s models.HourlyModelStats
+		var requests, errors, tokens float64
+
+		if err := rows.Scan(&s.ProjectID, &s.Model, &s.Hour, &requests, &errors, &s.CostUSD, &tokens, &s.P95LatencyMs); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly model stats: %w", err)
+		}
+		s.Requests = int64(requests)
+		s.Errors = int64(errors)
+		s.Tokens = int64(tokens)
+		stats = append(stats, &s)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetActiveOrganizations returns the organizations that recorded traffic since the given time
+func (r *ClickHouseRepository) GetActiveOrganizations(ctx context.Context, since time.Time) ([]string, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT DISTINCT organization_id
+		FROM metrics_hourly
+		WHERE hour >= toStartOfHour(?)
+		ORDER BY organization_id
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []string{}
+	for rows.Next() {
+		var orgID string
+		if err := rows.Scan(&orgID); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, orgID)
+	}
+
+	return orgs, rows.Err()
+}
+
+// SaveAnomalies stores anomalies in a single batch; a newer version of a record replaces the old one
+func (r *ClickHouseRepository) SaveAnomalies(ctx context.Context, anomalies []*models.Anomaly) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO anomalies (
+			id, organization_id, project_id, model, metric, direction, severity, score,
+			value, baseline, started_at, last_seen_at, ongoing, explanation, detected_at
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare anomalies batch: %w", err)
+	}
+
+	for _, a := range anomalies {
+		var ongoing uint8
+		if a.Ongoing {
+			ongoing = 1
+		}
+
+		if err := batch.Append(
+			a.ID,
+			a.OrganizationID,
+			a.ProjectID,
+			a.Model,
+			a.Metric,
+			a.Direction,
+			a.Severity,
+			a.Score,
+			a.Value,
+			a.Baseline,
+			a.StartedAt,
+			a.LastSeenAt,
+			ongoing,
+			a.Explanation,
+			a.DetectedAt,
+		); err != nil {
+			return fmt.Errorf("failed to append anomaly: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save anomalies: %w", err)
+	}
+	return nil
+}
+
+// ListAnomalies returns the anomalies overlapping the query window, newest first
+func (r *ClickHouseRepository) ListAnomalies(ctx context.Context, query *models.AnomalyQuery) ([]*models.Anomaly, error) {
+	if query.OrganizationID == "" {
+		return nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+
+	where := " WHERE organization_id = ? AND last_seen_at > ? AND started_at < ?"
+	args := []interface{}{query.OrganizationID, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", query.ProjectID},
+		{"model", query.Model},
+		{"metric", query.Metric},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+	if len(query.Severities) > 0 {
+		where += " AND severity IN ?"
+		args = append(args, query.Severities)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, project_id, model, metric, direction, severity, score,
+			value, baseline, started_at, last_seen_at, ongoing, explanation, detected_at
+		FROM anomalies FINAL`+where+`
+		ORDER BY started_at DESC, score DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAnomalies(rows)
+}
+
+// ListRecentAnomalies returns the anomalies of an organization still marked ongoing or last
+// seen since the given time, oldest first, so detection can extend or close them
+func (r *ClickHouseRepository) ListRecentAnomalies(ctx context.Context, orgID string, since time.Time) ([]*models.Anomaly, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, project_id, model, metric, direction, severity, score,
+			value, baseline, started_at, last_seen_at, ongoing, explanation, detected_at
+		FROM anomalies FINAL
+		WHERE organization_id = ? AND (ongoing = 1 OR last_seen_at >= ?)
+		ORDER BY started_at
+	`, orgID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAnomalies(rows)
+}
+
+// scanAnomalies reads the rows of an anomaly SELECT
+func scanAnomalies(rows driver.Rows) ([]*models.Anomaly, error) {
+	anomalies := []*models.Anomaly{}
+	for rows.Next() {
+		var a models.Anomaly
+		var ongoing uint8
+
+		if err := rows.Scan(
+			&a.ID,
+			&a.OrganizationID,
+			&a.ProjectID,
+			&a.Model,
+			&a.Metric,
+			&a.Direction,
+			&a.Severity,
+			&a.Score,
+			&a.Value,
+			&a.Baseline,
+			&a.StartedAt,
+			&a.LastSeenAt,
+			&ongoing,
+			&a.Explanation,
+			&a.DetectedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+
+		a.Ongoing = ongoing == 1
+		anomalies = append(anomalies, &a)
+	}
+
+	return anomalies, rows.Err()
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// backfillFilter builds the WHERE clause selecting the traces of a cost backfill
+func backfillFilter(backfill *models.CostBackfill) (string, []interface{}, error) {
+	if backfill.OrganizationID == "" {
+		return "", nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{backfill.OrganizationID, backfill.StartTime, backfill.EndTime}
+
+	if backfill.Model != "" {
+		// Span models may carry a provider prefix such as openai/gpt-4o
+		model := strings.ToLower(backfill.Model)
+		where += " AND trace_id IN (SELECT trace_id FROM spans WHERE organization_id = ? AND (lower(model) = ? OR endsWith(lower(model), ?)))"
+		args = append(args, backfill.OrganizationID, model, "/"+model)
+	}
+
+	return where, args, nil
+}
+
+// CountBackfillTraces counts the traces a cost backfill covers
+func (r *ClickHouseRepository) CountBackfillTraces(ctx context.Context, backfill *models.CostBackfill) (int64, error) {
+	where, args, err := backfillFilter(backfill)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	if err := r.conn.QueryRow(ctx, "SELECT count() FROM traces"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count backfill traces: %w", err)
+	}
+	return int64(count), nil
+}
+
+// GetBackfillTraces returns the next page of a cost backfill's traces after its cursor,
+// oldest first, with spans attached
+func (r *ClickHouseRepository) GetBackfillTraces(ctx context.Context, backfill *models.CostBackfill, limit int) ([]*models.Trace, error) {
+	where, args, err := backfillFilter(backfill)
+	if err != nil {
+		return nil, err
+	}
+
+	if backfill.CursorTraceID != "" {
+		where += " AND (timestamp, trace_id) > (?, ?)"
+		args = append(args, backfill.CursorTimestamp, backfill.CursorTraceID)
+	}
+
+	traces, err := r.queryTracePage(ctx, `
+		SELECT
+			trace_id, organization_id, project_id, timestamp,
+			trace_type, duration_ms, status, total_cost_usd,
+			total_tokens, model, provider, user_id, metadata, price_source
+		FROM traces`+where+" ORDER BY timestamp ASC, trace_id ASC LIMIT ?", append(args, limit))
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return traces, nil
+	}
+
+	if err := r.attachSpans(ctx, traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// UpdateTraceCosts rewrites the costs of traces and their spans in place, together with
+// the cost_usd metric of each trace, replaces their unpriced_spans metrics, and then
+// rebuilds the aggregates they feed. Costs are updated by mutation rather than deleted
+// and re-inserted, so an interrupted call never loses rows and can simply be repeated.
+func (r *ClickHouseRepository) UpdateTraceCosts(ctx context.Context, orgID string, traces []*models.Trace, unpriced []*models.Metric) error {
+	if len(traces) == 0 {
+		return nil
+	}
+
+	scope := aggregateScope{first: traces[0].Timestamp, last: traces[0].Timestamp}
+	projects := make(map[string]bool)
+	users := make(map[string]bool)
+
+	var (
+		traceIDs, traceSources                    []string
+		traceCosts                                []float64
+		spanIDs, spanSources, unpricedSpans       []string
+		costs, inputs, cached, outputs, reasoning []float64
+		images, audio, cacheSavings, batchSavings []float64
+	)
+
+	for _, trace := range traces {
+		scope.count++
+		if trace.Timestamp.Before(scope.first) {
+			scope.first = trace.Timestamp
+		}
+		if trace.Timestamp.After(scope.last) {
+			scope.last = trace.Timestamp
+		}
+		if !projects[trace.ProjectID] {
+			projects[trace.ProjectID] = true
+			scope.projects = append(scope.projects, trace.ProjectID)
+		}
+		if trace.UserID != "" && !users[trace.UserID] {
+			users[trace.UserID] = true
+			scope.users = append(scope.users, trace.UserID)
+		}
+
+		traceIDs = append(traceIDs, trace.TraceID)
+		traceCosts = append(traceCosts, trace.TotalCostUSD)
+		traceSources = append(traceSources, trace.PriceSource)
+
+		for _, span := range trace.Spans {
+			spanIDs = append(spanIDs, span.SpanID)
+			spanSources = append(spanSources, span.PriceSource)
+			if span.Unpriced {
+				unpricedSpans = append(unpricedSpans, span.SpanID)
+			}
+			costs = append(costs, span.CostUSD)
+			inputs = append(inputs, span.CostComponents.Input)
+			cached = append(cached, span.CostComponents.CachedInput)
+			outputs = append(outputs, span.CostComponents.Output)
+			reasoning = append(reasoning, span.CostComponents.Reasoning)
+			images = append(images, span.CostComponents.Image)
+			audio = append(audio, span.CostComponents.Audio)
+			cacheSavings = append(cacheSavings, span.CostComponents.CacheSavings)
+			batchSavings = append(batchSavings, span.CostComponents.BatchSavings)
+		}
+	}
+
+	// Values are bound inline, so a page of spans needs more than the default query size
+	mctx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 2,
+		"max_query_size": 64 << 20,
+	}))
+
+	if len(spanIDs) > 0 {
+		columns := []struct {
+			name   string
+			values []float64
+		}{
+			{"cost_usd", costs},
+			{"input_cost_usd", inputs},
+			{"cached_input_cost_usd", cached},
+			{"output_cost_usd", outputs},
+			{"reasoning_cost_usd", reasoning},
+			{"image_cost_usd", images},
+			{"audio_cost_usd", audio},
+			{"cache_savings_usd", cacheSavings},
+			{"batch_savings_usd", batchSavings},
+		}
+
+		sets := make([]string, 0, len(columns)+2)
+		args := make([]interface{}, 0, 2*len(columns)+6)
+		for _, column := range columns {
+			sets = append(sets, column.name+" = transform(span_id, ?, CAST(? AS Array(Float64)), "+column.name+")")
+			args = append(args, spanIDs, column.values)
+		}
+		sets = append(sets,
+			"price_source = transform(span_id, ?, ?, toString(price_source))",
+			"unpriced = has(?, span_id)",
+		)
+		args = append(args, spanIDs, spanSources, unpricedSpans, orgID, traceIDs, spanIDs)
+
+		if err := r.conn.Exec(mctx, "ALTER TABLE spans UPDATE "+strings.Join(sets, ", ")+
+			" WHERE organization_id = ? AND has(?, trace_id) AND has(?, span_id)", args...); err != nil {
+			return fmt.Errorf("failed to update span costs: %w", err)
+		}
+	}
+
+	if err := r.conn.Exec(mctx, `
+		ALTER TABLE traces UPDATE
+			total_cost_usd = transform(trace_id, ?, CAST(? AS Array(Float64)), total_cost_usd),
+			price_source = transform(trace_id, ?, ?, toString(price_source))
+		WHERE organization_id = ? AND has(?, trace_id)
+	`, traceIDs, traceCosts, traceIDs, traceSources, orgID, traceIDs); err != nil {
+		return fmt.Errorf("failed to update trace costs: %w", err)
+	}
+
+	if err := r.conn.Exec(mctx, `
+		ALTER TABLE metrics UPDATE
+			metric_value = transform(JSONExtractString(tags, 'trace_id'), ?, CAST(? AS Array(Float64)), metric_value)
+		WHERE organization_id = ? AND metric_name = 'cost_usd' AND has(?, JSONExtractString(tags, 'trace_id'))
+	`, traceIDs, traceCosts, orgID, traceIDs); err != nil {
+		return fmt.Errorf("failed to update cost metrics: %w", err)
+	}
+
+	if err := r.conn.Exec(mctx, `
+		ALTER TABLE metrics DELETE
+		WHERE organization_id = ? AND metric_name = 'unpriced_spans' AND has(?, JSONExtractString(tags, 'trace_id'))
+	`, orgID, traceIDs); err != nil {
+		return fmt.Errorf("failed to clear unpriced span metrics: %w", err)
+	}
+	if err := r.SaveMetrics(ctx, unpriced); err != nil {
+		return err
+	}
+
+	// Mutations bypass the materialized views, so their rows are recomputed from the base tables
+	return r.rebuildAggregates(mctx, orgID, &scope)
+}
+
+// SaveCostBackfill writes a new version of a cost backfill record
+func (r *ClickHouseRepository) SaveCostBackfill(ctx context.Context, backfill *models.CostBackfill) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO cost_backfills (
+			id, organization_id, model, start_time, end_time, requested_by, status,
+			traces_total, traces_processed, traces_updated, spans_repriced, cost_before,
+			cost_after, cursor_timestamp, cursor_trace_id, error, requested_at,
+			completed_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		backfill.ID,
+		backfill.OrganizationID,
+		backfill.Model,
+		backfill.StartTime,
+		backfill.EndTime,
+		backfill.RequestedBy,
+		backfill.Status,
+		uint64(backfill.TracesTotal),
+		uint64(backfill.TracesProcessed),
+		uint64(backfill.TracesUpdated),
+		uint64(backfill.SpansRepriced),
+		backfill.CostBefore,
+		backfill.CostAfter,
+		backfill.CursorTimestamp,
+		backfill.CursorTraceID,
+		backfill.Error,
+		backfill.RequestedAt,
+		backfill.CompletedAt,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save cost backfill: %w", err)
+	}
+	return nil
+}
+
+// GetCostBackfill returns the latest version of a cost backfill record
+func (r *ClickHouseRepository) GetCostBackfill(ctx context.Context, orgID, id string) (*models.CostBackfill, error) {
+	backfills, err := r.queryCostBackfills(ctx, " WHERE organization_id = ? AND id = ?", orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(backfills) == 0 {
+		return nil, fmt.Errorf("cost backfill %s: %w", id, ErrNotFound)
+	}
+	return backfills[0], nil
+}
+
+// ListCostBackfills returns an organization's cost backfills, newest first
+func (r *ClickHouseRepository) ListCostBackfills(ctx context.Context, orgID string, limit int) ([]*models.CostBackfill, error) {
+	return r.queryCostBackfills(ctx, " WHERE organization_id = ? ORDER BY requested_at DESC LIMIT ?", orgID, limit)
+}
+
+// ListUnfinishedCostBackfills returns the pending and running backfills of every organization
+func (r *ClickHouseRepository) ListUnfinishedCostBackfills(ctx context.Context) ([]*models.CostBackfill, error) {
+	return r.queryCostBackfills(ctx, " WHERE status IN ('pending', 'running') ORDER BY requested_at ASC")
+}
+
+func (r *ClickHouseRepository) queryCostBackfills(ctx context.Context, clause string, args ...interface{}) ([]*models.CostBackfill, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, model, start_time, end_time, requested_by, status,
+			traces_total, traces_processed, traces_updated, spans_repriced, cost_before,
+			cost_after, cursor_timestamp, cursor_trace_id, error, requested_at, completed_at
+		FROM cost_backfills FINAL`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost backfills: %w", err)
+	}
+	defer rows.Close()
+
+	backfills := []*models.CostBackfill{}
+	for rows.Next() {
+		var backfill models.CostBackfill
+		var total, processed, updated, repriced uint64
+
+		if err := rows.Scan(
+			&backfill.ID,
+			&backfill.OrganizationID,
+			&backfill.Model,
+			&backfill.StartTime,
+			&backfill.EndTime,
+			&backfill.RequestedBy,
+			&backfill.Status,
+			&total,
+			&processed,
+			&updated,
+			&repriced,
+			&backfill.CostBefore,
+			&backfill.CostAfter,
+			&backfill.CursorTimestamp,
+			&backfill.CursorTraceID,
+			&backfill.Error,
+			&backfill.RequestedAt,
+			&backfill.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan cost backfill: %w", err)
+		}
+
+		backfill.TracesTotal = int64(total)
+		backfill.TracesProcessed = int64(processed)
+		backfill.TracesUpdated = int64(updated)
+		backfill.SpansRepriced = int64(repriced)
+		backfills = append(backfills, &backfill)
+	}
+
+	return backfills, rows.Err()
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// SaveBudget writes a new version of a budget
+func (r *ClickHouseRepository) SaveBudget(ctx context.Context, budget *models.Budget) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO budgets (
+			id, organization_id, name, scope, scope_value, period, limit_usd,
+			thresholds, enforcement, created_by, created_at, updated_at, deleted
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		budget.ID,
+		budget.OrganizationID,
+		budget.Name,
+		budget.Scope,
+		budget.ScopeValue,
+		budget.Period,
+		budget.LimitUSD,
+		budget.Thresholds,
+		budget.Enforcement,
+		budget.CreatedBy,
+		budget.CreatedAt,
+		budget.UpdatedAt,
+		budget.Deleted,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save budget: %w", err)
+	}
+	return nil
+}
+
+// ListBudgets returns the budgets of an organization, or of every organization when orgID is empty
+func (r *ClickHouseRepository) ListBudgets(ctx context.Context, orgID string) ([]*models.Budget, error) {
+	query := `
+		SELECT
+			id, organization_id, name, scope, scope_value, period, limit_usd,
+			thresholds, enforcement, created_by, created_at, updated_at
+		FROM budgets FINAL
+		WHERE deleted = 0`
+	var args []interface{}
+	if orgID != "" {
+		query += " AND organization_id = ?"
+		args = append(args, orgID)
+	}
+
+	rows, err := r.conn.Query(ctx, query+" ORDER BY organization_id, created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []*models.Budget{}
+	for rows.Next() {
+		var budget models.Budget
+		if err := rows.Scan(
+			&budget.ID,
+			&budget.OrganizationID,
+			&budget.Name,
+			&budget.Scope,
+			&budget.ScopeValue,
+			&budget.Period,
+			&budget.LimitUSD,
+			&budget.Thresholds,
+			&budget.Enforcement,
+			&budget.CreatedBy,
+			&budget.CreatedAt,
+			&budget.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, &budget)
+	}
+
+	return budgets, rows.Err()
+}
+
+// GetBudgetSpend sums the cost counted against a budget between start and end. Model
+// budgets count the cost of the model's spans, the other scopes the cost of whole traces.
+func (r *ClickHouseRepository) GetBudgetSpend(ctx context.Context, budget *models.Budget, start, end time.Time) (float64, error) {
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{budget.OrganizationID, start, end}
+
+	var query string
+	switch budget.Scope {
+	case models.BudgetScopeOrganization:
+		query = "SELECT sum(total_cost_usd) FROM traces" + where
+	case models.BudgetScopeProject:
+		query = "SELECT sum(total_cost_usd) FROM traces" + where + " AND project_id = ?"
+		args = append(args, budget.ScopeValue)
+	case models.BudgetScopeAPIKey:
+		query = "SELECT sum(total_cost_usd) FROM traces" + where + " AND api_key_id = ?"
+		args = append(args, budget.ScopeValue)
+	case models.BudgetScopeModel:
+		// Span models may carry a provider prefix such as openai/gpt-4o
+		model := strings.ToLower(budget.ScopeValue)
+		query = "SELECT sum(cost_usd) FROM spans WHERE organization_id = ? AND trace_id IN (SELECT trace_id FROM traces" + where +
+			") AND (lower(model) = ? OR endsWith(lower(model), ?))"
+		args = append(append([]interface{}{budget.OrganizationID}, args...), model, "/"+model)
+	default:
+		return 0, fmt.Errorf("unknown budget scope %q: %w", budget.Scope, ErrInvalidInput)
+	}
+
+	var spend float64
+	if err := r.conn.QueryRow(ctx, query, args...).Scan(&spend); err != nil {
+		return 0, fmt.Errorf("failed to query budget spend: %w", err)
+	}
+	return spend, nil
+}
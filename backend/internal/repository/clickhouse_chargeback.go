@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// GetTagPathCosts sums the cost of traces in the query's range grouped by the values of
+// the given metadata tags; a trace without a tag has an empty value for it
+func (r *ClickHouseRepository) GetTagPathCosts(ctx context.Context, query *models.AnalyticsQuery, keys []string) ([]*models.TagPathCost, error) {
+	where, filterArgs := analyticsFilter(query)
+	args := append([]interface{}{keys}, filterArgs...)
+	args = append(args, query.StartTime, query.EndTime)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			arrayMap(k -> JSONExtractString(metadata, k), ?) AS tag_values,
+			sum(total_cost_usd) AS cost,
+			count(),
+			sum(total_tokens)
+		FROM traces`+where+`
+			AND timestamp >= ? AND timestamp < ?
+		GROUP BY tag_values
+		ORDER BY cost DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag costs: %w", err)
+	}
+	defer rows.Close()
+
+	costs := []*models.TagPathCost{}
+	for rows.Next() {
+		var (
+			cost             models.TagPathCost
+			requests, tokens uint64
+		)
+		if err := rows.Scan(&cost.Values, &cost.TotalCost, &requests, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to scan tag cost: %w", err)
+		}
+		cost.RequestCount = int64(requests)
+		cost.TotalTokens = int64(tokens)
+		costs = append(costs, &cost)
+	}
+
+	return costs, rows.Err()
+}
+
+// SaveChargebackConfig writes a new version of an organization's chargeback configuration
+func (r *ClickHouseRepository) SaveChargebackConfig(ctx context.Context, config *models.ChargebackConfig) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO chargeback_configs (organization_id, hierarchy, untagged_policy, default_bucket, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, config.OrganizationID, config.Hierarchy, config.UntaggedPolicy, config.DefaultBucket, config.UpdatedBy, config.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save chargeback config: %w", err)
+	}
+	return nil
+}
+
+// GetChargebackConfig returns an organization's chargeback configuration
+func (r *ClickHouseRepository) GetChargebackConfig(ctx context.Context, orgID string) (*models.ChargebackConfig, error) {
+	var config models.ChargebackConfig
+	err := r.conn.QueryRow(ctx, `
+		SELECT organization_id, hierarchy, untagged_policy, default_bucket, updated_by, updated_at
+		FROM chargeback_configs FINAL
+		WHERE organization_id = ?
+	`, orgID).Scan(&config.OrganizationID, &config.Hierarchy, &config.UntaggedPolicy, &config.DefaultBucket, &config.UpdatedBy, &config.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chargeback config: %w", err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// cohortFilter builds the WHERE clause selecting the traces of a cohort
+func cohortFilter(orgID string, cohort *models.Cohort) (string, []interface{}) {
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{orgID, cohort.StartTime, cohort.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", cohort.ProjectID},
+		{"model", cohort.Model},
+		{"provider", cohort.Provider},
+		{"user_id", cohort.UserID},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+
+	// Sorted so the same cohort always yields the same statement
+	keys := make([]string, 0, len(cohort.Tags))
+	for key := range cohort.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		where += " AND JSONExtractString(metadata, ?) = ?"
+		args = append(args, key, cohort.Tags[key])
+	}
+
+	return where, args
+}
+
+// GetCohortStats aggregates the traces of a cohort in total and for its busiest models
+func (r *ClickHouseRepository) GetCohortStats(ctx context.Context, orgID string, cohort *models.Cohort, limit int) (*models.CohortStats, error) {
+	if orgID == "" {
+		return nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+	where, filterArgs := cohortFilter(orgID, cohort)
+
+	const aggregates = `count() AS requests, countIf(status = 'error'), sum(total_cost_usd), sum(total_tokens),
+			avg(duration_ms), stddevSamp(duration_ms), quantiles(0.50, 0.90, 0.95, 0.99)(duration_ms)`
+
+	args := append([]interface{}{}, filterArgs...)
+	args = append(args, filterArgs...)
+	args = append(args, limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT * FROM (
+			SELECT 'total' AS kind, '' AS key, `+aggregates+`
+			FROM traces`+where+`
+		) UNION ALL SELECT * FROM (
+			SELECT 'model', model, `+aggregates+`
+			FROM traces`+where+`
+			GROUP BY model ORDER BY requests DESC, model LIMIT ?
+		)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohort stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &models.CohortStats{ByModel: []*models.CohortGroupStats{}}
+	for rows.Next() {
+		var kind string
+		var group models.CohortGroupStats
+		var requests, errors, tokens uint64
+		var quantiles []float64
+
+		if err := rows.Scan(
+			&kind,
+			&group.Model,
+			&requests,
+			&errors,
+			&group.TotalCost,
+			&tokens,
+			&group.AvgLatencyMs,
+			&group.LatencyStdDev,
+			&quantiles,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort stats: %w", err)
+		}
+
+		group.Requests = int64(requests)
+		group.Errors = int64(errors)
+		group.TotalTokens = int64(tokens)
+		if len(quantiles) == 4 {
+			group.P50LatencyMs, group.P90LatencyMs, group.P95LatencyMs, group.P99LatencyMs = quantiles[0], quantiles[1], quantiles[2], quantiles[3]
+		}
+
+		// Averages and quantiles are NaN over no traces, the standard deviation over one
+		for _, value := range []*float64{&group.AvgLatencyMs, &group.LatencyStdDev, &group.P50LatencyMs, &group.P90LatencyMs, &group.P95LatencyMs, &group.P99LatencyMs} {
+			if math.IsNaN(*value) {
+				*value = 0
+			}
+		}
+
+		if kind == "total" {
+			group.Model = ""
+			stats.Total = group
+		} else {
+			stats.ByModel = append(stats.ByModel, &group)
+		}
+	}
+
+	return stats, rows.Err()
+}
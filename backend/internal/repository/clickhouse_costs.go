@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// GetCostBreakdown computes the cost of an organization's traces grouped by model,
+// provider, project, end user and the requested metadata tags
+func (r *ClickHouseRepository) GetCostBreakdown(ctx context.Context, query *models.CostQuery) (*models.CostBreakdown, error) {
+	breakdown := &models.CostBreakdown{
+		ByModel:       []models.ModelCost{},
+		ByProvider:    []models.ProviderCost{},
+		ByProject:     []models.ProjectCost{},
+		ByUser:        []models.UserCost{},
+		ByTag:         []models.TagCost{},
+		TopExpensive:  []models.ExpensiveTrace{},
+		UsageByModel:  []models.ModelUsageCost{},
+		ByPriceSource: []models.PriceSourceCost{},
+	}
+
+	daily, err := r.getDailyCosts(ctx, &query.AnalyticsQuery)
+	if err != nil {
+		return nil, err
+	}
+	breakdown.DailyCosts = daily
+
+	for _, day := range daily {
+		breakdown.TotalCost += day.TotalCost
+		breakdown.TotalCalls += day.RequestCount
+	}
+	if breakdown.TotalCalls > 0 {
+		breakdown.AvgCost = breakdown.TotalCost / float64(breakdown.TotalCalls)
+	}
+
+	if err := r.getCostGroups(ctx, query, breakdown); err != nil {
+		return nil, err
+	}
+
+	top, err := r.getExpensiveTraces(ctx, &query.AnalyticsQuery, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	breakdown.TopExpensive = top
+
+	if err := r.getUsageCosts(ctx, query, breakdown); err != nil {
+		return nil, err
+	}
+
+	sources, err := r.getPriceSourceCosts(ctx, &query.AnalyticsQuery)
+	if err != nil {
+		return nil, err
+	}
+	breakdown.ByPriceSource = sources
+
+	return breakdown, nil
+}
+
+// getDailyCosts reads whole days inside the range from the daily_costs view and
+// aggregates the partial days at either end from the raw traces. Days are calendar
+// days in the query's time zone.
+func (r *ClickHouseRepository) getDailyCosts(ctx context.Context, query *models.AnalyticsQuery) ([]models.DailyCost, error) {
+	timeZone := queryTimeZone(query)
+
+	fullFrom := query.StartTime.UTC().Truncate(24 * time.Hour)
+	if fullFrom.Before(query.StartTime) {
+		fullFrom = fullFrom.Add(24 * time.Hour)
+	}
+	fullTo := query.EndTime.UTC().Truncate(24 * time.Hour)
+
+	// The view's days are UTC days, so other time zones are read from raw traces only
+	if timeZone != "UTC" {
+		fullFrom, fullTo = query.StartTime, query.StartTime
+	}
+
+	where, filterArgs := analyticsFilter(query)
+
+	args := append([]interface{}{}, filterArgs...)
+	args = append(args, fullFrom, fullTo, timeZone)
+	args = append(args, filterArgs...)
+	args = append(args, query.StartTime, query.EndTime, fullFrom, fullTo)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT toString(day) AS date, sum(cost), sum(requests)
+		FROM (
+			SELECT day, sum(total_cost) AS cost, sum(trace_count) AS requests
+			FROM daily_costs`+where+`
+				AND day >= toDate(?) AND day < toDate(?)
+			GROUP BY day
+			UNION ALL
+			SELECT toDate(timestamp, ?) AS day, sum(total_cost_usd) AS cost, count() AS requests
+			FROM traces`+where+`
+				AND timestamp >= ? AND timestamp < ?
+				AND (timestamp < ? OR timestamp >= ?)
+			GROUP BY day
+		)
+		GROUP BY day
+		ORDER BY day
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily costs: %w", err)
+	}
+	defer rows.Close()
+
+	costs := []models.DailyCost{}
+	for rows.Next() {
+		var cost models.DailyCost
+		var requests uint64
+
+		if err := rows.Scan(&cost.Date, &cost.TotalCost, &requests); err != nil {
+			return nil, fmt.Errorf("failed to scan daily cost: %w", err)
+		}
+		cost.RequestCount = int64(requests)
+		costs = append(costs, cost)
+	}
+
+	return costs, rows.Err()
+}
+
+// getCostGroups fills the grouped breakdowns of a cost query in one round trip
+func (r *ClickHouseRepository) getCostGroups(ctx context.Context, query *models.CostQuery, breakdown *models.CostBreakdown) error {
+	where, filterArgs := analyticsFilter(&query.AnalyticsQuery)
+	where += " AND timestamp >= ? AND timestamp < ?"
+	filterArgs = append(filterArgs, query.StartTime, query.EndTime)
+
+	// Each grouping yields (kind, key, sub_key, cost, requests, tokens)
+	groupings := []string{
+		`SELECT 'model' AS kind, model AS key, any(provider) AS sub_key, sum(total_cost_usd) AS cost, count() AS requests, sum(total_tokens) AS tokens
+		FROM traces` + where + `
+		GROUP BY key ORDER BY cost DESC LIMIT ?`,
+		`SELECT 'provider', provider, '', sum(total_cost_usd) AS cost, count(), sum(total_tokens)
+		FROM traces` + where + `
+		GROUP BY provider ORDER BY cost DESC LIMIT ?`,
+		`SELECT 'project', project_id, '', sum(total_cost_usd) AS cost, count(), sum(total_tokens)
+		FROM traces` + where + `
+		GROUP BY project_id ORDER BY cost DESC LIMIT ?`,
+		`SELECT 'user', user_id, '', sum(total_cost_usd) AS cost, count(), sum(total_tokens)
+		FROM traces` + where + ` AND user_id != ''
+		GROUP BY user_id ORDER BY cost DESC LIMIT ?`,
+	}
+
+	var args []interface{}
+	for range groupings {
+		args = append(args, filterArgs...)
+		args = append(args, query.Limit)
+	}
+
+	if len(query.TagKeys) > 0 {
+		groupings = append(groupings, `SELECT 'tag', JSONExtractString(metadata, tag_key) AS tag_value, tag_key, sum(total_cost_usd) AS cost, count(), sum(total_tokens)
+		FROM traces ARRAY JOIN ? AS tag_key`+where+` AND JSONHas(metadata, tag_key)
+		GROUP BY tag_key, tag_value ORDER BY cost DESC LIMIT ? BY tag_key`)
+		args = append(args, query.TagKeys)
+		args = append(args, filterArgs...)
+		args = append(args, query.Limit)
+	}
+
+	statement := "SELECT * FROM ("
+	for i, grouping := range groupings {
+		if i > 0 {
+			statement += ") UNION ALL SELECT * FROM ("
+		}
+		statement += grouping
+	}
+	statement += ")"
+
+	rows, err := r.conn.Query(ctx, statement, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query cost breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			kind, key, subKey string
+			cost              float64
+			requests, tokens  uint64
+		)
+
+		if err := rows.Scan(&kind, &key, &subKey, &cost, &requests, &tokens); err != nil {
+			return fmt.Errorf("failed to scan cost breakdown: %w", err)
+		}
+
+		switch kind {
+		case "model":
+			breakdown.ByModel = append(breakdown.ByModel, models.ModelCost{
+				Model:         key,
+				Provider:      subKey,
+				TotalCost:     cost,
+				RequestCount:  int64(requests),
+				AvgCostPerReq: cost / float64(requests),
+				TotalTokens:   int64(tokens),
+			})
+		case "provider":
+			breakdown.ByProvider = append(breakdown.ByProvider, models.ProviderCost{
+				Provider:  key,
+				TotalCost: cost,
+				Count:     int64(requests),
+			})
+		case "project":
+			breakdown.ByProject = append(breakdown.ByProject, models.ProjectCost{
+				ProjectID:    key,
+				TotalCost:    cost,
+				RequestCount: int64(requests),
+			})
+		case "user":
+			breakdown.ByUser = append(breakdown.ByUser, models.UserCost{
+				UserID:       key,
+				TotalCost:    cost,
+				RequestCount: int64(requests),
+				TotalTokens:  int64(tokens),
+			})
+		case "tag":
+			breakdown.ByTag = append(breakdown.ByTag, models.TagCost{
+				Key:          subKey,
+				Value:        key,
+				TotalCost:    cost,
+				RequestCount: int64(requests),
+			})
+		}
+	}
+
+	return rows.Err()
+}
+
+// getExpensiveTraces returns the most expensive traces in the range
+func (r *ClickHouseRepository) getExpensiveTraces(ctx context.Context, query *models.AnalyticsQuery, limit int) ([]models.ExpensiveTrace, error) {
+	where, args := analyticsFilter(query)
+	args = append(args, query.StartTime, query.EndTime, limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT trace_id, model, provider, total_cost_usd, total_tokens, timestamp
+		FROM traces`+where+`
+			AND timestamp >= ? AND timestamp < ?
+		ORDER BY total_cost_usd DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expensive traces: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []models.ExpensiveTrace{}
+	for rows.Next() {
+		var trace models.ExpensiveTrace
+		var tokens uint32
+		var timestamp time.Time
+
+		if err := rows.Scan(&trace.TraceID, &trace.Model, &trace.Provider, &trace.Cost, &tokens, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan expensive trace: %w", err)
+		}
+		trace.Tokens = int64(tokens)
+		trace.Timestamp = timestamp.UTC().Format(time.RFC3339)
+		traces = append(traces, trace)
+	}
+
+	return traces, rows.Err()
+}
+
+// getUsageCosts splits span costs by usage category, per model and in total
+func (r *ClickHouseRepository) getUsageCosts(ctx context.Context, query *models.CostQuery, breakdown *models.CostBreakdown) error {
+	where, args := analyticsFilter(&query.AnalyticsQuery)
+	args = append(append([]interface{}{query.OrganizationID}, args...), query.StartTime, query.EndTime)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			model,
+			any(provider),
+			sum(prompt_tokens),
+			sum(cached_prompt_tokens),
+			sum(completion_tokens),
+			sum(reasoning_tokens),
+			sum(image_tokens),
+			sum(audio_seconds),
+			countIf(batch = 1),
+			sum(input_cost_usd),
+			sum(cached_input_cost_usd),
+			sum(output_cost_usd),
+			sum(reasoning_cost_usd),
+			sum(image_cost_usd),
+			sum(audio_cost_usd),
+			sum(cache_savings_usd),
+			sum(batch_savings_usd)
+		FROM spans
+		WHERE organization_id = ? AND model != '' AND trace_id IN (
+			SELECT trace_id FROM traces`+where+` AND timestamp >= ? AND timestamp < ?
+		)
+		GROUP BY model
+		ORDER BY sum(cost_usd) DESC, model
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query usage costs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var usage models.ModelUsageCost
+		var promptTokens, cachedTokens, completionTokens, reasoningTokens, imageTokens, batchRequests uint64
+
+		if err := rows.Scan(
+			&usage.Model,
+			&usage.Provider,
+			&promptTokens,
+			&cachedTokens,
+			&completionTokens,
+			&reasoningTokens,
+			&imageTokens,
+			&usage.AudioSeconds,
+			&batchRequests,
+			&usage.Costs.Input,
+			&usage.Costs.CachedInput,
+			&usage.Costs.Output,
+			&usage.Costs.Reasoning,
+			&usage.Costs.Image,
+			&usage.Costs.Audio,
+			&usage.Costs.CacheSavings,
+			&usage.Costs.BatchSavings,
+		); err != nil {
+			return fmt.Errorf("failed to scan usage cost: %w", err)
+		}
+
+		usage.PromptTokens = int64(promptTokens)
+		usage.CachedPromptTokens = int64(cachedTokens)
+		usage.CompletionTokens = int64(completionTokens)
+		usage.ReasoningTokens = int64(reasoningTokens)
+		usage.ImageTokens = int64(imageTokens)
+		usage.BatchRequests = int64(batchRequests)
+		if promptTokens > 0 {
+			usage.CacheHitRate = float64(cachedTokens) / float64(promptTokens) * 100
+		}
+
+		// Totals cover every model, the list only the most expensive
+		total := &breakdown.Components
+		total.Input += usage.Costs.Input
+		total.CachedInput += usage.Costs.CachedInput
+		total.Output += usage.Costs.Output
+		total.Reasoning += usage.Costs.Reasoning
+		total.Image += usage.Costs.Image
+		total.Audio += usage.Costs.Audio
+		total.CacheSavings += usage.Costs.CacheSavings
+		total.BatchSavings += usage.Costs.BatchSavings
+
+		if query.Limit <= 0 || len(breakdown.UsageByModel) < query.Limit {
+			breakdown.UsageByModel = append(breakdown.UsageByModel, usage)
+		}
+	}
+
+	return rows.Err()
+}
+
+// getPriceSourceCosts totals span costs by where their price came from
+func (r *ClickHouseRepository) getPriceSourceCosts(ctx context.Context, query *models.AnalyticsQuery) ([]models.PriceSourceCost, error) {
+	where, args := analyticsFilter(query)
+	args = append(append([]interface{}{query.OrganizationID}, args...), query.StartTime, query.EndTime)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT price_source, sum(cost_usd) AS cost, count()
+		FROM spans
+		WHERE organization_id = ? AND trace_id IN (
+			SELECT trace_id FROM traces`+where+` AND timestamp >= ? AND timestamp < ?
+		)
+		GROUP BY price_source
+		ORDER BY cost DESC, price_source
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price source costs: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []models.PriceSourceCost{}
+	for rows.Next() {
+		var source models.PriceSourceCost
+		var spans uint64
+
+		if err := rows.Scan(&source.Source, &source.TotalCost, &spans); err != nil {
+			return nil, fmt.Errorf("failed to scan price source cost: %w", err)
+		}
+		source.SpanCount = int64(spans)
+		sources = append(sources, source)
+	}
+
+	return sources, rows.Err()
+}
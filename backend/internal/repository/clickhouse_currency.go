@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// SaveExchangeRates stores daily exchange rates in a single batch, replacing the rates of
+// the same currency and day
+func (r *ClickHouseRepository) SaveExchangeRates(ctx context.Context, rates []*models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO exchange_rates (currency, date, rate, source, updated_by, updated_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare exchange rates batch: %w", err)
+	}
+
+	for _, rate := range rates {
+		date, err := time.Parse("2006-01-02", rate.Date)
+		if err != nil {
+			return fmt.Errorf("invalid exchange rate date %q: %w", rate.Date, ErrInvalidInput)
+		}
+		if err := batch.Append(rate.Currency, date, rate.Rate, rate.Source, rate.UpdatedBy, rate.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to append exchange rate: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save exchange rates: %w", err)
+	}
+	return nil
+}
+
+// ListExchangeRates returns the daily rates of a currency, or of every currency when
+// currency is empty, ordered by currency and date
+func (r *ClickHouseRepository) ListExchangeRates(ctx context.Context, currency string) ([]*models.ExchangeRate, error) {
+	query := `
+		SELECT currency, date, rate, source, updated_by, updated_at
+		FROM exchange_rates FINAL`
+	var args []interface{}
+	if currency != "" {
+		query += " WHERE currency = ?"
+		args = append(args, currency)
+	}
+
+	rows, err := r.conn.Query(ctx, query+" ORDER BY currency, date", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []*models.ExchangeRate{}
+	for rows.Next() {
+		var (
+			rate models.ExchangeRate
+			date time.Time
+		)
+		if err := rows.Scan(&rate.Currency, &date, &rate.Rate, &rate.Source, &rate.UpdatedBy, &rate.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rate.Date = date.Format("2006-01-02")
+		rates = append(rates, &rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// SaveCurrencySetting writes a new version of an organization's display currency
+func (r *ClickHouseRepository) SaveCurrencySetting(ctx context.Context, setting *models.CurrencySetting) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO currency_settings (organization_id, currency, updated_by, updated_at)
+		VALUES (?, ?, ?, ?)
+	`, setting.OrganizationID, setting.Currency, setting.UpdatedBy, setting.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save currency setting: %w", err)
+	}
+	return nil
+}
+
+// GetCurrencySetting returns an organization's display currency
+func (r *ClickHouseRepository) GetCurrencySetting(ctx context.Context, orgID string) (*models.CurrencySetting, error) {
+	var setting models.CurrencySetting
+	err := r.conn.QueryRow(ctx, `
+		SELECT organization_id, currency, updated_by, updated_at
+		FROM currency_settings FINAL
+		WHERE organization_id = ?
+	`, orgID).Scan(&setting.OrganizationID, &setting.Currency, &setting.UpdatedBy, &setting.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get currency setting: %w", err)
+	}
+	return &setting, nil
+}
@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// hashedUserID is the SQL of the hash a user ID is stored as in the erasure audit log
+func hashedUserID(column string) string {
+	return "concat('sha256:', lower(hex(SHA256(" + column + "))))"
+}
+
+// erasureFilter builds the WHERE clause selecting the traces of an erasure
+func erasureFilter(filter *models.ErasureFilter) (string, []interface{}, error) {
+	if filter.OrganizationID == "" {
+		return "", nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+
+	set := 0
+	for _, value := range []string{filter.UserID, filter.UserIDHash, filter.TraceID} {
+		if value != "" {
+			set++
+		}
+	}
+	if set == 1 {
+		switch {
+		case filter.UserID != "":
+			return " WHERE organization_id = ? AND user_id = ?", []interface{}{filter.OrganizationID, filter.UserID}, nil
+		case filter.UserIDHash != "":
+			return " WHERE organization_id = ? AND user_id != '' AND " + hashedUserID("user_id") + " = ?",
+				[]interface{}{filter.OrganizationID, filter.UserIDHash}, nil
+		default:
+			return " WHERE organization_id = ? AND trace_id = ?", []interface{}{filter.OrganizationID, filter.TraceID}, nil
+		}
+	}
+	return "", nil, fmt.Errorf("exactly one of user_id, user_id_hash or trace_id is required: %w", ErrInvalidInput)
+}
+
+// CountErasure counts the traces and spans an erasure would remove
+func (r *ClickHouseRepository) CountErasure(ctx context.Context, filter *models.ErasureFilter) (*models.ErasureCounts, error) {
+	where, args, err := erasureFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var traces, spans uint64
+	if err := r.conn.QueryRow(ctx, "SELECT count() FROM traces"+where, args...).Scan(&traces); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	spanSQL := "SELECT count() FROM spans WHERE organization_id = ? AND trace_id IN (SELECT trace_id FROM traces" + where + ")"
+	if err := r.conn.QueryRow(ctx, spanSQL, append([]interface{}{filter.OrganizationID}, args...)...).Scan(&spans); err != nil {
+		return nil, fmt.Errorf("failed to count spans: %w", err)
+	}
+
+	return &models.ErasureCounts{Traces: int64(traces), Spans: int64(spans)}, nil
+}
+
+// aggregateScope is the range of aggregate rows touched by an erasure or cost rewrite
+type aggregateScope struct {
+	count    uint64
+	first    time.Time
+	last     time.Time
+	projects []string
+	users    []string
+}
+
+// EraseTraces permanently removes the selected traces with their spans and metrics,
+// then rebuilds the affected rows of the derived aggregates. Deletes are issued as
+// mutations so the data is physically rewritten, and each one is waited on. Totals of the
+// current hour and day include the erased traces until they close and are rebuilt by
+// RebuildPendingAggregates.
+func (r *ClickHouseRepository) EraseTraces(ctx context.Context, filter *models.ErasureFilter) error {
+	where, args, err := erasureFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	var scope aggregateScope
+	if err := r.conn.QueryRow(ctx, `
+		SELECT count(), min(timestamp), max(timestamp), groupUniqArray(project_id), groupUniqArrayIf(user_id, user_id != '')
+		FROM traces`+where, args...,
+	).Scan(&scope.count, &scope.first, &scope.last, &scope.projects, &scope.users); err != nil {
+		return fmt.Errorf("failed to scope erasure: %w", err)
+	}
+	if scope.count == 0 {
+		return nil
+	}
+
+	// Block until each mutation has finished on all replicas
+	mctx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 2,
+	}))
+
+	traceIDs := "SELECT trace_id FROM traces" + where
+
+	spanArgs := append([]interface{}{filter.OrganizationID}, args...)
+	if err := r.conn.Exec(mctx, "ALTER TABLE spans DELETE WHERE organization_id = ? AND trace_id IN ("+traceIDs+")", spanArgs...); err != nil {
+		return fmt.Errorf("failed to delete spans: %w", err)
+	}
+
+	metricSQL := "ALTER TABLE metrics DELETE WHERE organization_id = ? AND (JSONExtractString(tags, 'trace_id') IN (" + traceIDs + ")"
+	metricArgs := append([]interface{}{filter.OrganizationID}, args...)
+	switch {
+	case filter.UserID != "":
+		metricSQL += " OR JSONExtractString(tags, 'user_id') = ?"
+		metricArgs = append(metricArgs, filter.UserID)
+	case filter.UserIDHash != "":
+		metricSQL += " OR (JSONExtractString(tags, 'user_id') != '' AND " + hashedUserID("JSONExtractString(tags, 'user_id')") + " = ?)"
+		metricArgs = append(metricArgs, filter.UserIDHash)
+	}
+	if err := r.conn.Exec(mctx, metricSQL+")", metricArgs...); err != nil {
+		return fmt.Errorf("failed to delete metrics: %w", err)
+	}
+
+	if err := r.conn.Exec(mctx, "ALTER TABLE traces DELETE"+where, args...); err != nil {
+		return fmt.Errorf("failed to delete traces: %w", err)
+	}
+
+	if err := r.rebuildAggregates(mctx, filter.OrganizationID, &scope); err != nil {
+		return err
+	}
+
+	// An erased user has no traces left, so their rows go too, including the open hour the
+	// rebuild leaves alone
+	if filter.UserID != "" || filter.UserIDHash != "" {
+		if err := r.conn.Exec(mctx, `
+			ALTER TABLE user_stats_hourly DELETE
+			WHERE organization_id = ? AND has(?, user_id)
+		`, filter.OrganizationID, scope.users); err != nil {
+			return fmt.Errorf("failed to clear user stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// rebuildAggregates recomputes the materialized view rows covered by an erasure or cost
+// rewrite from the base tables. Only closed buckets, before the current hour or day, are
+// rebuilt: ingestion still writes the open ones through the views, and clearing and
+// refilling them would count the traces landing in between twice. Open buckets are
+// recorded in aggregate_rebuilds and rebuilt once their day has closed.
+func (r *ClickHouseRepository) rebuildAggregates(ctx context.Context, orgID string, scope *aggregateScope) error {
+	// One cutoff for every statement, so an hour closing mid-rebuild is not refilled uncleared
+	closed := time.Now().UTC().Truncate(time.Hour)
+
+	if !scope.last.Before(closed) {
+		if err := r.deferRebuild(ctx, orgID, scope, closed); err != nil {
+			return err
+		}
+	}
+
+	if err := r.conn.Exec(ctx, `
+		ALTER TABLE daily_costs DELETE
+		WHERE organization_id = ? AND has(?, project_id) AND day BETWEEN toDate(?) AND toDate(?)
+			AND day < toDate(?)
+	`, orgID, scope.projects, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to clear daily costs: %w", err)
+	}
+
+	if err := r.conn.Exec(ctx, `
+		INSERT INTO daily_costs (day, organization_id, project_id, total_cost, trace_count)
+		SELECT
+			toDate(timestamp) AS day,
+			organization_id,
+			project_id,
+			sum(total_cost_usd),
+			count()
+		FROM traces
+		WHERE organization_id = ? AND has(?, project_id)
+			AND toDate(timestamp) BETWEEN toDate(?) AND toDate(?) AND toDate(timestamp) < toDate(?)
+		GROUP BY day, organization_id, project_id
+	`, orgID, scope.projects, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to rebuild daily costs: %w", err)
+	}
+
+	if err := r.conn.Exec(ctx, `
+		ALTER TABLE metrics_hourly DELETE
+		WHERE organization_id = ? AND has(?, project_id) AND hour BETWEEN toStartOfHour(?) AND ? AND hour < ?
+	`, orgID, scope.projects, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to clear hourly metrics: %w", err)
+	}
+
+	if err := r.conn.Exec(ctx, `
+		INSERT INTO metrics_hourly
+		SELECT
+			toStartOfHour(timestamp) AS hour,
+			organization_id,
+			project_id,
+			metric_name,
+			JSONExtractString(tags, 'model') AS model,
+			JSONExtractString(tags, 'provider') AS provider,
+			sumState(metric_value) AS total_value,
+			countState() AS count,
+			quantilesState(0.50, 0.90, 0.95, 0.99)(metric_value) AS quantiles
+		FROM metrics
+		WHERE organization_id = ? AND has(?, project_id)
+			AND timestamp >= toStartOfHour(?) AND timestamp < least(toStartOfHour(?) + INTERVAL 1 HOUR, ?)
+		GROUP BY hour, organization_id, project_id, metric_name, model, provider
+	`, orgID, scope.projects, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to rebuild hourly metrics: %w", err)
+	}
+
+	if len(scope.users) == 0 {
+		return nil
+	}
+
+	if err := r.conn.Exec(ctx, `
+		ALTER TABLE user_stats_hourly DELETE
+		WHERE organization_id = ? AND has(?, user_id) AND hour BETWEEN toStartOfHour(?) AND ? AND hour < ?
+	`, orgID, scope.users, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to clear user stats: %w", err)
+	}
+
+	if err := r.conn.Exec(ctx, `
+		INSERT INTO user_stats_hourly
+		SELECT
+			toStartOfHour(timestamp) AS hour,
+			organization_id,
+			project_id,
+			user_id,
+			countState() AS request_count,
+			countIfState(status = 'error') AS error_count,
+			sumState(total_cost_usd) AS total_cost,
+			sumState(toUInt64(total_tokens)) AS total_tokens,
+			sumState(toUInt64(duration_ms)) AS total_duration_ms,
+			quantilesState(0.50, 0.95, 0.99)(duration_ms) AS latency_quantiles,
+			minState(timestamp) AS first_seen,
+			maxState(timestamp) AS last_seen
+		FROM traces
+		WHERE organization_id = ? AND has(?, user_id)
+			AND timestamp >= toStartOfHour(?) AND timestamp < least(toStartOfHour(?) + INTERVAL 1 HOUR, ?)
+		GROUP BY hour, organization_id, project_id, user_id
+	`, orgID, scope.users, scope.first, scope.last, closed); err != nil {
+		return fmt.Errorf("failed to rebuild user stats: %w", err)
+	}
+
+	return nil
+}
+
+// deferRebuild records the buckets of scope from the open hour on, to be rebuilt once the
+// day of the last one has closed
+func (r *ClickHouseRepository) deferRebuild(ctx context.Context, orgID string, scope *aggregateScope, closed time.Time) error {
+	first := scope.first
+	if first.Before(closed) {
+		first = closed
+	}
+	last := scope.last.UTC()
+	due := time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, time.UTC)
+
+	if err := r.conn.Exec(ctx, `
+		INSERT INTO aggregate_rebuilds (
+			id, organization_id, projects, users, first_timestamp, last_timestamp, due_at, done, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)
+	`, uuid.New().String(), orgID, scope.projects, scope.users, first, scope.last, due, time.Now()); err != nil {
+		return fmt.Errorf("failed to record open aggregate buckets: %w", err)
+	}
+	return nil
+}
+
+// RebuildPendingAggregates rebuilds the open buckets recorded by earlier erasures and cost
+// rewrites whose day has since closed
+func (r *ClickHouseRepository) RebuildPendingAggregates(ctx context.Context) error {
+	rows, err := r.conn.Query(ctx, `
+		SELECT id, organization_id, projects, users, first_timestamp, last_timestamp
+		FROM aggregate_rebuilds FINAL
+		WHERE done = 0 AND due_at <= now64(3)
+		ORDER BY due_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query pending aggregate rebuilds: %w", err)
+	}
+
+	type pendingRebuild struct {
+		id    string
+		orgID string
+		scope aggregateScope
+	}
+	var pending []pendingRebuild
+	for rows.Next() {
+		var p pendingRebuild
+		if err := rows.Scan(&p.id, &p.orgID, &p.scope.projects, &p.scope.users, &p.scope.first, &p.scope.last); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pending aggregate rebuild: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	mctx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 2,
+	}))
+
+	for _, p := range pending {
+		if err := r.rebuildAggregates(mctx, p.orgID, &p.scope); err != nil {
+			return fmt.Errorf("aggregate rebuild %s: %w", p.id, err)
+		}
+		if err := r.conn.Exec(ctx, `
+			INSERT INTO aggregate_rebuilds (
+				id, organization_id, projects, users, first_timestamp, last_timestamp, due_at, done, updated_at
+			)
+			SELECT id, organization_id, projects, users, first_timestamp, last_timestamp, due_at, 1, ?
+			FROM aggregate_rebuilds FINAL
+			WHERE id = ?
+		`, time.Now(), p.id); err != nil {
+			return fmt.Errorf("failed to finish aggregate rebuild %s: %w", p.id, err)
+		}
+	}
+	return nil
+}
+
+// SaveErasureRecord writes a new version of an erasure audit record
+func (r *ClickHouseRepository) SaveErasureRecord(ctx context.Context, record *models.ErasureRecord) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO erasure_audit (
+			id, organization_id, subject_type, subject_id, requested_by, status,
+			traces_deleted, spans_deleted, error, requested_at, completed_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		record.ID,
+		record.OrganizationID,
+		record.SubjectType,
+		record.SubjectID,
+		record.RequestedBy,
+		record.Status,
+		uint64(record.TracesDeleted),
+		uint64(record.SpansDeleted),
+		record.Error,
+		record.RequestedAt,
+		record.CompletedAt,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save erasure record: %w", err)
+	}
+	return nil
+}
+
+// GetErasureRecord returns the latest version of an erasure audit record
+func (r *ClickHouseRepository) GetErasureRecord(ctx context.Context, orgID, id string) (*models.ErasureRecord, error) {
+	records, err := r.queryErasureRecords(ctx, " WHERE organization_id = ? AND id = ?", orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("erasure %s: %w", id, ErrNotFound)
+	}
+	return records[0], nil
+}
+
+// ListErasureRecords returns an organization's erasure audit records, newest first
+func (r *ClickHouseRepository) ListErasureRecords(ctx context.Context, orgID string, limit int) ([]*models.ErasureRecord, error) {
+	return r.queryErasureRecords(ctx, " WHERE organization_id = ? ORDER BY requested_at DESC LIMIT ?", orgID, limit)
+}
+
+// ListUnfinishedErasureRecords returns the pending and running erasures of every organization
+func (r *ClickHouseRepository) ListUnfinishedErasureRecords(ctx context.Context) ([]*models.ErasureRecord, error) {
+	return r.queryErasureRecords(ctx, " WHERE status IN ('pending', 'running') ORDER BY requested_at ASC")
+}
+
+func (r *ClickHouseRepository) queryErasureRecords(ctx context.Context, clause string, args ...interface{}) ([]*models.ErasureRecord, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, subject_type, subject_id, requested_by, status,
+			traces_deleted, spans_deleted, error, requested_at, completed_at
+		FROM erasure_audit FINAL`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query erasure records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*models.ErasureRecord{}
+	for rows.Next() {
+		var record models.ErasureRecord
+		var tracesDeleted, spansDeleted uint64
+
+		if err := rows.Scan(
+			&record.ID,
+			&record.OrganizationID,
+			&record.SubjectType,
+			&record.SubjectID,
+			&record.RequestedBy,
+			&record.Status,
+			&tracesDeleted,
+			&spansDeleted,
+			&record.Error,
+			&record.RequestedAt,
+			&record.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan erasure record: %w", err)
+		}
+
+		record.TracesDeleted = int64(tracesDeleted)
+		record.SpansDeleted = int64(spansDeleted)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetExistingTraceIDs returns which of the given trace IDs are already stored by any
+// organization, each mapped to whether orgID is one of them
+func (r *ClickHouseRepository) GetExistingTraceIDs(ctx context.Context, orgID string, traceIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(traceIDs) == 0 {
+		return existing, nil
+	}
+
+	placeholders := make([]string, 0, len(traceIDs))
+	args := []interface{}{orgID}
+	for _, id := range traceIDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT trace_id, max(organization_id = ?)
+		FROM traces
+		WHERE trace_id IN (`+strings.Join(placeholders, ", ")+`)
+		GROUP BY trace_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing traces: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var own uint8
+		if err := rows.Scan(&id, &own); err != nil {
+			return nil, fmt.Errorf("failed to scan trace id: %w", err)
+		}
+		existing[id] = own == 1
+	}
+
+	return existing, rows.Err()
+}
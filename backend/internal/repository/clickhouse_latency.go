@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// latencyGroupColumns maps a latency grouping onto the column it reads
+var latencyGroupColumns = map[string]string{
+	"":          "''",
+	"model":     "model",
+	"provider":  "provider",
+	"span_name": "name",
+}
+
+// latencyBuckets maps a time series granularity onto its bucketing function
+var latencyBuckets = map[string]string{
+	"hour": "toStartOfHour(%s)",
+	"day":  "toStartOfDay(%s)",
+	"week": "toDateTime(toStartOfWeek(%s, 1))",
+}
+
+// latencySource builds the FROM/WHERE part of a latency query and returns it with
+// its arguments, the grouping expression and the time column
+func latencySource(query *models.LatencyQuery) (string, []interface{}, string, string, error) {
+	group, ok := latencyGroupColumns[query.GroupBy]
+	if !ok || (query.GroupBy == "span_name" && query.Level != "span") {
+		return "", nil, "", "", fmt.Errorf("unsupported latency grouping %q: %w", query.GroupBy, ErrInvalidInput)
+	}
+
+	traceWhere := " WHERE organization_id = ?"
+	traceArgs := []interface{}{query.OrganizationID}
+	if query.ProjectID != "" {
+		traceWhere += " AND project_id = ?"
+		traceArgs = append(traceArgs, query.ProjectID)
+	}
+	traceWhere += " AND timestamp >= ? AND timestamp < ?"
+	traceArgs = append(traceArgs, query.StartTime, query.EndTime)
+
+	var source, timeColumn string
+	var args []interface{}
+
+	switch query.Level {
+	case "trace":
+		source, timeColumn, args = "traces"+traceWhere, "timestamp", traceArgs
+	case "span":
+		// Spans carry no organization, so they are scoped through their traces
+		source = "spans WHERE trace_id IN (SELECT trace_id FROM traces" + traceWhere + ")"
+		timeColumn, args = "start_time", traceArgs
+	default:
+		return "", nil, "", "", fmt.Errorf("unsupported latency level %q: %w", query.Level, ErrInvalidInput)
+	}
+
+	if query.Model != "" {
+		source += " AND model = ?"
+		args = append(args, query.Model)
+	}
+	if query.Provider != "" {
+		source += " AND provider = ?"
+		args = append(args, query.Provider)
+	}
+
+	return source, args, group, timeColumn, nil
+}
+
+// GetLatencyDistribution computes latency percentiles, a histogram and a percentile
+// time series for each of the busiest groups in two round trips
+func (r *ClickHouseRepository) GetLatencyDistribution(ctx context.Context, query *models.LatencyQuery) ([]*models.LatencySeries, error) {
+	source, sourceArgs, group, timeColumn, err := latencySource(query)
+	if err != nil {
+		return nil, err
+	}
+	bucket, ok := latencyBuckets[query.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported granularity %q: %w", query.Granularity, ErrInvalidInput)
+	}
+
+	// Histogram counts come back cumulative: one "duration <= bound" count per bucket bound
+	args := append([]interface{}{query.Buckets}, sourceArgs...)
+	args = append(args, query.Limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			`+group+` AS grp,
+			count(),
+			avg(duration_ms),
+			quantiles(0.50, 0.90, 0.95, 0.99)(duration_ms),
+			sumForEach(arrayMap(bound -> toUInt64(duration_ms <= bound), ?))
+		FROM `+source+`
+		GROUP BY grp
+		ORDER BY count() DESC, grp
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latency distribution: %w", err)
+	}
+	defer rows.Close()
+
+	series := []*models.LatencySeries{}
+	byGroup := make(map[string]*models.LatencySeries)
+	groups := []string{}
+
+	for rows.Next() {
+		var s models.LatencySeries
+		var count uint64
+		var quantiles []float64
+		var cumulative []uint64
+
+		if err := rows.Scan(&s.Group, &count, &s.Stats.AvgLatencyMs, &quantiles, &cumulative); err != nil {
+			return nil, fmt.Errorf("failed to scan latency distribution: %w", err)
+		}
+		s.Stats.Count = int64(count)
+		setLatencyQuantiles(&s.Stats, quantiles)
+		s.Histogram = latencyHistogram(query.Buckets, cumulative, count)
+		s.TimeSeries = []models.LatencyPoint{}
+
+		series = append(series, &s)
+		byGroup[s.Group] = &s
+		groups = append(groups, s.Group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return series, nil
+	}
+
+	timeBucket := fmt.Sprintf(bucket, timeColumn)
+	args = append(sourceArgs, groups)
+
+	rows, err = r.conn.Query(ctx, `
+		SELECT
+			`+group+` AS grp,
+			`+timeBucket+` AS bucket,
+			count(),
+			avg(duration_ms),
+			quantiles(0.50, 0.90, 0.95, 0.99)(duration_ms)
+		FROM `+source+` AND has(?, `+group+`)
+		GROUP BY grp, bucket
+		ORDER BY grp, bucket
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latency time series: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupKey string
+		var point models.LatencyPoint
+		var count uint64
+		var quantiles []float64
+
+		if err := rows.Scan(&groupKey, &point.Timestamp, &count, &point.AvgLatencyMs, &quantiles); err != nil {
+			return nil, fmt.Errorf("failed to scan latency time series: %w", err)
+		}
+		point.Count = int64(count)
+		setLatencyQuantiles(&point.LatencyStats, quantiles)
+
+		if s, ok := byGroup[groupKey]; ok {
+			s.TimeSeries = append(s.TimeSeries, point)
+		}
+	}
+
+	return series, rows.Err()
+}
+
+// setLatencyQuantiles copies the result of quantiles(0.50, 0.90, 0.95, 0.99)
+func setLatencyQuantiles(stats *models.LatencyStats, quantiles []float64) {
+	if len(quantiles) == 4 {
+		stats.P50LatencyMs = quantiles[0]
+		stats.P90LatencyMs = quantiles[1]
+		stats.P95LatencyMs = quantiles[2]
+		stats.P99LatencyMs = quantiles[3]
+	}
+}
+
+// latencyHistogram turns cumulative "duration <= bound" counts into per-bucket counts,
+// adding an open-ended bucket for the durations above the last bound
+func latencyHistogram(bounds []float64, cumulative []uint64, total uint64) []models.LatencyBucket {
+	histogram := make([]models.LatencyBucket, 0, len(bounds)+1)
+
+	var lower float64
+	var below uint64
+	for i, bound := range bounds {
+		var count uint64
+		if i < len(cumulative) {
+			count = cumulative[i] - below
+			below = cumulative[i]
+		}
+
+		max := bound
+		histogram = append(histogram, models.LatencyBucket{MinMs: lower, MaxMs: &max, Count: int64(count)})
+		lower = bound
+	}
+
+	return append(histogram, models.LatencyBucket{MinMs: lower, Count: int64(total - below)})
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// intervalSeconds is the width of each time series interval, used to turn sums into rates
+var intervalSeconds = map[string]int{
+	"minute": 60,
+	"hour":   3600,
+	"day":    86400,
+	"week":   604800,
+}
+
+// rawMetricAggregates maps each custom metric aggregation onto its aggregate over raw metrics
+var rawMetricAggregates = map[string]string{
+	"sum":   "sum(metric_value)",
+	"avg":   "avg(metric_value)",
+	"min":   "min(metric_value)",
+	"max":   "max(metric_value)",
+	"count": "count()",
+	"rate":  "sum(metric_value) / %d",
+	"p50":   "quantile(0.50)(metric_value)",
+	"p90":   "quantile(0.90)(metric_value)",
+	"p95":   "quantile(0.95)(metric_value)",
+	"p99":   "quantile(0.99)(metric_value)",
+}
+
+// hourlyMetricAggregates maps each custom metric aggregation onto its aggregate over metrics_hourly.
+// The rollup keeps no extremes, so min and max need raw metrics.
+var hourlyMetricAggregates = map[string]string{
+	"sum":   "sumMerge(total_value)",
+	"avg":   "sumMerge(total_value) / countMerge(count)",
+	"count": "countMerge(count)",
+	"rate":  "sumMerge(total_value) / %d",
+	"p50":   "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[1]",
+	"p90":   "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[2]",
+	"p95":   "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[3]",
+	"p99":   "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[4]",
+}
+
+// hourlyMetricColumns are the tags metrics_hourly keeps as columns
+var hourlyMetricColumns = map[string]string{
+	"project":  "project_id",
+	"model":    "model",
+	"provider": "provider",
+}
+
+// QueryMetrics aggregates a custom metric over time for each of the busiest groups.
+// Buckets without data are omitted.
+func (r *ClickHouseRepository) QueryMetrics(ctx context.Context, query *models.CustomMetricQuery) ([]*models.TimeSeries, error) {
+	interval, ok := seriesIntervals[query.Granularity]
+	if !ok || (query.Source == SeriesSourceHourly && query.Granularity == "minute") {
+		return nil, fmt.Errorf("unsupported interval %q for %s: %w", query.Granularity, query.Source, ErrInvalidInput)
+	}
+
+	var table, timeColumn, aggregate, rank, group string
+	var groupArgs []interface{}
+	where := " WHERE organization_id = ? AND metric_name = ?"
+	args := []interface{}{query.OrganizationID, query.MetricName}
+
+	switch query.Source {
+	case SeriesSourceMetrics:
+		aggregate, ok = rawMetricAggregates[query.Aggregation]
+		table, timeColumn, rank = "metrics", "timestamp", "count()"
+		where += " AND timestamp >= ? AND timestamp < ?"
+
+		switch query.GroupBy {
+		case "":
+			group = "''"
+		case "project":
+			group = "project_id"
+		default:
+			group, groupArgs = "JSONExtractString(tags, ?)", []interface{}{query.GroupBy}
+		}
+	case SeriesSourceHourly:
+		aggregate, ok = hourlyMetricAggregates[query.Aggregation]
+		table, timeColumn, rank = "metrics_hourly", "hour", "countMerge(count)"
+		where += " AND hour >= toStartOfHour(?) AND hour < ?"
+
+		group = "''"
+		if query.GroupBy != "" {
+			var known bool
+			if group, known = hourlyMetricColumns[query.GroupBy]; !known {
+				return nil, fmt.Errorf("cannot group %s by %q: %w", query.Source, query.GroupBy, ErrInvalidInput)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported metric source %q: %w", query.Source, ErrInvalidInput)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported aggregation %q for %s: %w", query.Aggregation, query.Source, ErrInvalidInput)
+	}
+	if query.Aggregation == "rate" {
+		aggregate = fmt.Sprintf(aggregate, intervalSeconds[query.Granularity])
+	}
+	args = append(args, query.StartTime, query.EndTime)
+
+	if query.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, query.ProjectID)
+	}
+	for key, value := range query.Tags {
+		if query.Source == SeriesSourceHourly {
+			column, ok := hourlyMetricColumns[key]
+			if !ok || key == "project" {
+				return nil, fmt.Errorf("cannot filter %s by tag %q: %w", query.Source, key, ErrInvalidInput)
+			}
+			where += " AND " + column + " = ?"
+			args = append(args, value)
+			continue
+		}
+		where += " AND JSONExtractString(tags, ?) = ?"
+		args = append(args, key, value)
+	}
+
+	// Keep the series of the busiest groups only
+	sql := `
+		SELECT ` + group + ` AS grp, ` + fmt.Sprintf(interval, timeColumn) + ` AS bucket, toFloat64(` + aggregate + `)
+		FROM ` + table + where
+	queryArgs := append(append([]interface{}{}, groupArgs...), args...)
+	if query.GroupBy != "" {
+		sql += ` AND grp IN (
+			SELECT ` + group + ` AS g FROM ` + table + where + `
+			GROUP BY g ORDER BY ` + rank + ` DESC LIMIT ?
+		)`
+		queryArgs = append(queryArgs, groupArgs...)
+		queryArgs = append(queryArgs, args...)
+		queryArgs = append(queryArgs, query.Limit)
+	}
+	sql += `
+		GROUP BY grp, bucket
+		ORDER BY grp, bucket`
+
+	rows, err := r.conn.Query(ctx, sql, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	series := []*models.TimeSeries{}
+	var current *models.TimeSeries
+	for rows.Next() {
+		var groupKey string
+		var point models.TimeSeriesValue
+
+		if err := rows.Scan(&groupKey, &point.Timestamp, &point.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan metric series: %w", err)
+		}
+
+		if current == nil || current.Group != groupKey {
+			current = &models.TimeSeries{Group: groupKey}
+			series = append(series, current)
+		}
+		current.Points = append(current.Points, point)
+	}
+
+	return series, rows.Err()
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// GetModelUsage aggregates LLM calls per model from the spans of an organization's traces.
+// Spans are used rather than traces so that multi-model traces count every model they call.
+func (r *ClickHouseRepository) GetModelUsage(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ModelUsage, error) {
+	where, args := analyticsFilter(&models.AnalyticsQuery{OrganizationID: orgID, ProjectID: projectID})
+	args = append(append([]interface{}{orgID}, args...), startTime, endTime)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			model,
+			any(provider),
+			count(),
+			countIf(status = 'error'),
+			sum(cost_usd),
+			sum(total_tokens),
+			sum(prompt_tokens),
+			sum(completion_tokens),
+			avg(duration_ms),
+			stddevSamp(duration_ms),
+			quantiles(0.50, 0.95, 0.99)(duration_ms)
+		FROM spans
+		WHERE organization_id = ? AND model != '' AND trace_id IN (
+			SELECT trace_id FROM traces`+where+` AND timestamp >= ? AND timestamp < ?
+		)
+		GROUP BY model
+		ORDER BY count() DESC, model
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.ModelUsage{}
+	for rows.Next() {
+		var u models.ModelUsage
+		var calls, errors, tokens, promptTokens, completionTokens uint64
+		var quantiles []float64
+
+		if err := rows.Scan(
+			&u.Model,
+			&u.Provider,
+			&calls,
+			&errors,
+			&u.TotalCost,
+			&tokens,
+			&promptTokens,
+			&completionTokens,
+			&u.AvgLatency,
+			&u.LatencyStdDev,
+			&quantiles,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan model usage: %w", err)
+		}
+
+		u.Count = int64(calls)
+		u.CallCount = int64(calls)
+		u.ErrorCount = int64(errors)
+		u.TotalTokens = int64(tokens)
+		u.PromptTokens = int64(promptTokens)
+		u.CompletionTokens = int64(completionTokens)
+		u.AvgTokens = float64(tokens) / float64(calls)
+		// stddevSamp is NaN for a single call
+		if math.IsNaN(u.LatencyStdDev) {
+			u.LatencyStdDev = 0
+		}
+		if len(quantiles) == 3 {
+			u.P50LatencyMs, u.P95LatencyMs, u.P99LatencyMs = quantiles[0], quantiles[1], quantiles[2]
+		}
+
+		usage = append(usage, &u)
+	}
+
+	return usage, rows.Err()
+}
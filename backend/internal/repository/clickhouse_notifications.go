@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// SaveNotificationChannel writes a new version of a notification channel
+func (r *ClickHouseRepository) SaveNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	config, err := json.Marshal(channel.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode channel config: %w", err)
+	}
+
+	err = r.conn.Exec(ctx, `
+		INSERT INTO notification_channels (
+			id, organization_id, name, type, config, template, kinds, enabled,
+			created_by, created_at, updated_at, deleted
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		channel.ID,
+		channel.OrganizationID,
+		channel.Name,
+		channel.Type,
+		string(config),
+		channel.Template,
+		channel.Kinds,
+		channel.Enabled,
+		channel.CreatedBy,
+		channel.CreatedAt,
+		channel.UpdatedAt,
+		channel.Deleted,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notification channel: %w", err)
+	}
+	return nil
+}
+
+// ListNotificationChannels returns the notification channels of an organization
+func (r *ClickHouseRepository) ListNotificationChannels(ctx context.Context, orgID string) ([]*models.NotificationChannel, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, name, type, config, template, kinds, enabled,
+			created_by, created_at, updated_at
+		FROM notification_channels FINAL
+		WHERE deleted = 0 AND organization_id = ?
+		ORDER BY created_at
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []*models.NotificationChannel{}
+	for rows.Next() {
+		var (
+			channel models.NotificationChannel
+			config  string
+			enabled uint8
+		)
+		if err := rows.Scan(
+			&channel.ID,
+			&channel.OrganizationID,
+			&channel.Name,
+			&channel.Type,
+			&config,
+			&channel.Template,
+			&channel.Kinds,
+			&enabled,
+			&channel.CreatedBy,
+			&channel.CreatedAt,
+			&channel.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		if err := json.Unmarshal([]byte(config), &channel.Config); err != nil {
+			return nil, fmt.Errorf("failed to decode config of channel %s: %w", channel.ID, err)
+		}
+		channel.Enabled = enabled == 1
+		channels = append(channels, &channel)
+	}
+
+	return channels, rows.Err()
+}
+
+// SaveNotificationDelivery records the outcome of sending a notification to a channel
+func (r *ClickHouseRepository) SaveNotificationDelivery(ctx context.Context, delivery *models.NotificationDelivery) error {
+	err := r.conn.Exec(ctx, `
+		INSERT INTO notification_deliveries (
+			id, organization_id, channel_id, channel_type, kind, title, status,
+			attempts, error, created_at, completed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		delivery.ID,
+		delivery.OrganizationID,
+		delivery.ChannelID,
+		delivery.ChannelType,
+		delivery.Kind,
+		delivery.Title,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.Error,
+		delivery.CreatedAt,
+		delivery.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notification delivery: %w", err)
+	}
+	return nil
+}
+
+// ListNotificationDeliveries returns an organization's delivery log, newest first
+func (r *ClickHouseRepository) ListNotificationDeliveries(ctx context.Context, query *models.NotificationDeliveryQuery) ([]*models.NotificationDelivery, error) {
+	if query.OrganizationID == "" {
+		return nil, fmt.Errorf("organization_id is required: %w", ErrInvalidInput)
+	}
+
+	where := " WHERE organization_id = ? AND created_at >= ? AND created_at < ?"
+	args := []interface{}{query.OrganizationID, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"channel_id", query.ChannelID},
+		{"status", query.Status},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			id, organization_id, channel_id, channel_type, kind, title, status,
+			attempts, error, created_at, completed_at
+		FROM notification_deliveries`+where+`
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.NotificationDelivery{}
+	for rows.Next() {
+		var (
+			delivery models.NotificationDelivery
+			attempts uint16
+		)
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.OrganizationID,
+			&delivery.ChannelID,
+			&delivery.ChannelType,
+			&delivery.Kind,
+			&delivery.Title,
+			&delivery.Status,
+			&attempts,
+			&delivery.Error,
+			&delivery.CreatedAt,
+			&delivery.CompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan notification delivery: %w", err)
+		}
+		delivery.Attempts = int(attempts)
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
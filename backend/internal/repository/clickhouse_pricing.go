@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// ListModelPrices returns the latest version of every price entry
+func (r *ClickHouseRepository) ListModelPrices(ctx context.Context) ([]*models.ModelPrice, error) {
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			provider, model, aliases, match_prefix, input_per_million, output_per_million,
+			cached_input_per_million, reasoning_per_million, image_input_per_million,
+			audio_per_minute, batch_discount, effective_from, source, catalog_version,
+			updated_by, updated_at, organization_id, project_id
+		FROM model_prices FINAL
+		ORDER BY organization_id, project_id, provider, model, effective_from
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []*models.ModelPrice{}
+	for rows.Next() {
+		var price models.ModelPrice
+		var matchPrefix uint8
+		var catalogVersion uint32
+
+		if err := rows.Scan(
+			&price.Provider,
+			&price.Model,
+			&price.Aliases,
+			&matchPrefix,
+			&price.InputPerMillion,
+			&price.OutputPerMillion,
+			&price.CachedInputPerMillion,
+			&price.ReasoningPerMillion,
+			&price.ImageInputPerMillion,
+			&price.AudioPerMinute,
+			&price.BatchDiscount,
+			&price.EffectiveFrom,
+			&price.Source,
+			&catalogVersion,
+			&price.UpdatedBy,
+			&price.UpdatedAt,
+			&price.OrganizationID,
+			&price.ProjectID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan model price: %w", err)
+		}
+
+		price.MatchPrefix = matchPrefix == 1
+		price.CatalogVersion = int(catalogVersion)
+		prices = append(prices, &price)
+	}
+
+	return prices, rows.Err()
+}
+
+// SaveModelPrices stores price entries in a single batch, replacing entries with the same
+// scope, provider, model and effective date
+func (r *ClickHouseRepository) SaveModelPrices(ctx context.Context, prices []*models.ModelPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO model_prices (
+			provider, model, aliases, match_prefix, input_per_million, output_per_million,
+			cached_input_per_million, reasoning_per_million, image_input_per_million,
+			audio_per_minute, batch_discount, effective_from, source, catalog_version,
+			updated_by, updated_at, organization_id, project_id
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare model prices batch: %w", err)
+	}
+
+	for _, price := range prices {
+		var matchPrefix uint8
+		if price.MatchPrefix {
+			matchPrefix = 1
+		}
+		aliases := price.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+
+		if err := batch.Append(
+			price.Provider,
+			price.Model,
+			aliases,
+			matchPrefix,
+			price.InputPerMillion,
+			price.OutputPerMillion,
+			price.CachedInputPerMillion,
+			price.ReasoningPerMillion,
+			price.ImageInputPerMillion,
+			price.AudioPerMinute,
+			price.BatchDiscount,
+			price.EffectiveFrom,
+			price.Source,
+			uint32(price.CatalogVersion),
+			price.UpdatedBy,
+			price.UpdatedAt,
+			price.OrganizationID,
+			price.ProjectID,
+		); err != nil {
+			return fmt.Errorf("failed to append model price: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save model prices: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// streamPageSize is the number of traces loaded per keyset page by StreamTraces
+const streamPageSize = 500
+
+// StreamTraces walks every trace matching the query, newest first, with spans attached.
+// Traces are loaded in keyset pages so memory stays bounded regardless of result size.
+// Iteration stops at the first error returned by fn.
+func (r *ClickHouseRepository) StreamTraces(ctx context.Context, query *models.TraceQuery, fn func(*models.Trace) error) error {
+	where, args := traceFilter(query)
+
+	var (
+		lastTimestamp time.Time
+		lastTraceID   string
+		emitted       int
+	)
+
+	for {
+		pageSQL := `
+			SELECT
+				trace_id, organization_id, project_id, timestamp,
+				trace_type, duration_ms, status, total_cost_usd,
+				total_tokens, model, provider, user_id, metadata, price_source
+			FROM traces` + where
+		pageArgs := append([]interface{}{}, args...)
+
+		if lastTraceID != "" {
+			pageSQL += " AND (timestamp, trace_id) < (?, ?)"
+			pageArgs = append(pageArgs, lastTimestamp, lastTraceID)
+		}
+
+		pageSQL += " ORDER BY timestamp DESC, trace_id DESC LIMIT ?"
+		pageArgs = append(pageArgs, streamPageSize)
+
+		traces, err := r.queryTracePage(ctx, pageSQL, pageArgs)
+		if err != nil {
+			return err
+		}
+		if len(traces) == 0 {
+			return nil
+		}
+
+		if err := r.attachSpans(ctx, traces); err != nil {
+			return err
+		}
+
+		for _, trace := range traces {
+			if query.Limit > 0 && emitted >= query.Limit {
+				return nil
+			}
+			if err := fn(trace); err != nil {
+				return err
+			}
+			emitted++
+		}
+
+		last := traces[len(traces)-1]
+		lastTimestamp, lastTraceID = last.Timestamp, last.TraceID
+
+		if len(traces) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+// traceFilter builds the WHERE clause shared by trace listing queries
+func traceFilter(query *models.TraceQuery) (string, []interface{}) {
+	where := " WHERE organization_id = ?"
+	args := []interface{}{query.OrganizationID}
+
+	if query.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, query.ProjectID)
+	}
+
+	if !query.StartTime.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, query.StartTime)
+	}
+
+	if !query.EndTime.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, query.EndTime)
+	}
+
+	if query.Status != "" {
+		where += " AND status = ?"
+		args = append(args, query.Status)
+	}
+
+	if query.UserID != "" {
+		where += " AND user_id = ?"
+		args = append(args, query.UserID)
+	}
+
+	if query.Model != "" {
+		where += " AND model = ?"
+		args = append(args, query.Model)
+	}
+
+	if query.Provider != "" {
+		where += " AND provider = ?"
+		args = append(args, query.Provider)
+	}
+
+	return where, args
+}
+
+// queryTracePage runs a trace SELECT including metadata and scans the rows
+func (r *ClickHouseRepository) queryTracePage(ctx context.Context, sql string, args []interface{}) ([]*models.Trace, error) {
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer rows.Close()
+
+	var traces []*models.Trace
+	for rows.Next() {
+		var trace models.Trace
+		var durationMs, totalTokens uint32
+		var metadataJSON string
+
+		if err := rows.Scan(
+			&trace.TraceID,
+			&trace.OrganizationID,
+			&trace.ProjectID,
+			&trace.Timestamp,
+			&trace.TraceType,
+			&durationMs,
+			&trace.Status,
+			&trace.TotalCostUSD,
+			&totalTokens,
+			&trace.Model,
+			&trace.Provider,
+			&trace.UserID,
+			&metadataJSON,
+			&trace.PriceSource,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan trace: %w", err)
+		}
+
+		trace.DurationMs = int64(durationMs)
+		trace.TotalTokens = int(totalTokens)
+
+		if metadataJSON != "" && metadataJSON != "{}" {
+			json.Unmarshal([]byte(metadataJSON), &trace.Metadata)
+		}
+
+		traces = append(traces, &trace)
+	}
+
+	return traces, rows.Err()
+}
+
+// attachSpans loads the spans of a page of traces with a single query
+func (r *ClickHouseRepository) attachSpans(ctx context.Context, traces []*models.Trace) error {
+	byID := make(map[string]*models.Trace, len(traces))
+	placeholders := make([]string, 0, len(traces))
+	args := make([]interface{}, 0, 2*len(traces))
+
+	for _, trace := range traces {
+		byID[trace.TraceID] = trace
+		placeholders = append(placeholders, "(?, ?)")
+		args = append(args, trace.OrganizationID, trace.TraceID)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT `+spanColumns+`
+		FROM spans
+		WHERE (organization_id, trace_id) IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY trace_id, start_time ASC
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query spans: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		span, err := scanSpan(rows)
+		if err != nil {
+			return err
+		}
+		if trace, ok := byID[span.TraceID]; ok {
+			trace.Spans = append(trace.Spans, span)
+		}
+	}
+
+	return rows.Err()
+}
@@ -220,3 +220,83 @@ func TestGetMetricSummary(t *testing.T) {
 
 	t.Logf("Metric Summary: %+v", summary)
 }
+
+// TestGetTopUsers tests end-user aggregation
+func TestGetTopUsers(t *testing.T) {
+	repo, err := NewClickHouseRepository(getTestDSN())
+	if err != nil {
+		t.Skipf("Skipping test: ClickHouse not available: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	orgID := "test-org-" + uuid.New().String()
+	projectID := "test-proj-" + uuid.New().String()
+
+	// Heavy user with two traces, light user with one
+	for i, userID := range []string{"heavy-user", "heavy-user", "light-user"} {
+		trace := &models.Trace{
+			TraceID:        uuid.New().String(),
+			OrganizationID: orgID,
+			ProjectID:      projectID,
+			Timestamp:      time.Now().Add(time.Duration(-i) * time.Minute),
+			TraceType:      "single_call",
+			DurationMs:     int64(100 + i*50),
+			Status:         "success",
+			TotalCostUSD:   0.01,
+			TotalTokens:    100,
+			Model:          "gpt-4",
+			Provider:       "openai",
+			UserID:         userID,
+			Metadata:       map[string]string{},
+			Spans:          []models.Span{},
+		}
+
+		if err := repo.SaveTrace(ctx, trace); err != nil {
+			t.Fatalf("Failed to save trace %d: %v", i, err)
+		}
+	}
+
+	users, err := repo.GetTopUsers(ctx, &models.UserAnalyticsQuery{
+		OrganizationID: orgID,
+		ProjectID:      projectID,
+		StartTime:      time.Now().Add(-1 * time.Hour),
+		EndTime:        time.Now().Add(time.Minute),
+		SortBy:         "cost",
+		Limit:          10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get top users: %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+
+	if users[0].UserID != "heavy-user" || users[0].RequestCount != 2 {
+		t.Errorf("Expected heavy-user with 2 requests first, got %s with %d", users[0].UserID, users[0].RequestCount)
+	}
+}
+
+// TestLatencyHistogram tests conversion of cumulative bucket counts
+func TestLatencyHistogram(t *testing.T) {
+	histogram := latencyHistogram([]float64{100, 500}, []uint64{3, 7}, 10)
+
+	if len(histogram) != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", len(histogram))
+	}
+
+	expected := []int64{3, 4, 3}
+	for i, bucket := range histogram {
+		if bucket.Count != expected[i] {
+			t.Errorf("Bucket %d: expected %d, got %d", i, expected[i], bucket.Count)
+		}
+	}
+
+	if histogram[1].MinMs != 100 || *histogram[1].MaxMs != 500 {
+		t.Errorf("Expected bucket (100, 500], got %+v", histogram[1])
+	}
+	if histogram[2].MinMs != 500 || histogram[2].MaxMs != nil {
+		t.Errorf("Expected open-ended last bucket, got %+v", histogram[2])
+	}
+}
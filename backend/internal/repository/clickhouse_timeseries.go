@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// Time series sources
+const (
+	SeriesSourceTraces  = "traces"
+	SeriesSourceMetrics = "metrics"
+	SeriesSourceHourly  = "metrics_hourly"
+)
+
+// traceSeriesMetrics maps each time series metric onto its aggregate over raw traces
+var traceSeriesMetrics = map[string]string{
+	"requests":    "count()",
+	"cost":        "sum(total_cost_usd)",
+	"tokens":      "sum(total_tokens)",
+	"latency_avg": "avg(duration_ms)",
+	"latency_p50": "quantile(0.50)(duration_ms)",
+	"latency_p90": "quantile(0.90)(duration_ms)",
+	"latency_p95": "quantile(0.95)(duration_ms)",
+	"latency_p99": "quantile(0.99)(duration_ms)",
+	"error_rate":  "countIf(status = 'error') * 100.0 / count()",
+}
+
+// hourlySeriesMetric is a time series metric read from metrics_hourly
+type hourlySeriesMetric struct {
+	name      string // metric_name recorded per trace
+	aggregate string
+}
+
+// hourlySeriesMetrics maps each time series metric onto its aggregate over metrics_hourly
+var hourlySeriesMetrics = map[string]hourlySeriesMetric{
+	"requests":    {"requests", "sumMerge(total_value)"},
+	"cost":        {"cost_usd", "sumMerge(total_value)"},
+	"tokens":      {"tokens", "sumMerge(total_value)"},
+	"latency_avg": {"latency_ms", "sumMerge(total_value) / countMerge(count)"},
+	"latency_p50": {"latency_ms", "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[1]"},
+	"latency_p90": {"latency_ms", "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[2]"},
+	"latency_p95": {"latency_ms", "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[3]"},
+	"latency_p99": {"latency_ms", "quantilesMerge(0.50, 0.90, 0.95, 0.99)(quantiles)[4]"},
+	"error_rate":  {"errors", "sumMerge(total_value) * 100.0 / countMerge(count)"},
+}
+
+// seriesGroupColumns maps each group-by dimension onto its column per source
+var seriesGroupColumns = map[string]map[string]string{
+	SeriesSourceTraces: {
+		"":         "''",
+		"model":    "model",
+		"provider": "provider",
+		"project":  "project_id",
+		"user":     "user_id",
+		"status":   "status",
+	},
+	SeriesSourceHourly: {
+		"":         "''",
+		"model":    "model",
+		"provider": "provider",
+		"project":  "project_id",
+	},
+}
+
+// seriesIntervals maps each interval onto its bucketing function
+var seriesIntervals = map[string]string{
+	"minute": "toStartOfMinute(%s)",
+	"hour":   "toStartOfHour(%s)",
+	"day":    "toStartOfDay(%s)",
+	"week":   "toDateTime(toMonday(%s))",
+}
+
+// GetTimeSeries returns one metric bucketed over time for each of the busiest groups.
+// Buckets without data are omitted.
+func (r *ClickHouseRepository) GetTimeSeries(ctx context.Context, query *models.TimeSeriesQuery) ([]*models.TimeSeries, error) {
+	group, ok := seriesGroupColumns[query.Source][query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("cannot group %s by %q: %w", query.Source, query.GroupBy, ErrInvalidInput)
+	}
+	interval, ok := seriesIntervals[query.Granularity]
+	if !ok || (query.Source == SeriesSourceHourly && query.Granularity == "minute") {
+		return nil, fmt.Errorf("unsupported interval %q for %s: %w", query.Granularity, query.Source, ErrInvalidInput)
+	}
+
+	var table, timeColumn, aggregate, rank, where string
+	var args []interface{}
+
+	switch query.Source {
+	case SeriesSourceTraces:
+		metric, ok := traceSeriesMetrics[query.MetricName]
+		if !ok {
+			return nil, fmt.Errorf("unsupported metric %q: %w", query.MetricName, ErrInvalidInput)
+		}
+		table, timeColumn, aggregate, rank = "traces", "timestamp", metric, "count()"
+		where, args = seriesTraceFilter(query)
+	case SeriesSourceHourly:
+		metric, ok := hourlySeriesMetrics[query.MetricName]
+		if !ok {
+			return nil, fmt.Errorf("unsupported metric %q: %w", query.MetricName, ErrInvalidInput)
+		}
+		if query.UserID != "" || query.Status != "" {
+			return nil, fmt.Errorf("user and status filters need raw traces: %w", ErrInvalidInput)
+		}
+		table, timeColumn, aggregate, rank = "metrics_hourly", "hour", metric.aggregate, "countMerge(count)"
+		where, args = seriesHourlyFilter(query, metric.name)
+	default:
+		return nil, fmt.Errorf("unsupported time series source %q: %w", query.Source, ErrInvalidInput)
+	}
+
+	// Keep the series of the busiest groups only
+	sql := `
+		SELECT ` + group + ` AS grp, ` + fmt.Sprintf(interval, timeColumn) + ` AS bucket, toFloat64(` + aggregate + `)
+		FROM ` + table + where
+	if query.GroupBy != "" {
+		sql += ` AND ` + group + ` IN (
+			SELECT ` + group + ` FROM ` + table + where + `
+			GROUP BY ` + group + ` ORDER BY ` + rank + ` DESC LIMIT ?
+		)`
+		args = append(args, args...)
+		args = append(args, query.Limit)
+	}
+	sql += `
+		GROUP BY grp, bucket
+		ORDER BY grp, bucket`
+
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time series: %w", err)
+	}
+	defer rows.Close()
+
+	series := []*models.TimeSeries{}
+	var current *models.TimeSeries
+	for rows.Next() {
+		var groupKey string
+		var point models.TimeSeriesValue
+
+		if err := rows.Scan(&groupKey, &point.Timestamp, &point.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan time series: %w", err)
+		}
+
+		if current == nil || current.Group != groupKey {
+			current = &models.TimeSeries{Group: groupKey}
+			series = append(series, current)
+		}
+		current.Points = append(current.Points, point)
+	}
+
+	return series, rows.Err()
+}
+
+// seriesTraceFilter builds the WHERE clause of a time series over raw traces
+func seriesTraceFilter(query *models.TimeSeriesQuery) (string, []interface{}) {
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{query.OrganizationID, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", query.ProjectID},
+		{"model", query.Model},
+		{"provider", query.Provider},
+		{"user_id", query.UserID},
+		{"status", query.Status},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+
+	return where, args
+}
+
+// seriesHourlyFilter builds the WHERE clause of a time series over metrics_hourly
+func seriesHourlyFilter(query *models.TimeSeriesQuery, metricName string) (string, []interface{}) {
+	where := " WHERE organization_id = ? AND metric_name = ? AND hour >= toStartOfHour(?) AND hour < ?"
+	args := []interface{}{query.OrganizationID, metricName, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", query.ProjectID},
+		{"model", query.Model},
+		{"provider", query.Provider},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+
+	return where, args
+}
+
+// SaveMetrics stores metrics in a single batch
+func (r *ClickHouseRepository) SaveMetrics(ctx context.Context, metrics []*models.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO metrics (timestamp, organization_id, project_id, metric_name, metric_value, tags)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare metrics batch: %w", err)
+	}
+
+	for _, metric := range metrics {
+		tagsJSON := "{}"
+		if metric.Tags != nil {
+			if data, err := json.Marshal(metric.Tags); err == nil {
+				tagsJSON = string(data)
+			}
+		}
+
+		if err := batch.Append(
+			metric.Timestamp,
+			metric.OrganizationID,
+			metric.ProjectID,
+			metric.MetricName,
+			metric.MetricValue,
+			tagsJSON,
+		); err != nil {
+			return fmt.Errorf("failed to append metric: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save metrics: %w", err)
+	}
+	return nil
+}
+
+// GetMetrics returns raw metric data points, newest first
+func (r *ClickHouseRepository) GetMetrics(ctx context.Context, query *models.MetricQuery) ([]*models.Metric, error) {
+	where := " WHERE organization_id = ? AND timestamp >= ? AND timestamp < ?"
+	args := []interface{}{query.OrganizationID, query.StartTime, query.EndTime}
+
+	for _, filter := range []struct{ column, value string }{
+		{"project_id", query.ProjectID},
+		{"metric_name", query.MetricName},
+		{"JSONExtractString(tags, 'model')", query.Model},
+		{"JSONExtractString(tags, 'provider')", query.Provider},
+	} {
+		if filter.value != "" {
+			where += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+	args = append(args, limit)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT timestamp, organization_id, project_id, metric_name, metric_value, tags
+		FROM metrics`+where+`
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := []*models.Metric{}
+	for rows.Next() {
+		var metric models.Metric
+		var tagsJSON string
+
+		if err := rows.Scan(
+			&metric.Timestamp,
+			&metric.OrganizationID,
+			&metric.ProjectID,
+			&metric.MetricName,
+			&metric.MetricValue,
+			&tagsJSON,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan metric: %w", err)
+		}
+
+		if tagsJSON != "" && tagsJSON != "{}" {
+			json.Unmarshal([]byte(tagsJSON), &metric.Tags)
+		}
+		metric.Model, _ = metric.Tags["model"].(string)
+		metric.Provider, _ = metric.Tags["provider"].(string)
+
+		metrics = append(metrics, &metric)
+	}
+
+	return metrics, rows.Err()
+}
+
+// GetProviderMetrics aggregates request volume, cost, latency and errors per provider
+func (r *ClickHouseRepository) GetProviderMetrics(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ProviderMetrics, error) {
+	where, args := analyticsFilter(&models.AnalyticsQuery{OrganizationID: orgID, ProjectID: projectID})
+	args = append(args, startTime, endTime)
+
+	rows, err := r.conn.Query(ctx, `
+		SELECT
+			provider,
+			count(),
+			sum(total_cost_usd),
+			avg(duration_ms),
+			countIf(status = 'error') * 100.0 / count()
+		FROM traces`+where+`
+			AND timestamp >= ? AND timestamp < ?
+		GROUP BY provider
+		ORDER BY count() DESC, provider
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provider metrics: %w", err)
+	}
+	defer rows.Close()
+
+	providers := []*models.ProviderMetrics{}
+	for rows.Next() {
+		var p models.ProviderMetrics
+		var requests uint64
+
+		if err := rows.Scan(&p.Provider, &requests, &p.TotalCost, &p.AvgLatencyMs, &p.ErrorRate); err != nil {
+			return nil, fmt.Errorf("failed to scan provider metrics: %w", err)
+		}
+		p.RequestCount = int64(requests)
+		providers = append(providers, &p)
+	}
+
+	return providers, rows.Err()
+}
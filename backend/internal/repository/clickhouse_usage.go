@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// SaveUsage adds metered usage; rows for the same organization and day are summed up
+func (r *ClickHouseRepository) SaveUsage(ctx context.Context, records []*models.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO usage_daily (organization_id, date, traces, spans, bytes)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare usage batch: %w", err)
+	}
+
+	for _, record := range records {
+		if err := batch.Append(
+			record.OrganizationID,
+			record.Date,
+			uint64(record.Traces),
+			uint64(record.Spans),
+			uint64(record.Bytes),
+		); err != nil {
+			return fmt.Errorf("failed to append usage: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage returns the daily usage of an organization between start and end, or of every
+// organization when orgID is empty
+func (r *ClickHouseRepository) GetUsage(ctx context.Context, orgID string, start, end time.Time) ([]*models.UsageRecord, error) {
+	query := `
+		SELECT organization_id, date, sum(traces), sum(spans), sum(bytes)
+		FROM usage_daily
+		WHERE date >= toDate(?) AND date < toDate(?)`
+	args := []interface{}{start, end}
+	if orgID != "" {
+		query += " AND organization_id = ?"
+		args = append(args, orgID)
+	}
+
+	rows, err := r.conn.Query(ctx, query+" GROUP BY organization_id, date ORDER BY organization_id, date", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*models.UsageRecord{}
+	for rows.Next() {
+		var (
+			record               models.UsageRecord
+			traces, spans, bytes uint64
+		)
+		if err := rows.Scan(&record.OrganizationID, &record.Date, &traces, &spans, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		record.Traces = int64(traces)
+		record.Spans = int64(spans)
+		record.Bytes = int64(bytes)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// userSortColumns maps sort_by values to ORDER BY expressions
+var userSortColumns = map[string]string{
+	"cost":     "total_cost",
+	"tokens":   "total_tokens",
+	"requests": "request_count",
+	"errors":   "error_count",
+	"latency":  "p95_latency",
+}
+
+// userStatsSelect is the aggregate projection shared by the user_stats_hourly queries
+const userStatsSelect = `
+	countMerge(request_count) AS request_count,
+	countIfMerge(error_count) AS error_count,
+	sumMerge(total_cost) AS total_cost,
+	sumMerge(total_tokens) AS total_tokens,
+	sumMerge(total_duration_ms) AS total_duration_ms,
+	quantilesMerge(0.50, 0.95, 0.99)(latency_quantiles) AS latency,
+	latency[2] AS p95_latency,
+	minMerge(first_seen) AS first_seen,
+	maxMerge(last_seen) AS last_seen
+`
+
+// userStatsFilter builds the WHERE clause for user_stats_hourly queries
+func userStatsFilter(query *models.UserAnalyticsQuery) (string, []interface{}) {
+	where := " WHERE organization_id = ?"
+	args := []interface{}{query.OrganizationID}
+
+	if query.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, query.ProjectID)
+	}
+
+	if query.UserID != "" {
+		where += " AND user_id = ?"
+		args = append(args, query.UserID)
+	}
+
+	if !query.StartTime.IsZero() {
+		where += " AND hour >= toStartOfHour(?)"
+		args = append(args, query.StartTime)
+	}
+
+	if !query.EndTime.IsZero() {
+		where += " AND hour <= ?"
+		args = append(args, query.EndTime)
+	}
+
+	return where, args
+}
+
+// GetTopUsers returns end users ranked by cost, tokens, requests, errors or latency
+func (r *ClickHouseRepository) GetTopUsers(ctx context.Context, query *models.UserAnalyticsQuery) ([]*models.UserStats, error) {
+	orderBy, ok := userSortColumns[query.SortBy]
+	if !ok {
+		orderBy = userSortColumns["cost"]
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	where, args := userStatsFilter(query)
+	sql := "SELECT user_id," + userStatsSelect + " FROM user_stats_hourly" + where +
+		" GROUP BY user_id ORDER BY " + orderBy + " DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top users: %w", err)
+	}
+	defer rows.Close()
+
+	hours := query.EndTime.Sub(query.StartTime).Hours()
+
+	var users []*models.UserStats
+	for rows.Next() {
+		stats, err := scanUserStats(rows, hours)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, stats)
+	}
+
+	return users, rows.Err()
+}
+
+// GetUserStats returns aggregated usage for a single end user
+func (r *ClickHouseRepository) GetUserStats(ctx context.Context, query *models.UserAnalyticsQuery) (*models.UserStats, error) {
+	if query.UserID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	where, args := userStatsFilter(query)
+	sql := "SELECT user_id," + userStatsSelect + " FROM user_stats_hourly" + where + " GROUP BY user_id"
+
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user stats: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, ErrNotFound
+	}
+
+	return scanUserStats(rows, query.EndTime.Sub(query.StartTime).Hours())
+}
+
+// GetUserTimeSeries returns request rate, error rate and latency over time for one end user
+func (r *ClickHouseRepository) GetUserTimeSeries(ctx context.Context, query *models.UserAnalyticsQuery) ([]*models.UserTimeSeriesPoint, error) {
+	if query.UserID == "" {
+		return nil, ErrInvalidInput
+	}
+
+	bucket := "hour"
+	if query.Granularity == "day" {
+		bucket = "toStartOfDay(hour)"
+	}
+
+	where, args := userStatsFilter(query)
+	sql := "SELECT " + bucket + " AS bucket," + userStatsSelect + " FROM user_stats_hourly" + where +
+		" GROUP BY bucket ORDER BY bucket ASC"
+
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user time series: %w", err)
+	}
+	defer rows.Close()
+
+	var points []*models.UserTimeSeriesPoint
+	for rows.Next() {
+		var (
+			point           models.UserTimeSeriesPoint
+			requestCount    uint64
+			errorCount      uint64
+			totalTokens     uint64
+			totalDurationMs uint64
+			latency         []float64
+			p95             float64
+			firstSeen       time.Time
+			lastSeen        time.Time
+		)
+
+		if err := rows.Scan(
+			&point.Timestamp,
+			&requestCount,
+			&errorCount,
+			&point.TotalCost,
+			&totalTokens,
+			&totalDurationMs,
+			&latency,
+			&p95,
+			&firstSeen,
+			&lastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user time series: %w", err)
+		}
+
+		point.RequestCount = int64(requestCount)
+		point.ErrorCount = int64(errorCount)
+		point.TotalTokens = int64(totalTokens)
+		if requestCount > 0 {
+			point.ErrorRate = float64(errorCount) / float64(requestCount) * 100
+			point.AvgLatencyMs = float64(totalDurationMs) / float64(requestCount)
+		}
+		if len(latency) == 3 {
+			point.P50LatencyMs = latency[0]
+			point.P95LatencyMs = latency[1]
+		}
+
+		points = append(points, &point)
+	}
+
+	return points, rows.Err()
+}
+
+// rowScanner is the subset of driver.Rows used by the scan helpers
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUserStats scans a row produced by userStatsSelect prefixed with user_id
+func scanUserStats(rows rowScanner, windowHours float64) (*models.UserStats, error) {
+	var (
+		stats           models.UserStats
+		requestCount    uint64
+		errorCount      uint64
+		totalTokens     uint64
+		totalDurationMs uint64
+		latency         []float64
+		p95             float64
+	)
+
+	if err := rows.Scan(
+		&stats.UserID,
+		&requestCount,
+		&errorCount,
+		&stats.TotalCost,
+		&totalTokens,
+		&totalDurationMs,
+		&latency,
+		&p95,
+		&stats.FirstSeen,
+		&stats.LastSeen,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan user stats: %w", err)
+	}
+
+	stats.RequestCount = int64(requestCount)
+	stats.ErrorCount = int64(errorCount)
+	stats.TotalTokens = int64(totalTokens)
+
+	if requestCount > 0 {
+		stats.ErrorRate = float64(errorCount) / float64(requestCount) * 100
+		stats.AvgCostPerReq = stats.TotalCost / float64(requestCount)
+		stats.AvgLatencyMs = float64(totalDurationMs) / float64(requestCount)
+	}
+
+	if len(latency) == 3 {
+		stats.P50LatencyMs = latency[0]
+		stats.P95LatencyMs = latency[1]
+		stats.P99LatencyMs = latency[2]
+	}
+
+	if windowHours > 0 {
+		stats.RequestsPerHour = float64(requestCount) / windowHours
+	}
+
+	return &stats, nil
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/Aditya-Pimpalkar/clarity/internal/models"
@@ -16,19 +15,96 @@ type Repository interface {
 	GetTraceByID(ctx context.Context, traceID string) (*models.Trace, error)
 	GetTraces(ctx context.Context, query *models.TraceQuery) ([]*models.Trace, error)
 	GetTraceCount(ctx context.Context, query *models.TraceQuery) (int64, error)
+	StreamTraces(ctx context.Context, query *models.TraceQuery, fn func(*models.Trace) error) error
+	GetExistingTraceIDs(ctx context.Context, orgID string, traceIDs []string) (map[string]bool, error)
 
 	// Span operations
-	SaveSpan(ctx context.Context, span *models.Span) error
-	GetSpansByTraceID(ctx context.Context, traceID string) ([]models.Span, error)
+	SaveSpan(ctx context.Context, orgID string, span *models.Span) error
+	GetSpansByTraceID(ctx context.Context, orgID, traceID string) ([]models.Span, error)
 
 	// Metrics operations
 	SaveMetric(ctx context.Context, metric *models.Metric) error
+	SaveMetrics(ctx context.Context, metrics []*models.Metric) error
 	GetMetrics(ctx context.Context, query *models.MetricQuery) ([]*models.Metric, error)
+	QueryMetrics(ctx context.Context, query *models.CustomMetricQuery) ([]*models.TimeSeries, error)
+	GetTimeSeries(ctx context.Context, query *models.TimeSeriesQuery) ([]*models.TimeSeries, error)
+	GetProviderMetrics(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ProviderMetrics, error)
 	GetMetricSummary(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) (*models.MetricSummary, error)
 
 	// Analytics operations
-	GetCostBreakdown(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.CostBreakdown, error)
+	GetCostBreakdown(ctx context.Context, query *models.CostQuery) (*models.CostBreakdown, error)
 	GetModelUsage(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*models.ModelUsage, error)
+	GetTraceTotals(ctx context.Context, query *models.AnalyticsQuery, windows ...models.TimeWindow) ([]*models.TraceTotals, error)
+	GetDashboardBreakdowns(ctx context.Context, query *models.AnalyticsQuery, topModels int) (*models.DashboardBreakdowns, error)
+	GetLatencyDistribution(ctx context.Context, query *models.LatencyQuery) ([]*models.LatencySeries, error)
+	GetCohortStats(ctx context.Context, orgID string, cohort *models.Cohort, limit int) (*models.CohortStats, error)
+
+	// Pricing operations
+	ListModelPrices(ctx context.Context) ([]*models.ModelPrice, error)
+	SaveModelPrices(ctx context.Context, prices []*models.ModelPrice) error
+
+	// Cost backfill operations
+	CountBackfillTraces(ctx context.Context, backfill *models.CostBackfill) (int64, error)
+	GetBackfillTraces(ctx context.Context, backfill *models.CostBackfill, limit int) ([]*models.Trace, error)
+	UpdateTraceCosts(ctx context.Context, orgID string, traces []*models.Trace, unpriced []*models.Metric) error
+	SaveCostBackfill(ctx context.Context, backfill *models.CostBackfill) error
+	GetCostBackfill(ctx context.Context, orgID, id string) (*models.CostBackfill, error)
+	ListCostBackfills(ctx context.Context, orgID string, limit int) ([]*models.CostBackfill, error)
+	ListUnfinishedCostBackfills(ctx context.Context) ([]*models.CostBackfill, error)
+
+	// Budget operations
+	SaveBudget(ctx context.Context, budget *models.Budget) error
+	ListBudgets(ctx context.Context, orgID string) ([]*models.Budget, error)
+	GetBudgetSpend(ctx context.Context, budget *models.Budget, start, end time.Time) (float64, error)
+
+	// Usage metering operations
+	SaveUsage(ctx context.Context, records []*models.UsageRecord) error
+	GetUsage(ctx context.Context, orgID string, start, end time.Time) ([]*models.UsageRecord, error)
+
+	// Currency operations
+	SaveExchangeRates(ctx context.Context, rates []*models.ExchangeRate) error
+	ListExchangeRates(ctx context.Context, currency string) ([]*models.ExchangeRate, error)
+	SaveCurrencySetting(ctx context.Context, setting *models.CurrencySetting) error
+	GetCurrencySetting(ctx context.Context, orgID string) (*models.CurrencySetting, error)
+
+	// Chargeback operations
+	GetTagPathCosts(ctx context.Context, query *models.AnalyticsQuery, keys []string) ([]*models.TagPathCost, error)
+	SaveChargebackConfig(ctx context.Context, config *models.ChargebackConfig) error
+	GetChargebackConfig(ctx context.Context, orgID string) (*models.ChargebackConfig, error)
+
+	// Alert operations
+	SaveAlertRule(ctx context.Context, rule *models.AlertRule) error
+	ListAlertRules(ctx context.Context, orgID string) ([]*models.AlertRule, error)
+	SaveAlertEvents(ctx context.Context, events []*models.AlertEvent) error
+	ListAlertEvents(ctx context.Context, query *models.AlertEventQuery) ([]*models.AlertEvent, error)
+	GetLatestAlertEvents(ctx context.Context) ([]*models.AlertEvent, error)
+
+	// Notification operations
+	SaveNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error
+	ListNotificationChannels(ctx context.Context, orgID string) ([]*models.NotificationChannel, error)
+	SaveNotificationDelivery(ctx context.Context, delivery *models.NotificationDelivery) error
+	ListNotificationDeliveries(ctx context.Context, query *models.NotificationDeliveryQuery) ([]*models.NotificationDelivery, error)
+
+	// Anomaly operations
+	GetActiveOrganizations(ctx context.Context, since time.Time) ([]string, error)
+	GetHourlyModelStats(ctx context.Context, orgID string, startTime, endTime time.Time) ([]*models.HourlyModelStats, error)
+	SaveAnomalies(ctx context.Context, anomalies []*models.Anomaly) error
+	ListAnomalies(ctx context.Context, query *models.AnomalyQuery) ([]*models.Anomaly, error)
+	ListRecentAnomalies(ctx context.Context, orgID string, since time.Time) ([]*models.Anomaly, error)
+
+	// End-user analytics operations
+	GetTopUsers(ctx context.Context, query *models.UserAnalyticsQuery) ([]*models.UserStats, error)
+	GetUserStats(ctx context.Context, query *models.UserAnalyticsQuery) (*models.UserStats, error)
+	GetUserTimeSeries(ctx context.Context, query *models.UserAnalyticsQuery) ([]*models.UserTimeSeriesPoint, error)
+
+	// Erasure operations
+	CountErasure(ctx context.Context, filter *models.ErasureFilter) (*models.ErasureCounts, error)
+	EraseTraces(ctx context.Context, filter *models.ErasureFilter) error
+	RebuildPendingAggregates(ctx context.Context) error
+	SaveErasureRecord(ctx context.Context, record *models.ErasureRecord) error
+	GetErasureRecord(ctx context.Context, orgID, id string) (*models.ErasureRecord, error)
+	ListErasureRecords(ctx context.Context, orgID string, limit int) ([]*models.ErasureRecord, error)
+	ListUnfinishedErasureRecords(ctx context.Context) ([]*models.ErasureRecord, error)
 
 	// User operations
 	CreateUser(ctx context.Context, user *models.User) error
@@ -44,9 +120,6 @@ type Repository interface {
 	GetProject(ctx context.Context, projectID string) (*models.Project, error)
 	GetProjectsByOrg(ctx context.Context, orgID string) ([]*models.Project, error)
 
-	// Raw query operations (for analytics)
-	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
-
 	// Health check
 	Ping(ctx context.Context) error
 	Close() error
@@ -0,0 +1,589 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	defaultAlertWindowMinutes = 5
+	maxAlertWindowMinutes     = 7 * 24 * 60
+	maxAlertForMinutes        = 24 * 60
+	defaultAlertHistoryLimit  = 100
+	maxAlertHistoryLimit      = 1000
+)
+
+// alertMetrics lists the metrics alert rules can be written on; percent metrics take
+// thresholds between 0 and 100
+var alertMetrics = map[string]bool{
+	models.AlertMetricErrorRate:   true,
+	models.AlertMetricP95Latency:  false,
+	models.AlertMetricCostPerHour: false,
+	models.AlertMetricVolumeDrop:  true,
+}
+
+// alertState is where a rule is in its pending, firing, resolved cycle
+type alertState struct {
+	state       string
+	since       time.Time // when the state was entered
+	value       float64
+	evaluatedAt time.Time
+}
+
+// AlertService stores alert rules and evaluates them on a schedule. Rule states are kept
+// in memory; every state change is recorded as an alert event, which is also how states
+// are restored on startup and how instances that do not evaluate follow the one that does.
+type AlertService struct {
+	repo          repository.Repository
+	notifications *NotificationService // optional
+
+	mu     sync.Mutex
+	rules  map[string]*models.AlertRule // by ID
+	states map[string]*alertState       // by rule ID
+}
+
+// NewAlertService creates an alert service; call Refresh to load the rules. Firing and
+// resolved alerts are sent to the organization's notification channels when notifications
+// is set.
+func NewAlertService(repo repository.Repository, notifications *NotificationService) *AlertService {
+	return &AlertService{
+		repo:          repo,
+		notifications: notifications,
+		rules:         make(map[string]*models.AlertRule),
+		states:        make(map[string]*alertState),
+	}
+}
+
+// Refresh reloads every alert rule. Rules take the state of their latest alert event when
+// it is newer than the state in memory, as when another instance evaluates them.
+func (s *AlertService) Refresh(ctx context.Context) error {
+	rules, err := s.repo.ListAlertRules(ctx, "")
+	if err != nil {
+		return err
+	}
+	events, err := s.repo.GetLatestAlertEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := make(map[string]*models.AlertEvent, len(events))
+	for _, event := range events {
+		latest[event.RuleID] = event
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byID := make(map[string]*models.AlertRule, len(rules))
+	states := make(map[string]*alertState, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+		switch state, event := s.states[rule.ID], latest[rule.ID]; {
+		case event != nil && (state == nil || event.Timestamp.After(state.since)):
+			states[rule.ID] = &alertState{state: event.State, since: event.Timestamp, value: event.Value}
+		case state != nil:
+			states[rule.ID] = state
+		default:
+			states[rule.ID] = &alertState{state: models.AlertStateInactive}
+		}
+	}
+	s.rules = byID
+	s.states = states
+	return nil
+}
+
+// Start reloads and evaluates the rules once per interval until ctx is cancelled. Only one
+// instance sharing a database may evaluate; the others Follow it.
+func (s *AlertService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh alert rules: %v", err)
+				}
+				if _, err := s.Evaluate(ctx); err != nil {
+					log.Printf("❌ Failed to record alert events: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Follow reloads the rules, and the states recorded by the evaluating instance, once per
+// interval until ctx is cancelled
+func (s *AlertService) Follow(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh alert rules: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Evaluate runs every enabled rule now and returns the state changes. A rule whose
+// metric cannot be measured is logged and keeps its state.
+func (s *AlertService) Evaluate(ctx context.Context) ([]*models.AlertEvent, error) {
+	return s.evaluate(ctx, time.Now())
+}
+
+// evaluate runs every enabled rule at now
+func (s *AlertService) evaluate(ctx context.Context, now time.Time) ([]*models.AlertEvent, error) {
+	s.mu.Lock()
+	rules := make([]*models.AlertRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		if rule.Enabled {
+			rules = append(rules, rule)
+		}
+	}
+	s.mu.Unlock()
+
+	changes := []*alertTransition{}
+	for _, rule := range rules {
+		if ctx.Err() != nil {
+			break
+		}
+
+		value, err := s.measure(ctx, rule, now)
+		if err != nil {
+			log.Printf("❌ Failed to evaluate alert rule %s: %v", rule.ID, err)
+			continue
+		}
+
+		s.mu.Lock()
+		// Skip rules changed or deleted while they were measured
+		if s.rules[rule.ID] == rule {
+			if change := s.transition(rule, value, now); change != nil {
+				changes = append(changes, change)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	events := make([]*models.AlertEvent, 0, len(changes))
+	for _, change := range changes {
+		events = append(events, change.event)
+	}
+	// States only move once their events are stored, so a failed write is retried by the
+	// next evaluation instead of leaving a change unrecorded
+	if err := s.repo.SaveAlertEvents(ctx, events); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for _, change := range changes {
+		if state := s.states[change.rule.ID]; state != nil && s.rules[change.rule.ID] == change.rule {
+			state.state = change.event.State
+			state.since = now
+		}
+	}
+	s.mu.Unlock()
+
+	for _, change := range changes {
+		if event := change.event; event.State == models.AlertStateFiring || event.State == models.AlertStateResolved {
+			log.Printf("🔔 Alert %q of project %s is %s: %s %.4g (%s %.4g)",
+				change.rule.Name, change.rule.ProjectID, event.State, change.rule.Metric, event.Value, change.rule.Comparator, change.rule.Threshold)
+		}
+		s.notify(change.event)
+	}
+	return events, nil
+}
+
+// measure returns the value of a rule's metric over the window ending at now
+func (s *AlertService) measure(ctx context.Context, rule *models.AlertRule, now time.Time) (float64, error) {
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+	windows := []models.TimeWindow{{Start: now.Add(-window), End: now}}
+
+	// Volume drop compares the window with the one before it
+	if rule.Metric == models.AlertMetricVolumeDrop {
+		windows = append([]models.TimeWindow{{Start: now.Add(-2 * window), End: now.Add(-window)}}, windows...)
+	}
+
+	totals, err := s.repo.GetTraceTotals(ctx, &models.AnalyticsQuery{
+		OrganizationID: rule.OrganizationID,
+		ProjectID:      rule.ProjectID,
+		StartTime:      windows[0].Start,
+		EndTime:        now,
+	}, windows...)
+	if err != nil {
+		return 0, err
+	}
+	current := totals[len(totals)-1]
+
+	switch rule.Metric {
+	case models.AlertMetricErrorRate:
+		if current.TraceCount == 0 {
+			return 0, nil
+		}
+		return float64(current.ErrorCount) / float64(current.TraceCount) * 100, nil
+	case models.AlertMetricP95Latency:
+		return current.P95LatencyMs, nil
+	case models.AlertMetricCostPerHour:
+		return current.TotalCost / window.Hours(), nil
+	case models.AlertMetricVolumeDrop:
+		previous := totals[0].TraceCount
+		if previous == 0 {
+			return 0, nil
+		}
+		return float64(previous-current.TraceCount) / float64(previous) * 100, nil
+	default:
+		return 0, fmt.Errorf("unknown metric %q", rule.Metric)
+	}
+}
+
+// alertTransition is a state change of a rule waiting for its event to be stored
+type alertTransition struct {
+	rule  *models.AlertRule
+	event *models.AlertEvent
+}
+
+// transition works out a rule's next state after measuring value and returns the change,
+// if any, without applying it; the caller holds s.mu
+func (s *AlertService) transition(rule *models.AlertRule, value float64, now time.Time) *alertTransition {
+	state := s.states[rule.ID]
+	if state == nil {
+		state = &alertState{state: models.AlertStateInactive}
+		s.states[rule.ID] = state
+	}
+	state.value = value
+	state.evaluatedAt = now
+
+	next := state.state
+	since := state.since
+	if compareAlert(rule.Comparator, value, rule.Threshold) {
+		if next != models.AlertStatePending && next != models.AlertStateFiring {
+			next, since = models.AlertStatePending, now
+		}
+		if next == models.AlertStatePending && now.Sub(since) >= time.Duration(rule.ForMinutes)*time.Minute {
+			next = models.AlertStateFiring
+		}
+	} else {
+		switch next {
+		case models.AlertStatePending:
+			next = models.AlertStateInactive
+		case models.AlertStateFiring:
+			next = models.AlertStateResolved
+		}
+	}
+
+	if next == state.state {
+		return nil
+	}
+	return &alertTransition{rule: rule, event: newAlertEvent(rule, next, value, now)}
+}
+
+// ListRules returns an organization's alert rules and their states, optionally of one project
+func (s *AlertService) ListRules(ctx context.Context, orgID, projectID string) ([]models.AlertStatus, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	rules, err := s.repo.ListAlertRules(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]models.AlertStatus, 0, len(rules))
+	for _, rule := range rules {
+		if projectID == "" || rule.ProjectID == projectID {
+			statuses = append(statuses, s.status(rule))
+		}
+	}
+	return statuses, nil
+}
+
+// GetRule returns one alert rule and its state
+func (s *AlertService) GetRule(ctx context.Context, orgID, id string) (*models.AlertStatus, error) {
+	rule, err := s.findRule(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status(rule)
+	return &status, nil
+}
+
+// UpsertRule validates and stores an alert rule. A changed rule starts over as inactive,
+// resolving it if it was firing.
+func (s *AlertService) UpsertRule(ctx context.Context, orgID string, req *models.AlertRuleRequest, updatedBy string) (*models.AlertStatus, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	rule, err := newAlertRule(orgID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.ID = uuid.New().String()
+	rule.CreatedBy = updatedBy
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if req.ID != "" {
+		existing, err := s.findRule(ctx, orgID, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		rule.ID = existing.ID
+		rule.CreatedBy = existing.CreatedBy
+		rule.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.repo.SaveAlertRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.replaceRule(ctx, rule.ID, rule, now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status(rule)
+	return &status, nil
+}
+
+// DeleteRule deletes an alert rule, resolving it if it was firing
+func (s *AlertService) DeleteRule(ctx context.Context, orgID, id string) error {
+	rule, err := s.findRule(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	rule.Deleted = true
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.SaveAlertRule(ctx, rule); err != nil {
+		return err
+	}
+	s.replaceRule(ctx, id, nil, rule.UpdatedAt)
+	return nil
+}
+
+// ListEvents returns an organization's alert history, newest first
+func (s *AlertService) ListEvents(ctx context.Context, query *models.AlertEventQuery) ([]*models.AlertEvent, error) {
+	if query.OrganizationID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if query.EndTime.Before(query.StartTime) {
+		return nil, invalidArgument("end_time must be after start_time")
+	}
+	for _, state := range query.States {
+		if !validAlertState(state) {
+			return nil, invalidArgument("state must be pending, firing, resolved or inactive")
+		}
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultAlertHistoryLimit
+	}
+	if query.Limit > maxAlertHistoryLimit {
+		query.Limit = maxAlertHistoryLimit
+	}
+
+	return s.repo.ListAlertEvents(ctx, query)
+}
+
+// replaceRule swaps a rule in the cache for its new version, or drops it when rule is
+// nil, and closes out the old version's pending or firing state
+func (s *AlertService) replaceRule(ctx context.Context, id string, rule *models.AlertRule, now time.Time) {
+	s.mu.Lock()
+	var event *models.AlertEvent
+	if state, old := s.states[id], s.rules[id]; state != nil && old != nil {
+		switch state.state {
+		case models.AlertStatePending:
+			event = newAlertEvent(old, models.AlertStateInactive, state.value, now)
+		case models.AlertStateFiring:
+			event = newAlertEvent(old, models.AlertStateResolved, state.value, now)
+		}
+	}
+
+	delete(s.rules, id)
+	delete(s.states, id)
+	if rule != nil {
+		s.rules[id] = rule
+		s.states[id] = &alertState{state: models.AlertStateInactive, since: now}
+	}
+	s.mu.Unlock()
+
+	if event != nil {
+		s.notify(event)
+		if err := s.repo.SaveAlertEvents(ctx, []*models.AlertEvent{event}); err != nil {
+			log.Printf("❌ Failed to record alert event of rule %s: %v", id, err)
+		}
+	}
+}
+
+// notify sends firing and resolved events to the notification channels
+func (s *AlertService) notify(event *models.AlertEvent) {
+	if s.notifications == nil {
+		return
+	}
+	if event.State == models.AlertStateFiring || event.State == models.AlertStateResolved {
+		s.notifications.Notify(AlertNotification(event))
+	}
+}
+
+// status returns a rule with its state; the caller holds s.mu
+func (s *AlertService) status(rule *models.AlertRule) models.AlertStatus {
+	status := models.AlertStatus{AlertRule: *rule, State: models.AlertStateInactive}
+
+	state := s.states[rule.ID]
+	if state == nil {
+		return status
+	}
+	status.State = state.state
+	if !state.since.IsZero() {
+		since := state.since
+		status.StateSince = &since
+	}
+	if !state.evaluatedAt.IsZero() {
+		value, evaluatedAt := state.value, state.evaluatedAt
+		status.Value = &value
+		status.LastEvaluatedAt = &evaluatedAt
+	}
+	return status
+}
+
+// findRule returns one of an organization's alert rules
+func (s *AlertService) findRule(ctx context.Context, orgID, id string) (*models.AlertRule, error) {
+	rules, err := s.repo.ListAlertRules(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("alert rule %s: %w", id, repository.ErrNotFound)
+}
+
+// newAlertRule validates a rule request and fills in its defaults
+func newAlertRule(orgID string, req *models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule := &models.AlertRule{
+		OrganizationID: orgID,
+		ProjectID:      strings.TrimSpace(req.ProjectID),
+		Name:           strings.TrimSpace(req.Name),
+		Metric:         req.Metric,
+		Comparator:     req.Comparator,
+		Threshold:      req.Threshold,
+		WindowMinutes:  req.WindowMinutes,
+		ForMinutes:     req.ForMinutes,
+		Severity:       req.Severity,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+
+	if rule.ProjectID == "" {
+		return nil, invalidArgument("project_id is required")
+	}
+	if rule.Name == "" {
+		return nil, invalidArgument("name is required")
+	}
+
+	percent, ok := alertMetrics[rule.Metric]
+	if !ok {
+		return nil, invalidArgument("metric must be error_rate, p95_latency_ms, cost_per_hour or volume_drop")
+	}
+	switch rule.Comparator {
+	case models.AlertComparatorGT, models.AlertComparatorGTE, models.AlertComparatorLT, models.AlertComparatorLTE:
+	default:
+		return nil, invalidArgument("comparator must be gt, gte, lt or lte")
+	}
+	if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) || rule.Threshold < 0 {
+		return nil, invalidArgument("threshold must be a non-negative number")
+	}
+	if percent && rule.Threshold > 100 {
+		return nil, invalidArgument("threshold of %s is a percentage between 0 and 100", rule.Metric)
+	}
+
+	if rule.WindowMinutes == 0 {
+		rule.WindowMinutes = defaultAlertWindowMinutes
+	}
+	if rule.WindowMinutes < 1 || rule.WindowMinutes > maxAlertWindowMinutes {
+		return nil, invalidArgument("window_minutes must be between 1 and %d", maxAlertWindowMinutes)
+	}
+	if rule.ForMinutes < 0 || rule.ForMinutes > maxAlertForMinutes {
+		return nil, invalidArgument("for_minutes must be between 0 and %d", maxAlertForMinutes)
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = models.AlertSeverityWarning
+	case models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return nil, invalidArgument("severity must be info, warning or critical")
+	}
+
+	return rule, nil
+}
+
+// newAlertEvent records a rule entering a state
+func newAlertEvent(rule *models.AlertRule, state string, value float64, at time.Time) *models.AlertEvent {
+	return &models.AlertEvent{
+		ID:             uuid.New().String(),
+		OrganizationID: rule.OrganizationID,
+		ProjectID:      rule.ProjectID,
+		RuleID:         rule.ID,
+		RuleName:       rule.Name,
+		Metric:         rule.Metric,
+		Comparator:     rule.Comparator,
+		Threshold:      rule.Threshold,
+		Severity:       rule.Severity,
+		State:          state,
+		Value:          value,
+		Timestamp:      at,
+	}
+}
+
+// compareAlert reports whether value meets a rule's condition
+func compareAlert(comparator string, value, threshold float64) bool {
+	switch comparator {
+	case models.AlertComparatorGT:
+		return value > threshold
+	case models.AlertComparatorGTE:
+		return value >= threshold
+	case models.AlertComparatorLT:
+		return value < threshold
+	case models.AlertComparatorLTE:
+		return value <= threshold
+	}
+	return false
+}
+
+// validAlertState reports whether state is an alert state
+func validAlertState(state string) bool {
+	switch state {
+	case models.AlertStateInactive, models.AlertStatePending, models.AlertStateFiring, models.AlertStateResolved:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestAlertRuleValidation(t *testing.T) {
+	service := NewAlertService(&mockRepository{}, nil)
+	valid := models.AlertRuleRequest{ProjectID: "proj-1", Name: "Errors", Metric: models.AlertMetricErrorRate, Comparator: models.AlertComparatorGT, Threshold: 5}
+
+	invalid := []func(*models.AlertRuleRequest){
+		func(r *models.AlertRuleRequest) { r.ProjectID = "" },
+		func(r *models.AlertRuleRequest) { r.Name = " " },
+		func(r *models.AlertRuleRequest) { r.Metric = "p99" },
+		func(r *models.AlertRuleRequest) { r.Comparator = ">" },
+		func(r *models.AlertRuleRequest) { r.Threshold = 150 },
+		func(r *models.AlertRuleRequest) { r.WindowMinutes = -1 },
+		func(r *models.AlertRuleRequest) { r.ForMinutes = maxAlertForMinutes + 1 },
+		func(r *models.AlertRuleRequest) { r.Severity = "page" },
+	}
+	for i, change := range invalid {
+		req := valid
+		change(&req)
+		if _, err := service.UpsertRule(context.Background(), "org-1", &req, ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("case %d: expected ErrInvalidArgument, got %v", i, err)
+		}
+	}
+
+	rule, err := service.UpsertRule(context.Background(), "org-1", &valid, "dev@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.WindowMinutes != defaultAlertWindowMinutes || rule.Severity != models.AlertSeverityWarning || !rule.Enabled || rule.State != models.AlertStateInactive {
+		t.Errorf("expected defaults to be filled in, got %+v", rule)
+	}
+}
+
+func TestAlertStateTransitions(t *testing.T) {
+	repo := &mockRepository{totals: []*models.TraceTotals{{TraceCount: 100, ErrorCount: 10}}}
+	service := NewAlertService(repo, nil)
+	ctx := context.Background()
+
+	rule, err := service.UpsertRule(ctx, "org-1", &models.AlertRuleRequest{
+		ProjectID: "proj-1", Name: "Errors", Metric: models.AlertMetricErrorRate,
+		Comparator: models.AlertComparatorGT, Threshold: 5, ForMinutes: 5,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	expectState := func(at time.Time, want string, changed bool) {
+		t.Helper()
+		events, err := service.evaluate(ctx, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, _ := service.GetRule(ctx, "org-1", rule.ID)
+		if status.State != want || (len(events) == 1) != changed {
+			t.Fatalf("at %v: expected %s (changed %v), got %s with events %+v", at, want, changed, status.State, events)
+		}
+		if changed && events[0].State != want {
+			t.Fatalf("expected a %s event, got %+v", want, events[0])
+		}
+	}
+
+	// A 10% error rate holds the condition, but must hold for 5 minutes before firing
+	expectState(now, models.AlertStatePending, true)
+	expectState(now.Add(2*time.Minute), models.AlertStatePending, false)
+	expectState(now.Add(5*time.Minute), models.AlertStateFiring, true)
+	expectState(now.Add(6*time.Minute), models.AlertStateFiring, false)
+
+	repo.totals[0] = &models.TraceTotals{TraceCount: 100, ErrorCount: 1}
+	expectState(now.Add(7*time.Minute), models.AlertStateResolved, true)
+
+	// A new process picks up the state of the latest event
+	restored := NewAlertService(repo, nil)
+	if err := restored.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := restored.GetRule(ctx, "org-1", rule.ID); status.State != models.AlertStateResolved {
+		t.Errorf("expected the resolved state to be restored, got %s", status.State)
+	}
+
+	history, err := service.ListEvents(ctx, &models.AlertEventQuery{OrganizationID: "org-1", RuleID: rule.ID, EndTime: now.Add(time.Hour)})
+	if err != nil || len(history) != 3 || history[0].State != models.AlertStateResolved {
+		t.Errorf("expected pending, firing and resolved in the history, got %+v, %v", history, err)
+	}
+}
+
+func TestAlertStateWaitsForItsEvent(t *testing.T) {
+	repo := &mockRepository{totals: []*models.TraceTotals{{TraceCount: 100, ErrorCount: 10}}}
+	service := NewAlertService(repo, nil)
+	ctx := context.Background()
+
+	rule, err := service.UpsertRule(ctx, "org-1", &models.AlertRuleRequest{
+		ProjectID: "proj-1", Name: "Errors", Metric: models.AlertMetricErrorRate,
+		Comparator: models.AlertComparatorGT, Threshold: 5,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A change whose event cannot be stored is not applied
+	repo.alertEventsErr = errors.New("clickhouse unavailable")
+	if _, err := service.Evaluate(ctx); err == nil {
+		t.Fatal("expected the failed write to be reported")
+	}
+	if status, _ := service.GetRule(ctx, "org-1", rule.ID); status.State != models.AlertStateInactive {
+		t.Fatalf("expected the rule to stay inactive, got %s", status.State)
+	}
+
+	repo.alertEventsErr = nil
+	events, err := service.Evaluate(ctx)
+	if err != nil || len(events) != 1 || events[0].State != models.AlertStateFiring {
+		t.Fatalf("expected the change to be retried, got %+v, %v", events, err)
+	}
+
+	// An instance that does not evaluate follows the stored events
+	follower := NewAlertService(repo, nil)
+	if err := follower.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo.totals[0] = &models.TraceTotals{TraceCount: 100}
+	if _, err := service.evaluate(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := follower.GetRule(ctx, "org-1", rule.ID); status.State != models.AlertStateResolved {
+		t.Errorf("expected the follower to see the rule resolved, got %s", status.State)
+	}
+}
+
+func TestAlertMetrics(t *testing.T) {
+	repo := &mockRepository{totals: []*models.TraceTotals{
+		{TraceCount: 200, TotalCost: 3, P95LatencyMs: 900},
+		{TraceCount: 50, TotalCost: 1.5, P95LatencyMs: 1200},
+	}}
+	service := NewAlertService(repo, nil)
+	now := time.Now()
+
+	cases := map[string]float64{
+		models.AlertMetricP95Latency:  900,
+		models.AlertMetricCostPerHour: 6, // $3 in 30 minutes
+		models.AlertMetricVolumeDrop:  75,
+		models.AlertMetricErrorRate:   0,
+	}
+	for metric, want := range cases {
+		rule := &models.AlertRule{OrganizationID: "org-1", ProjectID: "proj-1", Metric: metric, WindowMinutes: 30}
+		if got, err := service.measure(context.Background(), rule, now); err != nil || !almostEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v (%v)", metric, want, got, err)
+		}
+	}
+}
+
+func TestAlertRuleChangeResolves(t *testing.T) {
+	repo := &mockRepository{totals: []*models.TraceTotals{{TotalCost: 10}}}
+	service := NewAlertService(repo, nil)
+	ctx := context.Background()
+
+	req := &models.AlertRuleRequest{ProjectID: "proj-1", Name: "Spend", Metric: models.AlertMetricCostPerHour, Comparator: models.AlertComparatorGTE, Threshold: 50, WindowMinutes: 10}
+	rule, err := service.UpsertRule(ctx, "org-1", req, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := service.Evaluate(ctx); len(events) != 1 || events[0].State != models.AlertStateFiring {
+		t.Fatalf("expected $60 an hour to fire at once, got %+v", events)
+	}
+
+	req.ID = rule.ID
+	req.Threshold = 100
+	if _, err := service.UpsertRule(ctx, "org-1", req, ""); err != nil {
+		t.Fatal(err)
+	}
+	if last := repo.alertEvents[len(repo.alertEvents)-1]; last.State != models.AlertStateResolved || last.Threshold != 50 {
+		t.Errorf("expected the old version to be resolved, got %+v", last)
+	}
+
+	if err := service.DeleteRule(ctx, "org-1", rule.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetRule(ctx, "org-1", rule.ID); err == nil {
+		t.Error("expected a deleted rule to be gone")
+	}
+	if events, _ := service.Evaluate(ctx); len(events) != 0 {
+		t.Errorf("deleted rules must not be evaluated, got %+v", events)
+	}
+}
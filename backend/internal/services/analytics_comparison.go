@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+const (
+	defaultComparisonModels = 10
+	maxComparisonModels     = 50
+	maxCohortTags           = 10
+)
+
+// CompareCohorts compares a cohort of traces against a baseline cohort, in total and per
+// model. The cohorts may cover different periods, different filters, or both. Without a
+// baseline period, the baseline covers the period before the comparison when both cohorts
+// share their filters, and the same period otherwise.
+func (s *AnalyticsService) CompareCohorts(ctx context.Context, query *models.ComparisonQuery) (*ComparisonReport, error) {
+	if err := validateComparisonQuery(query); err != nil {
+		return nil, err
+	}
+
+	baseline, err := s.repo.GetCohortStats(ctx, query.OrganizationID, &query.Baseline, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline stats: %w", err)
+	}
+	comparison, err := s.repo.GetCohortStats(ctx, query.OrganizationID, &query.Comparison, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comparison stats: %w", err)
+	}
+
+	report := &ComparisonReport{
+		Baseline:        query.Baseline,
+		Comparison:      query.Comparison,
+		ConfidenceLevel: 0.95,
+		Total:           compareCohortGroup("", &baseline.Total, &comparison.Total),
+	}
+
+	// Models of either cohort, busiest first by combined requests
+	byModel := make(map[string][2]*models.CohortGroupStats)
+	for i, groups := range [][]*models.CohortGroupStats{baseline.ByModel, comparison.ByModel} {
+		for _, group := range groups {
+			pair := byModel[group.Model]
+			pair[i] = group
+			byModel[group.Model] = pair
+		}
+	}
+
+	report.ByModel = make([]*CohortComparison, 0, len(byModel))
+	for model, pair := range byModel {
+		for i := range pair {
+			if pair[i] == nil {
+				pair[i] = &models.CohortGroupStats{Model: model}
+			}
+		}
+		report.ByModel = append(report.ByModel, compareCohortGroup(model, pair[0], pair[1]))
+	}
+	sort.Slice(report.ByModel, func(i, j int) bool {
+		a, b := report.ByModel[i], report.ByModel[j]
+		if volumeA, volumeB := a.Requests.Baseline+a.Requests.Comparison, b.Requests.Baseline+b.Requests.Comparison; volumeA != volumeB {
+			return volumeA > volumeB
+		}
+		return a.Model < b.Model
+	})
+	if len(report.ByModel) > query.Limit {
+		report.ByModel = report.ByModel[:query.Limit]
+	}
+
+	return report, nil
+}
+
+// validateComparisonQuery checks a comparison query and fills in its defaults
+func validateComparisonQuery(query *models.ComparisonQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+
+	comparison := &query.Comparison
+	if !comparison.StartTime.Before(comparison.EndTime) {
+		return invalidArgument("start_time must be before end_time")
+	}
+
+	baseline := &query.Baseline
+	if baseline.StartTime.IsZero() && baseline.EndTime.IsZero() {
+		baseline.StartTime, baseline.EndTime = comparison.StartTime, comparison.EndTime
+		if sameCohortFilters(baseline, comparison) {
+			baseline.StartTime = comparison.StartTime.Add(-comparison.EndTime.Sub(comparison.StartTime))
+			baseline.EndTime = comparison.StartTime
+		}
+	}
+	if !baseline.StartTime.Before(baseline.EndTime) {
+		return invalidArgument("baseline start_time must be before baseline end_time")
+	}
+
+	for _, cohort := range []*models.Cohort{baseline, comparison} {
+		if len(cohort.Tags) > maxCohortTags {
+			return invalidArgument("at most %d tag filters are allowed per cohort", maxCohortTags)
+		}
+		for key := range cohort.Tags {
+			if !metricTagKeyPattern.MatchString(key) {
+				return invalidArgument("invalid tag key %q", key)
+			}
+		}
+	}
+
+	if reflect.DeepEqual(*baseline, *comparison) {
+		return invalidArgument("baseline and comparison select the same traces")
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultComparisonModels
+	}
+	if query.Limit > maxComparisonModels {
+		query.Limit = maxComparisonModels
+	}
+	return nil
+}
+
+// sameCohortFilters reports whether two cohorts differ only in their time window
+func sameCohortFilters(a, b *models.Cohort) bool {
+	a2, b2 := *a, *b
+	a2.StartTime, a2.EndTime = b2.StartTime, b2.EndTime
+	if len(a2.Tags) == 0 && len(b2.Tags) == 0 {
+		a2.Tags, b2.Tags = nil, nil
+	}
+	return reflect.DeepEqual(a2, b2)
+}
+
+// compareCohortGroup computes the deltas and significance tests between two cohorts' stats
+func compareCohortGroup(model string, baseline, comparison *models.CohortGroupStats) *CohortComparison {
+	perRequest := func(total float64, requests int64) float64 {
+		if requests == 0 {
+			return 0
+		}
+		return total / float64(requests)
+	}
+
+	c := &CohortComparison{
+		Model:             model,
+		Requests:          newMetricDelta(float64(baseline.Requests), float64(comparison.Requests)),
+		Errors:            newMetricDelta(float64(baseline.Errors), float64(comparison.Errors)),
+		ErrorRate:         newMetricDelta(perRequest(float64(baseline.Errors)*100.0, baseline.Requests), perRequest(float64(comparison.Errors)*100.0, comparison.Requests)),
+		TotalCost:         newMetricDelta(baseline.TotalCost, comparison.TotalCost),
+		AvgCostPerRequest: newMetricDelta(perRequest(baseline.TotalCost, baseline.Requests), perRequest(comparison.TotalCost, comparison.Requests)),
+		TotalTokens:       newMetricDelta(float64(baseline.TotalTokens), float64(comparison.TotalTokens)),
+		AvgTokens:         newMetricDelta(perRequest(float64(baseline.TotalTokens), baseline.Requests), perRequest(float64(comparison.TotalTokens), comparison.Requests)),
+		AvgLatencyMs:      newMetricDelta(baseline.AvgLatencyMs, comparison.AvgLatencyMs),
+		P50LatencyMs:      newMetricDelta(baseline.P50LatencyMs, comparison.P50LatencyMs),
+		P90LatencyMs:      newMetricDelta(baseline.P90LatencyMs, comparison.P90LatencyMs),
+		P95LatencyMs:      newMetricDelta(baseline.P95LatencyMs, comparison.P95LatencyMs),
+		P99LatencyMs:      newMetricDelta(baseline.P99LatencyMs, comparison.P99LatencyMs),
+	}
+
+	if t, df, p, ok := welchTTest(comparison.AvgLatencyMs, comparison.LatencyStdDev, comparison.Requests, baseline.AvgLatencyMs, baseline.LatencyStdDev, baseline.Requests); ok {
+		c.LatencyTest = &SignificanceTest{
+			Method:           "welch_t_test",
+			Difference:       comparison.AvgLatencyMs - baseline.AvgLatencyMs,
+			Statistic:        t,
+			DegreesOfFreedom: df,
+			PValue:           p,
+			Significant:      p < significanceLevel,
+		}
+	}
+
+	if z, p, ok := twoProportionZTest(comparison.Errors, comparison.Requests, baseline.Errors, baseline.Requests); ok {
+		c.ErrorRateTest = &SignificanceTest{
+			Method:      "two_proportion_z_test",
+			Difference:  c.ErrorRate.Change,
+			Statistic:   z,
+			PValue:      p,
+			Significant: p < significanceLevel,
+		}
+	}
+
+	return c
+}
+
+// newMetricDelta compares a comparison value against its baseline
+func newMetricDelta(baseline, comparison float64) MetricDelta {
+	delta := MetricDelta{
+		Baseline:   baseline,
+		Comparison: comparison,
+		Change:     comparison - baseline,
+	}
+	if baseline != 0 {
+		percent := delta.Change / baseline * 100.0
+		delta.PercentChange = &percent
+	}
+	return delta
+}
+
+// ComparisonReport compares a cohort of traces against a baseline cohort
+type ComparisonReport struct {
+	Baseline        models.Cohort       `json:"baseline"`
+	Comparison      models.Cohort       `json:"comparison"`
+	ConfidenceLevel float64             `json:"confidence_level"`
+	Total           *CohortComparison   `json:"total"`
+	ByModel         []*CohortComparison `json:"by_model"`
+}
+
+// CohortComparison holds the deltas between two cohorts, overall or for one model.
+// Significance tests are omitted when either cohort has too little data.
+type CohortComparison struct {
+	Model             string            `json:"model,omitempty"`
+	Requests          MetricDelta       `json:"requests"`
+	Errors            MetricDelta       `json:"errors"`
+	ErrorRate         MetricDelta       `json:"error_rate"` // percent
+	TotalCost         MetricDelta       `json:"total_cost"`
+	AvgCostPerRequest MetricDelta       `json:"avg_cost_per_request"`
+	TotalTokens       MetricDelta       `json:"total_tokens"`
+	AvgTokens         MetricDelta       `json:"avg_tokens_per_request"`
+	AvgLatencyMs      MetricDelta       `json:"avg_latency_ms"`
+	P50LatencyMs      MetricDelta       `json:"p50_latency_ms"`
+	P90LatencyMs      MetricDelta       `json:"p90_latency_ms"`
+	P95LatencyMs      MetricDelta       `json:"p95_latency_ms"`
+	P99LatencyMs      MetricDelta       `json:"p99_latency_ms"`
+	LatencyTest       *SignificanceTest `json:"latency_test,omitempty"`    // comparison minus baseline
+	ErrorRateTest     *SignificanceTest `json:"error_rate_test,omitempty"` // comparison minus baseline
+}
+
+// MetricDelta is one metric in both cohorts; PercentChange is omitted when the baseline is zero
+type MetricDelta struct {
+	Baseline      float64  `json:"baseline"`
+	Comparison    float64  `json:"comparison"`
+	Change        float64  `json:"change"`
+	PercentChange *float64 `json:"percent_change,omitempty"`
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestCompareCohortsPreviousPeriod(t *testing.T) {
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	repo := &mockRepository{}
+
+	_, err := NewAnalyticsService(repo).CompareCohorts(context.Background(), &models.ComparisonQuery{
+		OrganizationID: "org-1",
+		Baseline:       models.Cohort{ProjectID: "proj-1"},
+		Comparison:     models.Cohort{ProjectID: "proj-1", StartTime: start, EndTime: end},
+	})
+	if err != nil {
+		t.Fatalf("CompareCohorts() error = %v", err)
+	}
+
+	if len(repo.cohortQueries) != 2 {
+		t.Fatalf("expected two cohort queries, got %d", len(repo.cohortQueries))
+	}
+	if baseline := repo.cohortQueries[0]; !baseline.StartTime.Equal(start.AddDate(0, 0, -7)) || !baseline.EndTime.Equal(start) {
+		t.Errorf("expected the baseline to be the previous week, got %v to %v", baseline.StartTime, baseline.EndTime)
+	}
+}
+
+func TestCompareCohorts(t *testing.T) {
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+
+	repo := &mockRepository{cohorts: map[string]*models.CohortStats{
+		"proj-v1": {
+			Total: models.CohortGroupStats{Requests: 1000, Errors: 10, TotalCost: 20, TotalTokens: 500000, AvgLatencyMs: 800, LatencyStdDev: 200, P95LatencyMs: 1200},
+			ByModel: []*models.CohortGroupStats{
+				{Model: "gpt-4", Requests: 800, Errors: 8, TotalCost: 18},
+				{Model: "gpt-3.5-turbo", Requests: 200, Errors: 2, TotalCost: 2},
+			},
+		},
+		"proj-v2": {
+			Total: models.CohortGroupStats{Requests: 1000, Errors: 60, TotalCost: 30, TotalTokens: 600000, AvgLatencyMs: 700, LatencyStdDev: 200, P95LatencyMs: 1000},
+			ByModel: []*models.CohortGroupStats{
+				{Model: "gpt-4", Requests: 900, Errors: 55, TotalCost: 29},
+				{Model: "claude-3-haiku", Requests: 100, Errors: 5, TotalCost: 1},
+			},
+		},
+	}}
+
+	report, err := NewAnalyticsService(repo).CompareCohorts(context.Background(), &models.ComparisonQuery{
+		OrganizationID: "org-1",
+		Baseline:       models.Cohort{ProjectID: "proj-v1"},
+		Comparison:     models.Cohort{ProjectID: "proj-v2", StartTime: start, EndTime: end},
+	})
+	if err != nil {
+		t.Fatalf("CompareCohorts() error = %v", err)
+	}
+
+	// Different cohorts default to the same period
+	if !report.Baseline.StartTime.Equal(start) || !report.Baseline.EndTime.Equal(end) {
+		t.Errorf("expected the baseline over the same period, got %v to %v", report.Baseline.StartTime, report.Baseline.EndTime)
+	}
+
+	total := report.Total
+	if total.TotalCost.Change != 10 || total.TotalCost.PercentChange == nil || *total.TotalCost.PercentChange != 50 {
+		t.Errorf("unexpected cost delta %+v", total.TotalCost)
+	}
+	if total.ErrorRate.Baseline != 1 || total.ErrorRate.Comparison != 6 {
+		t.Errorf("unexpected error rate delta %+v", total.ErrorRate)
+	}
+	if total.ErrorRateTest == nil || !total.ErrorRateTest.Significant || total.ErrorRateTest.Difference != 5 {
+		t.Errorf("expected a significant error rate increase, got %+v", total.ErrorRateTest)
+	}
+	if total.LatencyTest == nil || !total.LatencyTest.Significant || total.LatencyTest.Difference != -100 {
+		t.Errorf("expected a significant latency drop, got %+v", total.LatencyTest)
+	}
+	if total.P95LatencyMs.Change != -200 {
+		t.Errorf("unexpected p95 delta %+v", total.P95LatencyMs)
+	}
+
+	// Models from either cohort, busiest first
+	want := []string{"gpt-4", "gpt-3.5-turbo", "claude-3-haiku"}
+	if len(report.ByModel) != len(want) {
+		t.Fatalf("expected %d models, got %d", len(want), len(report.ByModel))
+	}
+	for i, model := range want {
+		if report.ByModel[i].Model != model {
+			t.Errorf("model %d: expected %s, got %s", i, model, report.ByModel[i].Model)
+		}
+	}
+	if added := report.ByModel[2]; added.Requests.Baseline != 0 || added.Requests.PercentChange != nil {
+		t.Errorf("expected a new model without a percent change, got %+v", added.Requests)
+	}
+}
+
+func TestCompareCohortsValidation(t *testing.T) {
+	now := time.Now()
+	window := models.Cohort{StartTime: now.Add(-time.Hour), EndTime: now}
+	service := NewAnalyticsService(&mockRepository{})
+
+	tests := []struct {
+		name  string
+		query models.ComparisonQuery
+	}{
+		{"missing organization", models.ComparisonQuery{Comparison: window}},
+		{"empty window", models.ComparisonQuery{OrganizationID: "org-1", Comparison: models.Cohort{StartTime: now, EndTime: now}}},
+		{"same cohorts", models.ComparisonQuery{OrganizationID: "org-1", Baseline: window, Comparison: window}},
+		{"invalid tag key", models.ComparisonQuery{
+			OrganizationID: "org-1",
+			Baseline:       models.Cohort{Tags: map[string]string{"bad key": "v1"}},
+			Comparison:     window,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CompareCohorts(context.Background(), &tt.query); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
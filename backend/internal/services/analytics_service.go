@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
 	"time"
 
 	"github.com/Aditya-Pimpalkar/clarity/internal/models"
@@ -25,18 +27,20 @@ func NewAnalyticsService(repo repository.Repository) *AnalyticsService {
 // ORIGINAL METHODS (Keep these - they work with your existing repository)
 // ============================================================================
 
-// GetDashboardSummary returns a comprehensive dashboard summary
-func (s *AnalyticsService) GetDashboardSummary(ctx context.Context, orgID, projectID string, timeRange string) (*DashboardSummary, error) {
+// GetDashboardSummary returns a comprehensive dashboard summary. Daily costs are bucketed
+// by calendar day in the range's time zone.
+func (s *AnalyticsService) GetDashboardSummary(ctx context.Context, orgID, projectID string, timeRange TimeRangeSpec) (*DashboardSummary, error) {
 	// Validate inputs
 	if orgID == "" || projectID == "" {
 		return nil, fmt.Errorf("organization_id and project_id are required")
 	}
 
 	// Parse time range
-	startTime, endTime, err := s.parseTimeRange(timeRange)
+	window, err := ResolveTimeRange(timeRange, 24*time.Hour, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("invalid time range: %w", err)
+		return nil, err
 	}
+	startTime, endTime := window.Start, window.End
 
 	// Get metric summary
 	metricSummary, err := s.repo.GetMetricSummary(ctx, orgID, projectID, startTime, endTime)
@@ -45,7 +49,16 @@ func (s *AnalyticsService) GetDashboardSummary(ctx context.Context, orgID, proje
 	}
 
 	// Get cost breakdown
-	costBreakdown, err := s.repo.GetCostBreakdown(ctx, orgID, projectID, startTime, endTime)
+	costBreakdown, err := s.repo.GetCostBreakdown(ctx, &models.CostQuery{
+		AnalyticsQuery: models.AnalyticsQuery{
+			OrganizationID: orgID,
+			ProjectID:      projectID,
+			StartTime:      startTime,
+			EndTime:        endTime,
+			TimeZone:       window.Location.String(),
+		},
+		Limit: 10,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cost breakdown: %w", err)
 	}
@@ -56,13 +69,15 @@ func (s *AnalyticsService) GetDashboardSummary(ctx context.Context, orgID, proje
 		return nil, fmt.Errorf("failed to get model usage: %w", err)
 	}
 
-	// Calculate additional insights
-	insights := s.generateInsights(metricSummary, costBreakdown, modelUsage)
+	// Calculate additional insights, including anomalies found by the background detector
+	anomalies := s.recentAnomalies(ctx, orgID, projectID, startTime, endTime)
+	insights := s.generateInsights(metricSummary, costBreakdown, modelUsage, anomalies)
 
 	return &DashboardSummary{
-		TimeRange:     timeRange,
+		TimeRange:     timeRange.Range,
 		StartTime:     startTime,
 		EndTime:       endTime,
+		TimeZone:      window.Location.String(),
 		MetricSummary: metricSummary,
 		CostBreakdown: costBreakdown,
 		ModelUsage:    modelUsage,
@@ -70,36 +85,31 @@ func (s *AnalyticsService) GetDashboardSummary(ctx context.Context, orgID, proje
 	}, nil
 }
 
-// parseTimeRange converts time range string to start and end times
-func (s *AnalyticsService) parseTimeRange(timeRange string) (time.Time, time.Time, error) {
-	now := time.Now()
-	var startTime time.Time
-
-	switch timeRange {
-	case "1h", "last_hour":
-		startTime = now.Add(-1 * time.Hour)
-	case "24h", "last_24h", "today":
-		startTime = now.Add(-24 * time.Hour)
-	case "7d", "last_7d", "week":
-		startTime = now.Add(-7 * 24 * time.Hour)
-	case "30d", "last_30d", "month":
-		startTime = now.Add(-30 * 24 * time.Hour)
-	case "90d", "last_90d":
-		startTime = now.Add(-90 * 24 * time.Hour)
-	default:
-		return time.Time{}, time.Time{}, fmt.Errorf("unsupported time range: %s", timeRange)
+// recentAnomalies returns the anomalies overlapping a window for dashboard insights. The
+// dashboards are served without them when they cannot be listed.
+func (s *AnalyticsService) recentAnomalies(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) []*models.Anomaly {
+	anomalies, err := s.repo.ListAnomalies(ctx, &models.AnomalyQuery{
+		OrganizationID: orgID,
+		ProjectID:      projectID,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Limit:          maxAnomalyInsights,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to list anomalies of organization %s for the dashboard: %v", orgID, err)
+		return nil
 	}
-
-	return startTime, now, nil
+	return anomalies
 }
 
 // generateInsights generates insights from the data
 func (s *AnalyticsService) generateInsights(
 	summary *models.MetricSummary,
-	costBreakdown []*models.CostBreakdown,
+	costBreakdown *models.CostBreakdown,
 	modelUsage []*models.ModelUsage,
+	anomalies []*models.Anomaly,
 ) []Insight {
-	insights := []Insight{}
+	insights := anomalyInsights(anomalies)
 
 	// Insight 1: Error rate
 	if summary.ErrorRate > 5.0 {
@@ -121,14 +131,17 @@ func (s *AnalyticsService) generateInsights(
 	}
 
 	// Insight 2: Cost concentration
-	if len(costBreakdown) > 0 && costBreakdown[0].Percentage > 80.0 {
-		insights = append(insights, Insight{
-			Type:        "info",
-			Category:    "cost",
-			Title:       "Cost Concentration",
-			Description: fmt.Sprintf("%.1f%% of costs come from %s. Consider model optimization or caching", costBreakdown[0].Percentage, costBreakdown[0].Model),
-			Severity:    "low",
-		})
+	if len(costBreakdown.ByModel) > 0 && costBreakdown.TotalCost > 0 {
+		top := costBreakdown.ByModel[0]
+		if share := top.TotalCost / costBreakdown.TotalCost * 100.0; share > 80.0 {
+			insights = append(insights, Insight{
+				Type:        "info",
+				Category:    "cost",
+				Title:       "Cost Concentration",
+				Description: fmt.Sprintf("%.1f%% of costs come from %s. Consider model optimization or caching", share, top.Model),
+				Severity:    "low",
+			})
+		}
 	}
 
 	// Insight 3: Latency
@@ -226,55 +239,100 @@ func (s *AnalyticsService) generateInsights(
 	return insights
 }
 
+// maxCostTagKeys caps how many metadata tags a cost breakdown may group by
+const maxCostTagKeys = 5
+
 // GetCostAnalysis returns detailed cost analysis
-func (s *AnalyticsService) GetCostAnalysis(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) (*CostAnalysis, error) {
-	// Get cost breakdown by model
-	breakdown, err := s.repo.GetCostBreakdown(ctx, orgID, projectID, startTime, endTime)
+func (s *AnalyticsService) GetCostAnalysis(ctx context.Context, query *models.CostQuery) (*CostAnalysis, error) {
+	if query.OrganizationID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return nil, invalidArgument("start_time must be before end_time")
+	}
+	if len(query.TagKeys) > maxCostTagKeys {
+		return nil, invalidArgument("at most %d tag keys can be grouped by", maxCostTagKeys)
+	}
+	loc, err := LoadTimeZone(query.TimeZone)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cost breakdown: %w", err)
+		return nil, err
 	}
+	query.TimeZone = loc.String()
 
-	// Calculate total cost
-	var totalCost float64
-	for _, item := range breakdown {
-		totalCost += item.TotalCost
+	breakdown, err := s.repo.GetCostBreakdown(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost breakdown: %w", err)
 	}
 
 	// Calculate daily average
-	days := endTime.Sub(startTime).Hours() / 24
-	if days == 0 {
+	days := query.EndTime.Sub(query.StartTime).Hours() / 24
+	if days < 1 {
 		days = 1
 	}
-	dailyAverage := totalCost / days
+	dailyAverage := breakdown.TotalCost / days
 
-	// Project monthly cost
+	// Project the next 30 days from the trend and weekly pattern of the complete days
 	monthlyProjection := dailyAverage * 30
+	projectionMethod := ForecastAverage
+	if history := completeDailyCosts(breakdown.DailyCosts, &query.AnalyticsQuery); len(history) > 0 {
+		model := fitForecastModel(history)
+		monthlyProjection = 0
+		for _, value := range model.forecast(30) {
+			monthlyProjection += math.Max(0, value)
+		}
+		projectionMethod = model.method
+	}
 
 	// Find most expensive model
 	var mostExpensiveModel string
 	var highestCost float64
-	if len(breakdown) > 0 {
-		mostExpensiveModel = breakdown[0].Model
-		highestCost = breakdown[0].TotalCost
+	if len(breakdown.ByModel) > 0 {
+		mostExpensiveModel = breakdown.ByModel[0].Model
+		highestCost = breakdown.ByModel[0].TotalCost
 	}
 
 	return &CostAnalysis{
-		TotalCost:          totalCost,
+		TotalCost:          breakdown.TotalCost,
 		DailyAverage:       dailyAverage,
 		MonthlyProjection:  monthlyProjection,
+		ProjectionMethod:   projectionMethod,
 		CostBreakdown:      breakdown,
 		MostExpensiveModel: mostExpensiveModel,
 		HighestCost:        highestCost,
 	}, nil
 }
 
-// GetPerformanceMetrics returns performance analysis
-func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) (*PerformanceMetrics, error) {
-	summary, err := s.repo.GetMetricSummary(ctx, orgID, projectID, startTime, endTime)
+// defaultLatencyBuckets are the histogram bounds used when a query sets none, in ms
+var defaultLatencyBuckets = []float64{100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+const (
+	maxLatencyBuckets    = 50
+	maxLatencyTimeBucket = 1000
+)
+
+// latencyGranularities maps each time series granularity onto its bucket width
+var latencyGranularities = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// GetPerformanceMetrics returns performance analysis with latency distributions
+func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, query *models.LatencyQuery) (*PerformanceMetrics, error) {
+	if err := s.validateLatencyQuery(query); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.repo.GetMetricSummary(ctx, query.OrganizationID, query.ProjectID, query.StartTime, query.EndTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metric summary: %w", err)
 	}
 
+	series, err := s.repo.GetLatencyDistribution(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latency distribution: %w", err)
+	}
+
 	// Calculate additional metrics
 	var status string
 	var recommendation string
@@ -298,348 +356,289 @@ func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, orgID, pro
 		Summary:        summary,
 		Status:         status,
 		Recommendation: recommendation,
+		Level:          query.Level,
+		GroupBy:        query.GroupBy,
+		Granularity:    query.Granularity,
+		Buckets:        query.Buckets,
+		Latency:        series,
 	}, nil
 }
 
-// GetModelComparison compares performance and cost across models
-func (s *AnalyticsService) GetModelComparison(ctx context.Context, orgID, projectID string, startTime, endTime time.Time) ([]*ModelComparison, error) {
-	modelUsage, err := s.repo.GetModelUsage(ctx, orgID, projectID, startTime, endTime)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get model usage: %w", err)
+// validateLatencyQuery checks a latency query and fills in its defaults
+func (s *AnalyticsService) validateLatencyQuery(query *models.LatencyQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return invalidArgument("start_time must be before end_time")
 	}
 
-	comparisons := make([]*ModelComparison, len(modelUsage))
-	for i, usage := range modelUsage {
-		// Calculate metrics per request
-		avgCostPerRequest := 0.0
-		avgTokensPerRequest := 0.0
-		if usage.CallCount > 0 {
-			avgCostPerRequest = usage.TotalCost / float64(usage.CallCount)
-			avgTokensPerRequest = float64(usage.TotalTokens) / float64(usage.CallCount)
-		}
+	switch query.Level {
+	case "":
+		query.Level = "trace"
+	case "trace", "span":
+	default:
+		return invalidArgument("level must be trace or span")
+	}
 
-		// Calculate efficiency score (lower is better)
-		// Score = (normalized_latency + normalized_cost) / 2
-		efficiencyScore := (usage.AvgLatency / 1000.0) + (avgCostPerRequest * 100.0)
-
-		comparisons[i] = &ModelComparison{
-			Model:               usage.Model,
-			TotalCalls:          usage.CallCount,
-			TotalCost:           usage.TotalCost,
-			AvgCostPerRequest:   avgCostPerRequest,
-			AvgLatency:          usage.AvgLatency,
-			AvgTokensPerRequest: avgTokensPerRequest,
-			EfficiencyScore:     efficiencyScore,
+	switch query.GroupBy {
+	case "", "model", "provider":
+	case "span_name":
+		if query.Level != "span" {
+			return invalidArgument("group_by=span_name requires level=span")
+		}
+	default:
+		return invalidArgument("group_by must be model, provider or span_name")
+	}
+
+	// Default to the finest granularity that keeps the series readable
+	window := query.EndTime.Sub(query.StartTime)
+	if query.Granularity == "" {
+		switch {
+		case window <= 2*24*time.Hour:
+			query.Granularity = "hour"
+		case window <= 90*24*time.Hour:
+			query.Granularity = "day"
+		default:
+			query.Granularity = "week"
 		}
 	}
-
-	return comparisons, nil
-}
-
-// ============================================================================
-// NEW DASHBOARD METHOD (For frontend dashboard API)
-// ============================================================================
-
-// GetDashboard returns dashboard stats matching frontend expectations
-func (s *AnalyticsService) GetDashboard(ctx context.Context, timeRange string, orgID string) (*DashboardStats, error) {
-	// Parse time range
-	startTime, endTime, err := s.parseTimeRange(timeRange)
-	if err != nil {
-		return nil, fmt.Errorf("invalid time range: %w", err)
+	width, ok := latencyGranularities[query.Granularity]
+	if !ok {
+		return invalidArgument("granularity must be hour, day or week")
+	}
+	if window/width > maxLatencyTimeBucket {
+		return invalidArgument("time range has more than %d %s buckets; use a coarser granularity", maxLatencyTimeBucket, query.Granularity)
 	}
 
-	duration := endTime.Sub(startTime)
-
-	// Get current period stats
-	totalTraces, err := s.getTotalTraces(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	if len(query.Buckets) == 0 {
+		query.Buckets = defaultLatencyBuckets
+	}
+	if len(query.Buckets) > maxLatencyBuckets {
+		return invalidArgument("at most %d histogram buckets are allowed", maxLatencyBuckets)
+	}
+	for i, bound := range query.Buckets {
+		if bound <= 0 || (i > 0 && bound <= query.Buckets[i-1]) {
+			return invalidArgument("histogram buckets must be positive and strictly increasing")
+		}
 	}
 
-	totalCost, err := s.getTotalCost(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	if query.Limit <= 0 {
+		query.Limit = 10
 	}
+	return nil
+}
 
-	totalTokens, err := s.getTotalTokens(ctx, orgID, startTime, endTime)
+// completeDailyCosts returns the cost of each complete calendar day in the query range,
+// oldest first, with zero for days without traces
+func completeDailyCosts(costs []models.DailyCost, query *models.AnalyticsQuery) []float64 {
+	loc, err := time.LoadLocation(query.TimeZone)
 	if err != nil {
-		return nil, err
+		loc = time.UTC
 	}
 
-	avgLatency, err := s.getAvgLatency(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	byDate := make(map[string]float64, len(costs))
+	for _, cost := range costs {
+		byDate[cost.Date] = cost.TotalCost
 	}
 
-	errorRate, successRate, err := s.getErrorRate(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	start := query.StartTime.In(loc)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	if day.Before(start) {
+		day = day.AddDate(0, 0, 1)
 	}
 
-	// Get previous period for trends
-	prevStart := startTime.Add(-duration)
-	prevEnd := startTime
+	var values []float64
+	for ; !day.AddDate(0, 0, 1).After(query.EndTime); day = day.AddDate(0, 0, 1) {
+		values = append(values, byDate[day.Format("2006-01-02")])
+	}
+	return values
+}
 
-	prevTraces, _ := s.getTotalTraces(ctx, orgID, prevStart, prevEnd)
-	prevCost, _ := s.getTotalCost(ctx, orgID, prevStart, prevEnd)
-	prevTokens, _ := s.getTotalTokens(ctx, orgID, prevStart, prevEnd)
-	prevLatency, _ := s.getAvgLatency(ctx, orgID, prevStart, prevEnd)
+// significanceLevel is the p-value below which a pairwise difference is reported as significant
+const significanceLevel = 0.05
 
-	// Calculate trends
-	trends := TrendData{
-		Traces:  calculatePercentChange(float64(prevTraces), float64(totalTraces)),
-		Cost:    calculatePercentChange(prevCost, totalCost),
-		Tokens:  calculatePercentChange(float64(prevTokens), float64(totalTokens)),
-		Latency: calculatePercentChange(prevLatency, avgLatency),
+// GetModelComparison compares performance and cost across models. When modelA and modelB
+// are set, the two models are also tested against each other over the same period.
+func (s *AnalyticsService) GetModelComparison(ctx context.Context, query *models.AnalyticsQuery, modelA, modelB string) (*ModelComparisonReport, error) {
+	if query.OrganizationID == "" {
+		return nil, invalidArgument("organization_id is required")
 	}
-
-	// Get top models
-	topModels, err := s.getTopModels(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	if !query.StartTime.Before(query.EndTime) {
+		return nil, invalidArgument("start_time must be before end_time")
 	}
-
-	// Get cost by day
-	costByDay, err := s.getCostByDay(ctx, orgID, startTime, endTime)
-	if err != nil {
-		return nil, err
+	if (modelA == "") != (modelB == "") {
+		return nil, invalidArgument("pairwise comparison needs exactly two models")
 	}
 
-	// Get traces by status
-	tracesByStatus, err := s.getTracesByStatus(ctx, orgID, startTime, endTime)
+	modelUsage, err := s.repo.GetModelUsage(ctx, query.OrganizationID, query.ProjectID, query.StartTime, query.EndTime)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get model usage: %w", err)
 	}
 
-	return &DashboardStats{
-		TotalTraces:    totalTraces,
-		TotalCost:      totalCost,
-		TotalTokens:    totalTokens,
-		AvgLatency:     avgLatency,
-		ErrorRate:      errorRate,
-		SuccessRate:    successRate,
-		Trends:         trends,
-		TopModels:      topModels,
-		CostByDay:      costByDay,
-		TracesByStatus: tracesByStatus,
-	}, nil
-}
-
-// ============================================================================
-// HELPER METHODS FOR DASHBOARD
-// ============================================================================
+	report := &ModelComparisonReport{
+		ConfidenceLevel: 0.95,
+		Models:          make([]*ModelComparison, 0, len(modelUsage)),
+	}
 
-func (s *AnalyticsService) getTotalTraces(ctx context.Context, orgID string, start, end time.Time) (int64, error) {
-	query := `
-		SELECT count() as total
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
+	var usageA, usageB *models.ModelUsage
+	for _, usage := range modelUsage {
+		report.Models = append(report.Models, compareModel(usage))
 
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return 0, err
+		switch usage.Model {
+		case modelA:
+			usageA = usage
+		case modelB:
+			usageB = usage
+		}
 	}
-	defer rows.Close()
 
-	var total int64
-	if rows.Next() {
-		if err := rows.Scan(&total); err != nil {
-			return 0, err
+	if modelA != "" {
+		if usageA == nil || usageB == nil {
+			return nil, invalidArgument("both %s and %s need calls in the selected period", modelA, modelB)
 		}
+		report.Pairwise = compareModelPair(usageA, usageB)
 	}
-	return total, nil
-}
 
-func (s *AnalyticsService) getTotalCost(ctx context.Context, orgID string, start, end time.Time) (float64, error) {
-	query := `
-		SELECT sum(total_cost_usd) as total_cost
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
+	return report, nil
+}
 
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return 0, err
+// compareModel derives the per-model comparison figures from raw usage
+func compareModel(usage *models.ModelUsage) *ModelComparison {
+	comparison := &ModelComparison{
+		Model:         usage.Model,
+		Provider:      usage.Provider,
+		TotalCalls:    usage.CallCount,
+		ErrorCount:    usage.ErrorCount,
+		TotalCost:     usage.TotalCost,
+		TotalTokens:   usage.TotalTokens,
+		AvgLatency:    usage.AvgLatency,
+		LatencyStdDev: usage.LatencyStdDev,
+		P50LatencyMs:  usage.P50LatencyMs,
+		P95LatencyMs:  usage.P95LatencyMs,
+		P99LatencyMs:  usage.P99LatencyMs,
 	}
-	defer rows.Close()
 
-	var totalCost float64
-	if rows.Next() {
-		if err := rows.Scan(&totalCost); err != nil {
-			return 0, err
-		}
+	if usage.CallCount > 0 {
+		comparison.AvgCostPerRequest = usage.TotalCost / float64(usage.CallCount)
+		comparison.AvgTokensPerRequest = float64(usage.TotalTokens) / float64(usage.CallCount)
+		comparison.ErrorRate = float64(usage.ErrorCount) / float64(usage.CallCount) * 100.0
+	}
+	if usage.TotalTokens > 0 {
+		comparison.CostPer1KTokens = usage.TotalCost / float64(usage.TotalTokens) * 1000.0
+	}
+	if usage.PromptTokens > 0 {
+		comparison.TokenRatio = float64(usage.CompletionTokens) / float64(usage.PromptTokens)
 	}
-	return totalCost, nil
-}
 
-func (s *AnalyticsService) getTotalTokens(ctx context.Context, orgID string, start, end time.Time) (int64, error) {
-	query := `
-		SELECT sum(total_tokens) as total_tokens
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
+	lower, upper := wilsonInterval(usage.ErrorCount, usage.CallCount, z95)
+	comparison.ErrorRateCI = ConfidenceInterval{Lower: lower * 100.0, Upper: upper * 100.0}
 
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return 0, err
+	return comparison
+}
+
+// compareModelPair tests two models for differences in mean latency and error rate
+func compareModelPair(a, b *models.ModelUsage) *PairwiseComparison {
+	pair := &PairwiseComparison{ModelA: a.Model, ModelB: b.Model}
+
+	if t, df, p, ok := welchTTest(a.AvgLatency, a.LatencyStdDev, a.CallCount, b.AvgLatency, b.LatencyStdDev, b.CallCount); ok {
+		pair.Latency = &SignificanceTest{
+			Method:           "welch_t_test",
+			Difference:       a.AvgLatency - b.AvgLatency,
+			Statistic:        t,
+			DegreesOfFreedom: df,
+			PValue:           p,
+			Significant:      p < significanceLevel,
+		}
 	}
-	defer rows.Close()
 
-	var totalTokens int64
-	if rows.Next() {
-		if err := rows.Scan(&totalTokens); err != nil {
-			return 0, err
+	if z, p, ok := twoProportionZTest(a.ErrorCount, a.CallCount, b.ErrorCount, b.CallCount); ok {
+		rateA := float64(a.ErrorCount) / float64(a.CallCount) * 100.0
+		rateB := float64(b.ErrorCount) / float64(b.CallCount) * 100.0
+		pair.ErrorRate = &SignificanceTest{
+			Method:      "two_proportion_z_test",
+			Difference:  rateA - rateB,
+			Statistic:   z,
+			PValue:      p,
+			Significant: p < significanceLevel,
 		}
 	}
-	return totalTokens, nil
+
+	return pair
 }
 
-// func (s *AnalyticsService) getAvgLatency(ctx context.Context, orgID string, start, end time.Time) (float64, error) {
-// 	query := `
-// 		SELECT avg(duration_ms) as avg_latency
-// 		FROM llm_observability.traces
-// 		WHERE organization_id = ?
-// 		AND timestamp >= ?
-// 		AND timestamp < ?
-// 	`
-
-// 	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-// 	if err != nil {
-// 		return 0, err
-// 	}
-// 	defer rows.Close()
-
-// 	var avgLatency float64
-// 	if rows.Next() {
-// 		if err := rows.Scan(&avgLatency); err != nil {
-// 			return 0, err
-// 		}
-// 	}
-// 	return avgLatency, nil
-// }
-
-func (s *AnalyticsService) getAvgLatency(ctx context.Context, orgID string, start, end time.Time) (float64, error) {
-	query := `
-		SELECT 
-			count() as trace_count,
-			avg(duration_ms) as avg_latency
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
-
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
+// ============================================================================
+// NEW DASHBOARD METHOD (For frontend dashboard API)
+// ============================================================================
+
+// GetDashboard returns dashboard stats matching frontend expectations.
+// Daily costs are bucketed by calendar day in the range's time zone.
+func (s *AnalyticsService) GetDashboard(ctx context.Context, orgID string, timeRange TimeRangeSpec) (*DashboardStats, error) {
+	window, err := ResolveTimeRange(timeRange, 24*time.Hour, time.Now())
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer rows.Close()
+	startTime, endTime := window.Start, window.End
 
-	var traceCount int64
-	var avgLatency float64
-	if rows.Next() {
-		if err := rows.Scan(&traceCount, &avgLatency); err != nil {
-			return 0, err
-		}
+	query := &models.AnalyticsQuery{
+		OrganizationID: orgID,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		TimeZone:       window.Location.String(),
 	}
 
-	// If no traces, return 0
-	if traceCount == 0 {
-		return 0, nil
+	// Current and previous period in one scan; the previous period drives the trends
+	prevStart := startTime.Add(-endTime.Sub(startTime))
+	totals, err := s.repo.GetTraceTotals(ctx, query,
+		models.TimeWindow{Start: prevStart, End: startTime},
+		models.TimeWindow{Start: startTime, End: endTime},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace totals: %w", err)
 	}
+	prev, current := totals[0], totals[1]
 
-	return avgLatency, nil
-}
-
-// func (s *AnalyticsService) getErrorRate(ctx context.Context, orgID string, start, end time.Time) (float64, float64, error) {
-// 	query := `
-// 		SELECT
-// 			countIf(status = 'error') * 100.0 / count() as error_rate,
-// 			countIf(status = 'success') * 100.0 / count() as success_rate
-// 		FROM llm_observability.traces
-// 		WHERE organization_id = ?
-// 		AND timestamp >= ?
-// 		AND timestamp < ?
-// 	`
-
-// 	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-// 	if err != nil {
-// 		return 0, 0, err
-// 	}
-// 	defer rows.Close()
-
-// 	var errorRate, successRate float64
-// 	if rows.Next() {
-// 		if err := rows.Scan(&errorRate, &successRate); err != nil {
-// 			return 0, 0, err
-// 		}
-// 	}
-// 	return errorRate, successRate, nil
-// }
-
-func (s *AnalyticsService) getErrorRate(ctx context.Context, orgID string, start, end time.Time) (float64, float64, error) {
-	query := `
-		SELECT 
-			count() as total_count
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
-
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
+	breakdowns, err := s.repo.GetDashboardBreakdowns(ctx, query, 10)
 	if err != nil {
-		return 0, 0, err
+		return nil, fmt.Errorf("failed to get dashboard breakdowns: %w", err)
 	}
-	defer rows.Close()
 
-	var totalCount int64
-	if rows.Next() {
-		if err := rows.Scan(&totalCount); err != nil {
-			return 0, 0, err
-		}
+	stats := &DashboardStats{
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TimeZone:    query.TimeZone,
+		TotalTraces: current.TraceCount,
+		TotalCost:   current.TotalCost,
+		TotalTokens: current.TotalTokens,
+		AvgLatency:  current.AvgLatencyMs,
+		Trends: TrendData{
+			Traces:  calculatePercentChange(float64(prev.TraceCount), float64(current.TraceCount)),
+			Cost:    calculatePercentChange(prev.TotalCost, current.TotalCost),
+			Tokens:  calculatePercentChange(float64(prev.TotalTokens), float64(current.TotalTokens)),
+			Latency: calculatePercentChange(prev.AvgLatencyMs, current.AvgLatencyMs),
+		},
+		TopModels:      make([]ModelStats, 0, len(breakdowns.TopModels)),
+		CostByDay:      make([]DailyCost, 0, len(breakdowns.CostByDay)),
+		TracesByStatus: make([]StatusCount, 0, len(breakdowns.ByStatus)),
 	}
 
-	// If no traces, return 0% for both
-	if totalCount == 0 {
-		return 0.0, 0.0, nil
+	if current.TraceCount > 0 {
+		stats.ErrorRate = float64(current.ErrorCount) / float64(current.TraceCount) * 100.0
+		stats.SuccessRate = float64(current.SuccessCount) / float64(current.TraceCount) * 100.0
 	}
 
-	// Now get error and success counts
-	query2 := `
-		SELECT 
-			countIf(status = 'error') as errors,
-			countIf(status = 'success') as successes
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-	`
-
-	rows2, err := s.repo.Query(ctx, query2, orgID, start, end)
-	if err != nil {
-		return 0, 0, err
+	for _, group := range breakdowns.TopModels {
+		stats.TopModels = append(stats.TopModels, ModelStats{Model: group.Key, Count: group.Count, Cost: group.Cost})
 	}
-	defer rows2.Close()
-
-	var errorCount, successCount int64
-	if rows2.Next() {
-		if err := rows2.Scan(&errorCount, &successCount); err != nil {
-			return 0, 0, err
-		}
+	for _, group := range breakdowns.CostByDay {
+		stats.CostByDay = append(stats.CostByDay, DailyCost{Date: group.Key, Cost: group.Cost})
 	}
+	for _, group := range breakdowns.ByStatus {
+		stats.TracesByStatus = append(stats.TracesByStatus, StatusCount{Status: group.Key, Count: group.Count})
+	}
+	stats.Insights = anomalyInsights(s.recentAnomalies(ctx, orgID, "", startTime, endTime))
 
-	errorRate := (float64(errorCount) / float64(totalCount)) * 100.0
-	successRate := (float64(successCount) / float64(totalCount)) * 100.0
-
-	return errorRate, successRate, nil
+	return stats, nil
 }
 
 func calculatePercentChange(old, new float64) float64 {
@@ -657,116 +656,20 @@ func calculatePercentChange(old, new float64) float64 {
 	return change
 }
 
-func (s *AnalyticsService) getTopModels(ctx context.Context, orgID string, start, end time.Time) ([]ModelStats, error) {
-	query := `
-		SELECT 
-			model,
-			count() as count,
-			sum(total_cost_usd) as total_cost
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-		GROUP BY model
-		ORDER BY count DESC
-		LIMIT 10
-	`
-
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var models []ModelStats
-	for rows.Next() {
-		var m ModelStats
-		if err := rows.Scan(&m.Model, &m.Count, &m.Cost); err != nil {
-			return nil, err
-		}
-		models = append(models, m)
-	}
-
-	return models, nil
-}
-
-func (s *AnalyticsService) getCostByDay(ctx context.Context, orgID string, start, end time.Time) ([]DailyCost, error) {
-	query := `
-		SELECT 
-			toDate(timestamp) as date,
-			sum(total_cost_usd) as cost
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-		GROUP BY date
-		ORDER BY date ASC
-	`
-
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var costs []DailyCost
-	for rows.Next() {
-		var c DailyCost
-		var date time.Time
-		if err := rows.Scan(&date, &c.Cost); err != nil {
-			return nil, err
-		}
-		c.Date = date.Format("2006-01-02")
-		costs = append(costs, c)
-	}
-
-	return costs, nil
-}
-
-func (s *AnalyticsService) getTracesByStatus(ctx context.Context, orgID string, start, end time.Time) ([]StatusCount, error) {
-	query := `
-		SELECT 
-			status,
-			count() as count
-		FROM llm_observability.traces
-		WHERE organization_id = ?
-		AND timestamp >= ?
-		AND timestamp < ?
-		GROUP BY status
-		ORDER BY count DESC
-	`
-
-	rows, err := s.repo.Query(ctx, query, orgID, start, end)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var statuses []StatusCount
-	for rows.Next() {
-		var s StatusCount
-		if err := rows.Scan(&s.Status, &s.Count); err != nil {
-			return nil, err
-		}
-		statuses = append(statuses, s)
-	}
-
-	return statuses, nil
-}
-
 // ============================================================================
 // TYPE DEFINITIONS
 // ============================================================================
 
 // DashboardSummary contains all data for the main dashboard
 type DashboardSummary struct {
-	TimeRange     string                  `json:"time_range"`
-	StartTime     time.Time               `json:"start_time"`
-	EndTime       time.Time               `json:"end_time"`
-	MetricSummary *models.MetricSummary   `json:"metric_summary"`
-	CostBreakdown []*models.CostBreakdown `json:"cost_breakdown"`
-	ModelUsage    []*models.ModelUsage    `json:"model_usage"`
-	Insights      []Insight               `json:"insights"`
+	TimeRange     string                `json:"time_range"`
+	StartTime     time.Time             `json:"start_time"`
+	EndTime       time.Time             `json:"end_time"`
+	TimeZone      string                `json:"time_zone"`
+	MetricSummary *models.MetricSummary `json:"metric_summary"`
+	CostBreakdown *models.CostBreakdown `json:"cost_breakdown"`
+	ModelUsage    []*models.ModelUsage  `json:"model_usage"`
+	Insights      []Insight             `json:"insights"`
 }
 
 // Insight represents an actionable insight
@@ -780,44 +683,103 @@ type Insight struct {
 
 // CostAnalysis contains detailed cost information
 type CostAnalysis struct {
-	TotalCost          float64                 `json:"total_cost"`
-	DailyAverage       float64                 `json:"daily_average"`
-	MonthlyProjection  float64                 `json:"monthly_projection"`
-	CostBreakdown      []*models.CostBreakdown `json:"cost_breakdown"`
-	MostExpensiveModel string                  `json:"most_expensive_model"`
-	HighestCost        float64                 `json:"highest_cost"`
+	TotalCost          float64                `json:"total_cost"`
+	DailyAverage       float64                `json:"daily_average"`
+	MonthlyProjection  float64                `json:"monthly_projection"`
+	ProjectionMethod   string                 `json:"projection_method"` // holt_winters, holt, average
+	CostBreakdown      *models.CostBreakdown  `json:"cost_breakdown"`
+	MostExpensiveModel string                 `json:"most_expensive_model"`
+	HighestCost        float64                `json:"highest_cost"`
+	Converted          *models.CostConversion `json:"converted,omitempty"`
 }
 
 // PerformanceMetrics contains performance analysis
 type PerformanceMetrics struct {
-	Summary        *models.MetricSummary `json:"summary"`
-	Status         string                `json:"status"` // excellent, good, fair, poor
-	Recommendation string                `json:"recommendation"`
+	Summary        *models.MetricSummary   `json:"summary"`
+	Status         string                  `json:"status"` // excellent, good, fair, poor
+	Recommendation string                  `json:"recommendation"`
+	Level          string                  `json:"level"` // trace, span
+	GroupBy        string                  `json:"group_by,omitempty"`
+	Granularity    string                  `json:"granularity"`
+	Buckets        []float64               `json:"buckets"`
+	Latency        []*models.LatencySeries `json:"latency"`
+}
+
+// ModelComparisonReport compares all models used in a period
+type ModelComparisonReport struct {
+	ConfidenceLevel float64                `json:"confidence_level"`
+	Models          []*ModelComparison     `json:"models"`
+	Pairwise        *PairwiseComparison    `json:"pairwise,omitempty"`
+	Converted       *models.CostConversion `json:"converted,omitempty"`
 }
 
 // ModelComparison compares different models
 type ModelComparison struct {
-	Model               string  `json:"model"`
-	TotalCalls          int64   `json:"total_calls"`
-	TotalCost           float64 `json:"total_cost"`
-	AvgCostPerRequest   float64 `json:"avg_cost_per_request"`
-	AvgLatency          float64 `json:"avg_latency"`
-	AvgTokensPerRequest float64 `json:"avg_tokens_per_request"`
-	EfficiencyScore     float64 `json:"efficiency_score"` // Lower is better
+	Model               string             `json:"model"`
+	Provider            string             `json:"provider"`
+	TotalCalls          int64              `json:"total_calls"`
+	ErrorCount          int64              `json:"error_count"`
+	TotalCost           float64            `json:"total_cost"`
+	TotalTokens         int64              `json:"total_tokens"`
+	AvgCostPerRequest   float64            `json:"avg_cost_per_request"`
+	CostPer1KTokens     float64            `json:"cost_per_1k_tokens"`
+	AvgTokensPerRequest float64            `json:"avg_tokens_per_request"`
+	TokenRatio          float64            `json:"token_ratio"` // completion / prompt tokens
+	AvgLatency          float64            `json:"avg_latency"`
+	LatencyStdDev       float64            `json:"latency_stddev"`
+	P50LatencyMs        float64            `json:"p50_latency_ms"`
+	P95LatencyMs        float64            `json:"p95_latency_ms"`
+	P99LatencyMs        float64            `json:"p99_latency_ms"`
+	ErrorRate           float64            `json:"error_rate"`
+	ErrorRateCI         ConfidenceInterval `json:"error_rate_ci"` // Wilson score interval
+
+	TotalCostConverted         *float64 `json:"total_cost_converted,omitempty"`
+	AvgCostPerRequestConverted *float64 `json:"avg_cost_per_request_converted,omitempty"`
+	CostPer1KTokensConverted   *float64 `json:"cost_per_1k_tokens_converted,omitempty"`
+}
+
+// ConfidenceInterval bounds an estimate, in the same unit as the estimate
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// PairwiseComparison tests two models against each other; a test is omitted
+// when there is not enough data to run it
+type PairwiseComparison struct {
+	ModelA    string            `json:"model_a"`
+	ModelB    string            `json:"model_b"`
+	Latency   *SignificanceTest `json:"latency,omitempty"`
+	ErrorRate *SignificanceTest `json:"error_rate,omitempty"`
+}
+
+// SignificanceTest is the outcome of a two-sample hypothesis test; Difference is A minus B
+type SignificanceTest struct {
+	Method           string  `json:"method"`
+	Difference       float64 `json:"difference"`
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom float64 `json:"degrees_of_freedom,omitempty"`
+	PValue           float64 `json:"p_value"`
+	Significant      bool    `json:"significant"`
 }
 
 // DashboardStats types for new dashboard API response
 type DashboardStats struct {
-	TotalTraces    int64         `json:"total_traces"`
-	TotalCost      float64       `json:"total_cost"`
-	TotalTokens    int64         `json:"total_tokens"`
-	AvgLatency     float64       `json:"avg_latency"`
-	ErrorRate      float64       `json:"error_rate"`
-	SuccessRate    float64       `json:"success_rate"`
-	Trends         TrendData     `json:"trends"`
-	TopModels      []ModelStats  `json:"top_models"`
-	CostByDay      []DailyCost   `json:"cost_by_day"`
-	TracesByStatus []StatusCount `json:"traces_by_status"`
+	StartTime      time.Time              `json:"start_time"`
+	EndTime        time.Time              `json:"end_time"`
+	TimeZone       string                 `json:"time_zone"`
+	TotalTraces    int64                  `json:"total_traces"`
+	TotalCost      float64                `json:"total_cost"`
+	TotalTokens    int64                  `json:"total_tokens"`
+	AvgLatency     float64                `json:"avg_latency"`
+	ErrorRate      float64                `json:"error_rate"`
+	SuccessRate    float64                `json:"success_rate"`
+	Trends         TrendData              `json:"trends"`
+	TopModels      []ModelStats           `json:"top_models"`
+	CostByDay      []DailyCost            `json:"cost_by_day"`
+	TracesByStatus []StatusCount          `json:"traces_by_status"`
+	Insights       []Insight              `json:"insights"`
+	Converted      *models.CostConversion `json:"converted,omitempty"`
 }
 
 type TrendData struct {
@@ -828,9 +790,10 @@ type TrendData struct {
 }
 
 type ModelStats struct {
-	Model string  `json:"model"`
-	Count int64   `json:"count"`
-	Cost  float64 `json:"cost"`
+	Model         string   `json:"model"`
+	Count         int64    `json:"count"`
+	Cost          float64  `json:"cost"`
+	CostConverted *float64 `json:"cost_converted,omitempty"`
 }
 
 type DailyCost struct {
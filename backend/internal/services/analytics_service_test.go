@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestGetDashboard(t *testing.T) {
+	repo := &mockRepository{
+		totals: []*models.TraceTotals{
+			{TraceCount: 50, TotalCost: 2, TotalTokens: 1000, AvgLatencyMs: 200},
+			{TraceCount: 100, ErrorCount: 5, SuccessCount: 95, TotalCost: 3, TotalTokens: 1500, AvgLatencyMs: 150},
+		},
+		breakdowns: &models.DashboardBreakdowns{
+			TopModels: []models.GroupTotal{{Key: "gpt-4", Count: 80, Cost: 2.5}},
+			CostByDay: []models.GroupTotal{{Key: "2025-03-01", Count: 40, Cost: 1}, {Key: "2025-03-02", Count: 60, Cost: 2}},
+			ByStatus:  []models.GroupTotal{{Key: "success", Count: 95}, {Key: "error", Count: 5}},
+		},
+	}
+
+	stats, err := NewAnalyticsService(repo).GetDashboard(context.Background(), "org-1", TimeRangeSpec{Range: "24h"})
+	if err != nil {
+		t.Fatalf("GetDashboard() error = %v", err)
+	}
+
+	if stats.TotalTraces != 100 || stats.TotalCost != 3 || stats.TotalTokens != 1500 || stats.AvgLatency != 150 {
+		t.Errorf("unexpected totals %+v", stats)
+	}
+	if stats.ErrorRate != 5 || stats.SuccessRate != 95 {
+		t.Errorf("expected 5%% errors and 95%% successes, got %v / %v", stats.ErrorRate, stats.SuccessRate)
+	}
+	if stats.Trends.Traces != 100 || stats.Trends.Cost != 50 || stats.Trends.Latency != -25 {
+		t.Errorf("unexpected trends %+v", stats.Trends)
+	}
+	if len(stats.TopModels) != 1 || stats.TopModels[0] != (ModelStats{Model: "gpt-4", Count: 80, Cost: 2.5}) {
+		t.Errorf("unexpected top models %+v", stats.TopModels)
+	}
+	if len(stats.CostByDay) != 2 || stats.CostByDay[1] != (DailyCost{Date: "2025-03-02", Cost: 2}) {
+		t.Errorf("unexpected cost by day %+v", stats.CostByDay)
+	}
+	if len(stats.TracesByStatus) != 2 || stats.TracesByStatus[1] != (StatusCount{Status: "error", Count: 5}) {
+		t.Errorf("unexpected status counts %+v", stats.TracesByStatus)
+	}
+}
+
+func TestGetDashboardAnomalyInsights(t *testing.T) {
+	repo := &mockRepository{anomalies: []*models.Anomaly{{
+		Metric: "cost", Direction: models.AnomalyAbove, Severity: models.AnomalySeverityHigh,
+		Explanation: "gpt-4 cost 6.1σ above baseline since 14:00 UTC",
+	}}}
+
+	stats, err := NewAnalyticsService(repo).GetDashboard(context.Background(), "org-1", TimeRangeSpec{Range: "24h"})
+	if err != nil {
+		t.Fatalf("GetDashboard() error = %v", err)
+	}
+	if len(stats.Insights) != 1 || stats.Insights[0].Category != "cost" || stats.Insights[0].Description != repo.anomalies[0].Explanation {
+		t.Errorf("expected the cost anomaly as an insight, got %+v", stats.Insights)
+	}
+
+	// The dashboard is still served when anomalies cannot be listed
+	repo.anomaliesErr = errors.New("anomalies unavailable")
+	stats, err = NewAnalyticsService(repo).GetDashboard(context.Background(), "org-1", TimeRangeSpec{Range: "24h"})
+	if err != nil || len(stats.Insights) != 0 {
+		t.Errorf("expected the dashboard without insights, got %+v, %v", stats, err)
+	}
+}
+
+func TestGetDashboardEmpty(t *testing.T) {
+	stats, err := NewAnalyticsService(&mockRepository{}).GetDashboard(context.Background(), "org-1", TimeRangeSpec{Range: "7d"})
+	if err != nil {
+		t.Fatalf("GetDashboard() error = %v", err)
+	}
+	if stats.ErrorRate != 0 || stats.SuccessRate != 0 || stats.TopModels == nil {
+		t.Errorf("expected zeroed stats with empty lists, got %+v", stats)
+	}
+
+	if _, err := NewAnalyticsService(&mockRepository{}).GetDashboard(context.Background(), "org-1", TimeRangeSpec{Range: "1y"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for unknown time range, got %v", err)
+	}
+}
+
+func TestGetCostAnalysis(t *testing.T) {
+	end := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	var daily []models.DailyCost
+	for day := end.AddDate(0, 0, -10); day.Before(end); day = day.AddDate(0, 0, 1) {
+		daily = append(daily, models.DailyCost{Date: day.Format("2006-01-02"), TotalCost: 3})
+	}
+
+	repo := &mockRepository{
+		costBreakdown: &models.CostBreakdown{
+			TotalCost:  30,
+			DailyCosts: daily,
+			ByModel:    []models.ModelCost{{Model: "gpt-4", TotalCost: 25}, {Model: "gpt-3.5-turbo", TotalCost: 5}},
+		},
+	}
+	service := NewAnalyticsService(repo)
+
+	analysis, err := service.GetCostAnalysis(context.Background(), &models.CostQuery{
+		AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -10), EndTime: end},
+	})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() error = %v", err)
+	}
+	if analysis.DailyAverage != 3 || math.Abs(analysis.MonthlyProjection-90) > 1e-9 {
+		t.Errorf("expected $3/day and $90/month, got %v / %v", analysis.DailyAverage, analysis.MonthlyProjection)
+	}
+	if analysis.ProjectionMethod != ForecastHolt {
+		t.Errorf("expected a trend projection from 10 days, got %s", analysis.ProjectionMethod)
+	}
+
+	// Growing spend projects above the flat daily average
+	for i := range daily {
+		daily[i].TotalCost = float64(i + 1)
+	}
+	analysis, err = service.GetCostAnalysis(context.Background(), &models.CostQuery{
+		AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -10), EndTime: end},
+	})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() error = %v", err)
+	}
+	if analysis.MonthlyProjection <= 55*3 {
+		t.Errorf("expected growth to project above $%v, got %v", 55*3, analysis.MonthlyProjection)
+	}
+	if analysis.MostExpensiveModel != "gpt-4" || analysis.HighestCost != 25 {
+		t.Errorf("unexpected most expensive model %q ($%v)", analysis.MostExpensiveModel, analysis.HighestCost)
+	}
+
+	invalid := []*models.CostQuery{
+		{AnalyticsQuery: models.AnalyticsQuery{StartTime: end.AddDate(0, 0, -1), EndTime: end}},
+		{AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end, EndTime: end}},
+		{AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -1), EndTime: end}, TagKeys: []string{"a", "b", "c", "d", "e", "f"}},
+		{AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -1), EndTime: end, TimeZone: "Local"}},
+		{AnalyticsQuery: models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -1), EndTime: end, TimeZone: "Mars/Olympus"}},
+	}
+	for i, query := range invalid {
+		if _, err := service.GetCostAnalysis(context.Background(), query); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("query %d: expected ErrInvalidArgument, got %v", i, err)
+		}
+	}
+}
+
+func TestGetPerformanceMetricsValidation(t *testing.T) {
+	service := NewAnalyticsService(&mockRepository{})
+	end := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+
+	newQuery := func(start time.Time) *models.LatencyQuery {
+		return &models.LatencyQuery{MetricQuery: models.MetricQuery{OrganizationID: "org-1", StartTime: start, EndTime: end}}
+	}
+
+	query := newQuery(end.AddDate(0, 0, -30))
+	metrics, err := service.GetPerformanceMetrics(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetPerformanceMetrics() error = %v", err)
+	}
+	if metrics.Level != "trace" || metrics.Granularity != "day" || len(metrics.Buckets) != len(defaultLatencyBuckets) {
+		t.Errorf("defaults not applied: %+v", metrics)
+	}
+
+	invalid := map[string]func(q *models.LatencyQuery){
+		"unknown level":              func(q *models.LatencyQuery) { q.Level = "metric" },
+		"span_name on traces":        func(q *models.LatencyQuery) { q.GroupBy = "span_name" },
+		"unknown granularity":        func(q *models.LatencyQuery) { q.Granularity = "minute" },
+		"too many hourly buckets":    func(q *models.LatencyQuery) { q.Granularity = "hour"; q.StartTime = end.AddDate(0, 0, -60) },
+		"decreasing bucket bounds":   func(q *models.LatencyQuery) { q.Buckets = []float64{500, 100} },
+		"non-positive bucket bounds": func(q *models.LatencyQuery) { q.Buckets = []float64{0, 100} },
+	}
+	for name, mutate := range invalid {
+		query := newQuery(end.AddDate(0, 0, -1))
+		mutate(query)
+		if _, err := service.GetPerformanceMetrics(context.Background(), query); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+	}
+
+	query = newQuery(end.AddDate(0, 0, -1))
+	query.Level, query.GroupBy = "span", "span_name"
+	if _, err := service.GetPerformanceMetrics(context.Background(), query); err != nil {
+		t.Errorf("span_name grouping on spans rejected: %v", err)
+	}
+}
+
+func TestGetModelComparison(t *testing.T) {
+	repo := &mockRepository{
+		modelUsage: []*models.ModelUsage{
+			{Model: "gpt-4", CallCount: 100, ErrorCount: 10, TotalCost: 6, TotalTokens: 200000, PromptTokens: 150000, CompletionTokens: 50000, AvgLatency: 20, LatencyStdDev: 10},
+			{Model: "claude-3-haiku", CallCount: 100, ErrorCount: 20, TotalCost: 0.5, TotalTokens: 100000, AvgLatency: 22, LatencyStdDev: 10},
+		},
+	}
+	service := NewAnalyticsService(repo)
+	end := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	query := &models.AnalyticsQuery{OrganizationID: "org-1", StartTime: end.AddDate(0, 0, -7), EndTime: end}
+
+	report, err := service.GetModelComparison(context.Background(), query, "gpt-4", "claude-3-haiku")
+	if err != nil {
+		t.Fatalf("GetModelComparison() error = %v", err)
+	}
+
+	gpt4 := report.Models[0]
+	if math.Abs(gpt4.CostPer1KTokens-0.03) > 1e-12 || gpt4.TokenRatio != 50000.0/150000.0 || gpt4.ErrorRate != 10 {
+		t.Errorf("unexpected per-model figures %+v", gpt4)
+	}
+	if gpt4.ErrorRateCI.Lower >= 10 || gpt4.ErrorRateCI.Upper <= 10 {
+		t.Errorf("error rate interval %+v does not contain the rate", gpt4.ErrorRateCI)
+	}
+	if report.Models[1].TokenRatio != 0 {
+		t.Errorf("expected no token ratio without prompt tokens, got %v", report.Models[1].TokenRatio)
+	}
+
+	pair := report.Pairwise
+	if pair == nil || pair.Latency == nil || pair.ErrorRate == nil {
+		t.Fatalf("expected both pairwise tests, got %+v", pair)
+	}
+	if pair.Latency.Significant || pair.Latency.Difference != -2 {
+		t.Errorf("latency difference should not be significant: %+v", pair.Latency)
+	}
+	if !pair.ErrorRate.Significant || pair.ErrorRate.Difference != -10 {
+		t.Errorf("error rate difference should be significant: %+v", pair.ErrorRate)
+	}
+
+	if _, err := service.GetModelComparison(context.Background(), query, "gpt-4", "mistral-large"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an unused model, got %v", err)
+	}
+	if _, err := service.GetModelComparison(context.Background(), query, "gpt-4", ""); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a single model, got %v", err)
+	}
+}
+
+func TestGetTimeSeries(t *testing.T) {
+	start := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	repo := &mockRepository{
+		series: []*models.TimeSeries{{
+			Group: "gpt-4",
+			Points: []models.TimeSeriesValue{
+				{Timestamp: start, Value: 4},
+				{Timestamp: start.Add(3 * time.Hour), Value: 2},
+			},
+		}},
+	}
+
+	report, err := NewAnalyticsService(repo).GetTimeSeries(context.Background(), &models.TimeSeriesQuery{
+		MetricQuery: models.MetricQuery{
+			OrganizationID: "org-1",
+			MetricName:     "cost",
+			StartTime:      start.Add(30 * time.Minute),
+			EndTime:        start.Add(5 * time.Hour),
+			Granularity:    "hour",
+		},
+		GroupBy: "model",
+	})
+	if err != nil {
+		t.Fatalf("GetTimeSeries() error = %v", err)
+	}
+
+	if report.Interval != "hour" || report.Source != "traces" {
+		t.Errorf("expected hourly buckets from traces, got %s from %s", report.Interval, report.Source)
+	}
+	points := report.Series[0].Points
+	want := []float64{4, 0, 0, 2, 0}
+	if len(points) != len(want) {
+		t.Fatalf("expected %d buckets, got %+v", len(want), points)
+	}
+	for i, point := range points {
+		if !point.Timestamp.Equal(start.Add(time.Duration(i)*time.Hour)) || point.Value != want[i] {
+			t.Errorf("bucket %d = %+v, want %v at %v", i, point, want[i], start.Add(time.Duration(i)*time.Hour))
+		}
+	}
+}
+
+func TestGetTimeSeriesSource(t *testing.T) {
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		window   time.Duration
+		interval string
+		groupBy  string
+		userID   string
+		want     string
+	}{
+		{"short range", 24 * time.Hour, "", "", "", "traces"},
+		{"long range", 30 * 24 * time.Hour, "", "model", "", "metrics_hourly"},
+		{"long range by minute", 12 * time.Hour, "minute", "", "", "traces"},
+		{"long range by user", 30 * 24 * time.Hour, "day", "user", "", "traces"},
+		{"long range for one user", 30 * 24 * time.Hour, "day", "", "user-1", "traces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			_, err := NewAnalyticsService(repo).GetTimeSeries(context.Background(), &models.TimeSeriesQuery{
+				MetricQuery: models.MetricQuery{
+					OrganizationID: "org-1",
+					StartTime:      end.Add(-tt.window),
+					EndTime:        end,
+					Granularity:    tt.interval,
+				},
+				GroupBy: tt.groupBy,
+				UserID:  tt.userID,
+			})
+			if err != nil {
+				t.Fatalf("GetTimeSeries() error = %v", err)
+			}
+			if got := repo.seriesQueries[0].Source; got != tt.want {
+				t.Errorf("source = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTimeSeriesValidation(t *testing.T) {
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query models.TimeSeriesQuery
+	}{
+		{"unknown metric", models.TimeSeriesQuery{MetricQuery: models.MetricQuery{MetricName: "latency_p42"}}},
+		{"unknown interval", models.TimeSeriesQuery{MetricQuery: models.MetricQuery{Granularity: "month"}}},
+		{"unknown group", models.TimeSeriesQuery{GroupBy: "region"}},
+		{"too many buckets", models.TimeSeriesQuery{MetricQuery: models.MetricQuery{Granularity: "minute"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.OrganizationID = "org-1"
+			query.StartTime, query.EndTime = end.Add(-30*24*time.Hour), end
+			if _, err := NewAnalyticsService(&mockRepository{}).GetTimeSeries(context.Background(), &query); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFillSeriesGapsWeeks(t *testing.T) {
+	// 2025-03-05 is a Wednesday, so the first bucket starts on Monday 2025-03-03
+	start := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	points := fillSeriesGaps([]models.TimeSeriesValue{{Timestamp: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Value: 7}}, start, start.Add(14*24*time.Hour), "week")
+
+	if len(points) != 3 {
+		t.Fatalf("expected 3 weekly buckets, got %+v", points)
+	}
+	if !points[0].Timestamp.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) || points[1].Value != 7 || points[2].Value != 0 {
+		t.Errorf("unexpected weekly buckets %+v", points)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	maxSeriesBuckets = 1000
+	maxSeriesGroups  = 50
+)
+
+// hourlyRollupThreshold is the range beyond which time series are read from metrics_hourly
+const hourlyRollupThreshold = 7 * 24 * time.Hour
+
+// seriesMetrics lists the metrics a time series can chart
+var seriesMetrics = map[string]bool{
+	"requests":    true,
+	"cost":        true,
+	"tokens":      true,
+	"latency_avg": true,
+	"latency_p50": true,
+	"latency_p90": true,
+	"latency_p95": true,
+	"latency_p99": true,
+	"error_rate":  true,
+}
+
+// seriesIntervals maps each time series interval onto its bucket width
+var seriesIntervals = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+}
+
+// GetTimeSeries returns one metric over time, optionally split by a dimension. Long ranges
+// are read from the metrics_hourly rollup and short ones from raw traces.
+func (s *AnalyticsService) GetTimeSeries(ctx context.Context, query *models.TimeSeriesQuery) (*TimeSeriesReport, error) {
+	if err := s.validateTimeSeriesQuery(query); err != nil {
+		return nil, err
+	}
+
+	series, err := s.repo.GetTimeSeries(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series: %w", err)
+	}
+
+	// An ungrouped series always has one line, even over a period without traffic
+	if query.GroupBy == "" && len(series) == 0 {
+		series = []*models.TimeSeries{{}}
+	}
+
+	for _, group := range series {
+		group.Points = fillSeriesGaps(group.Points, query.StartTime, query.EndTime, query.Granularity)
+	}
+
+	return &TimeSeriesReport{
+		Metric:    query.MetricName,
+		Interval:  query.Granularity,
+		GroupBy:   query.GroupBy,
+		Source:    query.Source,
+		StartTime: query.StartTime,
+		EndTime:   query.EndTime,
+		Series:    series,
+	}, nil
+}
+
+// validateTimeSeriesQuery checks a time series query, fills in its defaults and picks its source
+func (s *AnalyticsService) validateTimeSeriesQuery(query *models.TimeSeriesQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return invalidArgument("start_time must be before end_time")
+	}
+
+	if query.MetricName == "" {
+		query.MetricName = "requests"
+	}
+	if !seriesMetrics[query.MetricName] {
+		return invalidArgument("metric must be requests, cost, tokens, error_rate, latency_avg or latency_p50/p90/p95/p99")
+	}
+
+	switch query.GroupBy {
+	case "", "model", "provider", "project", "user", "status":
+	default:
+		return invalidArgument("group_by must be model, provider, project, user or status")
+	}
+
+	interval, err := validateSeriesInterval(query.Granularity, query.StartTime, query.EndTime)
+	if err != nil {
+		return err
+	}
+	query.Granularity = interval
+	query.Limit = seriesGroupLimit(query.Limit)
+
+	query.Source = timeSeriesSource(query)
+	return nil
+}
+
+// validateSeriesInterval checks a time series interval, defaulting to the finest one that
+// keeps the series readable
+func validateSeriesInterval(interval string, start, end time.Time) (string, error) {
+	window := end.Sub(start)
+	if interval == "" {
+		switch {
+		case window <= 6*time.Hour:
+			interval = "minute"
+		case window <= 2*24*time.Hour:
+			interval = "hour"
+		case window <= 90*24*time.Hour:
+			interval = "day"
+		default:
+			interval = "week"
+		}
+	}
+
+	width, ok := seriesIntervals[interval]
+	if !ok {
+		return "", invalidArgument("interval must be minute, hour, day or week")
+	}
+	if window/width > maxSeriesBuckets {
+		return "", invalidArgument("time range has more than %d %s buckets; use a coarser interval", maxSeriesBuckets, interval)
+	}
+	return interval, nil
+}
+
+// seriesGroupLimit clamps the number of groups charted in one time series
+func seriesGroupLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	}
+	if limit > maxSeriesGroups {
+		return maxSeriesGroups
+	}
+	return limit
+}
+
+// timeSeriesSource picks metrics_hourly for long ranges it can answer, and raw traces otherwise.
+// The rollup has no user or status dimension and cannot be bucketed below an hour.
+func timeSeriesSource(query *models.TimeSeriesQuery) string {
+	if query.EndTime.Sub(query.StartTime) <= hourlyRollupThreshold || query.Granularity == "minute" {
+		return repository.SeriesSourceTraces
+	}
+	if query.UserID != "" || query.Status != "" || query.GroupBy == "user" || query.GroupBy == "status" {
+		return repository.SeriesSourceTraces
+	}
+	return repository.SeriesSourceHourly
+}
+
+// seriesBucketStart returns the start of the UTC bucket containing t. Weeks start on Monday.
+func seriesBucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == "week" {
+		day := t.Truncate(24 * time.Hour)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return t.Truncate(seriesIntervals[interval])
+}
+
+// fillSeriesGaps returns one point per bucket between start and end, using zero for
+// buckets without data
+func fillSeriesGaps(points []models.TimeSeriesValue, start, end time.Time, interval string) []models.TimeSeriesValue {
+	values := make(map[int64]float64, len(points))
+	for _, point := range points {
+		values[seriesBucketStart(point.Timestamp, interval).Unix()] = point.Value
+	}
+
+	filled := []models.TimeSeriesValue{}
+	for bucket := seriesBucketStart(start, interval); bucket.Before(end); bucket = seriesBucketStart(bucket.Add(seriesIntervals[interval]), interval) {
+		filled = append(filled, models.TimeSeriesValue{Timestamp: bucket, Value: values[bucket.Unix()]})
+	}
+	return filled
+}
+
+// TimeSeriesReport is one metric over time for each group
+type TimeSeriesReport struct {
+	Metric    string               `json:"metric"`
+	Interval  string               `json:"interval"`
+	GroupBy   string               `json:"group_by,omitempty"`
+	Source    string               `json:"source"` // traces, metrics_hourly
+	StartTime time.Time            `json:"start_time"`
+	EndTime   time.Time            `json:"end_time"`
+	Series    []*models.TimeSeries `json:"series"`
+	Currency  string               `json:"currency,omitempty"` // of converted cost values
+}
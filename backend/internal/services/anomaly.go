@@ -0,0 +1,274 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// Detection window and baseline of the anomaly detector
+const (
+	anomalyBaselineHours = 7 * 24 // history the baseline is estimated from
+	anomalyWindowHours   = 24     // most recent complete hours scanned for anomalies
+	anomalyMinHistory    = 48     // hours with traffic needed before a series is scored
+	anomalySeasonalDays  = 3      // values per hour of day needed for a seasonal baseline
+	anomalyMinRequests   = 20     // requests an hour needs before its latency or error rate is scored
+	anomalyRelativeScale = 0.1    // smallest standard deviation as a fraction of the expected value
+)
+
+// madScale turns a median absolute deviation into a normal standard deviation
+const madScale = 1.4826
+
+// anomalySeverities maps robust z-score thresholds onto severities, most severe first
+var anomalySeverities = []struct {
+	score    float64
+	severity string
+}{
+	{8, models.AnomalySeverityHigh},
+	{5, models.AnomalySeverityMedium},
+	{3.5, models.AnomalySeverityLow},
+}
+
+// anomalyMetric describes how one metric is read from hourly stats and scored
+type anomalyMetric struct {
+	name     string
+	label    string
+	format   string
+	value    func(*models.HourlyModelStats) float64
+	rate     bool    // a per-request rate; hours with few requests have no value
+	below    bool    // drops are anomalous as well as spikes
+	minScale float64 // smallest deviation treated as one standard deviation
+}
+
+// anomalyMetrics are the metrics scanned for anomalies
+var anomalyMetrics = []anomalyMetric{
+	{
+		name: "cost", label: "cost", format: "$%.2f/h",
+		value: func(s *models.HourlyModelStats) float64 { return s.CostUSD },
+		below: true, minScale: 0.01,
+	},
+	{
+		name: "tokens", label: "token usage", format: "%.0f tokens/h",
+		value: func(s *models.HourlyModelStats) float64 { return float64(s.Tokens) },
+		below: true, minScale: 100,
+	},
+	{
+		name: "latency_p95", label: "p95 latency", format: "%.0fms",
+		value: func(s *models.HourlyModelStats) float64 { return s.P95LatencyMs },
+		rate:  true, minScale: 50,
+	},
+	{
+		name: "error_rate", label: "error rate", format: "%.1f%%",
+		value: func(s *models.HourlyModelStats) float64 { return float64(s.Errors) * 100.0 / float64(s.Requests) },
+		rate:  true, minScale: 1,
+	},
+}
+
+// hourlyPoint is one hour of a metric; ok is false for hours without a value
+type hourlyPoint struct {
+	hour  time.Time
+	value float64
+	ok    bool
+}
+
+// hourlyPoints lays one metric of a series out on an hourly grid over [start, end).
+// Missing hours count as zero for totals and have no value for rates.
+func hourlyPoints(stats []*models.HourlyModelStats, metric anomalyMetric, start, end time.Time) []hourlyPoint {
+	byHour := make(map[time.Time]*models.HourlyModelStats, len(stats))
+	for _, s := range stats {
+		byHour[s.Hour.UTC()] = s
+	}
+
+	points := make([]hourlyPoint, 0, int(end.Sub(start)/time.Hour))
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		point := hourlyPoint{hour: hour, ok: !metric.rate}
+		if s, found := byHour[hour]; found && (!metric.rate || s.Requests >= anomalyMinRequests) {
+			point.value = metric.value(s)
+			point.ok = true
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// anomalyBaseline is the expected value of a metric by UTC hour of day, with a robust
+// estimate of how far hours normally stray from it
+type anomalyBaseline struct {
+	profile [24]float64 // median per hour of day, or the overall median without seasonality
+	center  float64     // median residual
+	scale   float64     // robust standard deviation of the residuals
+}
+
+// fitAnomalyBaseline estimates a baseline from history. A daily seasonal profile is used
+// once every hour of the day has enough values. It returns nil for too short a history.
+func fitAnomalyBaseline(history []hourlyPoint, traffic int) *anomalyBaseline {
+	if traffic < anomalyMinHistory {
+		return nil
+	}
+
+	var values []float64
+	var byHour [24][]float64
+	for _, p := range history {
+		if p.ok {
+			values = append(values, p.value)
+			byHour[p.hour.Hour()] = append(byHour[p.hour.Hour()], p.value)
+		}
+	}
+	if len(values) < anomalyMinHistory {
+		return nil
+	}
+
+	seasonal := true
+	for _, hourValues := range byHour {
+		if len(hourValues) < anomalySeasonalDays {
+			seasonal = false
+			break
+		}
+	}
+
+	b := &anomalyBaseline{}
+	overall := median(values)
+	for h := range b.profile {
+		b.profile[h] = overall
+		if seasonal {
+			b.profile[h] = median(byHour[h])
+		}
+	}
+
+	residuals := make([]float64, 0, len(values))
+	for _, p := range history {
+		if p.ok {
+			residuals = append(residuals, p.value-b.profile[p.hour.Hour()])
+		}
+	}
+	b.center = median(residuals)
+
+	deviations := make([]float64, len(residuals))
+	var sumDeviation float64
+	for i, r := range residuals {
+		deviations[i] = math.Abs(r - b.center)
+		sumDeviation += deviations[i]
+	}
+	b.scale = madScale * median(deviations)
+	if b.scale == 0 {
+		// More than half the hours sit on the baseline; fall back to the mean absolute deviation
+		b.scale = math.Sqrt(math.Pi/2) * sumDeviation / float64(len(deviations))
+	}
+
+	return b
+}
+
+// score returns the expected value of an hour and the robust z-score of its value
+func (b *anomalyBaseline) score(p hourlyPoint, metric anomalyMetric) (float64, float64) {
+	expected := b.profile[p.hour.Hour()] + b.center
+	scale := math.Max(b.scale, math.Max(metric.minScale, anomalyRelativeScale*math.Abs(expected)))
+	return expected, (p.value - expected) / scale
+}
+
+// anomalySeverity returns the severity of a z-score, or "" below the lowest threshold
+func anomalySeverity(score float64) string {
+	for _, threshold := range anomalySeverities {
+		if math.Abs(score) >= threshold.score {
+			return threshold.severity
+		}
+	}
+	return ""
+}
+
+// detectSeriesAnomalies scores the hours of [windowStart, windowEnd) against a baseline fitted to
+// the hours before and merges consecutive anomalous hours into one anomaly per run
+func detectSeriesAnomalies(points []hourlyPoint, traffic int, metric anomalyMetric, windowStart, windowEnd time.Time) []*models.Anomaly {
+	split := sort.Search(len(points), func(i int) bool { return !points[i].hour.Before(windowStart) })
+	baseline := fitAnomalyBaseline(points[:split], traffic)
+	if baseline == nil {
+		return nil
+	}
+
+	anomalies := []*models.Anomaly{}
+	var current *models.Anomaly
+	for _, p := range points[split:] {
+		if !p.ok {
+			current = nil
+			continue
+		}
+
+		expected, z := baseline.score(p, metric)
+		direction := models.AnomalyAbove
+		if z < 0 {
+			direction = models.AnomalyBelow
+		}
+		if anomalySeverity(z) == "" || (direction == models.AnomalyBelow && !metric.below) {
+			current = nil
+			continue
+		}
+
+		if current == nil || current.Direction != direction {
+			current = &models.Anomaly{
+				Metric:    metric.name,
+				Direction: direction,
+				StartedAt: p.hour,
+			}
+			anomalies = append(anomalies, current)
+		}
+		current.LastSeenAt = p.hour.Add(time.Hour)
+		current.Ongoing = !current.LastSeenAt.Before(windowEnd)
+		if math.Abs(z) > current.Score {
+			current.Score = math.Abs(z)
+			current.Value = p.value
+			current.Baseline = expected
+		}
+	}
+
+	for _, a := range anomalies {
+		a.Severity = anomalySeverity(a.Score)
+	}
+	return anomalies
+}
+
+// anomalyID derives a stable ID from what identifies a run, so re-detecting it updates the record
+func anomalyID(a *models.Anomaly) string {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s|%d", a.OrganizationID, a.ProjectID, a.Model, a.Metric, a.Direction, a.StartedAt.Unix())
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// explainAnomaly describes an anomaly, e.g. "gpt-4 cost 4.2σ above baseline since 14:00 UTC"
+func explainAnomaly(a *models.Anomaly, metric anomalyMetric) string {
+	subject := a.Model
+	if subject == "" {
+		subject = "project " + a.ProjectID
+	}
+
+	layout := "15:04 MST"
+	if a.StartedAt.YearDay() != a.LastSeenAt.Add(-time.Hour).YearDay() {
+		layout = "Jan 2 15:04 MST"
+	}
+
+	var when string
+	if a.Ongoing {
+		when = "since " + a.StartedAt.Format(layout)
+	} else {
+		when = fmt.Sprintf("from %s to %s", a.StartedAt.Format(layout), a.LastSeenAt.Format(layout))
+	}
+
+	return fmt.Sprintf("%s %s %.1fσ %s baseline %s (%s vs %s expected)",
+		subject, metric.label, a.Score, a.Direction, when,
+		fmt.Sprintf(metric.format, a.Value), fmt.Sprintf(metric.format, math.Max(0, a.Baseline)))
+}
+
+// median returns the median of a non-empty series without reordering it
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	defaultAnomalyLimit = 100
+	maxAnomalyLimit     = 1000
+	maxAnomalyInsights  = 5
+)
+
+// anomalyCategories maps each anomaly metric onto its insight category
+var anomalyCategories = map[string]string{
+	"cost":        "cost",
+	"tokens":      "efficiency",
+	"latency_p95": "performance",
+	"error_rate":  "reliability",
+}
+
+// AnomalyService detects anomalies in the hourly cost, token, latency and error series of
+// each project and model, and serves the anomalies found
+type AnomalyService struct {
+	repo repository.Repository
+}
+
+// NewAnomalyService creates a new anomaly service
+func NewAnomalyService(repo repository.Repository) *AnomalyService {
+	return &AnomalyService{
+		repo: repo,
+	}
+}
+
+// Start runs detection for every active organization now and then once per interval
+// until the context is cancelled
+func (s *AnomalyService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.detectAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// detectAll runs detection for each organization with traffic in the detection window.
+// A failing organization is logged and does not stop the others.
+func (s *AnomalyService) detectAll(ctx context.Context) {
+	since := time.Now().Add(-anomalyWindowHours * time.Hour)
+	orgs, err := s.repo.GetActiveOrganizations(ctx, since)
+	if err != nil {
+		log.Printf("❌ Anomaly detection failed to list organizations: %v", err)
+		return
+	}
+
+	for _, orgID := range orgs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.DetectAnomalies(ctx, orgID); err != nil {
+			log.Printf("❌ Anomaly detection failed for organization %s: %v", orgID, err)
+		}
+	}
+}
+
+// DetectAnomalies scans the last day of complete hours of an organization and stores the
+// anomalies found. Each project and model is compared against its own previous week.
+func (s *AnomalyService) DetectAnomalies(ctx context.Context, orgID string) ([]*models.Anomaly, error) {
+	return s.detectAnomalies(ctx, orgID, time.Now())
+}
+
+// detectAnomalies scans the complete hours before now
+func (s *AnomalyService) detectAnomalies(ctx context.Context, orgID string, now time.Time) ([]*models.Anomaly, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	windowEnd := now.UTC().Truncate(time.Hour)
+	windowStart := windowEnd.Add(-anomalyWindowHours * time.Hour)
+	start := windowStart.Add(-anomalyBaselineHours * time.Hour)
+
+	stats, err := s.repo.GetHourlyModelStats(ctx, orgID, start, windowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly stats: %w", err)
+	}
+
+	// Split the stats into one series per project and model, keeping their order
+	type seriesKey struct{ project, model string }
+	var keys []seriesKey
+	series := make(map[seriesKey][]*models.HourlyModelStats)
+	for _, stat := range stats {
+		key := seriesKey{stat.ProjectID, stat.Model}
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], stat)
+	}
+
+	anomalies := []*models.Anomaly{}
+	for _, key := range keys {
+		var traffic int
+		for _, stat := range series[key] {
+			if stat.Hour.Before(windowStart) {
+				traffic++
+			}
+		}
+
+		for _, metric := range anomalyMetrics {
+			points := hourlyPoints(series[key], metric, start, windowEnd)
+			for _, a := range detectSeriesAnomalies(points, traffic, metric, windowStart, windowEnd) {
+				a.OrganizationID = orgID
+				a.ProjectID = key.project
+				a.Model = key.model
+				a.DetectedAt = now
+				anomalies = append(anomalies, a)
+			}
+		}
+	}
+
+	// Runs reaching back to the window start may have begun before it; they continue the
+	// stored anomaly rather than starting a new one each hour
+	recent, err := s.repo.ListRecentAnomalies(ctx, orgID, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent anomalies: %w", err)
+	}
+	closed := continueAnomalies(recent, anomalies, now)
+	for _, a := range anomalies {
+		if a.ID == "" {
+			a.ID = anomalyID(a)
+		}
+		a.Explanation = explainAnomaly(a, anomalyMetricByName(a.Metric))
+	}
+
+	if err := s.repo.SaveAnomalies(ctx, append(closed, anomalies...)); err != nil {
+		return nil, fmt.Errorf("failed to save anomalies: %w", err)
+	}
+	return anomalies, nil
+}
+
+// continueAnomalies carries the start and ID of stored anomalies over to the detected runs
+// that overlap them, and returns closed versions of the stored ongoing anomalies that no
+// detected run continues
+func continueAnomalies(stored, detected []*models.Anomaly, now time.Time) []*models.Anomaly {
+	type runKey struct{ project, model, metric, direction string }
+	claimed := make(map[*models.Anomaly]bool)
+
+	closed := []*models.Anomaly{}
+	for _, prev := range stored {
+		key := runKey{prev.ProjectID, prev.Model, prev.Metric, prev.Direction}
+
+		var next *models.Anomaly
+		for _, a := range detected {
+			if !claimed[a] && (runKey{a.ProjectID, a.Model, a.Metric, a.Direction}) == key &&
+				!a.StartedAt.After(prev.LastSeenAt) && !a.LastSeenAt.Before(prev.StartedAt) {
+				next = a
+				break
+			}
+		}
+
+		if next == nil {
+			if prev.Ongoing {
+				// The run fell out of the window or stopped while detection was not running
+				end := *prev
+				end.Ongoing = false
+				end.DetectedAt = now
+				end.Explanation = explainAnomaly(&end, anomalyMetricByName(end.Metric))
+				closed = append(closed, &end)
+			}
+			continue
+		}
+
+		claimed[next] = true
+		next.ID = prev.ID
+		if prev.StartedAt.Before(next.StartedAt) {
+			next.StartedAt = prev.StartedAt
+		}
+		if prev.Score > next.Score {
+			next.Score = prev.Score
+			next.Value = prev.Value
+			next.Baseline = prev.Baseline
+			next.Severity = prev.Severity
+		}
+	}
+	return closed
+}
+
+// ListAnomalies returns the stored anomalies overlapping the query window, newest first
+func (s *AnomalyService) ListAnomalies(ctx context.Context, query *models.AnomalyQuery) ([]*models.Anomaly, error) {
+	if err := validateAnomalyQuery(query); err != nil {
+		return nil, err
+	}
+
+	anomalies, err := s.repo.ListAnomalies(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomalies: %w", err)
+	}
+	return anomalies, nil
+}
+
+// validateAnomalyQuery checks an anomaly query and fills in its defaults
+func validateAnomalyQuery(query *models.AnomalyQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return invalidArgument("start_time must be before end_time")
+	}
+
+	if query.Metric != "" {
+		known := false
+		for _, metric := range anomalyMetrics {
+			known = known || metric.name == query.Metric
+		}
+		if !known {
+			return invalidArgument("unsupported metric %q", query.Metric)
+		}
+	}
+
+	for _, severity := range query.Severities {
+		switch severity {
+		case models.AnomalySeverityLow, models.AnomalySeverityMedium, models.AnomalySeverityHigh:
+		default:
+			return invalidArgument("severity must be low, medium or high")
+		}
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultAnomalyLimit
+	}
+	if query.Limit > maxAnomalyLimit {
+		query.Limit = maxAnomalyLimit
+	}
+	return nil
+}
+
+// anomalyInsights turns detected anomalies into dashboard insights
+func anomalyInsights(anomalies []*models.Anomaly) []Insight {
+	insights := []Insight{}
+	for _, a := range anomalies {
+		insightType := "warning"
+		if a.Direction == models.AnomalyBelow {
+			insightType = "info"
+		}
+
+		insights = append(insights, Insight{
+			Type:        insightType,
+			Category:    anomalyCategories[a.Metric],
+			Title:       fmt.Sprintf("Unusual %s", anomalyLabel(a.Metric)),
+			Description: a.Explanation,
+			Severity:    a.Severity,
+		})
+	}
+	return insights
+}
+
+// anomalyMetricByName returns the anomaly metric with the given name
+func anomalyMetricByName(name string) anomalyMetric {
+	for _, metric := range anomalyMetrics {
+		if metric.name == name {
+			return metric
+		}
+	}
+	return anomalyMetric{name: name, label: name, format: "%.2f"}
+}
+
+// anomalyLabel returns the display label of an anomaly metric
+func anomalyLabel(name string) string {
+	return anomalyMetricByName(name).label
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// anomalyTestStats builds eight days of hourly gpt-4 traffic with a daily cycle and a little noise,
+// letting spike rewrite individual hours
+func anomalyTestStats(end time.Time, spike func(hoursAgo int, s *models.HourlyModelStats)) []*models.HourlyModelStats {
+	var stats []*models.HourlyModelStats
+	for i := 8 * 24; i >= 1; i-- {
+		hour := end.Add(-time.Duration(i) * time.Hour)
+		cycle := 1 + 0.5*math.Sin(float64(hour.Hour())*math.Pi/12)
+		noise := float64((i*7919)%13) / 13 * 0.1
+
+		s := &models.HourlyModelStats{
+			ProjectID:    "proj-1",
+			Model:        "gpt-4",
+			Hour:         hour,
+			Requests:     100,
+			Errors:       1,
+			CostUSD:      2 * (cycle + noise),
+			Tokens:       int64(20000 * (cycle + noise)),
+			P95LatencyMs: 800 + 100*noise,
+		}
+		if spike != nil {
+			spike(i, s)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	now := time.Date(2025, 3, 15, 17, 20, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)
+
+	// Cost and tokens run at ten times the usual rate for the last three hours
+	repo := &mockRepository{hourlyStats: anomalyTestStats(end, func(hoursAgo int, s *models.HourlyModelStats) {
+		if hoursAgo <= 3 {
+			s.CostUSD *= 10
+			s.Tokens *= 10
+		}
+	})}
+
+	anomalies, err := NewAnomalyService(repo).detectAnomalies(context.Background(), "org-1", now)
+	if err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+
+	if len(anomalies) != 2 || len(repo.anomalies) != 2 {
+		t.Fatalf("expected cost and token anomalies to be found and saved, got %+v", anomalies)
+	}
+
+	cost := anomalies[0]
+	if cost.Metric != "cost" || cost.Direction != models.AnomalyAbove || cost.Severity != models.AnomalySeverityHigh {
+		t.Errorf("unexpected cost anomaly %+v", cost)
+	}
+	if !cost.StartedAt.Equal(end.Add(-3*time.Hour)) || !cost.LastSeenAt.Equal(end) || !cost.Ongoing {
+		t.Errorf("expected an ongoing run since 14:00, got %v to %v", cost.StartedAt, cost.LastSeenAt)
+	}
+	if cost.OrganizationID != "org-1" || cost.ProjectID != "proj-1" || cost.Model != "gpt-4" || cost.ID == "" {
+		t.Errorf("anomaly not attributed to its series: %+v", cost)
+	}
+	if !strings.HasPrefix(cost.Explanation, "gpt-4 cost ") || !strings.Contains(cost.Explanation, "above baseline since 14:00 UTC") {
+		t.Errorf("unexpected explanation %q", cost.Explanation)
+	}
+	if cost.Value < 5*cost.Baseline {
+		t.Errorf("expected the peak far above the baseline, got %v vs %v", cost.Value, cost.Baseline)
+	}
+
+	// Detecting the same run again must update the record rather than add one
+	again, err := NewAnomalyService(repo).detectAnomalies(context.Background(), "org-1", now.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+	if len(again) != 2 || again[0].ID != cost.ID {
+		t.Errorf("expected a stable anomaly ID, got %+v", again)
+	}
+}
+
+func TestDetectAnomaliesLongRun(t *testing.T) {
+	end := time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)
+	service := NewAnomalyService(&mockRepository{})
+	repo := service.repo.(*mockRepository)
+
+	// Cost runs at ten times the usual rate from 14:00 on the first day
+	spikeSince := func(start time.Time) func(int, *models.HourlyModelStats) {
+		return func(hoursAgo int, s *models.HourlyModelStats) {
+			if !s.Hour.Before(start) {
+				s.CostUSD *= 10
+			}
+		}
+	}
+	start := end.Add(-3 * time.Hour)
+
+	var first *models.Anomaly
+	for hours := 0; hours <= 30; hours++ {
+		now := end.Add(time.Duration(hours) * time.Hour)
+		repo.hourlyStats = anomalyTestStats(now, spikeSince(start))
+		anomalies, err := service.detectAnomalies(context.Background(), "org-1", now)
+		if err != nil {
+			t.Fatalf("detectAnomalies() error = %v", err)
+		}
+		if len(anomalies) != 1 {
+			t.Fatalf("hour %d: expected one cost anomaly, got %+v", hours, anomalies)
+		}
+		if first == nil {
+			first = anomalies[0]
+		}
+		if a := anomalies[0]; a.ID != first.ID || !a.StartedAt.Equal(start) || !a.Ongoing {
+			t.Fatalf("hour %d: expected the run since %v to be extended, got %+v", hours, start, a)
+		}
+	}
+
+	// Once the run stops, the stored anomaly is closed
+	now := end.Add(31 * time.Hour)
+	repo.hourlyStats = anomalyTestStats(now, nil)
+	if _, err := service.detectAnomalies(context.Background(), "org-1", now); err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+	ongoing, _ := repo.ListRecentAnomalies(context.Background(), "org-1", now)
+	if len(ongoing) != 0 {
+		t.Errorf("expected no anomaly left ongoing, got %+v", ongoing)
+	}
+	last := repo.anomalies[len(repo.anomalies)-1]
+	if last.ID != first.ID || last.Ongoing || !last.StartedAt.Equal(start) || !strings.Contains(last.Explanation, "from Mar 15 14:00 UTC") {
+		t.Errorf("expected the run to be closed, got %+v", last)
+	}
+}
+
+func TestDetectAnomaliesQuietSeries(t *testing.T) {
+	end := time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)
+	repo := &mockRepository{hourlyStats: anomalyTestStats(end, nil)}
+
+	anomalies, err := NewAnomalyService(repo).detectAnomalies(context.Background(), "org-1", end)
+	if err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies in normal traffic, got %+v", anomalies)
+	}
+}
+
+func TestDetectAnomaliesRates(t *testing.T) {
+	end := time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)
+
+	// Errors jump in a busy hour and in a quiet one; only the busy hour has enough requests
+	repo := &mockRepository{hourlyStats: anomalyTestStats(end, func(hoursAgo int, s *models.HourlyModelStats) {
+		switch hoursAgo {
+		case 10:
+			s.Errors = 40
+		case 5:
+			s.Requests, s.Errors = 5, 4
+		}
+	})}
+
+	anomalies, err := NewAnomalyService(repo).detectAnomalies(context.Background(), "org-1", end)
+	if err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+
+	var found []*models.Anomaly
+	for _, a := range anomalies {
+		if a.Metric == "error_rate" {
+			found = append(found, a)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one error rate anomaly, got %+v", found)
+	}
+	if a := found[0]; !a.StartedAt.Equal(end.Add(-10*time.Hour)) || a.Ongoing || !strings.Contains(a.Explanation, "from 07:00 UTC to 08:00 UTC") {
+		t.Errorf("unexpected error rate anomaly %+v", a)
+	}
+}
+
+func TestDetectAnomaliesShortHistory(t *testing.T) {
+	end := time.Date(2025, 3, 15, 17, 0, 0, 0, time.UTC)
+
+	// A model first seen a day and a half ago has no baseline yet
+	stats := anomalyTestStats(end, nil)
+	repo := &mockRepository{hourlyStats: stats[len(stats)-36:]}
+	repo.hourlyStats[len(repo.hourlyStats)-1].CostUSD *= 100
+
+	anomalies, err := NewAnomalyService(repo).detectAnomalies(context.Background(), "org-1", end)
+	if err != nil {
+		t.Fatalf("detectAnomalies() error = %v", err)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies without a baseline, got %+v", anomalies)
+	}
+}
+
+func TestListAnomaliesValidation(t *testing.T) {
+	now := time.Now()
+	service := NewAnomalyService(&mockRepository{})
+
+	tests := []struct {
+		name  string
+		query models.AnomalyQuery
+	}{
+		{"missing organization", models.AnomalyQuery{StartTime: now.Add(-time.Hour), EndTime: now}},
+		{"empty window", models.AnomalyQuery{OrganizationID: "org-1", StartTime: now, EndTime: now}},
+		{"unknown metric", models.AnomalyQuery{OrganizationID: "org-1", Metric: "latency_p50", StartTime: now.Add(-time.Hour), EndTime: now}},
+		{"unknown severity", models.AnomalyQuery{OrganizationID: "org-1", Severities: []string{"critical"}, StartTime: now.Add(-time.Hour), EndTime: now}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ListAnomalies(context.Background(), &tt.query); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAnomalyInsights(t *testing.T) {
+	insights := anomalyInsights([]*models.Anomaly{{
+		Metric:      "latency_p95",
+		Direction:   models.AnomalyAbove,
+		Severity:    models.AnomalySeverityMedium,
+		Explanation: "gpt-4 p95 latency 5.1σ above baseline since 14:00 UTC",
+	}})
+
+	if len(insights) != 1 {
+		t.Fatalf("expected one insight, got %d", len(insights))
+	}
+	if i := insights[0]; i.Category != "performance" || i.Title != "Unusual p95 latency" || i.Severity != "medium" || i.Type != "warning" {
+		t.Errorf("unexpected insight %+v", i)
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// JobTypeCostBackfill identifies cost recomputation jobs in the job manager
+const JobTypeCostBackfill = "cost_backfill"
+
+// backfillPageSize is the number of traces repriced and written per checkpoint
+const backfillPageSize = 500
+
+// CostBackfillService recomputes stored span and trace costs with the current prices,
+// e.g. after a price correction. Backfills run as background jobs that checkpoint after
+// every page of traces, so a cancelled, failed or interrupted backfill can be resumed.
+type CostBackfillService struct {
+	repo    repository.Repository
+	jobs    *JobManager
+	pricing *PricingService
+
+	mu      sync.Mutex
+	running map[string]string // backfill ID -> job ID of its current run
+}
+
+// NewCostBackfillService creates a new cost backfill service
+func NewCostBackfillService(repo repository.Repository, jobs *JobManager, pricing *PricingService) *CostBackfillService {
+	return &CostBackfillService{
+		repo:    repo,
+		jobs:    jobs,
+		pricing: pricing,
+		running: make(map[string]string),
+	}
+}
+
+// StartBackfill records a backfill of an organization's traces and runs it in the background.
+// The range ends at the start of the current UTC day at the latest, and by default: the
+// aggregates of the open day are still being written and cannot be rebuilt.
+func (s *CostBackfillService) StartBackfill(ctx context.Context, orgID string, req *models.CostBackfillRequest, requestedBy string) (*models.CostBackfill, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if req.StartTime.IsZero() {
+		return nil, invalidArgument("start_time is required")
+	}
+
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
+	end := req.EndTime
+	if end.IsZero() {
+		end = today
+	}
+	if end.After(today) {
+		return nil, invalidArgument("end_time must not be after the start of the current day (%s)", today.Format(time.RFC3339))
+	}
+	if !req.StartTime.Before(end) {
+		return nil, invalidArgument("start_time must be before end_time")
+	}
+
+	backfill := &models.CostBackfill{
+		ID:             uuid.New().String(),
+		OrganizationID: orgID,
+		Model:          strings.TrimSpace(req.Model),
+		StartTime:      req.StartTime,
+		EndTime:        end,
+		RequestedBy:    requestedBy,
+		Status:         JobPending,
+		RequestedAt:    now,
+	}
+	if err := s.repo.SaveCostBackfill(ctx, backfill); err != nil {
+		return nil, err
+	}
+
+	snapshot, _ := s.run(backfill)
+	return snapshot, nil
+}
+
+// ResumeBackfill restarts a cancelled, failed or interrupted backfill from its cursor
+func (s *CostBackfillService) ResumeBackfill(ctx context.Context, orgID, id string) (*models.CostBackfill, error) {
+	backfill, err := s.repo.GetCostBackfill(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if backfill.Status == JobCompleted {
+		return nil, invalidArgument("cost backfill %s has already completed", id)
+	}
+
+	backfill.Status = JobPending
+	backfill.Error = ""
+	backfill.CompletedAt = nil
+	snapshot, ok := s.run(backfill)
+	if !ok {
+		return nil, invalidArgument("cost backfill %s is already running", id)
+	}
+	return snapshot, nil
+}
+
+// ResumeUnfinished restarts the backfills a previous process left pending or running.
+// It is meant to be called once at startup.
+func (s *CostBackfillService) ResumeUnfinished(ctx context.Context) error {
+	backfills, err := s.repo.ListUnfinishedCostBackfills(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, backfill := range backfills {
+		processed, total := backfill.TracesProcessed, backfill.TracesTotal
+		if _, ok := s.run(backfill); ok {
+			log.Printf("🔁 Resuming cost backfill %s at %d/%d traces", backfill.ID, processed, total)
+		}
+	}
+	return nil
+}
+
+// CancelBackfill stops a running backfill after the page it is writing; it can be resumed later
+func (s *CostBackfillService) CancelBackfill(ctx context.Context, orgID, id string) (*models.CostBackfill, error) {
+	backfill, err := s.repo.GetCostBackfill(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	jobID := s.jobID(id)
+	if jobID == "" {
+		return nil, invalidArgument("cost backfill %s is not running", id)
+	}
+	if err := s.jobs.Cancel(orgID, jobID); err != nil {
+		return nil, err
+	}
+
+	backfill.JobID = jobID
+	return backfill, nil
+}
+
+// GetBackfill returns a backfill with its progress as of its last checkpoint
+func (s *CostBackfillService) GetBackfill(ctx context.Context, orgID, id string) (*models.CostBackfill, error) {
+	backfill, err := s.repo.GetCostBackfill(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	backfill.JobID = s.jobID(id)
+	return backfill, nil
+}
+
+// ListBackfills returns an organization's backfills, newest first
+func (s *CostBackfillService) ListBackfills(ctx context.Context, orgID string, limit int) ([]*models.CostBackfill, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	backfills, err := s.repo.ListCostBackfills(ctx, orgID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, backfill := range backfills {
+		backfill.JobID = s.jobID(backfill.ID)
+	}
+	return backfills, nil
+}
+
+// run starts a job for a backfill unless one is already running it, reporting whether it
+// did; the job owns backfill from here on and callers get a copy
+func (s *CostBackfillService) run(backfill *models.CostBackfill) (*models.CostBackfill, bool) {
+	params := map[string]interface{}{"backfill_id": backfill.ID}
+
+	// Checking and registering under one lock keeps two resumes from both starting a job,
+	// and a job from being unregistered before it is registered
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[backfill.ID] != "" {
+		return nil, false
+	}
+
+	job := s.jobs.Start(backfill.OrganizationID, JobTypeCostBackfill, params, func(ctx context.Context, progress JobProgress) error {
+		defer s.unregister(backfill.ID)
+		return s.backfill(ctx, backfill, progress)
+	})
+	s.running[backfill.ID] = job.ID
+
+	snapshot := *backfill
+	snapshot.JobID = job.ID
+	return &snapshot, true
+}
+
+// jobID returns the job running a backfill, if any
+func (s *CostBackfillService) jobID(backfillID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[backfillID]
+}
+
+func (s *CostBackfillService) unregister(backfillID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, backfillID)
+}
+
+// backfill reprices the backfill's traces page by page from its cursor, saving the cursor
+// and totals after each page is written
+func (s *CostBackfillService) backfill(ctx context.Context, backfill *models.CostBackfill, progress JobProgress) error {
+	backfill.Status = JobRunning
+	backfill.Error = ""
+
+	total, err := s.repo.CountBackfillTraces(ctx, backfill)
+	if err != nil {
+		return s.stop(ctx, backfill, err)
+	}
+	backfill.TracesTotal = total
+	progress.SetTotal(total)
+	progress.Add(backfill.TracesProcessed, 0)
+
+	if err := s.repo.SaveCostBackfill(ctx, backfill); err != nil {
+		return s.stop(ctx, backfill, err)
+	}
+
+	for ctx.Err() == nil {
+		traces, err := s.repo.GetBackfillTraces(ctx, backfill, backfillPageSize)
+		if err != nil {
+			return s.stop(ctx, backfill, err)
+		}
+		if len(traces) == 0 {
+			break
+		}
+
+		page := s.reprice(backfill.Model, traces)
+		if err := s.repo.UpdateTraceCosts(ctx, backfill.OrganizationID, page.changed, page.unpriced); err != nil {
+			return s.stop(ctx, backfill, err)
+		}
+
+		last := traces[len(traces)-1]
+		backfill.CursorTimestamp = last.Timestamp
+		backfill.CursorTraceID = last.TraceID
+		backfill.TracesProcessed += int64(len(traces))
+		backfill.TracesUpdated += int64(len(page.changed))
+		backfill.SpansRepriced += page.spans
+		backfill.CostBefore += page.costBefore
+		backfill.CostAfter += page.costAfter
+
+		if err := s.repo.SaveCostBackfill(ctx, backfill); err != nil {
+			return s.stop(ctx, backfill, err)
+		}
+
+		progress.Add(int64(len(traces)), 0)
+		progress.SetResult("traces_updated", backfill.TracesUpdated)
+		progress.SetResult("cost_before", backfill.CostBefore)
+		progress.SetResult("cost_after", backfill.CostAfter)
+
+		if len(traces) < backfillPageSize {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return s.stop(ctx, backfill, ctx.Err())
+	}
+
+	now := time.Now()
+	backfill.Status = JobCompleted
+	backfill.CompletedAt = &now
+
+	// Every page is already written, so a failed status write must not fail the backfill
+	if err := s.repo.SaveCostBackfill(context.Background(), backfill); err != nil {
+		log.Printf("❌ Failed to record completion of cost backfill %s: %v", backfill.ID, err)
+	}
+	return nil
+}
+
+// stop records a backfill as cancelled, or failed with the cause, and returns the cause.
+// The cursor is left at the last page written so the backfill can be resumed.
+func (s *CostBackfillService) stop(ctx context.Context, backfill *models.CostBackfill, cause error) error {
+	now := time.Now()
+	backfill.CompletedAt = &now
+	if ctx.Err() != nil {
+		backfill.Status = JobCancelled
+	} else {
+		backfill.Status = JobFailed
+		backfill.Error = cause.Error()
+	}
+
+	if err := s.repo.SaveCostBackfill(context.Background(), backfill); err != nil {
+		log.Printf("❌ Failed to record %s cost backfill %s: %v", backfill.Status, backfill.ID, err)
+	}
+	return fmt.Errorf("cost backfill %s %s: %w", backfill.ID, backfill.Status, cause)
+}
+
+// backfillPage is the outcome of repricing a page of traces
+type backfillPage struct {
+	changed    []*models.Trace
+	unpriced   []*models.Metric
+	spans      int64
+	costBefore float64
+	costAfter  float64
+}
+
+// reprice prices the spans of each trace again and recomputes the trace totals. Spans
+// whose cost the client supplied, and spans without usage, keep their cost; with a model,
+// only spans of that model are repriced.
+func (s *CostBackfillService) reprice(model string, traces []*models.Trace) *backfillPage {
+	page := &backfillPage{}
+	model = normalizeModelName(model)
+
+	for _, trace := range traces {
+		page.costBefore += trace.TotalCostUSD
+
+		repriced := 0
+		for i := range trace.Spans {
+			span := &trace.Spans[i]
+			if span.PriceSource == models.PriceSourceClient ||
+				(model != "" && normalizeModelName(span.Model) != model) ||
+				(span.PromptTokens == 0 && span.CompletionTokens == 0 && span.AudioSeconds == 0) {
+				continue
+			}
+
+			before := *span
+			s.pricing.priceSpan(span, trace.OrganizationID, trace.ProjectID)
+			if span.CostUSD != before.CostUSD || span.CostComponents != before.CostComponents ||
+				span.PriceSource != before.PriceSource || span.Unpriced != before.Unpriced {
+				repriced++
+			}
+		}
+
+		if repriced > 0 {
+			total := 0.0
+			for _, span := range trace.Spans {
+				total += span.CostUSD
+			}
+			trace.TotalCostUSD = total
+			trace.PriceSource = tracePriceSource(trace.Spans)
+
+			page.changed = append(page.changed, trace)
+			page.spans += int64(repriced)
+			for _, metric := range traceMetrics(trace) {
+				if metric.MetricName == "unpriced_spans" {
+					page.unpriced = append(page.unpriced, metric)
+				}
+			}
+		}
+
+		page.costAfter += trace.TotalCostUSD
+	}
+
+	return page
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// waitForJob polls a job until it finishes
+func waitForJob(t *testing.T, jobs *JobManager, orgID, jobID string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := jobs.Get(orgID, jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Done() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish", jobID)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCostBackfill(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{traces: []*models.Trace{
+		{TraceID: "t-1", OrganizationID: "org-1", Timestamp: at, TotalCostUSD: 6, Spans: []models.Span{
+			{SpanID: "s-1", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, StartTime: at, CostUSD: 5, PriceSource: models.PriceSourceCatalog},
+			{SpanID: "s-2", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, StartTime: at, CostUSD: 1, PriceSource: models.PriceSourceClient},
+		}},
+		{TraceID: "t-2", OrganizationID: "org-1", Timestamp: at.Add(time.Minute), TotalCostUSD: 99, Spans: []models.Span{
+			{SpanID: "s-3", Provider: "anthropic", Model: "claude-3-5-sonnet", PromptTokens: 1000000, StartTime: at, CostUSD: 99},
+		}},
+		{TraceID: "t-3", OrganizationID: "org-2", Timestamp: at, TotalCostUSD: 5, Spans: []models.Span{
+			{SpanID: "s-4", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, StartTime: at, CostUSD: 5},
+		}},
+		{TraceID: "t-4", OrganizationID: "org-1", Timestamp: at.AddDate(0, 0, -7), TotalCostUSD: 5, Spans: []models.Span{
+			{SpanID: "s-5", Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, StartTime: at, CostUSD: 5},
+		}},
+	}}
+	jobs := NewJobManager(time.Hour)
+	service := NewCostBackfillService(repo, jobs, NewPricingService(repo))
+
+	req := &models.CostBackfillRequest{Model: "gpt-4o", StartTime: at.Add(-time.Hour), EndTime: at.Add(time.Hour)}
+	if _, err := service.StartBackfill(context.Background(), "org-1", &models.CostBackfillRequest{StartTime: at, EndTime: at}, "admin"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an empty range, got %v", err)
+	}
+	if _, err := service.StartBackfill(context.Background(), "org-1", &models.CostBackfillRequest{StartTime: at, EndTime: time.Now().Add(time.Hour)}, "admin"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a range into the open day, got %v", err)
+	}
+
+	backfill, err := service.StartBackfill(context.Background(), "org-1", req, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backfill.Status != JobPending || backfill.JobID == "" {
+		t.Errorf("expected a pending backfill with a job, got %+v", backfill)
+	}
+
+	if job := waitForJob(t, jobs, "org-1", backfill.JobID); job.Status != JobCompleted || job.Processed != 2 || job.Total != 2 {
+		t.Fatalf("unexpected job state %+v", job)
+	}
+
+	byID := make(map[string]*models.Trace)
+	for _, trace := range repo.traces {
+		byID[trace.TraceID] = trace
+	}
+	if trace := byID["t-1"]; math.Abs(trace.TotalCostUSD-3.5) > 1e-9 || trace.Spans[0].CostUSD != 2.5 || trace.Spans[1].CostUSD != 1 {
+		t.Errorf("expected t-1 repriced to $3.50 keeping the client cost, got %+v", trace)
+	}
+	if byID["t-1"].PriceSource != models.PriceSourceMixed {
+		t.Errorf("expected a mixed price source, got %q", byID["t-1"].PriceSource)
+	}
+	// Another model, another organization and a trace outside the range
+	for id, cost := range map[string]float64{"t-2": 99, "t-3": 5, "t-4": 5} {
+		if byID[id].TotalCostUSD != cost || byID[id].Spans[0].CostUSD != cost {
+			t.Errorf("trace %s should be untouched, got %+v", id, byID[id])
+		}
+	}
+	if len(repo.costUpdates) != 1 || strings.Join(repo.costUpdates[0], ",") != "t-1" {
+		t.Errorf("expected only t-1 to be written, got %v", repo.costUpdates)
+	}
+
+	last := repo.backfills[len(repo.backfills)-1]
+	if last.Status != JobCompleted || last.TracesProcessed != 2 || last.TracesUpdated != 1 || last.SpansRepriced != 1 {
+		t.Errorf("unexpected final record %+v", last)
+	}
+	if last.CostBefore != 105 || math.Abs(last.CostAfter-102.5) > 1e-9 || last.CursorTraceID != "t-2" {
+		t.Errorf("unexpected totals or cursor %+v", last)
+	}
+
+	if _, err := service.ResumeBackfill(context.Background(), "org-1", backfill.ID); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected a completed backfill not to resume, got %v", err)
+	}
+}
+
+func TestCostBackfillResume(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &mockRepository{failCostUpdate: 2}
+	for i := 0; i < 600; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		repo.traces = append(repo.traces, &models.Trace{
+			TraceID: fmt.Sprintf("t-%03d", i), OrganizationID: "org-1", Timestamp: at, TotalCostUSD: 5,
+			Spans: []models.Span{{SpanID: fmt.Sprintf("s-%03d", i), Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, StartTime: at, CostUSD: 5}},
+		})
+	}
+	jobs := NewJobManager(time.Hour)
+	service := NewCostBackfillService(repo, jobs, NewPricingService(repo))
+
+	backfill, err := service.StartBackfill(context.Background(), "org-1", &models.CostBackfillRequest{StartTime: start, EndTime: start.Add(time.Hour)}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitForJob(t, jobs, "org-1", backfill.JobID); job.Status != JobFailed || job.Processed != 500 {
+		t.Fatalf("expected the second page to fail, got %+v", job)
+	}
+
+	failed, err := service.GetBackfill(context.Background(), "org-1", backfill.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != JobFailed || failed.TracesProcessed != 500 || failed.CursorTraceID != "t-499" || failed.Error == "" {
+		t.Fatalf("expected a failed backfill checkpointed after the first page, got %+v", failed)
+	}
+
+	resumed, err := service.ResumeBackfill(context.Background(), "org-1", backfill.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitForJob(t, jobs, "org-1", resumed.JobID); job.Status != JobCompleted || job.Processed != 600 {
+		t.Fatalf("unexpected resumed job state %+v", job)
+	}
+
+	if len(repo.costUpdates) != 2 || len(repo.costUpdates[0]) != 500 || len(repo.costUpdates[1]) != 100 || repo.costUpdates[1][0] != "t-500" {
+		t.Errorf("expected the resumed run to start after the cursor, got pages of %d and %d traces",
+			len(repo.costUpdates[0]), len(repo.costUpdates[len(repo.costUpdates)-1]))
+	}
+
+	last := repo.backfills[len(repo.backfills)-1]
+	if last.Status != JobCompleted || last.TracesProcessed != 600 || last.TracesUpdated != 600 || last.Error != "" {
+		t.Errorf("unexpected final record %+v", last)
+	}
+	for _, trace := range repo.traces {
+		if trace.TotalCostUSD != 2.5 {
+			t.Fatalf("expected every trace repriced to $2.50, got %s at %v", trace.TraceID, trace.TotalCostUSD)
+		}
+	}
+}
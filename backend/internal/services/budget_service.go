@@ -0,0 +1,630 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// defaultBudgetThresholds are the warning levels of a budget, in percent of its limit
+var defaultBudgetThresholds = []float64{50, 80, 100}
+
+// BudgetExceededError is returned when a used-up hard budget rejects a trace
+type BudgetExceededError struct {
+	Check *models.BudgetCheck
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: hard budget %s is used up", ErrBudgetExceeded, e.Check.BlockedBy)
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) match
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// budgetMode is what evaluating budgets for a call does
+type budgetMode int
+
+const (
+	budgetAdmit   budgetMode = iota // reject if a hard budget is already used up, else reserve the cost
+	budgetRecord                    // charge the call to its budgets
+	budgetRelease                   // drop the reservation of a call that was not stored
+	budgetCheck                     // reject if the estimated cost would overrun a hard budget
+)
+
+// budgetSpend is the running spend of a budget in one period
+type budgetSpend struct {
+	periodStart time.Time
+	countedTo   time.Time // stored spend is counted up to here; later calls are added as recorded
+	amount      float64
+	reserved    float64 // cost of admitted calls not yet recorded or released
+	notified    float64 // highest threshold already logged this period
+}
+
+// budgetTarget is what a call charges to budgets: its total cost, and its cost per model
+type budgetTarget struct {
+	projectID string
+	apiKeyID  string
+	cost      float64
+	modelCost map[string]float64 // by normalized model name
+}
+
+// BudgetService tracks spend against budgets as traces arrive and enforces hard budgets.
+// Spend is counted in memory and resynced from the stored traces on Refresh, so instances
+// sharing a database converge on the same totals.
+type BudgetService struct {
+	repo          repository.Repository
+	notifications *NotificationService // optional
+
+	mu        sync.Mutex
+	budgets   map[string][]*models.Budget // by organization
+	spend     map[string]*budgetSpend     // by budget ID
+	recorded  map[string]*budgetSpend     // charged from the watermark on, by budget ID
+	watermark time.Time                   // when the last Refresh began
+}
+
+// NewBudgetService creates a budget service; call Refresh to load budgets. Crossed
+// thresholds are sent to the organization's notification channels when notifications is set.
+func NewBudgetService(repo repository.Repository, notifications *NotificationService) *BudgetService {
+	return &BudgetService{
+		repo:          repo,
+		notifications: notifications,
+		budgets:       make(map[string][]*models.Budget),
+		spend:         make(map[string]*budgetSpend),
+		recorded:      make(map[string]*budgetSpend),
+	}
+}
+
+// Refresh reloads every budget and recounts its spend in the current period. Stored spend
+// is counted up to a watermark taken as the refresh begins, and traces from the watermark
+// on are added from memory, so none is counted twice. A trace stamped before the watermark
+// but stored after the query is missed until the next refresh.
+func (s *BudgetService) Refresh(ctx context.Context) error {
+	watermark := time.Now()
+	s.mu.Lock()
+	s.recorded = make(map[string]*budgetSpend)
+	s.watermark = watermark
+	s.mu.Unlock()
+
+	budgets, err := s.repo.ListBudgets(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	byOrg := make(map[string][]*models.Budget)
+	spend := make(map[string]*budgetSpend, len(budgets))
+
+	for _, budget := range budgets {
+		start, end := budgetPeriod(budget.Period, watermark)
+		amount, err := s.repo.GetBudgetSpend(ctx, budget, start, watermark)
+		if err != nil {
+			return err
+		}
+
+		byOrg[budget.OrganizationID] = append(byOrg[budget.OrganizationID], budget)
+		spend[budget.ID] = &budgetSpend{
+			periodStart: start,
+			countedTo:   minTime(watermark, end),
+			amount:      amount,
+			notified:    crossedThreshold(budget, amount),
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, current := range spend {
+		if recorded := s.recorded[id]; recorded != nil && recorded.periodStart.Equal(current.periodStart) {
+			current.amount += recorded.amount
+		}
+		if previous := s.spend[id]; previous != nil && previous.periodStart.Equal(current.periodStart) {
+			// Calls admitted before the refresh are still in flight
+			current.reserved = previous.reserved
+			// Thresholds already notified this period are not notified again
+			current.notified = math.Max(current.notified, previous.notified)
+		}
+	}
+	s.budgets = byOrg
+	s.spend = spend
+	return nil
+}
+
+// Start refreshes budgets and their spend in the background until ctx is cancelled
+func (s *BudgetService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh budgets: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// UpsertBudget creates a budget, or updates the organization's budget with the request's ID
+func (s *BudgetService) UpsertBudget(ctx context.Context, orgID string, req *models.BudgetRequest, updatedBy string) (*models.Budget, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	budget, err := newBudget(orgID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	budget.ID = uuid.New().String()
+	budget.CreatedBy = updatedBy
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	if req.ID != "" {
+		existing, err := s.findBudget(ctx, orgID, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		budget.ID = existing.ID
+		budget.CreatedBy = existing.CreatedBy
+		budget.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.repo.SaveBudget(ctx, budget); err != nil {
+		return nil, err
+	}
+
+	// The scope or period may have changed, so the spend is counted again
+	start, end := budgetPeriod(budget.Period, now)
+	counted := time.Now()
+	amount, err := s.repo.GetBudgetSpend(ctx, budget, start, minTime(counted, end))
+	if err != nil {
+		log.Printf("❌ Failed to count spend of budget %s; it starts at $0 until the next refresh: %v", budget.ID, err)
+	}
+
+	s.mu.Lock()
+	s.removeBudget(orgID, budget.ID)
+	s.budgets[orgID] = append(s.budgets[orgID], budget)
+	s.spend[budget.ID] = &budgetSpend{
+		periodStart: start,
+		countedTo:   minTime(counted, end),
+		amount:      amount,
+		notified:    crossedThreshold(budget, amount),
+	}
+	s.mu.Unlock()
+
+	return budget, nil
+}
+
+// DeleteBudget removes an organization's budget
+func (s *BudgetService) DeleteBudget(ctx context.Context, orgID, id string) error {
+	budget, err := s.findBudget(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	budget.Deleted = true
+	budget.UpdatedAt = time.Now()
+	if err := s.repo.SaveBudget(ctx, budget); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.removeBudget(orgID, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// ListStatus returns the consumption of an organization's budgets in their current periods
+func (s *BudgetService) ListStatus(ctx context.Context, orgID string) ([]models.BudgetStatus, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	budgets, err := s.repo.ListBudgets(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := s.status(ctx, budget)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// GetStatus returns the consumption of one budget in its current period
+func (s *BudgetService) GetStatus(ctx context.Context, orgID, id string) (*models.BudgetStatus, error) {
+	budget, err := s.findBudget(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.status(ctx, budget)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// MonthlyBudget returns the organization's monthly budget of a scope with the lowest limit,
+// or nil. The scope value is a project ID or a model name; it is ignored for organizations.
+func (s *BudgetService) MonthlyBudget(orgID, scope, value string) *models.Budget {
+	if scope == models.BudgetScopeModel {
+		value = normalizeModelName(value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tightest *models.Budget
+	for _, budget := range s.budgets[orgID] {
+		if budget.Period != models.BudgetPeriodMonthly || budget.Scope != scope ||
+			(scope != models.BudgetScopeOrganization && budget.ScopeValue != value) {
+			continue
+		}
+		if tightest == nil || budget.LimitUSD < tightest.LimitUSD {
+			tightest = budget
+		}
+	}
+	return tightest
+}
+
+// Check reports whether a call may go ahead under the budgets it would be charged to.
+// Model budgets apply only when the request names the model.
+func (s *BudgetService) Check(orgID, apiKeyID string, req *models.BudgetCheckRequest) (*models.BudgetCheck, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if req.EstimatedCostUSD < 0 || math.IsNaN(req.EstimatedCostUSD) || math.IsInf(req.EstimatedCostUSD, 0) {
+		return nil, invalidArgument("estimated_cost_usd must be a non-negative number")
+	}
+
+	target := budgetTarget{
+		projectID: req.ProjectID,
+		apiKeyID:  apiKeyID,
+		cost:      req.EstimatedCostUSD,
+		modelCost: make(map[string]float64),
+	}
+	if model := normalizeModelName(req.Model); model != "" {
+		target.modelCost[model] = req.EstimatedCostUSD
+	}
+
+	return s.evaluate(orgID, target, budgetCheck, time.Now()), nil
+}
+
+// Admit reports whether a trace may be stored: not when a hard budget it is charged to
+// is already used up, counting the traces admitted but not yet stored. An admitted trace's
+// cost is reserved until it is recorded, or released if it could not be stored.
+func (s *BudgetService) Admit(trace *models.Trace) *models.BudgetCheck {
+	return s.evaluate(trace.OrganizationID, traceBudgetTarget(trace), budgetAdmit, trace.Timestamp)
+}
+
+// Record charges a stored trace to its budgets and returns their state afterwards;
+// Allowed is false once a hard budget is used up
+func (s *BudgetService) Record(trace *models.Trace) *models.BudgetCheck {
+	return s.evaluate(trace.OrganizationID, traceBudgetTarget(trace), budgetRecord, trace.Timestamp)
+}
+
+// Release drops the reservation of an admitted trace that could not be stored
+func (s *BudgetService) Release(trace *models.Trace) {
+	s.evaluate(trace.OrganizationID, traceBudgetTarget(trace), budgetRelease, trace.Timestamp)
+}
+
+// evaluate matches a call made at the given time against the organization's budgets. Like
+// the stored spend, calls count in the period of their timestamp; those of past periods are
+// left out of the running count, and timestamps in the future count as now.
+func (s *BudgetService) evaluate(orgID string, target budgetTarget, mode budgetMode, at time.Time) *models.BudgetCheck {
+	now := time.Now()
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+	check := &models.BudgetCheck{Allowed: true, State: models.BudgetStateOK, Budgets: []models.BudgetStatus{}}
+
+	type reservation struct {
+		spend  *budgetSpend
+		amount float64
+	}
+	var reservations []reservation
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, budget := range s.budgets[orgID] {
+		amount, ok := target.charge(budget)
+		if !ok {
+			continue
+		}
+
+		start, end := budgetPeriod(budget.Period, at)
+		spend := s.spend[budget.ID]
+		switch {
+		case spend == nil || spend.periodStart.Before(start):
+			spend = &budgetSpend{periodStart: start}
+			s.spend[budget.ID] = spend
+		case start.Before(spend.periodStart):
+			continue
+		}
+
+		switch mode {
+		case budgetAdmit:
+			reservations = append(reservations, reservation{spend, amount})
+		case budgetRelease:
+			spend.reserved = math.Max(0, spend.reserved-amount)
+			continue
+		case budgetRecord:
+			spend.reserved = math.Max(0, spend.reserved-amount)
+			// Calls before the watermarks are in the stored spend already
+			if !at.Before(spend.countedTo) {
+				spend.amount += amount
+			}
+			if !at.Before(s.watermark) {
+				recorded := s.recorded[budget.ID]
+				if recorded == nil || !recorded.periodStart.Equal(start) {
+					recorded = &budgetSpend{periodStart: start}
+					s.recorded[budget.ID] = recorded
+				}
+				recorded.amount += amount
+			}
+			if threshold := crossedThreshold(budget, spend.amount); threshold > spend.notified {
+				spend.notified = threshold
+				log.Printf("⚠️  Budget %q of organization %s reached %.0f%% of its %s limit of $%.2f",
+					budget.Name, budget.OrganizationID, threshold, budget.Period, budget.LimitUSD)
+				if s.notifications != nil {
+					s.notifications.Notify(BudgetNotification(budget, threshold, spend.amount, now))
+				}
+			}
+		}
+
+		status := budgetStatus(budget, spend.amount, start, end)
+		check.Budgets = append(check.Budgets, status)
+
+		blocked := spend.amount >= budget.LimitUSD ||
+			(mode == budgetAdmit && spend.amount+spend.reserved >= budget.LimitUSD) ||
+			(mode == budgetCheck && amount > 0 && spend.amount+spend.reserved+amount > budget.LimitUSD)
+		if budget.Enforcement == models.BudgetEnforcementHard && blocked && check.Allowed {
+			check.Allowed = false
+			check.BlockedBy = budget.ID
+		}
+
+		if check.RemainingUSD == nil || status.RemainingUSD < *check.RemainingUSD {
+			remaining := status.RemainingUSD
+			check.RemainingUSD = &remaining
+		}
+		if status.PercentUsed > check.PercentUsed {
+			check.PercentUsed = status.PercentUsed
+		}
+		if budgetStateRank[status.State] > budgetStateRank[check.State] {
+			check.State = status.State
+		}
+	}
+
+	// A trace is reserved against every budget or none
+	if check.Allowed {
+		for _, r := range reservations {
+			r.spend.reserved += r.amount
+		}
+	}
+	return check
+}
+
+// status computes a budget's consumption, from the running count when there is one
+func (s *BudgetService) status(ctx context.Context, budget *models.Budget) (models.BudgetStatus, error) {
+	start, end := budgetPeriod(budget.Period, time.Now())
+
+	s.mu.Lock()
+	spend, counted := s.spend[budget.ID]
+	s.mu.Unlock()
+
+	amount := 0.0
+	if counted && spend.periodStart.Equal(start) {
+		amount = spend.amount
+	} else {
+		var err error
+		if amount, err = s.repo.GetBudgetSpend(ctx, budget, start, end); err != nil {
+			return models.BudgetStatus{}, err
+		}
+	}
+
+	return budgetStatus(budget, amount, start, end), nil
+}
+
+// findBudget returns an organization's budget from the database, so budgets created on
+// other instances are found before the next refresh
+func (s *BudgetService) findBudget(ctx context.Context, orgID, id string) (*models.Budget, error) {
+	budgets, err := s.repo.ListBudgets(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, budget := range budgets {
+		if budget.ID == id {
+			return budget, nil
+		}
+	}
+	return nil, fmt.Errorf("budget %s: %w", id, repository.ErrNotFound)
+}
+
+// removeBudget drops a budget from the cache; the caller holds s.mu
+func (s *BudgetService) removeBudget(orgID, id string) {
+	budgets := s.budgets[orgID][:0]
+	for _, budget := range s.budgets[orgID] {
+		if budget.ID != id {
+			budgets = append(budgets, budget)
+		}
+	}
+	s.budgets[orgID] = budgets
+	delete(s.spend, id)
+}
+
+// newBudget validates a budget request and fills in its defaults
+func newBudget(orgID string, req *models.BudgetRequest) (*models.Budget, error) {
+	budget := &models.Budget{
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Scope:          req.Scope,
+		ScopeValue:     strings.TrimSpace(req.ScopeValue),
+		Period:         req.Period,
+		LimitUSD:       req.LimitUSD,
+		Enforcement:    req.Enforcement,
+	}
+
+	switch budget.Scope {
+	case models.BudgetScopeOrganization:
+		budget.ScopeValue = ""
+	case models.BudgetScopeProject, models.BudgetScopeAPIKey:
+		if budget.ScopeValue == "" {
+			return nil, invalidArgument("scope_value is required for %s budgets", budget.Scope)
+		}
+	case models.BudgetScopeModel:
+		if budget.ScopeValue = normalizeModelName(budget.ScopeValue); budget.ScopeValue == "" {
+			return nil, invalidArgument("scope_value is required for model budgets")
+		}
+	default:
+		return nil, invalidArgument("scope must be organization, project, model or api_key")
+	}
+
+	if budget.Period != models.BudgetPeriodDaily && budget.Period != models.BudgetPeriodMonthly {
+		return nil, invalidArgument("period must be daily or monthly")
+	}
+	if !(budget.LimitUSD > 0) || math.IsInf(budget.LimitUSD, 0) {
+		return nil, invalidArgument("limit_usd must be a positive number")
+	}
+
+	if budget.Enforcement == "" {
+		budget.Enforcement = models.BudgetEnforcementSoft
+	}
+	if budget.Enforcement != models.BudgetEnforcementSoft && budget.Enforcement != models.BudgetEnforcementHard {
+		return nil, invalidArgument("enforcement must be soft or hard")
+	}
+
+	thresholds := req.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetThresholds
+	}
+	seen := make(map[float64]bool)
+	for _, threshold := range thresholds {
+		if !(threshold > 0 && threshold <= 100) {
+			return nil, invalidArgument("thresholds must be percentages between 0 and 100")
+		}
+		if !seen[threshold] {
+			seen[threshold] = true
+			budget.Thresholds = append(budget.Thresholds, threshold)
+		}
+	}
+	sort.Float64s(budget.Thresholds)
+
+	if budget.Name == "" {
+		budget.Name = strings.TrimSpace(fmt.Sprintf("%s %s %s budget", budget.Period, budget.Scope, budget.ScopeValue))
+	}
+	return budget, nil
+}
+
+// traceBudgetTarget is what a trace charges to budgets
+func traceBudgetTarget(trace *models.Trace) budgetTarget {
+	target := budgetTarget{
+		projectID: trace.ProjectID,
+		apiKeyID:  trace.APIKeyID,
+		cost:      trace.TotalCostUSD,
+		modelCost: make(map[string]float64),
+	}
+	for _, span := range trace.Spans {
+		if model := normalizeModelName(span.Model); model != "" {
+			target.modelCost[model] += span.CostUSD
+		}
+	}
+	return target
+}
+
+// charge returns the amount a call charges to a budget, and whether the budget applies
+func (t budgetTarget) charge(budget *models.Budget) (float64, bool) {
+	switch budget.Scope {
+	case models.BudgetScopeOrganization:
+		return t.cost, true
+	case models.BudgetScopeProject:
+		return t.cost, t.projectID == budget.ScopeValue
+	case models.BudgetScopeAPIKey:
+		return t.cost, t.apiKeyID != "" && t.apiKeyID == budget.ScopeValue
+	case models.BudgetScopeModel:
+		cost, ok := t.modelCost[budget.ScopeValue]
+		return cost, ok
+	}
+	return 0, false
+}
+
+// budgetStateRank orders budget states from best to worst
+var budgetStateRank = map[string]int{
+	models.BudgetStateOK:       0,
+	models.BudgetStateWarning:  1,
+	models.BudgetStateExceeded: 2,
+}
+
+// budgetStatus computes a budget's state from its spend
+func budgetStatus(budget *models.Budget, spent float64, start, end time.Time) models.BudgetStatus {
+	status := models.BudgetStatus{
+		Budget:       *budget,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		SpentUSD:     spent,
+		RemainingUSD: math.Max(budget.LimitUSD-spent, 0),
+		PercentUsed:  spent / budget.LimitUSD * 100,
+		Threshold:    crossedThreshold(budget, spent),
+		State:        models.BudgetStateOK,
+	}
+
+	switch {
+	case spent >= budget.LimitUSD:
+		status.State = models.BudgetStateExceeded
+	case status.Threshold > 0:
+		status.State = models.BudgetStateWarning
+	}
+	return status
+}
+
+// crossedThreshold returns the highest threshold a budget's spend has reached, or 0
+func crossedThreshold(budget *models.Budget, spent float64) float64 {
+	percent := spent / budget.LimitUSD * 100
+	crossed := 0.0
+	for _, threshold := range budget.Thresholds {
+		if percent >= threshold {
+			crossed = threshold
+		}
+	}
+	return crossed
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// budgetPeriod returns the UTC period containing at
+func budgetPeriod(period string, at time.Time) (time.Time, time.Time) {
+	at = at.UTC()
+	if period == models.BudgetPeriodDaily {
+		start := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+	start := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	at := time.Date(2025, 2, 14, 23, 30, 0, 0, time.FixedZone("PST", -8*3600))
+
+	start, end := budgetPeriod(models.BudgetPeriodDaily, at)
+	if !start.Equal(time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("expected the UTC day, got %v to %v", start, end)
+	}
+
+	start, end = budgetPeriod(models.BudgetPeriodMonthly, at)
+	if !start.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the UTC month, got %v to %v", start, end)
+	}
+}
+
+func TestNewBudget(t *testing.T) {
+	invalid := []models.BudgetRequest{
+		{Scope: "team", Period: "monthly", LimitUSD: 10},
+		{Scope: "project", Period: "monthly", LimitUSD: 10},
+		{Scope: "organization", Period: "weekly", LimitUSD: 10},
+		{Scope: "organization", Period: "monthly", LimitUSD: 0},
+		{Scope: "organization", Period: "monthly", LimitUSD: math.Inf(1)},
+		{Scope: "organization", Period: "monthly", LimitUSD: 10, Enforcement: "strict"},
+		{Scope: "organization", Period: "monthly", LimitUSD: 10, Thresholds: []float64{50, 120}},
+	}
+	for _, req := range invalid {
+		if _, err := newBudget("org-1", &req); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("expected ErrInvalidArgument for %+v, got %v", req, err)
+		}
+	}
+
+	budget, err := newBudget("org-1", &models.BudgetRequest{Scope: "model", ScopeValue: "openai/GPT-4o", Period: "daily", LimitUSD: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget.ScopeValue != "gpt-4o" || budget.Enforcement != models.BudgetEnforcementSoft || budget.Name != "daily model gpt-4o budget" {
+		t.Errorf("unexpected defaults %+v", budget)
+	}
+	if len(budget.Thresholds) != 3 || budget.Thresholds[0] != 50 || budget.Thresholds[2] != 100 {
+		t.Errorf("expected the default thresholds, got %v", budget.Thresholds)
+	}
+
+	budget, _ = newBudget("org-1", &models.BudgetRequest{Scope: "organization", ScopeValue: "ignored", Period: "monthly", LimitUSD: 5, Thresholds: []float64{90, 25, 90}})
+	if budget.ScopeValue != "" || len(budget.Thresholds) != 2 || budget.Thresholds[0] != 25 {
+		t.Errorf("expected sorted unique thresholds and no scope value, got %+v", budget)
+	}
+}
+
+func TestBudgetRecord(t *testing.T) {
+	repo := &mockRepository{}
+	service := NewBudgetService(repo, nil)
+
+	budget, err := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "organization", Period: "monthly", LimitUSD: 10,
+	}, "user:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spend stored before this instance started is picked up by a refresh
+	repo.budgetSpend = map[string]float64{budget.ID: 4}
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cost      float64
+		state     string
+		threshold float64
+	}{
+		{1.5, models.BudgetStateWarning, 50},
+		{3, models.BudgetStateWarning, 80},
+		{2, models.BudgetStateExceeded, 100},
+	}
+	for _, tt := range tests {
+		check := service.Record(&models.Trace{OrganizationID: "org-1", TotalCostUSD: tt.cost})
+		if len(check.Budgets) != 1 || check.State != tt.state || check.Budgets[0].Threshold != tt.threshold {
+			t.Fatalf("after $%v: expected %s at %v%%, got %+v", tt.cost, tt.state, tt.threshold, check)
+		}
+		if !check.Allowed {
+			t.Error("a soft budget must never block")
+		}
+	}
+
+	status, err := service.GetStatus(context.Background(), "org-1", budget.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SpentUSD != 10.5 || status.RemainingUSD != 0 || math.Abs(status.PercentUsed-105) > 1e-9 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if check := service.Record(&models.Trace{OrganizationID: "org-2", TotalCostUSD: 1}); len(check.Budgets) != 0 || !check.Allowed {
+		t.Errorf("another organization's traces must not match, got %+v", check)
+	}
+
+	if err := service.DeleteBudget(context.Background(), "org-1", budget.ID); err != nil {
+		t.Fatal(err)
+	}
+	if statuses, _ := service.ListStatus(context.Background(), "org-1"); len(statuses) != 0 {
+		t.Errorf("expected the budget to be deleted, got %+v", statuses)
+	}
+}
+
+func TestBudgetRefreshKeepsRecordedSpend(t *testing.T) {
+	repo := &mockRepository{}
+	service := NewBudgetService(repo, nil)
+
+	budget, err := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "organization", Period: "monthly", LimitUSD: 10,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A trace recorded while the refresh queries is not in the stored spend it reads
+	repo.budgetSpend = map[string]float64{budget.ID: 4}
+	repo.onBudgetSpend = func() {
+		service.Record(&models.Trace{OrganizationID: "org-1", TotalCostUSD: 2, Timestamp: time.Now()})
+	}
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	repo.onBudgetSpend = nil
+
+	// A late trace of the previous month is not charged to this one
+	month, _ := budgetPeriod(models.BudgetPeriodMonthly, time.Now())
+	service.Record(&models.Trace{OrganizationID: "org-1", TotalCostUSD: 3, Timestamp: month.Add(-time.Hour)})
+
+	status, err := service.GetStatus(context.Background(), "org-1", budget.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SpentUSD != 6 {
+		t.Errorf("expected $4 stored plus $2 recorded during the refresh, got %+v", status)
+	}
+}
+
+func TestHardBudgetRejectsTraces(t *testing.T) {
+	saved := 0
+	repo := &mockRepository{saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+		saved++
+		return nil
+	}}
+	budgets := NewBudgetService(repo, nil)
+	service := NewTraceService(repo, nil, NewPricingService(repo), budgets, nil)
+
+	budget, err := budgets.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "project", ScopeValue: "proj-1", Period: "daily", LimitUSD: 0.001, Enforcement: "hard",
+	}, "user:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := func(projectID string) *models.TraceRequest {
+		return &models.TraceRequest{
+			OrganizationID: "org-1",
+			ProjectID:      projectID,
+			TraceType:      "single_call",
+			Spans:          []models.SpanRequest{{Name: "call", Model: "gpt-4", Provider: "openai", PromptTokens: 1000, Status: "success"}},
+		}
+	}
+
+	// The first trace goes over the limit and is still stored; the next ones are rejected
+	resp, err := service.CreateTrace(context.Background(), trace("proj-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Budget == nil || resp.Budget.State != models.BudgetStateExceeded || resp.Budget.Allowed {
+		t.Errorf("expected the response to report the used-up budget, got %+v", resp.Budget)
+	}
+
+	_, err = service.CreateTrace(context.Background(), trace("proj-1"))
+	var exceeded *BudgetExceededError
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &exceeded) || exceeded.Check.BlockedBy != budget.ID {
+		t.Fatalf("expected the hard budget to reject the trace, got %v", err)
+	}
+	if saved != 1 {
+		t.Errorf("a rejected trace must not be stored, got %d saved", saved)
+	}
+
+	resp, err = service.CreateTrace(context.Background(), trace("proj-2"))
+	if err != nil || resp.Budget != nil {
+		t.Errorf("traces of other projects must be unaffected, got %+v, %v", resp, err)
+	}
+}
+
+func TestBudgetCheck(t *testing.T) {
+	repo := &mockRepository{}
+	service := NewBudgetService(repo, nil)
+
+	model, _ := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "model", ScopeValue: "gpt-4o", Period: "monthly", LimitUSD: 1, Enforcement: "hard",
+	}, "user:admin")
+	key, _ := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "api_key", ScopeValue: "key_1", Period: "daily", LimitUSD: 10,
+	}, "user:admin")
+	repo.budgetSpend = map[string]float64{model.ID: 0.9, key.ID: 6}
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		req     models.BudgetCheckRequest
+		allowed bool
+		matched int
+	}{
+		{"fits", "", models.BudgetCheckRequest{Model: "openai/gpt-4o", EstimatedCostUSD: 0.05}, true, 1},
+		{"would overrun", "", models.BudgetCheckRequest{Model: "gpt-4o", EstimatedCostUSD: 0.2}, false, 1},
+		{"other model", "", models.BudgetCheckRequest{Model: "gpt-4o-mini", EstimatedCostUSD: 5}, true, 0},
+		{"soft api key budget", "key_1", models.BudgetCheckRequest{EstimatedCostUSD: 50}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := service.Check("org-1", tt.apiKey, &tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if check.Allowed != tt.allowed || len(check.Budgets) != tt.matched {
+				t.Errorf("expected allowed=%v with %d budgets, got %+v", tt.allowed, tt.matched, check)
+			}
+		})
+	}
+
+	if _, err := service.Check("org-1", "", &models.BudgetCheckRequest{EstimatedCostUSD: -1}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a negative estimate, got %v", err)
+	}
+}
+
+func TestBudgetAdmitReservesCost(t *testing.T) {
+	repo := &mockRepository{}
+	service := NewBudgetService(repo, nil)
+
+	if _, err := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "organization", Period: "daily", LimitUSD: 5, Enforcement: "hard",
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two traces admitted before either is stored can't both spend the same headroom
+	first := &models.Trace{OrganizationID: "org-1", TotalCostUSD: 5}
+	if !service.Admit(first).Allowed {
+		t.Fatal("the first trace should be admitted")
+	}
+	second := &models.Trace{OrganizationID: "org-1", TotalCostUSD: 1}
+	if service.Admit(second).Allowed {
+		t.Fatal("the second trace should be rejected while the first is reserved")
+	}
+
+	// A trace that could not be stored gives its reservation back
+	service.Release(first)
+	if !service.Admit(second).Allowed {
+		t.Fatal("the released reservation should admit the next trace")
+	}
+	service.Record(second)
+
+	budgets, err := service.ListStatus(context.Background(), "org-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 1 || budgets[0].SpentUSD != 1 {
+		t.Errorf("expected only the recorded trace to be spent, got %+v", budgets)
+	}
+}
+
+func TestBudgetRefreshWatermark(t *testing.T) {
+	repo := &mockRepository{}
+	service := NewBudgetService(repo, nil)
+
+	budget, err := service.UpsertBudget(context.Background(), "org-1", &models.BudgetRequest{
+		Scope: "organization", Period: "monthly", LimitUSD: 10,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A trace stamped before the refresh began is in the stored spend the refresh reads
+	stamped := time.Now()
+	repo.budgetSpend = map[string]float64{budget.ID: 4}
+	repo.onBudgetSpend = func() {
+		service.Record(&models.Trace{OrganizationID: "org-1", TotalCostUSD: 2, Timestamp: stamped})
+	}
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	repo.onBudgetSpend = nil
+
+	status, err := service.GetStatus(context.Background(), "org-1", budget.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SpentUSD != 4 {
+		t.Errorf("expected the stored $4 without counting the trace twice, got %+v", status)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// maxChargebackLevels caps how many tags a chargeback hierarchy may have
+const maxChargebackLevels = maxCostTagKeys
+
+// ChargebackService attributes LLM spend to internal teams by the metadata tags on
+// traces, and issues monthly statements per team
+type ChargebackService struct {
+	repo repository.Repository
+}
+
+// NewChargebackService creates a new chargeback service
+func NewChargebackService(repo repository.Repository) *ChargebackService {
+	return &ChargebackService{repo: repo}
+}
+
+// GetConfig returns an organization's chargeback configuration; by default spend is
+// attributed by the team tag and untagged spend is charged to the unallocated bucket
+func (s *ChargebackService) GetConfig(ctx context.Context, orgID string) (*models.ChargebackConfig, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	config, err := s.repo.GetChargebackConfig(ctx, orgID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.ChargebackConfig{
+			OrganizationID: orgID,
+			Hierarchy:      []string{"team"},
+			UntaggedPolicy: models.UntaggedPolicyBucket,
+			DefaultBucket:  models.DefaultChargebackBucket,
+		}, nil
+	}
+	return config, err
+}
+
+// UpdateConfig validates and stores an organization's chargeback configuration
+func (s *ChargebackService) UpdateConfig(ctx context.Context, orgID string, req *models.ChargebackConfigRequest, updatedBy string) (*models.ChargebackConfig, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if len(req.Hierarchy) == 0 || len(req.Hierarchy) > maxChargebackLevels {
+		return nil, invalidArgument("hierarchy must have between 1 and %d tags", maxChargebackLevels)
+	}
+
+	config := &models.ChargebackConfig{
+		OrganizationID: orgID,
+		Hierarchy:      make([]string, 0, len(req.Hierarchy)),
+		UntaggedPolicy: req.UntaggedPolicy,
+		DefaultBucket:  strings.TrimSpace(req.DefaultBucket),
+		UpdatedBy:      updatedBy,
+		UpdatedAt:      time.Now(),
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range req.Hierarchy {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			return nil, invalidArgument("hierarchy tags must be non-empty and distinct")
+		}
+		seen[key] = true
+		config.Hierarchy = append(config.Hierarchy, key)
+	}
+
+	switch config.UntaggedPolicy {
+	case "":
+		config.UntaggedPolicy = models.UntaggedPolicyBucket
+	case models.UntaggedPolicyBucket, models.UntaggedPolicyProportional:
+	default:
+		return nil, invalidArgument("untagged_policy must be bucket or proportional")
+	}
+	if config.DefaultBucket == "" {
+		config.DefaultBucket = models.DefaultChargebackBucket
+	}
+
+	if err := s.repo.SaveChargebackConfig(ctx, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetReport attributes an organization's spend in a billing period (YYYY-MM, the current
+// month when empty) to its teams, optionally for one project
+func (s *ChargebackService) GetReport(ctx context.Context, orgID, projectID, period string) (*models.ChargebackReport, error) {
+	config, err := s.GetConfig(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := billingPeriod(period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	costs, err := s.repo.GetTagPathCosts(ctx, &models.AnalyticsQuery{
+		OrganizationID: orgID,
+		ProjectID:      projectID,
+		StartTime:      start,
+		EndTime:        end,
+	}, config.Hierarchy)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newChargebackReport(config, costs)
+	report.Period = start.Format(billingPeriodLayout)
+	report.PeriodStart = start
+	report.PeriodEnd = end
+	for i := range report.Statements {
+		statement := &report.Statements[i]
+		statement.ID = orgID + ":" + report.Period + ":" + statement.Team
+		statement.Period = report.Period
+	}
+	return report, nil
+}
+
+// GetStatements returns the teams' statements for a billing period, or the statement of
+// one team when team is set
+func (s *ChargebackService) GetStatements(ctx context.Context, orgID, period, team string) (*models.ChargebackReport, error) {
+	report, err := s.GetReport(ctx, orgID, "", period)
+	if err != nil || team == "" {
+		return report, err
+	}
+
+	for _, statement := range report.Statements {
+		if statement.Team == team {
+			report.Statements = []models.ChargebackStatement{statement}
+			return report, nil
+		}
+	}
+	return nil, fmt.Errorf("statement for team %s in %s: %w", team, report.Period, repository.ErrNotFound)
+}
+
+// newChargebackReport builds the statements of a report from spend grouped by the
+// hierarchy's tag values. Spend without the top-level tag is charged to the default
+// bucket, or spread over teams in proportion to their tagged spend.
+func newChargebackReport(config *models.ChargebackConfig, costs []*models.TagPathCost) *models.ChargebackReport {
+	report := &models.ChargebackReport{
+		OrganizationID: config.OrganizationID,
+		Hierarchy:      config.Hierarchy,
+		UntaggedPolicy: config.UntaggedPolicy,
+		Statements:     []models.ChargebackStatement{},
+	}
+
+	var tagged, untagged []*models.TagPathCost
+	for _, cost := range costs {
+		report.TotalCost += cost.TotalCost
+		if len(cost.Values) == 0 || cost.Values[0] == "" {
+			report.UntaggedCost += cost.TotalCost
+			untagged = append(untagged, cost)
+		} else {
+			report.TaggedCost += cost.TotalCost
+			tagged = append(tagged, cost)
+		}
+	}
+
+	statements := make(map[string]*models.ChargebackStatement)
+	statementFor := func(team string) *models.ChargebackStatement {
+		if statements[team] == nil {
+			statements[team] = &models.ChargebackStatement{
+				OrganizationID: config.OrganizationID,
+				TagKey:         config.Hierarchy[0],
+				Team:           team,
+				Lines:          []models.ChargebackLine{},
+			}
+		}
+		return statements[team]
+	}
+	addLine := func(team string, cost *models.TagPathCost, allocated float64) {
+		line := models.ChargebackLine{
+			Tags:          make(map[string]string),
+			DirectCost:    cost.TotalCost,
+			AllocatedCost: allocated,
+			TotalCost:     cost.TotalCost + allocated,
+			RequestCount:  cost.RequestCount,
+			TotalTokens:   cost.TotalTokens,
+		}
+		for i, value := range cost.Values {
+			if value != "" && i < len(config.Hierarchy) {
+				line.Tags[config.Hierarchy[i]] = value
+			}
+		}
+
+		statement := statementFor(team)
+		statement.DirectCost += line.DirectCost
+		statement.AllocatedCost += line.AllocatedCost
+		statement.TotalCost += line.TotalCost
+		statement.RequestCount += line.RequestCount
+		statement.TotalTokens += line.TotalTokens
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	// Proportional allocation needs tagged spend to be proportional to
+	proportional := config.UntaggedPolicy == models.UntaggedPolicyProportional && report.TaggedCost > 0
+	for _, cost := range tagged {
+		allocated := 0.0
+		if proportional {
+			allocated = report.UntaggedCost * cost.TotalCost / report.TaggedCost
+		}
+		addLine(cost.Values[0], cost, allocated)
+	}
+	if !proportional {
+		for _, cost := range untagged {
+			addLine(config.DefaultBucket, cost, 0)
+		}
+	}
+
+	for _, statement := range statements {
+		sort.SliceStable(statement.Lines, func(i, j int) bool {
+			return statement.Lines[i].TotalCost > statement.Lines[j].TotalCost
+		})
+		if report.TotalCost > 0 {
+			statement.Share = statement.TotalCost / report.TotalCost * 100
+		}
+		report.Statements = append(report.Statements, *statement)
+	}
+	sort.Slice(report.Statements, func(i, j int) bool {
+		a, b := report.Statements[i], report.Statements[j]
+		if a.TotalCost != b.TotalCost {
+			return a.TotalCost > b.TotalCost
+		}
+		return a.Team < b.Team
+	})
+
+	return report
+}
+
+// WriteChargebackCSV writes one row per statement line, with a column per hierarchy tag
+func WriteChargebackCSV(w io.Writer, report *models.ChargebackReport) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"statement_id", "organization_id", "period"}
+	header = append(header, report.Hierarchy...)
+	header = append(header, "direct_cost_usd", "allocated_cost_usd", "total_cost_usd", "requests", "tokens")
+	if report.Converted != nil {
+		header = append(header, "currency", "exchange_rate", "direct_cost_converted", "allocated_cost_converted", "total_cost_converted")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, statement := range report.Statements {
+		for _, line := range statement.Lines {
+			row := []string{statement.ID, statement.OrganizationID, statement.Period}
+			for i, key := range report.Hierarchy {
+				value := line.Tags[key]
+				if i == 0 {
+					value = statement.Team
+				}
+				row = append(row, value)
+			}
+			row = append(row,
+				formatCost(line.DirectCost),
+				formatCost(line.AllocatedCost),
+				formatCost(line.TotalCost),
+				strconv.FormatInt(line.RequestCount, 10),
+				strconv.FormatInt(line.TotalTokens, 10),
+			)
+			if report.Converted != nil {
+				row = append(row,
+					statement.Currency,
+					strconv.FormatFloat(statement.ExchangeRate, 'f', -1, 64),
+					formatCost(*line.DirectCostConverted),
+					formatCost(*line.AllocatedCostConverted),
+					formatCost(*line.TotalCostConverted),
+				)
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+func chargebackFixture() *mockRepository {
+	at := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+	trace := func(cost float64, tags map[string]string) *models.Trace {
+		return &models.Trace{OrganizationID: "org-1", TotalCostUSD: cost, TotalTokens: 100, Timestamp: at, Metadata: tags}
+	}
+
+	return &mockRepository{traces: []*models.Trace{
+		trace(30, map[string]string{"team": "search", "feature": "autocomplete"}),
+		trace(10, map[string]string{"team": "search", "feature": "ranking"}),
+		trace(20, map[string]string{"team": "support"}),
+		trace(40, map[string]string{"feature": "ranking"}),
+		trace(99, nil),
+	}}
+}
+
+func TestChargebackConfig(t *testing.T) {
+	service := NewChargebackService(&mockRepository{})
+	ctx := context.Background()
+
+	config, err := service.GetConfig(ctx, "org-1")
+	if err != nil || config.Hierarchy[0] != "team" || config.UntaggedPolicy != models.UntaggedPolicyBucket {
+		t.Fatalf("unexpected default config %+v, %v", config, err)
+	}
+
+	invalid := []models.ChargebackConfigRequest{
+		{},
+		{Hierarchy: []string{"team", " team "}},
+		{Hierarchy: []string{"a", "b", "c", "d", "e", "f"}},
+		{Hierarchy: []string{"team"}, UntaggedPolicy: "ignore"},
+	}
+	for _, req := range invalid {
+		if _, err := service.UpdateConfig(ctx, "org-1", &req, ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("expected ErrInvalidArgument for %+v, got %v", req, err)
+		}
+	}
+
+	if _, err := service.UpdateConfig(ctx, "org-1", &models.ChargebackConfigRequest{Hierarchy: []string{"cost_center"}}, "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := service.GetConfig(ctx, "org-1"); config.Hierarchy[0] != "cost_center" || config.DefaultBucket != models.DefaultChargebackBucket {
+		t.Errorf("expected the stored config with defaults filled in, got %+v", config)
+	}
+}
+
+func TestChargebackDefaultBucket(t *testing.T) {
+	repo := chargebackFixture()
+	service := NewChargebackService(repo)
+	ctx := context.Background()
+
+	req := &models.ChargebackConfigRequest{Hierarchy: []string{"team", "feature"}}
+	if _, err := service.UpdateConfig(ctx, "org-1", req, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := service.GetReport(ctx, "org-1", "", "2025-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalCost != 199 || report.TaggedCost != 60 || report.UntaggedCost != 139 || len(report.Statements) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	bucket := report.Statements[0]
+	if bucket.Team != models.DefaultChargebackBucket || bucket.TotalCost != 139 || len(bucket.Lines) != 2 || bucket.ID != "org-1:2025-05:unallocated" {
+		t.Errorf("expected untagged spend in the default bucket, got %+v", bucket)
+	}
+
+	search := report.Statements[1]
+	if search.Team != "search" || search.TotalCost != 40 || len(search.Lines) != 2 || search.Lines[0].Tags["feature"] != "autocomplete" {
+		t.Errorf("unexpected search statement %+v", search)
+	}
+
+	// One team's statement, as CSV with a column per hierarchy tag
+	report, err = service.GetStatements(ctx, "org-1", "2025-05", "search")
+	if err != nil || len(report.Statements) != 1 {
+		t.Fatalf("expected the search statement, got %+v, %v", report, err)
+	}
+	var buf bytes.Buffer
+	if err := WriteChargebackCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "statement_id,organization_id,period,team,feature,direct_cost_usd,allocated_cost_usd,total_cost_usd,requests,tokens" ||
+		lines[1] != "org-1:2025-05:search,org-1,2025-05,search,autocomplete,30,0,30,1,100" {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+
+	if _, err := service.GetStatements(ctx, "org-1", "2025-05", "billing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a team without spend, got %v", err)
+	}
+}
+
+func TestChargebackProportionalAllocation(t *testing.T) {
+	repo := chargebackFixture()
+	service := NewChargebackService(repo)
+	ctx := context.Background()
+
+	req := &models.ChargebackConfigRequest{Hierarchy: []string{"team"}, UntaggedPolicy: models.UntaggedPolicyProportional}
+	if _, err := service.UpdateConfig(ctx, "org-1", req, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := service.GetReport(ctx, "org-1", "", "2025-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Statements) != 2 {
+		t.Fatalf("expected untagged spend to be spread over the two teams, got %+v", report.Statements)
+	}
+
+	// search has 40 of the 60 tagged dollars, so two thirds of the 139 untagged
+	search, support := report.Statements[0], report.Statements[1]
+	if search.Team != "search" || !almostEqual(search.AllocatedCost, 139*40.0/60) || !almostEqual(search.TotalCost+support.TotalCost, 199) {
+		t.Errorf("unexpected allocation %+v, %+v", search, support)
+	}
+	if !almostEqual(search.Share+support.Share, 100) {
+		t.Errorf("expected shares to add up to 100, got %v and %v", search.Share, support.Share)
+	}
+
+	// Without tagged spend there is nothing to be proportional to
+	empty := NewChargebackService(&mockRepository{traces: repo.traces[3:], chargeback: repo.chargeback})
+	report, err = empty.GetReport(ctx, "org-1", "", "2025-05")
+	if err != nil || len(report.Statements) != 1 || report.Statements[0].Team != models.DefaultChargebackBucket {
+		t.Errorf("expected the default bucket without tagged spend, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,593 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// maxExchangeRatesPerRequest caps how many rates one call may load
+const maxExchangeRatesPerRequest = 10000
+
+// currencyCodePattern matches ISO 4217 currency codes
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// CurrencyService keeps daily exchange rates and organizations' display currencies, and
+// converts USD costs into them on read. Rates are cached in memory and reloaded on Refresh.
+type CurrencyService struct {
+	repo repository.Repository
+
+	mu         sync.RWMutex
+	rates      map[string][]models.ExchangeRate // by currency, ascending by date
+	currencies map[string]string                // display currency by organization, until the next refresh
+}
+
+// NewCurrencyService creates a currency service; call Refresh to load the rates
+func NewCurrencyService(repo repository.Repository) *CurrencyService {
+	return &CurrencyService{
+		repo:       repo,
+		rates:      make(map[string][]models.ExchangeRate),
+		currencies: make(map[string]string),
+	}
+}
+
+// Refresh reloads every exchange rate and forgets cached display currencies
+func (s *CurrencyService) Refresh(ctx context.Context) error {
+	rates, err := s.repo.ListExchangeRates(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	byCurrency := make(map[string][]models.ExchangeRate)
+	for _, rate := range rates {
+		byCurrency[rate.Currency] = append(byCurrency[rate.Currency], *rate)
+	}
+	for _, list := range byCurrency {
+		sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	}
+
+	s.mu.Lock()
+	s.rates = byCurrency
+	s.currencies = make(map[string]string)
+	s.mu.Unlock()
+	return nil
+}
+
+// Start reloads exchange rates in the background until ctx is cancelled
+func (s *CurrencyService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh exchange rates: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// LoadRatesFile loads exchange rates from a JSON array of rates, or a CSV file with a
+// currency,date,rate header, and returns how many were loaded. An empty path loads nothing.
+func (s *CurrencyService) LoadRatesFile(ctx context.Context, path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open exchange rates file: %w", err)
+	}
+	defer file.Close()
+
+	var rates []models.ExchangeRate
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(file).Decode(&rates)
+	} else {
+		rates, err = parseExchangeRatesCSV(file)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse exchange rates file: %w", err)
+	}
+
+	return s.UpsertRates(ctx, rates, "file", "file:"+filepath.Base(path))
+}
+
+// UpsertRates validates and stores exchange rates, replacing the rates of the same currency
+// and day, and returns how many were stored
+func (s *CurrencyService) UpsertRates(ctx context.Context, rates []models.ExchangeRate, source, updatedBy string) (int, error) {
+	if len(rates) == 0 {
+		return 0, invalidArgument("at least one rate is required")
+	}
+	if len(rates) > maxExchangeRatesPerRequest {
+		return 0, invalidArgument("at most %d rates can be loaded at once", maxExchangeRatesPerRequest)
+	}
+
+	now := time.Now()
+	stored := make([]*models.ExchangeRate, 0, len(rates))
+	for i, rate := range rates {
+		currency, err := normalizeCurrency(rate.Currency)
+		if err != nil {
+			return 0, fmt.Errorf("rate %d: %w", i, err)
+		}
+		if currency == models.BaseCurrency {
+			return 0, invalidArgument("rate %d: USD is the base currency", i)
+		}
+		if _, err := time.Parse("2006-01-02", rate.Date); err != nil {
+			return 0, invalidArgument("rate %d: date must be YYYY-MM-DD", i)
+		}
+		if !(rate.Rate > 0) || math.IsInf(rate.Rate, 0) {
+			return 0, invalidArgument("rate %d: rate must be a positive number", i)
+		}
+
+		if rate.Source == "" {
+			rate.Source = source
+		}
+		rate.Currency = currency
+		rate.UpdatedBy = updatedBy
+		rate.UpdatedAt = now
+		stored = append(stored, &rate)
+	}
+
+	if err := s.repo.SaveExchangeRates(ctx, stored); err != nil {
+		return 0, err
+	}
+	if err := s.Refresh(ctx); err != nil {
+		log.Printf("❌ Failed to reload exchange rates; new rates apply after the next refresh: %v", err)
+	}
+	return len(stored), nil
+}
+
+// ListRates returns the stored rates of a currency, optionally between two YYYY-MM-DD dates
+func (s *CurrencyService) ListRates(ctx context.Context, currency, startDate, endDate string) ([]*models.ExchangeRate, error) {
+	if currency != "" {
+		var err error
+		if currency, err = normalizeCurrency(currency); err != nil {
+			return nil, err
+		}
+	}
+
+	rates, err := s.repo.ListExchangeRates(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dates in YYYY-MM-DD order the same as strings
+	filtered := rates[:0]
+	for _, rate := range rates {
+		if (startDate == "" || rate.Date >= startDate) && (endDate == "" || rate.Date <= endDate) {
+			filtered = append(filtered, rate)
+		}
+	}
+	return filtered, nil
+}
+
+// GetDisplayCurrency returns the currency an organization's costs are displayed in; USD
+// when it has not chosen one
+func (s *CurrencyService) GetDisplayCurrency(ctx context.Context, orgID string) (*models.CurrencySetting, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	setting, err := s.repo.GetCurrencySetting(ctx, orgID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.CurrencySetting{OrganizationID: orgID, Currency: models.BaseCurrency}, nil
+	}
+	return setting, err
+}
+
+// SetDisplayCurrency sets the currency an organization's costs are displayed in; it must
+// have exchange rates unless it is USD
+func (s *CurrencyService) SetDisplayCurrency(ctx context.Context, orgID, currency, updatedBy string) (*models.CurrencySetting, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Converter(currency); err != nil {
+		return nil, err
+	}
+
+	setting := &models.CurrencySetting{
+		OrganizationID: orgID,
+		Currency:       currency,
+		UpdatedBy:      updatedBy,
+		UpdatedAt:      time.Now(),
+	}
+	if err := s.repo.SaveCurrencySetting(ctx, setting); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.currencies[orgID] = currency
+	s.mu.Unlock()
+	return setting, nil
+}
+
+// ResolveCurrency returns the requested display currency, or the organization's when none
+// is requested, after checking that costs can be converted into it. An explicitly requested
+// currency that can't be used is an error; an organization's that can't, for instance
+// because its rates were never loaded, falls back to USD so its reports keep working.
+func (s *CurrencyService) ResolveCurrency(ctx context.Context, orgID, requested string) (string, error) {
+	if requested != "" {
+		return s.checkCurrency(requested)
+	}
+
+	s.mu.RLock()
+	currency, ok := s.currencies[orgID]
+	s.mu.RUnlock()
+
+	if !ok {
+		setting, err := s.GetDisplayCurrency(ctx, orgID)
+		if err != nil {
+			log.Printf("⚠️  Failed to get display currency of organization %s, showing USD: %v", orgID, err)
+			return models.BaseCurrency, nil
+		}
+		currency = setting.Currency
+
+		s.mu.Lock()
+		s.currencies[orgID] = currency
+		s.mu.Unlock()
+	}
+
+	resolved, err := s.checkCurrency(currency)
+	if err != nil {
+		log.Printf("⚠️  Display currency %s of organization %s can't be used, showing USD: %v", currency, orgID, err)
+		return models.BaseCurrency, nil
+	}
+	return resolved, nil
+}
+
+// DisplayConverter returns the converter into the display currency ResolveCurrency picks,
+// or nil for USD
+func (s *CurrencyService) DisplayConverter(ctx context.Context, orgID, requested string) (*CurrencyConverter, error) {
+	currency, err := s.ResolveCurrency(ctx, orgID, requested)
+	if err != nil {
+		return nil, err
+	}
+	return s.Converter(currency)
+}
+
+// checkCurrency normalizes a currency code and checks costs can be converted into it
+func (s *CurrencyService) checkCurrency(currency string) (string, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.Converter(currency); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// Converter returns a converter from USD into a currency, or nil for USD itself
+func (s *CurrencyService) Converter(currency string) (*CurrencyConverter, error) {
+	if currency == models.BaseCurrency {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	rates := s.rates[currency]
+	s.mu.RUnlock()
+
+	if len(rates) == 0 {
+		return nil, invalidArgument("no exchange rates are loaded for %s", currency)
+	}
+	return &CurrencyConverter{Currency: currency, rates: rates}, nil
+}
+
+// CurrencyConverter converts USD amounts into a currency at the rate of the day they
+// belong to. A day without a rate uses the latest earlier one, and days before the first
+// rate use the first.
+type CurrencyConverter struct {
+	Currency string
+	rates    []models.ExchangeRate // ascending by date
+}
+
+// RateOn returns the rate for a YYYY-MM-DD date
+func (c *CurrencyConverter) RateOn(date string) float64 {
+	i := sort.Search(len(c.rates), func(i int) bool { return c.rates[i].Date > date })
+	if i == 0 {
+		return c.rates[0].Rate
+	}
+	return c.rates[i-1].Rate
+}
+
+// Rate returns the rate for the UTC day of a time
+func (c *CurrencyConverter) Rate(at time.Time) float64 {
+	return c.RateOn(at.UTC().Format("2006-01-02"))
+}
+
+// RangeRate returns the mean of the daily rates over [start, end), the rate at which
+// amounts aggregated over that range are converted
+func (c *CurrencyConverter) RangeRate(start, end time.Time) float64 {
+	start = start.UTC().Truncate(24 * time.Hour)
+	if !end.After(start) {
+		return c.Rate(start)
+	}
+
+	var sum float64
+	var days int
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		sum += c.Rate(day)
+		days++
+	}
+	return sum / float64(days)
+}
+
+// ConvertTrace fills in a trace's and its spans' costs in the converter's currency, at the
+// rate of the trace's day
+func (c *CurrencyConverter) ConvertTrace(trace *models.Trace) {
+	rate := c.Rate(trace.Timestamp)
+	total := trace.TotalCostUSD * rate
+
+	trace.Currency = c.Currency
+	trace.ExchangeRate = rate
+	trace.TotalCostConverted = &total
+	for i := range trace.Spans {
+		cost := trace.Spans[i].CostUSD * rate
+		trace.Spans[i].CostConverted = &cost
+	}
+}
+
+// ConvertCosts converts daily USD costs at each day's rate. The total, which may cover
+// more than the days listed, is converted at the days' effective rate.
+func (c *CurrencyConverter) ConvertCosts(totalUSD float64, days []models.ConvertedDailyCost) *models.CostConversion {
+	conversion := &models.CostConversion{Currency: c.Currency, DailyCosts: make([]models.ConvertedDailyCost, 0, len(days))}
+
+	var daysUSD, daysConverted float64
+	for _, day := range days {
+		day.Rate = c.RateOn(day.Date)
+		day.Cost = day.CostUSD * day.Rate
+		daysUSD += day.CostUSD
+		daysConverted += day.Cost
+		conversion.DailyCosts = append(conversion.DailyCosts, day)
+	}
+
+	// Without daily costs the range is converted at the rate of today
+	conversion.Rate = c.Rate(time.Now())
+	if daysUSD > 0 {
+		conversion.Rate = daysConverted / daysUSD
+	}
+	conversion.TotalCost = totalUSD * conversion.Rate
+	return conversion
+}
+
+// ConvertDashboard adds the dashboard's costs in the converter's currency
+func (c *CurrencyConverter) ConvertDashboard(stats *DashboardStats) {
+	days := make([]models.ConvertedDailyCost, 0, len(stats.CostByDay))
+	for _, day := range stats.CostByDay {
+		days = append(days, models.ConvertedDailyCost{Date: day.Date, CostUSD: day.Cost})
+	}
+	stats.Converted = c.ConvertCosts(stats.TotalCost, days)
+
+	for i := range stats.TopModels {
+		cost := stats.TopModels[i].Cost * stats.Converted.Rate
+		stats.TopModels[i].CostConverted = &cost
+	}
+}
+
+// ConvertCostAnalysis adds the cost analysis' costs in the converter's currency
+func (c *CurrencyConverter) ConvertCostAnalysis(analysis *CostAnalysis) {
+	var days []models.ConvertedDailyCost
+	if analysis.CostBreakdown != nil {
+		for _, day := range analysis.CostBreakdown.DailyCosts {
+			days = append(days, models.ConvertedDailyCost{Date: day.Date, CostUSD: day.TotalCost})
+		}
+	}
+	analysis.Converted = c.ConvertCosts(analysis.TotalCost, days)
+}
+
+// ConvertModelComparison adds the models' costs in the converter's currency, at the
+// effective rate over the compared range
+func (c *CurrencyConverter) ConvertModelComparison(report *ModelComparisonReport, start, end time.Time) {
+	rate := c.RangeRate(start, end)
+
+	var total float64
+	for _, model := range report.Models {
+		total += model.TotalCost
+		model.TotalCostConverted = convertedAmount(model.TotalCost, rate)
+		model.AvgCostPerRequestConverted = convertedAmount(model.AvgCostPerRequest, rate)
+		model.CostPer1KTokensConverted = convertedAmount(model.CostPer1KTokens, rate)
+	}
+	report.Converted = &models.CostConversion{
+		Currency:   c.Currency,
+		Rate:       rate,
+		TotalCost:  total * rate,
+		DailyCosts: []models.ConvertedDailyCost{},
+	}
+}
+
+// ConvertTimeSeries adds a cost series' values in the converter's currency, at the rate
+// of each bucket's day; series of other metrics are left alone
+func (c *CurrencyConverter) ConvertTimeSeries(report *TimeSeriesReport) {
+	if report.Metric != "cost" {
+		return
+	}
+
+	report.Currency = c.Currency
+	for _, series := range report.Series {
+		for i := range series.Points {
+			series.Points[i].ValueConverted = convertedAmount(series.Points[i].Value, c.Rate(series.Points[i].Timestamp))
+		}
+	}
+}
+
+// ConvertForecast adds the forecast's projections and budgets in the converter's
+// currency, at today's rate
+func (c *CurrencyConverter) ConvertForecast(forecast *CostForecast) {
+	rate := c.Rate(time.Now())
+
+	converted := &ConvertedForecast{
+		Currency: c.Currency,
+		Rate:     rate,
+		Total:    scaleForecastSeries(forecast.Total, rate),
+		Budget:   scaleBudgetForecast(forecast.Budget, rate),
+	}
+	for _, group := range forecast.Groups {
+		converted.Groups = append(converted.Groups, scaleForecastSeries(group, rate))
+	}
+	forecast.Converted = converted
+}
+
+// ConvertUserStats adds an end user's costs in the converter's currency, at the
+// effective rate over [start, end)
+func (c *CurrencyConverter) ConvertUserStats(stats *models.UserStats, start, end time.Time) {
+	rate := c.RangeRate(start, end)
+
+	stats.Currency = c.Currency
+	stats.ExchangeRate = rate
+	stats.TotalCostConverted = convertedAmount(stats.TotalCost, rate)
+	stats.AvgCostPerReqConverted = convertedAmount(stats.AvgCostPerReq, rate)
+}
+
+// ConvertUserDetail adds an end user's costs in the converter's currency: the totals at
+// the effective rate over [start, end), each bucket at the rate of its day
+func (c *CurrencyConverter) ConvertUserDetail(detail *UserDetail, start, end time.Time) {
+	if detail.Stats != nil {
+		c.ConvertUserStats(detail.Stats, start, end)
+	}
+	for _, point := range detail.TimeSeries {
+		point.TotalCostConverted = convertedAmount(point.TotalCost, c.Rate(point.Timestamp))
+	}
+}
+
+// ConvertChargeback adds a chargeback report's and its statements' costs in the
+// converter's currency, at the effective rate over the billing period
+func (c *CurrencyConverter) ConvertChargeback(report *models.ChargebackReport) {
+	rate := c.RangeRate(report.PeriodStart, report.PeriodEnd)
+
+	report.Converted = &models.CostConversion{
+		Currency:   c.Currency,
+		Rate:       rate,
+		TotalCost:  report.TotalCost * rate,
+		DailyCosts: []models.ConvertedDailyCost{},
+	}
+	for i := range report.Statements {
+		statement := &report.Statements[i]
+		statement.Currency = c.Currency
+		statement.ExchangeRate = rate
+		statement.DirectCostConverted = convertedAmount(statement.DirectCost, rate)
+		statement.AllocatedCostConverted = convertedAmount(statement.AllocatedCost, rate)
+		statement.TotalCostConverted = convertedAmount(statement.TotalCost, rate)
+		for j := range statement.Lines {
+			line := &statement.Lines[j]
+			line.DirectCostConverted = convertedAmount(line.DirectCost, rate)
+			line.AllocatedCostConverted = convertedAmount(line.AllocatedCost, rate)
+			line.TotalCostConverted = convertedAmount(line.TotalCost, rate)
+		}
+	}
+}
+
+// convertedAmount returns a USD amount converted at rate
+func convertedAmount(usd, rate float64) *float64 {
+	converted := usd * rate
+	return &converted
+}
+
+// scaleForecastSeries returns a copy of a projection with its amounts multiplied by rate
+func scaleForecastSeries(series *CostForecastSeries, rate float64) *CostForecastSeries {
+	if series == nil {
+		return nil
+	}
+
+	scaled := *series
+	scaled.MonthToDate *= rate
+	scaled.Projected *= rate
+	scaled.Lower *= rate
+	scaled.Upper *= rate
+	scaled.StdDev *= rate
+	scaled.Daily = make([]ForecastPoint, len(series.Daily))
+	for i, point := range series.Daily {
+		scaled.Daily[i] = ForecastPoint{Date: point.Date, Value: point.Value * rate, Lower: point.Lower * rate, Upper: point.Upper * rate}
+	}
+	scaled.Budget = scaleBudgetForecast(series.Budget, rate)
+	return &scaled
+}
+
+// scaleBudgetForecast returns a copy of a budget comparison with its amounts multiplied by rate
+func scaleBudgetForecast(budget *BudgetForecast, rate float64) *BudgetForecast {
+	if budget == nil {
+		return nil
+	}
+
+	scaled := *budget
+	scaled.Budget *= rate
+	scaled.MonthToDate *= rate
+	scaled.Projected *= rate
+	scaled.Remaining *= rate
+	return &scaled
+}
+
+// normalizeCurrency upper-cases a currency code and checks it is ISO 4217 shaped
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyCodePattern.MatchString(currency) {
+		return "", invalidArgument("currency must be a three-letter ISO 4217 code such as EUR")
+	}
+	return currency, nil
+}
+
+// parseExchangeRatesCSV reads rates from CSV with a currency, date and rate header; a
+// source column is optional
+func parseExchangeRatesCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"currency", "date", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	rates := make([]models.ExchangeRate, 0, len(records)-1)
+	for line, record := range records[1:] {
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line+2, record[columns["rate"]])
+		}
+		rate := models.ExchangeRate{
+			Currency: record[columns["currency"]],
+			Date:     strings.TrimSpace(record[columns["date"]]),
+			Rate:     value,
+		}
+		if i, ok := columns["source"]; ok {
+			rate.Source = record[i]
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
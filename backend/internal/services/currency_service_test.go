@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func newCurrencyFixture(t *testing.T) (*mockRepository, *CurrencyService) {
+	t.Helper()
+
+	repo := &mockRepository{}
+	service := NewCurrencyService(repo)
+	_, err := service.UpsertRates(context.Background(), []models.ExchangeRate{
+		{Currency: "eur", Date: "2025-01-01", Rate: 0.9},
+		{Currency: "EUR", Date: "2025-01-03", Rate: 0.95},
+	}, "test", "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, service
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestExchangeRateCarryForward(t *testing.T) {
+	_, service := newCurrencyFixture(t)
+
+	converter, err := service.Converter("EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]float64{
+		"2024-12-31": 0.9, // before the first rate
+		"2025-01-01": 0.9,
+		"2025-01-02": 0.9, // carried forward
+		"2025-01-03": 0.95,
+		"2025-06-01": 0.95,
+	}
+	for date, want := range cases {
+		if got := converter.RateOn(date); got != want {
+			t.Errorf("rate on %s: expected %v, got %v", date, want, got)
+		}
+	}
+
+	if converter, err := service.Converter(models.BaseCurrency); converter != nil || err != nil {
+		t.Errorf("expected no conversion for USD, got %v, %v", converter, err)
+	}
+	if _, err := service.Converter("GBP"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a currency without rates, got %v", err)
+	}
+}
+
+func TestUpsertRatesValidation(t *testing.T) {
+	service := NewCurrencyService(&mockRepository{})
+
+	invalid := [][]models.ExchangeRate{
+		nil,
+		{{Currency: "EURO", Date: "2025-01-01", Rate: 1}},
+		{{Currency: "USD", Date: "2025-01-01", Rate: 1}},
+		{{Currency: "EUR", Date: "01/01/2025", Rate: 1}},
+		{{Currency: "EUR", Date: "2025-01-01", Rate: 0}},
+		{{Currency: "EUR", Date: "2025-01-01", Rate: math.NaN()}},
+	}
+	for _, rates := range invalid {
+		if _, err := service.UpsertRates(context.Background(), rates, "test", ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("expected ErrInvalidArgument for %+v, got %v", rates, err)
+		}
+	}
+}
+
+func TestLoadRatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	csv := "currency,date,rate\nJPY,2025-01-01,157.2\nGBP,2025-01-01,0.8\n"
+	if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &mockRepository{}
+	service := NewCurrencyService(repo)
+	count, err := service.LoadRatesFile(context.Background(), path)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 rates loaded, got %d, %v", count, err)
+	}
+	if rate := repo.exchangeRates[0]; rate.Source != "file" || rate.UpdatedBy != "file:rates.csv" {
+		t.Errorf("unexpected stored rate %+v", rate)
+	}
+
+	rates, err := service.ListRates(context.Background(), "jpy", "", "")
+	if err != nil || len(rates) != 1 || rates[0].Rate != 157.2 {
+		t.Errorf("expected the JPY rate, got %+v, %v", rates, err)
+	}
+}
+
+func TestDisplayCurrency(t *testing.T) {
+	_, service := newCurrencyFixture(t)
+	ctx := context.Background()
+
+	setting, err := service.GetDisplayCurrency(ctx, "org-1")
+	if err != nil || setting.Currency != models.BaseCurrency {
+		t.Fatalf("expected USD by default, got %+v, %v", setting, err)
+	}
+
+	if _, err := service.SetDisplayCurrency(ctx, "org-1", "GBP", "admin@example.com"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected a currency without rates to be rejected, got %v", err)
+	}
+	if _, err := service.SetDisplayCurrency(ctx, "org-1", "eur", "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if currency, err := service.ResolveCurrency(ctx, "org-1", ""); err != nil || currency != "EUR" {
+		t.Errorf("expected the organization's currency, got %q, %v", currency, err)
+	}
+	if currency, err := service.ResolveCurrency(ctx, "org-1", "usd"); err != nil || currency != models.BaseCurrency {
+		t.Errorf("expected a requested currency to override the organization's, got %q, %v", currency, err)
+	}
+}
+
+func TestConvertTraceAndDashboard(t *testing.T) {
+	_, service := newCurrencyFixture(t)
+	converter, _ := service.Converter("EUR")
+
+	trace := &models.Trace{
+		TotalCostUSD: 2,
+		Timestamp:    time.Date(2025, 1, 2, 23, 0, 0, 0, time.UTC),
+		Spans:        []models.Span{{CostUSD: 1.5}, {CostUSD: 0.5}},
+	}
+	converter.ConvertTrace(trace)
+	if trace.Currency != "EUR" || trace.ExchangeRate != 0.9 || !almostEqual(*trace.TotalCostConverted, 1.8) || !almostEqual(*trace.Spans[0].CostConverted, 1.35) {
+		t.Errorf("unexpected converted trace %+v", trace)
+	}
+	if trace.TotalCostUSD != 2 {
+		t.Errorf("conversion must keep the USD cost, got %v", trace.TotalCostUSD)
+	}
+
+	stats := &DashboardStats{
+		TotalCost: 30,
+		TopModels: []ModelStats{{Model: "gpt-4", Cost: 30}},
+		CostByDay: []DailyCost{{Date: "2025-01-02", Cost: 10}, {Date: "2025-01-03", Cost: 20}},
+	}
+	converter.ConvertDashboard(stats)
+	converted := stats.Converted
+	if converted.Currency != "EUR" || len(converted.DailyCosts) != 2 || !almostEqual(converted.DailyCosts[1].Cost, 19) {
+		t.Fatalf("unexpected conversion %+v", converted)
+	}
+	if !almostEqual(converted.TotalCost, 28) || !almostEqual(converted.Rate, 28.0/30) {
+		t.Errorf("expected the total converted at each day's rate, got %+v", converted)
+	}
+	if !almostEqual(*stats.TopModels[0].CostConverted, 28) {
+		t.Errorf("expected top models at the effective rate, got %v", *stats.TopModels[0].CostConverted)
+	}
+}
+
+func TestDisplayCurrencyFallsBackToUSD(t *testing.T) {
+	repo, service := newCurrencyFixture(t)
+	ctx := context.Background()
+	if _, err := service.SetDisplayCurrency(ctx, "org-1", "EUR", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance that has not loaded the rates yet
+	fresh := NewCurrencyService(repo)
+	if currency, err := fresh.ResolveCurrency(ctx, "org-1", ""); err != nil || currency != models.BaseCurrency {
+		t.Errorf("expected the organization's currency to fall back to USD, got %q, %v", currency, err)
+	}
+	if converter, err := fresh.DisplayConverter(ctx, "org-1", ""); converter != nil || err != nil {
+		t.Errorf("expected no conversion, got %v, %v", converter, err)
+	}
+	if _, err := fresh.ResolveCurrency(ctx, "org-1", "EUR"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected a requested currency without rates to be rejected, got %v", err)
+	}
+}
+
+func TestConvertReports(t *testing.T) {
+	_, service := newCurrencyFixture(t)
+	converter, _ := service.Converter("EUR")
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 2)
+
+	if rate := converter.RangeRate(start, end); !almostEqual(rate, 0.925) {
+		t.Errorf("expected the mean of the days' rates, got %v", rate)
+	}
+
+	comparison := &ModelComparisonReport{Models: []*ModelComparison{{Model: "gpt-4", TotalCost: 10, AvgCostPerRequest: 0.1}}}
+	converter.ConvertModelComparison(comparison, start, end)
+	if !almostEqual(*comparison.Models[0].TotalCostConverted, 9.25) || !almostEqual(comparison.Converted.TotalCost, 9.25) {
+		t.Errorf("unexpected converted models %+v", comparison.Models[0])
+	}
+
+	series := &TimeSeriesReport{Metric: "cost", Series: []*models.TimeSeries{{Points: []models.TimeSeriesValue{
+		{Timestamp: start, Value: 2}, {Timestamp: start.AddDate(0, 0, 1), Value: 2},
+	}}}}
+	converter.ConvertTimeSeries(series)
+	if points := series.Series[0].Points; series.Currency != "EUR" || !almostEqual(*points[0].ValueConverted, 1.8) || !almostEqual(*points[1].ValueConverted, 1.9) {
+		t.Errorf("expected each bucket at its day's rate, got %+v", points)
+	}
+	requests := &TimeSeriesReport{Metric: "requests", Series: []*models.TimeSeries{{Points: []models.TimeSeriesValue{{Timestamp: start, Value: 2}}}}}
+	converter.ConvertTimeSeries(requests)
+	if requests.Series[0].Points[0].ValueConverted != nil {
+		t.Error("expected series of other metrics to be left alone")
+	}
+
+	forecast := &CostForecast{
+		Total:  &CostForecastSeries{Projected: 100, Daily: []ForecastPoint{{Value: 10}}},
+		Budget: &BudgetForecast{Budget: 200, Projected: 100},
+	}
+	converter.ConvertForecast(forecast)
+	if converted := forecast.Converted; !almostEqual(converted.Total.Projected, 95) || !almostEqual(converted.Total.Daily[0].Value, 9.5) || !almostEqual(converted.Budget.Budget, 190) {
+		t.Errorf("expected the forecast at today's rate, got %+v", converted)
+	}
+	if forecast.Total.Projected != 100 || forecast.Total.Daily[0].Value != 10 {
+		t.Errorf("conversion must keep the USD forecast, got %+v", forecast.Total)
+	}
+
+	report := &models.ChargebackReport{
+		PeriodStart: start, PeriodEnd: end, TotalCost: 20,
+		Statements: []models.ChargebackStatement{{Team: "search", TotalCost: 20, Lines: []models.ChargebackLine{{TotalCost: 20}}}},
+	}
+	converter.ConvertChargeback(report)
+	statement := report.Statements[0]
+	if statement.Currency != "EUR" || !almostEqual(*statement.TotalCostConverted, 18.5) || !almostEqual(*statement.Lines[0].TotalCostConverted, 18.5) {
+		t.Errorf("unexpected converted statement %+v", statement)
+	}
+	var buf bytes.Buffer
+	if err := WriteChargebackCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); !strings.HasSuffix(lines[0], ",currency,exchange_rate,direct_cost_converted,allocated_cost_converted,total_cost_converted") ||
+		!strings.HasSuffix(lines[1], ",EUR,0.925,0,0,18.5") {
+		t.Errorf("expected the converted columns, got %q", buf.String())
+	}
+}
+
+func TestExportConvertsCurrency(t *testing.T) {
+	repo, currency := newCurrencyFixture(t)
+	repo.traces = exportFixture()
+	service := NewExportService(repo, NewJobManager(time.Hour), t.TempDir(), currency)
+
+	query := &models.TraceQuery{OrganizationID: "org-1", Currency: "eur"}
+	if err := service.ResolveCurrency(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := service.Export(context.Background(), query, ExportFormatCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasSuffix(lines[0], ",cost_usd,span_status,error_message,input,output,currency,exchange_rate,trace_total_cost_converted,span_cost_converted") {
+		t.Errorf("expected the currency columns in the header, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], ",EUR,0.95,") {
+		t.Errorf("expected converted costs in %q", lines[1])
+	}
+
+	query = &models.TraceQuery{OrganizationID: "org-1", Currency: "GBP"}
+	if err := service.ResolveCurrency(context.Background(), query); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a currency without rates, got %v", err)
+	}
+}
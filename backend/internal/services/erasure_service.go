@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// JobTypeErasure identifies end-user erasure jobs in the job manager
+const JobTypeErasure = "erasure"
+
+// ErasureService handles right-to-erasure requests and keeps their audit trail
+type ErasureService struct {
+	repo repository.Repository
+	jobs *JobManager
+}
+
+// NewErasureService creates a new erasure service
+func NewErasureService(repo repository.Repository, jobs *JobManager) *ErasureService {
+	return &ErasureService{
+		repo: repo,
+		jobs: jobs,
+	}
+}
+
+// DeleteTrace records an erasure request for a single trace and runs it in the background.
+// The returned audit record can be polled with GetErasure until it completes.
+func (s *ErasureService) DeleteTrace(ctx context.Context, orgID, traceID, requestedBy string) (*models.ErasureRecord, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	filter := &models.ErasureFilter{OrganizationID: orgID, TraceID: traceID}
+	counts, err := s.repo.CountErasure(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if counts.Traces == 0 {
+		return nil, fmt.Errorf("trace %s: %w", traceID, repository.ErrNotFound)
+	}
+
+	record := newErasureRecord(orgID, models.ErasureSubjectTrace, traceID, requestedBy)
+	if err := s.repo.SaveErasureRecord(ctx, record); err != nil {
+		return nil, err
+	}
+
+	// The job owns record from here on; callers get a copy
+	snapshot := *record
+	s.run(record, filter)
+	return &snapshot, nil
+}
+
+// StartUserErasure records an erasure request for an end user and runs it in the background.
+// The returned audit record can be polled with GetErasure until it completes.
+func (s *ErasureService) StartUserErasure(ctx context.Context, orgID, userID, requestedBy string) (*models.ErasureRecord, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if userID == "" {
+		return nil, invalidArgument("user_id is required")
+	}
+
+	record := newErasureRecord(orgID, models.ErasureSubjectUser, hashSubject(userID), requestedBy)
+	if err := s.repo.SaveErasureRecord(ctx, record); err != nil {
+		return nil, err
+	}
+
+	// The job owns record from here on; callers get a copy
+	snapshot := *record
+	s.run(record, &models.ErasureFilter{OrganizationID: orgID, UserID: userID})
+	return &snapshot, nil
+}
+
+// Start rebuilds, every interval, the aggregate buckets that erasures and cost backfills
+// touched while they were still open. Exactly one instance sharing a database should run it.
+func (s *ErasureService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.repo.RebuildPendingAggregates(ctx); err != nil {
+				log.Printf("❌ Failed to rebuild pending aggregates: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ResumeUnfinished restarts the erasures a previous process left pending or running, so
+// no subject is left partly erased. It is meant to be called once at startup.
+func (s *ErasureService) ResumeUnfinished(ctx context.Context) error {
+	records, err := s.repo.ListUnfinishedErasureRecords(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		filter := &models.ErasureFilter{OrganizationID: record.OrganizationID}
+		switch record.SubjectType {
+		case models.ErasureSubjectUser:
+			// Only the hash of the user ID is stored; the traces are matched on it
+			filter.UserIDHash = record.SubjectID
+		case models.ErasureSubjectTrace:
+			filter.TraceID = record.SubjectID
+		default:
+			log.Printf("❌ Cannot resume erasure %s of unknown subject type %q", record.ID, record.SubjectType)
+			continue
+		}
+
+		log.Printf("🔁 Resuming %s erasure %s", record.SubjectType, record.ID)
+		s.run(record, filter)
+	}
+	return nil
+}
+
+// run erases the traces selected by filter in a background job
+func (s *ErasureService) run(record *models.ErasureRecord, filter *models.ErasureFilter) {
+	params := map[string]interface{}{"erasure_id": record.ID}
+
+	s.jobs.Start(record.OrganizationID, JobTypeErasure, params, func(ctx context.Context, progress JobProgress) error {
+		counts, err := s.repo.CountErasure(ctx, filter)
+		if err != nil {
+			return s.fail(record, err)
+		}
+		progress.SetTotal(counts.Traces)
+
+		if err := s.erase(ctx, record, filter, counts); err != nil {
+			return err
+		}
+		progress.Add(counts.Traces, 0)
+		return nil
+	})
+}
+
+// GetErasure returns the audit record of an erasure request
+func (s *ErasureService) GetErasure(ctx context.Context, orgID, id string) (*models.ErasureRecord, error) {
+	return s.repo.GetErasureRecord(ctx, orgID, id)
+}
+
+// ListErasures returns an organization's erasure audit records, newest first
+func (s *ErasureService) ListErasures(ctx context.Context, orgID string, limit int) ([]*models.ErasureRecord, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	return s.repo.ListErasureRecords(ctx, orgID, limit)
+}
+
+// erase deletes the selected traces, recording each status change in the audit log
+func (s *ErasureService) erase(ctx context.Context, record *models.ErasureRecord, filter *models.ErasureFilter, counts *models.ErasureCounts) error {
+	record.Status = JobRunning
+	if err := s.repo.SaveErasureRecord(ctx, record); err != nil {
+		return err
+	}
+
+	if err := s.repo.EraseTraces(ctx, filter); err != nil {
+		return s.fail(record, err)
+	}
+
+	now := time.Now()
+	record.Status = JobCompleted
+	record.TracesDeleted = counts.Traces
+	record.SpansDeleted = counts.Spans
+	record.CompletedAt = &now
+
+	// The data is already gone, so a failed audit write must not report the erasure as failed
+	if err := s.repo.SaveErasureRecord(context.Background(), record); err != nil {
+		log.Printf("❌ Failed to record completion of erasure %s: %v", record.ID, err)
+	}
+	return nil
+}
+
+// fail marks an erasure as failed in the audit log and returns the cause
+func (s *ErasureService) fail(record *models.ErasureRecord, cause error) error {
+	now := time.Now()
+	record.Status = JobFailed
+	record.Error = cause.Error()
+	record.CompletedAt = &now
+
+	if err := s.repo.SaveErasureRecord(context.Background(), record); err != nil {
+		log.Printf("❌ Failed to record failure of erasure %s: %v", record.ID, err)
+	}
+	return fmt.Errorf("erasure %s failed: %w", record.ID, cause)
+}
+
+// newErasureRecord creates a pending audit record
+func newErasureRecord(orgID, subjectType, subjectID, requestedBy string) *models.ErasureRecord {
+	return &models.ErasureRecord{
+		ID:             uuid.New().String(),
+		OrganizationID: orgID,
+		SubjectType:    subjectType,
+		SubjectID:      subjectID,
+		RequestedBy:    requestedBy,
+		Status:         JobPending,
+		RequestedAt:    time.Now(),
+	}
+}
+
+// hashSubject hashes an end-user ID for the audit log
+func hashSubject(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+func TestDeleteTrace(t *testing.T) {
+	repo := &mockRepository{traces: []*models.Trace{
+		{TraceID: "t-1", OrganizationID: "org-1", Spans: []models.Span{{SpanID: "s-1"}, {SpanID: "s-2"}}},
+	}}
+	jobs := NewJobManager(time.Hour)
+	service := NewErasureService(repo, jobs)
+
+	if _, err := service.DeleteTrace(context.Background(), "org-2", "t-1", "user:u"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another organization's trace, got %v", err)
+	}
+	if len(repo.erasures) != 0 {
+		t.Fatal("nothing should be erased for a trace outside the organization")
+	}
+
+	record, err := service.DeleteTrace(context.Background(), "org-1", "t-1", "user:u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != JobPending || record.SubjectID != "t-1" {
+		t.Errorf("unexpected audit record %+v", record)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		list := jobs.List("org-1", JobTypeErasure)
+		if len(list) == 1 && list[0].Done() {
+			if list[0].Status != JobCompleted {
+				t.Fatalf("unexpected job state %+v", list[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("erasure job did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(repo.erasures) != 1 || repo.erasures[0].TraceID != "t-1" {
+		t.Errorf("expected trace t-1 to be erased, got %+v", repo.erasures)
+	}
+
+	var statuses []string
+	for _, r := range repo.erasureRecords {
+		statuses = append(statuses, r.Status)
+	}
+	if strings.Join(statuses, ",") != "pending,running,completed" {
+		t.Errorf("expected pending, running then completed audit versions, got %v", statuses)
+	}
+	if last := repo.erasureRecords[len(repo.erasureRecords)-1]; last.TracesDeleted != 1 || last.SpansDeleted != 2 {
+		t.Errorf("unexpected final audit record %+v", last)
+	}
+}
+
+func TestStartUserErasure(t *testing.T) {
+	repo := &mockRepository{traces: []*models.Trace{
+		{TraceID: "t-1", OrganizationID: "org-1", UserID: "alice"},
+		{TraceID: "t-2", OrganizationID: "org-1", UserID: "alice"},
+		{TraceID: "t-3", OrganizationID: "org-1", UserID: "bob"},
+	}}
+	jobs := NewJobManager(time.Hour)
+	service := NewErasureService(repo, jobs)
+
+	record, err := service.StartUserErasure(context.Background(), "org-1", "alice", "api_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != JobPending || strings.Contains(record.SubjectID, "alice") || !strings.HasPrefix(record.SubjectID, "sha256:") {
+		t.Errorf("audit record must be pending and not contain the raw user id: %+v", record)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		list := jobs.List("org-1", JobTypeErasure)
+		if len(list) == 1 && list[0].Done() {
+			if list[0].Status != JobCompleted || list[0].Processed != 2 {
+				t.Fatalf("unexpected job state %+v", list[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("erasure job did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	last := repo.erasureRecords[len(repo.erasureRecords)-1]
+	if last.ID != record.ID || last.Status != JobCompleted || last.TracesDeleted != 2 {
+		t.Errorf("unexpected final audit record %+v", last)
+	}
+
+	if _, err := service.StartUserErasure(context.Background(), "org-1", "", "api_key"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for missing user_id, got %v", err)
+	}
+}
+
+func TestResumeUnfinishedErasures(t *testing.T) {
+	repo := &mockRepository{
+		traces: []*models.Trace{
+			{TraceID: "t-1", OrganizationID: "org-1", UserID: "alice"},
+			{TraceID: "t-2", OrganizationID: "org-1", UserID: "bob"},
+		},
+		erasureRecords: []models.ErasureRecord{
+			{ID: "e-1", OrganizationID: "org-1", SubjectType: models.ErasureSubjectUser, SubjectID: hashSubject("alice"), Status: JobPending},
+			{ID: "e-1", OrganizationID: "org-1", SubjectType: models.ErasureSubjectUser, SubjectID: hashSubject("alice"), Status: JobRunning},
+			{ID: "e-2", OrganizationID: "org-1", SubjectType: models.ErasureSubjectTrace, SubjectID: "t-9", Status: JobCompleted},
+		},
+	}
+	jobs := NewJobManager(time.Hour)
+	service := NewErasureService(repo, jobs)
+
+	if err := service.ResumeUnfinished(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		list := jobs.List("org-1", JobTypeErasure)
+		if len(list) == 1 && list[0].Done() {
+			if list[0].Status != JobCompleted || list[0].Processed != 1 {
+				t.Fatalf("expected alice's trace to be erased, got %+v", list[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one resumed erasure job, got %+v", list)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(repo.erasures) != 1 || repo.erasures[0].UserIDHash != hashSubject("alice") || repo.erasures[0].UserID != "" {
+		t.Errorf("expected the erasure to be resumed by the hashed user id, got %+v", repo.erasures)
+	}
+}
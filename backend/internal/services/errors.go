@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidArgument marks errors caused by bad caller input rather than backend failures
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrNotReady marks requests for results of work that has not finished yet
+var ErrNotReady = errors.New("not ready")
+
+// ErrBudgetExceeded marks calls rejected because a hard budget is used up
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// ErrQuotaExceeded marks traces rejected because a quota of the organization's plan is used up
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// invalidArgument builds an error that wraps ErrInvalidArgument
+func invalidArgument(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArgument, fmt.Sprintf(format, args...))
+}
+
+// errJobPanicked is recorded when a background job panics
+var errJobPanicked = errors.New("job panicked")
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// Export formats
+const (
+	ExportFormatJSON  = "json"
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+	ExportFormatOTLP  = "otlp"
+)
+
+// JobTypeExport identifies trace export jobs in the job manager
+const JobTypeExport = "export"
+
+// exportFormats maps each format to its content type and file extension
+var exportFormats = map[string]struct {
+	contentType string
+	extension   string
+}{
+	ExportFormatJSON:  {"application/json", "json"},
+	ExportFormatJSONL: {"application/x-ndjson", "jsonl"},
+	ExportFormatCSV:   {"text/csv", "csv"},
+	ExportFormatOTLP:  {"application/json", "otlp.json"},
+}
+
+// ExportService streams traces out of the repository in interchange formats
+type ExportService struct {
+	repo      repository.Repository
+	jobs      *JobManager
+	exportDir string
+	currency  *CurrencyService // optional; exports are in USD only without it
+
+	mu    sync.Mutex
+	files map[string]string // export file paths by job ID; kept out of the job results
+}
+
+// NewExportService creates a new export service writing job output under exportDir
+func NewExportService(repo repository.Repository, jobs *JobManager, exportDir string, currency *CurrencyService) *ExportService {
+	s := &ExportService{
+		repo:      repo,
+		jobs:      jobs,
+		exportDir: exportDir,
+		currency:  currency,
+		files:     make(map[string]string),
+	}
+
+	// Delete export files once their job is forgotten
+	jobs.OnExpire(func(job *Job) {
+		if job.Type != JobTypeExport {
+			return
+		}
+		s.mu.Lock()
+		path, ok := s.files[job.ID]
+		delete(s.files, job.ID)
+		s.mu.Unlock()
+		if ok {
+			os.Remove(path)
+		}
+	})
+
+	// Job records don't survive a restart, so files left by a previous run can't be downloaded
+	s.removeStaleFiles()
+
+	return s
+}
+
+// removeStaleFiles deletes export files older than the job retention period. Newer files
+// are kept in case another instance sharing the directory still serves them.
+func (s *ExportService) removeStaleFiles() {
+	entries, err := os.ReadDir(s.exportDir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.jobs.retention)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.exportDir, entry.Name())); err != nil {
+			log.Printf("⚠️  Failed to remove stale export file %s: %v", entry.Name(), err)
+		}
+	}
+}
+
+// ContentType returns the HTTP content type and file name for an export format
+func (s *ExportService) ContentType(format string) (contentType, filename string, err error) {
+	info, ok := exportFormats[format]
+	if !ok {
+		return "", "", invalidArgument("unsupported export format %q: must be json, jsonl, csv or otlp", format)
+	}
+	return info.contentType, "traces-" + time.Now().UTC().Format("20060102-150405") + "." + info.extension, nil
+}
+
+// Export writes every trace matching the query to w and returns the number of traces written
+func (s *ExportService) Export(ctx context.Context, query *models.TraceQuery, format string, w io.Writer) (int64, error) {
+	return s.export(ctx, query, format, w, nil)
+}
+
+// ValidateExport checks an export's format and filters before anything is written
+func (s *ExportService) ValidateExport(query *models.TraceQuery, format string) error {
+	return s.validateExport(query, format)
+}
+
+// ResolveCurrency sets the query's display currency to the requested one, or the
+// organization's when none is requested, and checks costs can be converted into it
+func (s *ExportService) ResolveCurrency(ctx context.Context, query *models.TraceQuery) error {
+	if s.currency == nil {
+		if query.Currency != "" && query.Currency != models.BaseCurrency {
+			return invalidArgument("currency conversion is not enabled")
+		}
+		return nil
+	}
+
+	currency, err := s.currency.ResolveCurrency(ctx, query.OrganizationID, query.Currency)
+	if err != nil {
+		return err
+	}
+	query.Currency = currency
+	return nil
+}
+
+// StartExportJob writes a large export to a file in the background
+func (s *ExportService) StartExportJob(query *models.TraceQuery, format string) (*Job, error) {
+	if err := s.validateExport(query, format); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	params := map[string]interface{}{
+		"format":     format,
+		"project_id": query.ProjectID,
+		"start_time": query.StartTime,
+		"end_time":   query.EndTime,
+		"model":      query.Model,
+		"provider":   query.Provider,
+		"status":     query.Status,
+		"user_id":    query.UserID,
+		"currency":   query.Currency,
+	}
+
+	_, filename, _ := s.ContentType(format)
+	path := filepath.Join(s.exportDir, fmt.Sprintf("%s-%d-%s", query.OrganizationID, time.Now().UnixNano(), filename))
+
+	job := s.jobs.Start(query.OrganizationID, JobTypeExport, params, func(ctx context.Context, progress JobProgress) error {
+		if total, err := s.repo.GetTraceCount(ctx, query); err == nil {
+			if query.Limit > 0 && total > int64(query.Limit) {
+				total = int64(query.Limit)
+			}
+			progress.SetTotal(total)
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+
+		buffered := bufio.NewWriter(file)
+		count, err := s.export(ctx, query, format, buffered, progress)
+		if err == nil {
+			err = buffered.Flush()
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+
+		info, _ := file.Stat()
+		progress.SetResult("filename", filename)
+		progress.SetResult("traces", count)
+		if info != nil {
+			progress.SetResult("bytes", info.Size())
+		}
+		return nil
+	})
+
+	s.mu.Lock()
+	s.files[job.ID] = path
+	s.mu.Unlock()
+
+	return job, nil
+}
+
+// GetExportJob returns the state of an export job
+func (s *ExportService) GetExportJob(orgID, jobID string) (*Job, error) {
+	job, err := s.jobs.Get(orgID, jobID)
+	if err != nil || job.Type != JobTypeExport {
+		return nil, fmt.Errorf("export job %s: %w", jobID, repository.ErrNotFound)
+	}
+	return job, nil
+}
+
+// ListExportJobs returns an organization's export jobs, newest first
+func (s *ExportService) ListExportJobs(orgID string) []*Job {
+	return s.jobs.List(orgID, JobTypeExport)
+}
+
+// ExportFile returns the path and download name of a finished export job
+func (s *ExportService) ExportFile(orgID, jobID string) (path, filename string, err error) {
+	job, err := s.GetExportJob(orgID, jobID)
+	if err != nil {
+		return "", "", err
+	}
+	if job.Status != JobCompleted {
+		return "", "", fmt.Errorf("%w: export job is %s", ErrNotReady, job.Status)
+	}
+
+	s.mu.Lock()
+	path, ok := s.files[job.ID]
+	s.mu.Unlock()
+	if !ok {
+		return "", "", fmt.Errorf("export file of job %s: %w", jobID, repository.ErrNotFound)
+	}
+	filename, _ = job.Result["filename"].(string)
+	return path, filename, nil
+}
+
+// export streams traces through the writer for the requested format
+func (s *ExportService) export(ctx context.Context, query *models.TraceQuery, format string, w io.Writer, progress JobProgress) (int64, error) {
+	if err := s.validateExport(query, format); err != nil {
+		return 0, err
+	}
+
+	var converter *CurrencyConverter
+	if query.Currency != "" && query.Currency != models.BaseCurrency {
+		if s.currency == nil {
+			return 0, invalidArgument("currency conversion is not enabled")
+		}
+		var err error
+		if converter, err = s.currency.Converter(query.Currency); err != nil {
+			return 0, err
+		}
+	}
+
+	writer := newTraceWriter(format, w, converter != nil)
+
+	var count int64
+	err := s.repo.StreamTraces(ctx, query, func(trace *models.Trace) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if converter != nil {
+			converter.ConvertTrace(trace)
+		}
+		if err := writer.WriteTrace(trace); err != nil {
+			return fmt.Errorf("failed to write trace %s: %w", trace.TraceID, err)
+		}
+		count++
+		if progress != nil {
+			progress.Add(1, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
+
+// validateExport checks the export format and required filters
+func (s *ExportService) validateExport(query *models.TraceQuery, format string) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if _, ok := exportFormats[format]; !ok {
+		return invalidArgument("unsupported export format %q: must be json, jsonl, csv or otlp", format)
+	}
+	if !query.StartTime.IsZero() && !query.EndTime.IsZero() && query.EndTime.Before(query.StartTime) {
+		return invalidArgument("end_time must be after start_time")
+	}
+	return nil
+}
+
+// traceWriter serializes traces one at a time
+type traceWriter interface {
+	WriteTrace(trace *models.Trace) error
+	Close() error
+}
+
+// newTraceWriter returns the writer for a validated export format; converted adds the
+// display currency columns to CSV
+func newTraceWriter(format string, w io.Writer, converted bool) traceWriter {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVTraceWriter(w, converted)
+	case ExportFormatJSONL:
+		return &jsonlTraceWriter{encoder: json.NewEncoder(w)}
+	case ExportFormatOTLP:
+		return &jsonArrayTraceWriter{w: w, prefix: `{"resourceSpans":[`, suffix: "]}\n", convert: func(t *models.Trace) interface{} {
+			return traceToOTLP(t)
+		}}
+	default:
+		return &jsonArrayTraceWriter{w: w, prefix: "[", suffix: "]\n", convert: func(t *models.Trace) interface{} {
+			return t
+		}}
+	}
+}
+
+// jsonlTraceWriter writes one full trace with spans per line
+type jsonlTraceWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlTraceWriter) WriteTrace(trace *models.Trace) error {
+	return j.encoder.Encode(trace)
+}
+
+func (j *jsonlTraceWriter) Close() error {
+	return nil
+}
+
+// jsonArrayTraceWriter streams elements into a JSON array wrapped in prefix/suffix
+type jsonArrayTraceWriter struct {
+	w       io.Writer
+	prefix  string
+	suffix  string
+	convert func(*models.Trace) interface{}
+	count   int
+}
+
+func (j *jsonArrayTraceWriter) WriteTrace(trace *models.Trace) error {
+	separator := ","
+	if j.count == 0 {
+		separator = j.prefix
+	}
+
+	data, err := json.Marshal(j.convert(trace))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+
+	j.count++
+	return nil
+}
+
+func (j *jsonArrayTraceWriter) Close() error {
+	if j.count == 0 {
+		if _, err := io.WriteString(j.w, j.prefix); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(j.w, j.suffix)
+	return err
+}
+
+// csvColumns are the flattened span columns written by the CSV exporter
+var csvColumns = []string{
+	"trace_id", "trace_timestamp", "organization_id", "project_id", "user_id",
+	"trace_type", "trace_status", "trace_model", "trace_provider", "trace_duration_ms",
+	"trace_total_tokens", "trace_total_cost_usd",
+	"span_id", "parent_span_id", "span_name", "span_model", "span_provider",
+	"start_time", "end_time", "duration_ms", "prompt_tokens", "completion_tokens",
+	"total_tokens", "cost_usd", "span_status", "error_message", "input", "output",
+}
+
+// csvCurrencyColumns are appended when costs are converted into a display currency
+var csvCurrencyColumns = []string{
+	"currency", "exchange_rate", "trace_total_cost_converted", "span_cost_converted",
+}
+
+// csvTraceWriter writes one row per span, repeating the trace columns
+type csvTraceWriter struct {
+	writer      *csv.Writer
+	columns     []string
+	converted   bool
+	wroteHeader bool
+}
+
+func newCSVTraceWriter(w io.Writer, converted bool) *csvTraceWriter {
+	columns := csvColumns
+	if converted {
+		columns = append(append([]string{}, csvColumns...), csvCurrencyColumns...)
+	}
+	return &csvTraceWriter{writer: csv.NewWriter(w), columns: columns, converted: converted}
+}
+
+func (c *csvTraceWriter) WriteTrace(trace *models.Trace) error {
+	if !c.wroteHeader {
+		if err := c.writer.Write(c.columns); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+
+	traceColumns := []string{
+		trace.TraceID,
+		trace.Timestamp.UTC().Format(time.RFC3339Nano),
+		trace.OrganizationID,
+		trace.ProjectID,
+		trace.UserID,
+		trace.TraceType,
+		trace.Status,
+		trace.Model,
+		trace.Provider,
+		strconv.FormatInt(trace.DurationMs, 10),
+		strconv.Itoa(trace.TotalTokens),
+		strconv.FormatFloat(trace.TotalCostUSD, 'f', -1, 64),
+	}
+
+	if len(trace.Spans) == 0 {
+		row := append(traceColumns, make([]string, len(csvColumns)-len(traceColumns))...)
+		if c.converted {
+			row = append(row, trace.Currency, formatCost(trace.ExchangeRate), formatCostPtr(trace.TotalCostConverted), "")
+		}
+		if err := c.writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for _, span := range trace.Spans {
+		row := append(append([]string{}, traceColumns...),
+			span.SpanID,
+			span.ParentSpanID,
+			span.Name,
+			span.Model,
+			span.Provider,
+			span.StartTime.UTC().Format(time.RFC3339Nano),
+			span.EndTime.UTC().Format(time.RFC3339Nano),
+			strconv.FormatInt(span.DurationMs, 10),
+			strconv.Itoa(span.PromptTokens),
+			strconv.Itoa(span.CompletionTokens),
+			strconv.Itoa(span.TotalTokens),
+			strconv.FormatFloat(span.CostUSD, 'f', -1, 64),
+			span.Status,
+			span.ErrorMessage,
+			span.Input,
+			span.Output,
+		)
+		if c.converted {
+			row = append(row, trace.Currency, formatCost(trace.ExchangeRate), formatCostPtr(trace.TotalCostConverted), formatCostPtr(span.CostConverted))
+		}
+		if err := c.writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvTraceWriter) Close() error {
+	if !c.wroteHeader {
+		if err := c.writer.Write(c.columns); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// formatCost formats an amount for CSV without losing precision
+func formatCost(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatCostPtr formats an optional amount, empty when unset
+func formatCostPtr(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return formatCost(*value)
+}
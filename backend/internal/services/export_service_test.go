@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func exportFixture() []*models.Trace {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*models.Trace{
+		{
+			TraceID:        "7f1c2a4e-0000-4000-8000-000000000001",
+			OrganizationID: "org-1",
+			ProjectID:      "proj-1",
+			Timestamp:      start,
+			TraceType:      "agent",
+			Status:         "success",
+			Model:          "gpt-4",
+			Provider:       "openai",
+			Spans: []models.Span{
+				{SpanID: "span-a", Name: "plan", StartTime: start, EndTime: start.Add(time.Second), Model: "gpt-4", Provider: "openai", PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Status: "success"},
+				{SpanID: "span-b", ParentSpanID: "span-a", Name: "tool", StartTime: start, EndTime: start.Add(time.Second), Status: "error", ErrorMessage: "boom"},
+			},
+		},
+		{
+			TraceID:        "trace-2",
+			OrganizationID: "org-1",
+			Timestamp:      start,
+			Status:         "success",
+		},
+	}
+}
+
+func TestExport(t *testing.T) {
+	service := NewExportService(&mockRepository{traces: exportFixture()}, NewJobManager(time.Hour), t.TempDir(), nil)
+	query := &models.TraceQuery{OrganizationID: "org-1"}
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := service.Export(context.Background(), query, ExportFormatJSONL, &buf)
+		if err != nil || count != 2 {
+			t.Fatalf("Export() = %d, %v", count, err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %d", len(lines))
+		}
+		var trace models.Trace
+		if err := json.Unmarshal([]byte(lines[0]), &trace); err != nil || len(trace.Spans) != 2 {
+			t.Errorf("first line did not decode to a trace with spans: %v", err)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := service.Export(context.Background(), query, ExportFormatJSON, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var traces []models.Trace
+		if err := json.Unmarshal(buf.Bytes(), &traces); err != nil || len(traces) != 2 {
+			t.Errorf("expected a JSON array of 2 traces, got %d (%v)", len(traces), err)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := service.Export(context.Background(), query, ExportFormatCSV, &buf); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Header, one row per span of the first trace, one empty-span row for the second
+		if len(records) != 4 {
+			t.Fatalf("expected 4 records, got %d", len(records))
+		}
+		if records[2][len(csvColumns)-3] != "boom" {
+			t.Errorf("expected error_message column to be exported, got %q", records[2][len(csvColumns)-3])
+		}
+	})
+
+	t.Run("otlp", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := service.Export(context.Background(), query, ExportFormatOTLP, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var data OTLPTracesData
+		if err := json.Unmarshal(buf.Bytes(), &data); err != nil || len(data.ResourceSpans) != 2 {
+			t.Fatalf("expected 2 resourceSpans, got %v", err)
+		}
+		spans := data.ResourceSpans[0].ScopeSpans[0].Spans
+		if spans[0].TraceID != "7f1c2a4e000040008000000000000001" {
+			t.Errorf("unexpected OTLP trace id %q", spans[0].TraceID)
+		}
+		if len(spans[1].SpanID) != otlpSpanIDHexLength || spans[1].ParentSpanID != spans[0].SpanID {
+			t.Errorf("span ids not mapped consistently: %+v", spans[1])
+		}
+		if spans[1].Status.Code != otlpStatusError {
+			t.Errorf("expected error status, got %d", spans[1].Status.Code)
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		empty := NewExportService(&mockRepository{}, NewJobManager(time.Hour), t.TempDir(), nil)
+		var buf bytes.Buffer
+		if _, err := empty.Export(context.Background(), query, ExportFormatJSON, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(buf.String()) != "[]" {
+			t.Errorf("expected empty array, got %q", buf.String())
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := service.Export(context.Background(), query, "xml", &bytes.Buffer{})
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("expected ErrInvalidArgument, got %v", err)
+		}
+	})
+}
+
+func TestExportServiceRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "org-1-1-traces.jsonl")
+	fresh := filepath.Join(dir, "org-1-2-traces.jsonl")
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	NewExportService(&mockRepository{}, NewJobManager(time.Hour), dir, nil)
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the stale export file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("expected the recent export file to be kept, got %v", err)
+	}
+}
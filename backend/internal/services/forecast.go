@@ -0,0 +1,203 @@
+package services
+
+import "math"
+
+// Forecasting methods, from most to least informed
+const (
+	ForecastHoltWinters = "holt_winters"
+	ForecastHolt        = "holt"
+	ForecastAverage     = "average"
+)
+
+// weeklySeason is the length of the weekly cycle in daily data
+const weeklySeason = 7
+
+// Smoothing parameters tried when fitting a model
+var (
+	forecastAlphas = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	forecastBetas  = []float64{0, 0.05, 0.1, 0.2, 0.3}
+	forecastGammas = []float64{0.05, 0.1, 0.2, 0.3, 0.5}
+)
+
+// forecastModel is an additive exponential smoothing model fitted to a daily series
+type forecastModel struct {
+	method   string
+	level    float64
+	trend    float64
+	seasonal []float64 // indexed by position in the series modulo the season; nil without seasonality
+	n        int       // number of observations fitted
+	alpha    float64
+	beta     float64
+	gamma    float64
+	sigma    float64 // standard deviation of the one-step-ahead errors
+}
+
+// fitForecastModel fits the richest model the history supports: Holt-Winters with weekly
+// seasonality from two full weeks, Holt's linear trend from three days, and the mean otherwise.
+// Parameters are chosen by minimising the one-step-ahead squared error.
+func fitForecastModel(values []float64) *forecastModel {
+	switch {
+	case len(values) >= 2*weeklySeason:
+		return fitBestModel(values, forecastGammas, fitHoltWinters)
+	case len(values) >= 3:
+		return fitBestModel(values, []float64{0}, func(values []float64, alpha, beta, _ float64) (*forecastModel, float64) {
+			return fitHolt(values, alpha, beta)
+		})
+	default:
+		return fitAverage(values)
+	}
+}
+
+// fitBestModel grid-searches the smoothing parameters of a model
+func fitBestModel(values []float64, gammas []float64, fit func(values []float64, alpha, beta, gamma float64) (*forecastModel, float64)) *forecastModel {
+	var best *forecastModel
+	bestSSE := math.Inf(1)
+
+	for _, alpha := range forecastAlphas {
+		for _, beta := range forecastBetas {
+			for _, gamma := range gammas {
+				model, sse := fit(values, alpha, beta, gamma)
+				if sse < bestSSE {
+					best, bestSSE = model, sse
+				}
+			}
+		}
+	}
+	return best
+}
+
+// fitHoltWinters runs additive Holt-Winters over the series, initialised from the first two weeks
+func fitHoltWinters(values []float64, alpha, beta, gamma float64) (*forecastModel, float64) {
+	m := weeklySeason
+	first, second := mean(values[:m]), mean(values[m:2*m])
+
+	model := &forecastModel{
+		method:   ForecastHoltWinters,
+		level:    first,
+		trend:    (second - first) / float64(m),
+		seasonal: make([]float64, m),
+		n:        len(values),
+		alpha:    alpha,
+		beta:     beta,
+		gamma:    gamma,
+	}
+	for i := 0; i < m; i++ {
+		model.seasonal[i] = values[i] - first
+	}
+
+	var sse float64
+	for t := m; t < len(values); t++ {
+		season := model.seasonal[t%m]
+		err := values[t] - (model.level + model.trend + season)
+		sse += err * err
+
+		level := alpha*(values[t]-season) + (1-alpha)*(model.level+model.trend)
+		model.trend = beta*(level-model.level) + (1-beta)*model.trend
+		model.level = level
+		model.seasonal[t%m] = gamma*(values[t]-level) + (1-gamma)*season
+	}
+
+	model.sigma = math.Sqrt(sse / float64(len(values)-m))
+	return model, sse
+}
+
+// fitHolt runs Holt's linear trend method over the series
+func fitHolt(values []float64, alpha, beta float64) (*forecastModel, float64) {
+	model := &forecastModel{
+		method: ForecastHolt,
+		level:  values[0],
+		trend:  values[1] - values[0],
+		n:      len(values),
+		alpha:  alpha,
+		beta:   beta,
+	}
+
+	var sse float64
+	for t := 1; t < len(values); t++ {
+		err := values[t] - (model.level + model.trend)
+		sse += err * err
+
+		level := alpha*values[t] + (1-alpha)*(model.level+model.trend)
+		model.trend = beta*(level-model.level) + (1-beta)*model.trend
+		model.level = level
+	}
+
+	model.sigma = math.Sqrt(sse / float64(len(values)-1))
+	return model, sse
+}
+
+// fitAverage forecasts the mean of a short series
+func fitAverage(values []float64) *forecastModel {
+	model := &forecastModel{method: ForecastAverage, n: len(values)}
+	if len(values) == 0 {
+		return model
+	}
+
+	model.level = mean(values)
+	if len(values) > 1 {
+		var ss float64
+		for _, v := range values {
+			ss += (v - model.level) * (v - model.level)
+		}
+		model.sigma = math.Sqrt(ss / float64(len(values)-1))
+	}
+	return model
+}
+
+// forecast returns the point forecasts for the next h days
+func (m *forecastModel) forecast(h int) []float64 {
+	points := make([]float64, h)
+	for i := range points {
+		step := float64(i + 1)
+		points[i] = m.level + step*m.trend
+		if m.seasonal != nil {
+			points[i] += m.seasonal[(m.n+i)%len(m.seasonal)]
+		}
+	}
+	return points
+}
+
+// errorWeight is how much an error j steps back still moves the forecast
+func (m *forecastModel) errorWeight(j int) float64 {
+	if m.method == ForecastAverage {
+		return 0
+	}
+	weight := m.alpha * (1 + float64(j)*m.beta)
+	if m.seasonal != nil && j%len(m.seasonal) == 0 {
+		weight += m.gamma
+	}
+	return weight
+}
+
+// stepStdDev returns the standard deviation of the forecast h days ahead
+func (m *forecastModel) stepStdDev(h int) float64 {
+	variance := 1.0
+	for j := 1; j < h; j++ {
+		w := m.errorWeight(j)
+		variance += w * w
+	}
+	return m.sigma * math.Sqrt(variance)
+}
+
+// sumStdDev returns the standard deviation of the total of the next h days. Each future
+// error also moves every later forecast, so the step errors are correlated.
+func (m *forecastModel) sumStdDev(h int) float64 {
+	var variance float64
+	for k := 1; k <= h; k++ {
+		carried := 1.0
+		for j := 1; j <= h-k; j++ {
+			carried += m.errorWeight(j)
+		}
+		variance += carried * carried
+	}
+	return m.sigma * math.Sqrt(variance)
+}
+
+// mean returns the arithmetic mean of a non-empty series
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
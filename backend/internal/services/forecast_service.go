@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	defaultForecastHistoryDays = 56
+	maxForecastHistoryDays     = 365
+)
+
+// ForecastService projects future cost from daily cost history
+type ForecastService struct {
+	repo    repository.Repository
+	budgets *BudgetService // optional
+}
+
+// NewForecastService creates a new forecast service. Forecasts without an explicit budget
+// are compared against the matching monthly budgets when budgets is set.
+func NewForecastService(repo repository.Repository, budgets *BudgetService) *ForecastService {
+	return &ForecastService{
+		repo:    repo,
+		budgets: budgets,
+	}
+}
+
+// ForecastCosts projects month-end cost for the organization and, optionally, each model or
+// project. Days are UTC days; the current day is forecast rather than read as a partial day.
+func (s *ForecastService) ForecastCosts(ctx context.Context, query *models.ForecastQuery) (*CostForecast, error) {
+	return s.forecastCosts(ctx, query, time.Now())
+}
+
+// forecastCosts projects month-end cost as of now
+func (s *ForecastService) forecastCosts(ctx context.Context, query *models.ForecastQuery, now time.Time) (*CostForecast, error) {
+	if err := validateForecastQuery(query); err != nil {
+		return nil, err
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	horizon := int(monthEnd.Sub(today).Hours() / 24)
+
+	// The history always covers the month so far
+	historyStart := today.AddDate(0, 0, -query.HistoryDays)
+	if monthStart.Before(historyStart) {
+		historyStart = monthStart
+	}
+
+	seriesQuery := &models.TimeSeriesQuery{
+		MetricQuery: models.MetricQuery{
+			OrganizationID: query.OrganizationID,
+			ProjectID:      query.ProjectID,
+			MetricName:     "cost",
+			StartTime:      historyStart,
+			EndTime:        today,
+			Granularity:    "day",
+			Limit:          query.Limit,
+		},
+	}
+	seriesQuery.Source = timeSeriesSource(seriesQuery)
+
+	forecast := &CostForecast{
+		GeneratedAt:     now,
+		HistoryStart:    historyStart,
+		HistoryEnd:      today,
+		MonthStart:      monthStart,
+		MonthEnd:        monthEnd,
+		ConfidenceLevel: 0.95,
+		GroupBy:         query.GroupBy,
+	}
+
+	totals, err := s.repo.GetTimeSeries(ctx, seriesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily costs: %w", err)
+	}
+	var totalPoints []models.TimeSeriesValue
+	if len(totals) > 0 {
+		totalPoints = totals[0].Points
+	}
+	forecast.Total = forecastCostSeries("", fillSeriesGaps(totalPoints, historyStart, today, "day"), monthStart, today, horizon)
+
+	if query.GroupBy != "" {
+		grouped := *seriesQuery
+		grouped.GroupBy = query.GroupBy
+		grouped.Source = timeSeriesSource(&grouped)
+
+		groups, err := s.repo.GetTimeSeries(ctx, &grouped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily costs by %s: %w", query.GroupBy, err)
+		}
+
+		forecast.Groups = make([]*CostForecastSeries, 0, len(groups))
+		for _, group := range groups {
+			points := fillSeriesGaps(group.Points, historyStart, today, "day")
+			series := forecastCostSeries(group.Group, points, monthStart, today, horizon)
+			if budget := s.monthlyBudget(query.OrganizationID, query.GroupBy, group.Group); budget != nil {
+				series.Budget = storedBudgetForecast(budget, series)
+			}
+			forecast.Groups = append(forecast.Groups, series)
+		}
+	}
+
+	switch {
+	case query.Budget > 0:
+		forecast.Budget = budgetForecast(query.Budget, forecast.Total)
+	case query.ProjectID != "":
+		if budget := s.monthlyBudget(query.OrganizationID, models.BudgetScopeProject, query.ProjectID); budget != nil {
+			forecast.Budget = storedBudgetForecast(budget, forecast.Total)
+		}
+	default:
+		if budget := s.monthlyBudget(query.OrganizationID, models.BudgetScopeOrganization, ""); budget != nil {
+			forecast.Budget = storedBudgetForecast(budget, forecast.Total)
+		}
+	}
+
+	return forecast, nil
+}
+
+// monthlyBudget returns the tightest monthly budget of a scope, if there is a budget service
+func (s *ForecastService) monthlyBudget(orgID, scope, value string) *models.Budget {
+	if s.budgets == nil {
+		return nil
+	}
+	return s.budgets.MonthlyBudget(orgID, scope, value)
+}
+
+// storedBudgetForecast compares a projection against a stored budget
+func storedBudgetForecast(budget *models.Budget, series *CostForecastSeries) *BudgetForecast {
+	forecast := budgetForecast(budget.LimitUSD, series)
+	forecast.BudgetID = budget.ID
+	forecast.BudgetName = budget.Name
+	return forecast
+}
+
+// validateForecastQuery checks a forecast query and fills in its defaults
+func validateForecastQuery(query *models.ForecastQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+
+	switch query.GroupBy {
+	case "", "model", "project":
+	default:
+		return invalidArgument("group_by must be model or project")
+	}
+
+	if query.HistoryDays == 0 {
+		query.HistoryDays = defaultForecastHistoryDays
+	}
+	if query.HistoryDays < 1 || query.HistoryDays > maxForecastHistoryDays {
+		return invalidArgument("history_days must be between 1 and %d", maxForecastHistoryDays)
+	}
+
+	if query.Budget < 0 || math.IsNaN(query.Budget) || math.IsInf(query.Budget, 0) {
+		return invalidArgument("budget must be a positive amount")
+	}
+
+	query.Limit = seriesGroupLimit(query.Limit)
+	return nil
+}
+
+// forecastCostSeries fits a model to one group's daily costs and projects the rest of the month
+func forecastCostSeries(group string, points []models.TimeSeriesValue, monthStart, today time.Time, horizon int) *CostForecastSeries {
+	series := &CostForecastSeries{Group: group}
+
+	// Days before the first spend carry no signal about the trend
+	first := 0
+	for first < len(points) && points[first].Value == 0 {
+		first++
+	}
+
+	values := make([]float64, 0, len(points)-first)
+	for i, point := range points {
+		if !point.Timestamp.Before(monthStart) {
+			series.MonthToDate += point.Value
+		}
+		if i >= first {
+			values = append(values, point.Value)
+		}
+	}
+
+	model := fitForecastModel(values)
+	series.Method = model.method
+	series.HistoryDays = len(values)
+
+	var remaining float64
+	series.Daily = make([]ForecastPoint, horizon)
+	for i, value := range model.forecast(horizon) {
+		margin := z95 * model.stepStdDev(i+1)
+		value = math.Max(0, value)
+		remaining += value
+
+		series.Daily[i] = ForecastPoint{
+			Date:  today.AddDate(0, 0, i),
+			Value: value,
+			Lower: math.Max(0, value-margin),
+			Upper: value + margin,
+		}
+	}
+
+	series.StdDev = model.sumStdDev(horizon)
+	series.Projected = series.MonthToDate + remaining
+	series.Lower = series.MonthToDate + math.Max(0, remaining-z95*series.StdDev)
+	series.Upper = series.Projected + z95*series.StdDev
+
+	return series
+}
+
+// budgetForecast compares a month-end projection against a monthly budget
+func budgetForecast(budget float64, total *CostForecastSeries) *BudgetForecast {
+	forecast := &BudgetForecast{
+		Budget:               budget,
+		MonthToDate:          total.MonthToDate,
+		Projected:            total.Projected,
+		Remaining:            budget - total.MonthToDate,
+		ProjectedUtilization: total.Projected / budget * 100.0,
+		OnTrack:              total.Projected <= budget,
+	}
+
+	// Probability that the normal month-end distribution lands above the budget
+	switch {
+	case total.StdDev > 0:
+		forecast.ExceedProbability = 0.5 * math.Erfc((budget-total.Projected)/(total.StdDev*math.Sqrt2))
+	case total.Projected > budget:
+		forecast.ExceedProbability = 1
+	}
+
+	return forecast
+}
+
+// CostForecast projects month-end cost for an organization
+type CostForecast struct {
+	GeneratedAt     time.Time             `json:"generated_at"`
+	HistoryStart    time.Time             `json:"history_start"`
+	HistoryEnd      time.Time             `json:"history_end"`
+	MonthStart      time.Time             `json:"month_start"`
+	MonthEnd        time.Time             `json:"month_end"`
+	ConfidenceLevel float64               `json:"confidence_level"`
+	Total           *CostForecastSeries   `json:"total"`
+	GroupBy         string                `json:"group_by,omitempty"`
+	Groups          []*CostForecastSeries `json:"groups,omitempty"`
+	Budget          *BudgetForecast       `json:"budget,omitempty"`
+	Converted       *ConvertedForecast    `json:"converted,omitempty"`
+}
+
+// ConvertedForecast is a forecast's projections and budgets in a display currency. Future
+// costs have no rate yet, so every amount is converted at today's rate.
+type ConvertedForecast struct {
+	Currency string                `json:"currency"`
+	Rate     float64               `json:"rate"`
+	Total    *CostForecastSeries   `json:"total"`
+	Groups   []*CostForecastSeries `json:"groups,omitempty"`
+	Budget   *BudgetForecast       `json:"budget,omitempty"`
+}
+
+// CostForecastSeries is the month-end projection of one group
+type CostForecastSeries struct {
+	Group       string          `json:"group,omitempty"`
+	Method      string          `json:"method"` // holt_winters, holt, average
+	HistoryDays int             `json:"history_days"`
+	MonthToDate float64         `json:"month_to_date"`
+	Projected   float64         `json:"projected"`
+	Lower       float64         `json:"lower"`
+	Upper       float64         `json:"upper"`
+	StdDev      float64         `json:"std_dev"`
+	Daily       []ForecastPoint `json:"daily"`
+	Budget      *BudgetForecast `json:"budget,omitempty"` // of a group's monthly budget
+}
+
+// ForecastPoint is the forecast cost of one day
+type ForecastPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+	Lower float64   `json:"lower"`
+	Upper float64   `json:"upper"`
+}
+
+// BudgetForecast compares the month-end projection against a budget: the requested amount,
+// or a stored monthly budget
+type BudgetForecast struct {
+	BudgetID             string  `json:"budget_id,omitempty"`
+	BudgetName           string  `json:"budget_name,omitempty"`
+	Budget               float64 `json:"budget"`
+	MonthToDate          float64 `json:"month_to_date"`
+	Projected            float64 `json:"projected"`
+	Remaining            float64 `json:"remaining"`
+	ProjectedUtilization float64 `json:"projected_utilization"` // percent of budget
+	ExceedProbability    float64 `json:"exceed_probability"`
+	OnTrack              bool    `json:"on_track"`
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestForecastCosts(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	// $10 a day for four weeks, with the first day of March missing
+	var points []models.TimeSeriesValue
+	for day := today.AddDate(0, 0, -28); day.Before(today); day = day.AddDate(0, 0, 1) {
+		if day.Day() != 1 {
+			points = append(points, models.TimeSeriesValue{Timestamp: day, Value: 10})
+		}
+	}
+	repo := &mockRepository{series: []*models.TimeSeries{{Group: "gpt-4", Points: points}}}
+
+	forecast, err := NewForecastService(repo, nil).forecastCosts(context.Background(), &models.ForecastQuery{
+		OrganizationID: "org-1",
+		GroupBy:        "model",
+		Budget:         400,
+	}, now)
+	if err != nil {
+		t.Fatalf("forecastCosts() error = %v", err)
+	}
+
+	if len(repo.seriesQueries) != 2 || repo.seriesQueries[0].GroupBy != "" || repo.seriesQueries[1].GroupBy != "model" {
+		t.Fatalf("expected a total and a grouped query, got %+v", repo.seriesQueries)
+	}
+	if q := repo.seriesQueries[0]; q.MetricName != "cost" || q.Granularity != "day" || !q.EndTime.Equal(today) {
+		t.Errorf("unexpected history query %+v", q)
+	}
+
+	total := forecast.Total
+	if total.MonthToDate != 130 {
+		t.Errorf("expected $130 month to date, got %v", total.MonthToDate)
+	}
+	if len(total.Daily) != 17 || !total.Daily[0].Date.Equal(today) {
+		t.Errorf("expected 17 daily forecasts from today, got %d", len(total.Daily))
+	}
+	if total.Projected < 250 || total.Projected > 330 || total.Lower > total.Projected || total.Upper < total.Projected {
+		t.Errorf("unexpected projection %v [%v, %v]", total.Projected, total.Lower, total.Upper)
+	}
+	if len(forecast.Groups) != 1 || forecast.Groups[0].Group != "gpt-4" {
+		t.Errorf("unexpected groups %+v", forecast.Groups)
+	}
+
+	budget := forecast.Budget
+	if budget == nil || !budget.OnTrack || budget.Remaining != 270 || budget.ExceedProbability > 0.5 {
+		t.Errorf("expected the month to stay under the $400 budget, got %+v", budget)
+	}
+}
+
+func TestForecastCostsValidation(t *testing.T) {
+	invalid := []*models.ForecastQuery{
+		{},
+		{OrganizationID: "org-1", GroupBy: "user"},
+		{OrganizationID: "org-1", HistoryDays: maxForecastHistoryDays + 1},
+		{OrganizationID: "org-1", Budget: -5},
+	}
+	for i, query := range invalid {
+		if _, err := NewForecastService(&mockRepository{}, nil).ForecastCosts(context.Background(), query); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("query %d: expected ErrInvalidArgument, got %v", i, err)
+		}
+	}
+}
+
+func TestForecastCostsStoredBudgets(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{series: []*models.TimeSeries{{Group: "gpt-4", Points: []models.TimeSeriesValue{
+		{Timestamp: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), Value: 10},
+	}}}}
+
+	budgets := NewBudgetService(repo, nil)
+	for _, req := range []models.BudgetRequest{
+		{Name: "Org", Scope: models.BudgetScopeOrganization, Period: models.BudgetPeriodMonthly, LimitUSD: 300},
+		{Name: "Org daily", Scope: models.BudgetScopeOrganization, Period: models.BudgetPeriodDaily, LimitUSD: 20},
+		{Name: "GPT-4", Scope: models.BudgetScopeModel, ScopeValue: "openai/gpt-4", Period: models.BudgetPeriodMonthly, LimitUSD: 200},
+	} {
+		if _, err := budgets.UpsertBudget(context.Background(), "org-1", &req, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	forecast, err := NewForecastService(repo, budgets).forecastCosts(context.Background(), &models.ForecastQuery{
+		OrganizationID: "org-1",
+		GroupBy:        "model",
+	}, now)
+	if err != nil {
+		t.Fatalf("forecastCosts() error = %v", err)
+	}
+
+	if forecast.Budget == nil || forecast.Budget.Budget != 300 || forecast.Budget.BudgetName != "Org" {
+		t.Errorf("expected the monthly organization budget, got %+v", forecast.Budget)
+	}
+	if len(forecast.Groups) != 1 || forecast.Groups[0].Budget == nil || forecast.Groups[0].Budget.Budget != 200 {
+		t.Errorf("expected the gpt-4 budget on its group, got %+v", forecast.Groups)
+	}
+
+	// An explicit budget wins
+	forecast, _ = NewForecastService(repo, budgets).forecastCosts(context.Background(), &models.ForecastQuery{
+		OrganizationID: "org-1",
+		Budget:         50,
+	}, now)
+	if forecast.Budget == nil || forecast.Budget.Budget != 50 || forecast.Budget.BudgetID != "" {
+		t.Errorf("expected the requested budget, got %+v", forecast.Budget)
+	}
+}
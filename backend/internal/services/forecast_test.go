@@ -0,0 +1,62 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+// weekdayPattern is a weekly cycle with quiet weekends
+var weekdayPattern = []float64{10, 12, 11, 13, 9, -20, -25}
+
+func TestFitForecastModelSeasonal(t *testing.T) {
+	var values []float64
+	for day := 0; day < 56; day++ {
+		values = append(values, 100+2*float64(day)+weekdayPattern[day%7])
+	}
+
+	model := fitForecastModel(values)
+	if model.method != ForecastHoltWinters {
+		t.Fatalf("expected Holt-Winters for 8 weeks of history, got %s", model.method)
+	}
+
+	for i, got := range model.forecast(14) {
+		day := 56 + i
+		want := 100 + 2*float64(day) + weekdayPattern[day%7]
+		if math.Abs(got-want) > 0.05*want {
+			t.Errorf("day %d: forecast %.1f, want %.1f", day, got, want)
+		}
+	}
+}
+
+func TestFitForecastModelFallbacks(t *testing.T) {
+	if model := fitForecastModel([]float64{1, 2, 3, 4, 5}); model.method != ForecastHolt {
+		t.Errorf("expected Holt for 5 days, got %s", model.method)
+	} else if got := model.forecast(1)[0]; math.Abs(got-6) > 0.5 {
+		t.Errorf("expected the trend to continue to 6, got %v", got)
+	}
+
+	model := fitForecastModel([]float64{4, 6})
+	if model.method != ForecastAverage || model.forecast(3)[2] != 5 {
+		t.Errorf("expected a flat average of 5, got %s %v", model.method, model.forecast(3))
+	}
+
+	if model := fitForecastModel(nil); model.forecast(2)[1] != 0 || model.sumStdDev(2) != 0 {
+		t.Errorf("expected an empty history to forecast zero, got %+v", model)
+	}
+}
+
+func TestForecastUncertainty(t *testing.T) {
+	average := &forecastModel{method: ForecastAverage, sigma: 2}
+	if got := average.sumStdDev(9); math.Abs(got-6) > 1e-9 {
+		t.Errorf("independent errors over 9 days: got %v, want 6", got)
+	}
+
+	// Errors carried into later forecasts widen the interval of the total
+	holt := &forecastModel{method: ForecastHolt, alpha: 0.5, beta: 0.1, sigma: 2}
+	if holt.sumStdDev(9) <= average.sumStdDev(9) {
+		t.Errorf("expected correlated errors to widen the total, got %v", holt.sumStdDev(9))
+	}
+	if holt.stepStdDev(1) != 2 || holt.stepStdDev(5) <= holt.stepStdDev(2) {
+		t.Errorf("expected the step interval to widen with the horizon")
+	}
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// Import formats; each JSONL line holds one record in the given shape
+const (
+	ImportFormatAuto      = "auto"
+	ImportFormatClarity   = "clarity"
+	ImportFormatOTLP      = "otlp"
+	ImportFormatLangfuse  = "langfuse"
+	ImportFormatLangSmith = "langsmith"
+)
+
+// importParsers decode one JSONL line of each concrete format into traces
+var importParsers = map[string]func(line []byte) ([]*models.Trace, error){
+	ImportFormatClarity:   parseClarityLine,
+	ImportFormatOTLP:      parseOTLPLine,
+	ImportFormatLangfuse:  parseLangfuseLine,
+	ImportFormatLangSmith: parseLangSmithLine,
+}
+
+// detectImportFormat guesses the format of a line from its top-level keys
+func detectImportFormat(line []byte) (string, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(line, &keys); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	has := func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	}
+
+	switch {
+	case has("resourceSpans"):
+		return ImportFormatOTLP, nil
+	case has("observations"):
+		return ImportFormatLangfuse, nil
+	case has("run_type") || has("child_runs"):
+		return ImportFormatLangSmith, nil
+	case has("trace_id") && has("spans"):
+		return ImportFormatClarity, nil
+	}
+	return "", fmt.Errorf("unrecognized record: expected a clarity, otlp, langfuse or langsmith trace")
+}
+
+// parseClarityLine decodes a trace as written by the JSONL exporter
+func parseClarityLine(line []byte) ([]*models.Trace, error) {
+	var trace models.Trace
+	if err := json.Unmarshal(line, &trace); err != nil {
+		return nil, fmt.Errorf("invalid clarity trace: %w", err)
+	}
+	for i := range trace.Spans {
+		trace.Spans[i].TraceID = trace.TraceID
+	}
+	return []*models.Trace{&trace}, nil
+}
+
+// parseOTLPLine decodes one OTLP JSON TracesData document
+func parseOTLPLine(line []byte) ([]*models.Trace, error) {
+	var data OTLPTracesData
+	if err := json.Unmarshal(line, &data); err != nil {
+		return nil, fmt.Errorf("invalid OTLP document: %w", err)
+	}
+	return otlpToTraces(&data)
+}
+
+// langfuseTrace is a trace as exported by Langfuse, with its observations inlined
+type langfuseTrace struct {
+	ID           string                 `json:"id"`
+	Timestamp    string                 `json:"timestamp"`
+	Name         string                 `json:"name"`
+	UserID       string                 `json:"userId"`
+	SessionID    string                 `json:"sessionId"`
+	Release      string                 `json:"release"`
+	Tags         []string               `json:"tags"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	Observations []langfuseObservation  `json:"observations"`
+}
+
+// langfuseObservation is a Langfuse span, generation or event
+type langfuseObservation struct {
+	ID                  string                 `json:"id"`
+	Type                string                 `json:"type"`
+	Name                string                 `json:"name"`
+	ParentObservationID string                 `json:"parentObservationId"`
+	StartTime           string                 `json:"startTime"`
+	EndTime             string                 `json:"endTime"`
+	Model               string                 `json:"model"`
+	Input               json.RawMessage        `json:"input"`
+	Output              json.RawMessage        `json:"output"`
+	Level               string                 `json:"level"`
+	StatusMessage       string                 `json:"statusMessage"`
+	Metadata            map[string]interface{} `json:"metadata"`
+	Usage               struct {
+		Input            float64 `json:"input"`
+		Output           float64 `json:"output"`
+		PromptTokens     float64 `json:"promptTokens"`
+		CompletionTokens float64 `json:"completionTokens"`
+	} `json:"usage"`
+	CalculatedTotalCost float64 `json:"calculatedTotalCost"`
+	TotalCost           float64 `json:"totalCost"`
+}
+
+// parseLangfuseLine decodes a Langfuse trace export record
+func parseLangfuseLine(line []byte) ([]*models.Trace, error) {
+	var record langfuseTrace
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, fmt.Errorf("invalid langfuse trace: %w", err)
+	}
+	if record.ID == "" {
+		return nil, fmt.Errorf("langfuse trace is missing id")
+	}
+
+	metadata := stringMap(record.Metadata)
+	if record.Name != "" {
+		metadata = withMetadata(metadata, "name", record.Name)
+	}
+	if record.SessionID != "" {
+		metadata = withMetadata(metadata, "session_id", record.SessionID)
+	}
+	if record.Release != "" {
+		metadata = withMetadata(metadata, "release", record.Release)
+	}
+	if len(record.Tags) > 0 {
+		metadata = withMetadata(metadata, "tags", strings.Join(record.Tags, ","))
+	}
+
+	trace := &models.Trace{
+		TraceID:  record.ID,
+		UserID:   record.UserID,
+		Metadata: metadata,
+	}
+	if ts, err := parseImportTime(record.Timestamp); err == nil {
+		trace.Timestamp = ts
+	}
+
+	for _, obs := range record.Observations {
+		start, err := parseImportTime(obs.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("observation %s: %w", obs.ID, err)
+		}
+		end, err := parseImportTime(obs.EndTime)
+		if err != nil {
+			end = start
+		}
+
+		status := "success"
+		if obs.Level == "ERROR" {
+			status = "error"
+		}
+
+		cost := obs.CalculatedTotalCost
+		if cost == 0 {
+			cost = obs.TotalCost
+		}
+
+		spanMetadata := stringMap(obs.Metadata)
+		if obs.Type != "" {
+			spanMetadata = withMetadata(spanMetadata, "observation_type", strings.ToLower(obs.Type))
+		}
+
+		span := models.Span{
+			SpanID:           obs.ID,
+			TraceID:          record.ID,
+			ParentSpanID:     obs.ParentObservationID,
+			Name:             obs.Name,
+			StartTime:        start,
+			EndTime:          end,
+			Model:            obs.Model,
+			Provider:         inferProvider(obs.Model),
+			Input:            jsonText(obs.Input),
+			Output:           jsonText(obs.Output),
+			PromptTokens:     int(firstNonZero(obs.Usage.Input, obs.Usage.PromptTokens)),
+			CompletionTokens: int(firstNonZero(obs.Usage.Output, obs.Usage.CompletionTokens)),
+			CostUSD:          cost,
+			Status:           status,
+			Metadata:         spanMetadata,
+		}
+		if status == "error" {
+			span.ErrorMessage = obs.StatusMessage
+		}
+
+		trace.Spans = append(trace.Spans, span)
+	}
+
+	return []*models.Trace{trace}, nil
+}
+
+// langSmithRun is a LangSmith run; the root run of a trace carries its children inline
+type langSmithRun struct {
+	ID               string                 `json:"id"`
+	TraceID          string                 `json:"trace_id"`
+	ParentRunID      string                 `json:"parent_run_id"`
+	Name             string                 `json:"name"`
+	RunType          string                 `json:"run_type"`
+	StartTime        string                 `json:"start_time"`
+	EndTime          string                 `json:"end_time"`
+	Inputs           json.RawMessage        `json:"inputs"`
+	Outputs          json.RawMessage        `json:"outputs"`
+	Error            string                 `json:"error"`
+	SessionName      string                 `json:"session_name"`
+	Tags             []string               `json:"tags"`
+	PromptTokens     float64                `json:"prompt_tokens"`
+	CompletionTokens float64                `json:"completion_tokens"`
+	TotalCost        float64                `json:"total_cost"`
+	Extra            langSmithExtra         `json:"extra"`
+	ChildRuns        []langSmithRun         `json:"child_runs"`
+	Metadata         map[string]interface{} `json:"metadata"`
+}
+
+// langSmithExtra holds the LangSmith fields that identify the model
+type langSmithExtra struct {
+	Metadata         map[string]interface{} `json:"metadata"`
+	InvocationParams map[string]interface{} `json:"invocation_params"`
+}
+
+// parseLangSmithLine decodes a LangSmith root run with its child runs
+func parseLangSmithLine(line []byte) ([]*models.Trace, error) {
+	var root langSmithRun
+	if err := json.Unmarshal(line, &root); err != nil {
+		return nil, fmt.Errorf("invalid langsmith run: %w", err)
+	}
+	if root.ID == "" {
+		return nil, fmt.Errorf("langsmith run is missing id")
+	}
+
+	traceID := root.TraceID
+	if traceID == "" {
+		traceID = root.ID
+	}
+
+	metadata := stringMap(root.Extra.Metadata)
+	for key, value := range stringMap(root.Metadata) {
+		metadata = withMetadata(metadata, key, value)
+	}
+	if root.SessionName != "" {
+		metadata = withMetadata(metadata, "session_name", root.SessionName)
+	}
+	if len(root.Tags) > 0 {
+		metadata = withMetadata(metadata, "tags", strings.Join(root.Tags, ","))
+	}
+
+	trace := &models.Trace{
+		TraceID:  traceID,
+		Metadata: metadata,
+	}
+	if userID, ok := trace.Metadata["user_id"]; ok {
+		trace.UserID = userID
+	}
+
+	var walk func(run langSmithRun, parentID string) error
+	walk = func(run langSmithRun, parentID string) error {
+		start, err := parseImportTime(run.StartTime)
+		if err != nil {
+			return fmt.Errorf("run %s: %w", run.ID, err)
+		}
+		end, err := parseImportTime(run.EndTime)
+		if err != nil {
+			end = start
+		}
+
+		if run.ParentRunID != "" {
+			parentID = run.ParentRunID
+		}
+
+		model, _ := run.Extra.InvocationParams["model"].(string)
+		if model == "" {
+			model, _ = run.Extra.InvocationParams["model_name"].(string)
+		}
+		provider := inferProvider(model)
+		if llmType, ok := run.Extra.InvocationParams["_type"].(string); ok && provider == "" {
+			provider = inferProvider(llmType)
+		}
+
+		status := "success"
+		if run.Error != "" {
+			status = "error"
+		}
+
+		span := models.Span{
+			SpanID:           run.ID,
+			TraceID:          traceID,
+			ParentSpanID:     parentID,
+			Name:             run.Name,
+			StartTime:        start,
+			EndTime:          end,
+			Model:            model,
+			Provider:         provider,
+			Input:            jsonText(run.Inputs),
+			Output:           jsonText(run.Outputs),
+			PromptTokens:     int(run.PromptTokens),
+			CompletionTokens: int(run.CompletionTokens),
+			CostUSD:          run.TotalCost,
+			Status:           status,
+			ErrorMessage:     run.Error,
+		}
+		if run.RunType != "" {
+			span.Metadata = map[string]string{"run_type": run.RunType}
+		}
+		trace.Spans = append(trace.Spans, span)
+
+		for _, child := range run.ChildRuns {
+			if err := walk(child, run.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root, ""); err != nil {
+		return nil, err
+	}
+
+	return []*models.Trace{trace}, nil
+}
+
+// importTimeLayouts are the timestamp layouts accepted on import; zone-less times are UTC
+var importTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseImportTime parses the timestamp formats used by common exporters
+func parseImportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// inferProvider guesses the provider from a model or client name
+func inferProvider(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "":
+		return ""
+	case strings.Contains(name, "gpt") || strings.Contains(name, "openai") || strings.HasPrefix(name, "o1") || strings.HasPrefix(name, "o3"):
+		return "openai"
+	case strings.Contains(name, "claude") || strings.Contains(name, "anthropic"):
+		return "anthropic"
+	case strings.Contains(name, "gemini") || strings.Contains(name, "google") || strings.Contains(name, "vertex"):
+		return "google"
+	case strings.Contains(name, "mistral") || strings.Contains(name, "mixtral"):
+		return "mistral"
+	case strings.Contains(name, "command") || strings.Contains(name, "cohere"):
+		return "cohere"
+	}
+	return ""
+}
+
+// jsonText renders an arbitrary JSON value as span text; strings are unquoted
+func jsonText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// stringMap flattens a JSON object into string metadata
+func stringMap(values map[string]interface{}) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			result[key] = v
+		case nil:
+			continue
+		default:
+			data, _ := json.Marshal(v)
+			result[key] = string(data)
+		}
+	}
+	return result
+}
+
+// withMetadata sets a key on a possibly nil metadata map
+func withMetadata(metadata map[string]string, key, value string) map[string]string {
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[key] = value
+	return metadata
+}
+
+func firstNonZero(values ...float64) float64 {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
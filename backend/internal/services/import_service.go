@@ -0,0 +1,418 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// JobTypeImport identifies trace import jobs in the job manager
+const JobTypeImport = "import"
+
+const (
+	importBatchSize   = 200
+	maxImportLineSize = 64 << 20
+	maxImportErrors   = 1000
+
+	// traceRetention matches the TTL of the traces table; older traces would be expired
+	// by ClickHouse as soon as they were stored
+	traceRetention = 90 * 24 * time.Hour
+)
+
+// Reasons traces are skipped by an import
+const (
+	ImportSkipExisting = "existing"
+	ImportSkipExpired  = "expired"
+)
+
+// ImportOptions controls how a JSONL file is imported
+type ImportOptions struct {
+	OrganizationID string `json:"organization_id"`
+	ProjectID      string `json:"project_id,omitempty"` // used when a trace has no project
+	Format         string `json:"format"`
+}
+
+// ImportLineError records why a line could not be imported
+type ImportLineError struct {
+	Line  int64  `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	Lines       int64             `json:"lines"`
+	Imported    int64             `json:"imported"`
+	Skipped     int64             `json:"skipped"`
+	SkippedBy   map[string]int64  `json:"skipped_by"` // by reason: existing or expired
+	FailedLines int64             `json:"failed_lines"`
+	Errors      []ImportLineError `json:"errors"`
+}
+
+// ImportService loads historical traces from JSONL files
+type ImportService struct {
+	repo         repository.Repository
+	jobs         *JobManager
+	traceService *TraceService
+	now          func() time.Time
+}
+
+// NewImportService creates a new import service
+func NewImportService(repo repository.Repository, jobs *JobManager, traceService *TraceService) *ImportService {
+	return &ImportService{
+		repo:         repo,
+		jobs:         jobs,
+		traceService: traceService,
+		now:          time.Now,
+	}
+}
+
+// ValidateOptions checks import options before any data is read
+func (s *ImportService) ValidateOptions(opts *ImportOptions) error {
+	if opts.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if opts.Format == "" {
+		opts.Format = ImportFormatAuto
+	}
+	if _, ok := importParsers[opts.Format]; !ok && opts.Format != ImportFormatAuto {
+		return invalidArgument("unsupported import format %q: must be auto, clarity, otlp, langfuse or langsmith", opts.Format)
+	}
+	return nil
+}
+
+// Import reads JSONL from r and saves every trace it contains.
+// Lines that fail to parse or save are reported in the result; only read
+// and context errors abort the import. progress may be nil.
+func (s *ImportService) Import(ctx context.Context, r io.Reader, opts ImportOptions, progress JobProgress) (*ImportResult, error) {
+	if err := s.ValidateOptions(&opts); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{SkippedBy: map[string]int64{}, Errors: []ImportLineError{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+
+	var batch []importedLine
+	flush := func() error {
+		if err := s.saveBatch(ctx, batch, opts, result, progress); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		result.Lines++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			if progress != nil {
+				progress.Add(1, 0)
+			}
+			continue
+		}
+
+		traces, err := s.parseLine(line, opts)
+		if err != nil {
+			result.fail(result.Lines, err, progress)
+			continue
+		}
+
+		batch = append(batch, importedLine{number: result.Lines, traces: traces})
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read import at line %d: %w", result.Lines+1, err)
+	}
+
+	return result, flush()
+}
+
+// StartImportJob imports a spooled JSONL file in the background and deletes it afterwards
+func (s *ImportService) StartImportJob(path string, opts ImportOptions) (*Job, error) {
+	if err := s.ValidateOptions(&opts); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"format":     opts.Format,
+		"project_id": opts.ProjectID,
+	}
+
+	job := s.jobs.Start(opts.OrganizationID, JobTypeImport, params, func(ctx context.Context, progress JobProgress) error {
+		defer os.Remove(path)
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer file.Close()
+
+		result, err := s.Import(ctx, file, opts, progress)
+		if result != nil {
+			progress.SetResult("lines", result.Lines)
+			progress.SetResult("imported", result.Imported)
+			progress.SetResult("skipped", result.Skipped)
+			progress.SetResult("skipped_by", result.skippedBy())
+			progress.SetResult("failed_lines", result.FailedLines)
+			progress.SetResult("errors", result.Errors)
+		}
+		return err
+	})
+
+	return job, nil
+}
+
+// GetImportJob returns the state of an import job
+func (s *ImportService) GetImportJob(orgID, jobID string) (*Job, error) {
+	job, err := s.jobs.Get(orgID, jobID)
+	if err != nil || job.Type != JobTypeImport {
+		return nil, fmt.Errorf("import job %s: %w", jobID, repository.ErrNotFound)
+	}
+	return job, nil
+}
+
+// ListImportJobs returns an organization's import jobs, newest first
+func (s *ImportService) ListImportJobs(orgID string) []*Job {
+	return s.jobs.List(orgID, JobTypeImport)
+}
+
+// importedLine holds the traces parsed from one input line
+type importedLine struct {
+	number int64
+	traces []*models.Trace
+}
+
+// parseLine decodes a line and normalizes its traces for storage
+func (s *ImportService) parseLine(line []byte, opts ImportOptions) ([]*models.Trace, error) {
+	format := opts.Format
+	if format == ImportFormatAuto {
+		detected, err := detectImportFormat(line)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	traces, err := importParsers[format](line)
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("line contains no traces")
+	}
+
+	for _, trace := range traces {
+		if err := s.normalizeTrace(trace, opts); err != nil {
+			return nil, fmt.Errorf("trace %s: %w", trace.TraceID, err)
+		}
+	}
+	return traces, nil
+}
+
+// normalizeTrace fills in the fields derived from spans, keeping original IDs and timestamps.
+// Costs are only calculated for spans that did not carry one; carried costs are recorded
+// as client-priced.
+func (s *ImportService) normalizeTrace(trace *models.Trace, opts ImportOptions) error {
+	if trace.TraceID == "" {
+		return fmt.Errorf("trace_id is required")
+	}
+
+	// Imports always land in the caller's organization
+	trace.OrganizationID = opts.OrganizationID
+	if trace.ProjectID == "" {
+		trace.ProjectID = opts.ProjectID
+	}
+
+	var (
+		first, last time.Time
+		totalTokens int
+		totalCost   float64
+	)
+
+	for i := range trace.Spans {
+		span := &trace.Spans[i]
+		if span.SpanID == "" {
+			return fmt.Errorf("span %d is missing span_id", i)
+		}
+		span.TraceID = trace.TraceID
+
+		if span.EndTime.Before(span.StartTime) {
+			span.EndTime = span.StartTime
+		}
+		if span.DurationMs == 0 {
+			span.DurationMs = span.EndTime.Sub(span.StartTime).Milliseconds()
+		}
+		if span.TotalTokens == 0 {
+			span.TotalTokens = span.PromptTokens + span.CompletionTokens
+		}
+		if span.CostUSD == 0 && (span.TotalTokens > 0 || span.AudioSeconds > 0) {
+			s.traceService.priceSpan(span, trace.OrganizationID, trace.ProjectID, nil)
+		} else if span.CostUSD != 0 && span.PriceSource == "" {
+			span.PriceSource = models.PriceSourceClient
+		}
+		if span.Status == "" {
+			span.Status = "success"
+		}
+
+		if first.IsZero() || span.StartTime.Before(first) {
+			first = span.StartTime
+		}
+		if span.EndTime.After(last) {
+			last = span.EndTime
+		}
+		totalTokens += span.TotalTokens
+		totalCost += span.CostUSD
+
+		if trace.Model == "" && span.Model != "" {
+			trace.Model, trace.Provider = span.Model, span.Provider
+		}
+	}
+
+	if trace.Timestamp.IsZero() {
+		if first.IsZero() {
+			return fmt.Errorf("timestamp is required for traces without spans")
+		}
+		trace.Timestamp = first
+	}
+
+	if len(trace.Spans) > 0 {
+		if trace.DurationMs == 0 {
+			trace.DurationMs = last.Sub(first).Milliseconds()
+		}
+		if trace.TotalTokens == 0 {
+			trace.TotalTokens = totalTokens
+		}
+		if trace.TotalCostUSD == 0 {
+			trace.TotalCostUSD = totalCost
+		}
+	}
+
+	if trace.PriceSource == "" {
+		trace.PriceSource = tracePriceSource(trace.Spans)
+		if trace.PriceSource == "" && trace.TotalCostUSD != 0 {
+			trace.PriceSource = models.PriceSourceClient
+		}
+	}
+
+	if trace.Status == "" {
+		trace.Status = s.traceService.determineTraceStatus(trace.Spans)
+	}
+	if trace.TraceType == "" {
+		trace.TraceType = "single_call"
+		if len(trace.Spans) > 1 {
+			trace.TraceType = "multi_step"
+		}
+	}
+
+	return nil
+}
+
+// saveBatch stores the traces of a batch of lines. IDs the organization already stores are
+// skipped, so a file can be imported again; IDs stored by another organization fail their line.
+// Imported traces are historical, so no Kafka events are published for them.
+func (s *ImportService) saveBatch(ctx context.Context, batch []importedLine, opts ImportOptions, result *ImportResult, progress JobProgress) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for _, line := range batch {
+		for _, trace := range line.traces {
+			ids = append(ids, trace.TraceID)
+		}
+	}
+
+	existing, err := s.repo.GetExistingTraceIDs(ctx, opts.OrganizationID, ids)
+	if err != nil {
+		return err
+	}
+
+	expiredBefore := s.now().Add(-traceRetention)
+
+	var metrics []*models.Metric
+	for _, line := range batch {
+		var lineErr error
+		for _, trace := range line.traces {
+			if own, ok := existing[trace.TraceID]; ok {
+				if !own {
+					lineErr = fmt.Errorf("trace %s: ID is already used by another organization", trace.TraceID)
+					break
+				}
+				result.skip(ImportSkipExisting)
+				continue
+			}
+			if trace.Timestamp.Before(expiredBefore) {
+				result.skip(ImportSkipExpired)
+				continue
+			}
+			if err := s.repo.SaveTrace(ctx, trace); err != nil {
+				lineErr = fmt.Errorf("trace %s: %w", trace.TraceID, err)
+				break
+			}
+			// Guard against the same ID appearing twice in one file
+			existing[trace.TraceID] = true
+			result.Imported++
+			metrics = append(metrics, traceMetrics(trace)...)
+		}
+
+		if lineErr != nil {
+			result.fail(line.number, lineErr, progress)
+		} else if progress != nil {
+			progress.Add(1, 0)
+		}
+	}
+
+	if err := s.repo.SaveMetrics(ctx, metrics); err != nil {
+		log.Printf("❌ Failed to record metrics for imported traces: %v", err)
+	}
+
+	if progress != nil {
+		progress.SetResult("imported", result.Imported)
+		progress.SetResult("skipped", result.Skipped)
+		progress.SetResult("skipped_by", result.skippedBy())
+	}
+	return nil
+}
+
+// skippedBy copies the skip counts, for job results read while the import goes on
+func (r *ImportResult) skippedBy() map[string]int64 {
+	counts := make(map[string]int64, len(r.SkippedBy))
+	for reason, count := range r.SkippedBy {
+		counts[reason] = count
+	}
+	return counts
+}
+
+// skip counts a trace that was not imported, and why
+func (r *ImportResult) skip(reason string) {
+	r.Skipped++
+	r.SkippedBy[reason]++
+}
+
+// fail records a failed line, keeping at most maxImportErrors messages
+func (r *ImportResult) fail(line int64, err error, progress JobProgress) {
+	r.FailedLines++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, ImportLineError{Line: line, Error: err.Error()})
+	}
+	if progress != nil {
+		progress.Add(1, 1)
+	}
+}
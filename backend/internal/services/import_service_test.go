@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func newTestImportService(repo *mockRepository) *ImportService {
+	service := NewImportService(repo, NewJobManager(time.Hour), NewTraceService(repo, nil, NewPricingService(repo), nil, nil))
+	// The fixtures are from early 2025; import them as if it were still then
+	service.now = func() time.Time { return time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC) }
+	return service
+}
+
+func TestImport(t *testing.T) {
+	var saved []*models.Trace
+	repo := &mockRepository{
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			saved = append(saved, trace)
+			return nil
+		},
+	}
+
+	lines := []string{
+		// Langfuse trace with a generation missing its cost
+		`{"id":"lf-1","timestamp":"2025-03-01T10:00:00.000Z","name":"chat","userId":"u-1","tags":["prod"],"observations":[` +
+			`{"id":"obs-1","type":"GENERATION","name":"llm","startTime":"2025-03-01T10:00:00.000Z","endTime":"2025-03-01T10:00:02.000Z",` +
+			`"model":"gpt-4","input":[{"role":"user","content":"hi"}],"output":"hello","usage":{"input":1000,"output":500}}]}`,
+		// LangSmith root run with a failing child run
+		`{"id":"ls-root","trace_id":"ls-1","name":"agent","run_type":"chain","start_time":"2025-03-01T11:00:00.000000","end_time":"2025-03-01T11:00:05.000000",` +
+			`"inputs":{"q":"x"},"outputs":{"a":"y"},"child_runs":[{"id":"ls-child","name":"ChatAnthropic","run_type":"llm",` +
+			`"start_time":"2025-03-01T11:00:01","end_time":"2025-03-01T11:00:03","error":"rate limited","prompt_tokens":10,"completion_tokens":0,` +
+			`"total_cost":0.5,"extra":{"invocation_params":{"model":"claude-3-haiku"}}}]}`,
+		``,
+		`{"not":"a trace"}`,
+		`{broken json`,
+	}
+
+	result, err := newTestImportService(repo).Import(context.Background(), strings.NewReader(strings.Join(lines, "\n")), ImportOptions{
+		OrganizationID: "org-1",
+		ProjectID:      "proj-1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if result.Lines != 5 || result.Imported != 2 || result.FailedLines != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.Errors[0].Line != 4 || result.Errors[1].Line != 5 {
+		t.Errorf("errors reported on wrong lines: %+v", result.Errors)
+	}
+
+	langfuse := saved[0]
+	if langfuse.TraceID != "lf-1" || langfuse.OrganizationID != "org-1" || langfuse.ProjectID != "proj-1" || langfuse.UserID != "u-1" {
+		t.Errorf("langfuse trace identity not preserved: %+v", langfuse)
+	}
+	if !langfuse.Timestamp.Equal(time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("original timestamp not kept: %v", langfuse.Timestamp)
+	}
+	span := langfuse.Spans[0]
+	if span.Provider != "openai" || span.DurationMs != 2000 || span.Input != `[{"role":"user","content":"hi"}]` {
+		t.Errorf("langfuse span not mapped: %+v", span)
+	}
+	if span.CostUSD != 0.06 || langfuse.TotalCostUSD != 0.06 {
+		t.Errorf("expected missing cost to be calculated as 0.06, got %v / %v", span.CostUSD, langfuse.TotalCostUSD)
+	}
+
+	langsmith := saved[1]
+	if langsmith.TraceID != "ls-1" || len(langsmith.Spans) != 2 || langsmith.TraceType != "multi_step" {
+		t.Fatalf("langsmith trace not mapped: %+v", langsmith)
+	}
+	child := langsmith.Spans[1]
+	if child.ParentSpanID != "ls-root" || child.Status != "error" || child.CostUSD != 0.5 || child.Provider != "anthropic" {
+		t.Errorf("langsmith child run not mapped: %+v", child)
+	}
+	if langsmith.Status != "error" {
+		t.Errorf("expected trace status error, got %q", langsmith.Status)
+	}
+}
+
+func TestImportOTLPRoundTrip(t *testing.T) {
+	original := exportFixture()[0]
+
+	var buf bytes.Buffer
+	data, err := json.Marshal(OTLPTracesData{ResourceSpans: []OTLPResourceSpans{traceToOTLP(original)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(data)
+
+	var saved []*models.Trace
+	repo := &mockRepository{
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			saved = append(saved, trace)
+			return nil
+		},
+	}
+
+	result, err := newTestImportService(repo).Import(context.Background(), &buf, ImportOptions{
+		OrganizationID: "org-2",
+		Format:         ImportFormatOTLP,
+	}, nil)
+	if err != nil || result.Imported != 1 {
+		t.Fatalf("Import() = %+v, %v", result, err)
+	}
+
+	trace := saved[0]
+	if trace.OrganizationID != "org-2" || trace.ProjectID != original.ProjectID || len(trace.Spans) != 2 {
+		t.Fatalf("OTLP trace not mapped: %+v", trace)
+	}
+	if trace.Spans[0].PromptTokens != 10 || trace.Spans[0].Model != "gpt-4" {
+		t.Errorf("span attributes lost: %+v", trace.Spans[0])
+	}
+	if trace.Spans[1].ParentSpanID != trace.Spans[0].SpanID || trace.Spans[1].ErrorMessage != "boom" {
+		t.Errorf("span hierarchy or status lost: %+v", trace.Spans[1])
+	}
+}
+
+func TestImportSkipExisting(t *testing.T) {
+	saves := 0
+	repo := &mockRepository{
+		traces: []*models.Trace{{TraceID: "dup", OrganizationID: "org-1"}, {TraceID: "foreign", OrganizationID: "org-2"}},
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			saves++
+			return nil
+		},
+	}
+
+	input := `{"trace_id":"dup","timestamp":"2025-01-01T00:00:00Z","spans":[]}
+{"trace_id":"new","timestamp":"2025-01-01T00:00:00Z","spans":[]}
+{"trace_id":"new","timestamp":"2025-01-01T00:00:00Z","spans":[]}
+{"trace_id":"foreign","timestamp":"2025-01-01T00:00:00Z","spans":[]}`
+
+	result, err := newTestImportService(repo).Import(context.Background(), strings.NewReader(input), ImportOptions{
+		OrganizationID: "org-1",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saves != 1 || result.Imported != 1 || result.Skipped != 2 || result.SkippedBy[ImportSkipExisting] != 2 {
+		t.Errorf("expected one save and two skips, got saves=%d result=%+v", saves, result)
+	}
+	if result.FailedLines != 1 || len(result.Errors) != 1 || result.Errors[0].Line != 4 {
+		t.Errorf("expected the ID of another organization to fail its line, got %+v", result)
+	}
+}
+
+func TestImportSkipsExpired(t *testing.T) {
+	repo := &mockRepository{}
+	input := `{"trace_id":"old","timestamp":"2024-11-01T00:00:00Z","spans":[]}
+{"trace_id":"recent","timestamp":"2025-03-01T00:00:00Z","spans":[]}`
+
+	result, err := newTestImportService(repo).Import(context.Background(), strings.NewReader(input), ImportOptions{OrganizationID: "org-1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || result.SkippedBy[ImportSkipExpired] != 1 || result.FailedLines != 0 {
+		t.Errorf("expected the trace past the retention to be skipped as expired, got %+v", result)
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// Job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a snapshot of a background job's state
+type Job struct {
+	ID             string                 `json:"id"`
+	OrganizationID string                 `json:"organization_id"`
+	Type           string                 `json:"type"`
+	Status         string                 `json:"status"`
+	Processed      int64                  `json:"processed"`
+	Total          int64                  `json:"total"`
+	Errors         int64                  `json:"errors"`
+	Error          string                 `json:"error,omitempty"`
+	Params         map[string]interface{} `json:"params,omitempty"`
+	Result         map[string]interface{} `json:"result,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	StartedAt      *time.Time             `json:"started_at,omitempty"`
+	FinishedAt     *time.Time             `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job has reached a terminal status
+func (j *Job) Done() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobProgress lets a running job report how far it has got
+type JobProgress interface {
+	// SetTotal records the expected amount of work, if known
+	SetTotal(total int64)
+	// Add records processed units and failed units
+	Add(processed, failed int64)
+	// SetResult stores a result value visible through the job snapshot
+	SetResult(key string, value interface{})
+}
+
+// JobFunc is the body of a background job
+type JobFunc func(ctx context.Context, progress JobProgress) error
+
+// JobManager runs background jobs and tracks their progress in memory
+type JobManager struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	cancels   map[string]context.CancelFunc
+	onExpire  []func(job *Job)
+	retention time.Duration
+}
+
+// NewJobManager creates a job manager that forgets finished jobs after the retention period
+func NewJobManager(retention time.Duration) *JobManager {
+	m := &JobManager{
+		jobs:      make(map[string]*Job),
+		cancels:   make(map[string]context.CancelFunc),
+		retention: retention,
+	}
+
+	// Start cleanup goroutine
+	go m.cleanup()
+
+	return m
+}
+
+// OnExpire registers a hook called when a finished job is dropped, e.g. to delete its files
+func (m *JobManager) OnExpire(fn func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExpire = append(m.onExpire, fn)
+}
+
+// Start registers a job and runs fn in the background
+func (m *JobManager) Start(orgID, jobType string, params map[string]interface{}, fn JobFunc) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		ID:             uuid.New().String(),
+		OrganizationID: orgID,
+		Type:           jobType,
+		Status:         JobPending,
+		Params:         params,
+		Result:         make(map[string]interface{}),
+		CreatedAt:      time.Now(),
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.cancels[job.ID] = cancel
+	snapshot := m.snapshot(job)
+	m.mu.Unlock()
+
+	go m.run(ctx, job.ID, fn)
+
+	return snapshot
+}
+
+// Get returns a snapshot of a job, scoped to the organization
+func (m *JobManager) Get(orgID, jobID string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[jobID]
+	if !ok || (orgID != "" && job.OrganizationID != orgID) {
+		return nil, repository.ErrNotFound
+	}
+	return m.snapshot(job), nil
+}
+
+// List returns snapshots of an organization's jobs of the given type, newest first
+func (m *JobManager) List(orgID, jobType string) []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := []*Job{}
+	for _, job := range m.jobs {
+		if job.OrganizationID != orgID || (jobType != "" && job.Type != jobType) {
+			continue
+		}
+		jobs = append(jobs, m.snapshot(job))
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel requests cancellation of a running job
+func (m *JobManager) Cancel(orgID, jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[jobID]
+	if !ok || (orgID != "" && job.OrganizationID != orgID) {
+		return repository.ErrNotFound
+	}
+	if cancel, ok := m.cancels[jobID]; ok && !job.Done() {
+		cancel()
+	}
+	return nil
+}
+
+// run executes a job and records its terminal state
+func (m *JobManager) run(ctx context.Context, jobID string, fn JobFunc) {
+	now := time.Now()
+	m.update(jobID, func(job *Job) {
+		job.Status = JobRunning
+		job.StartedAt = &now
+	})
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("❌ Job %s panicked: %v", jobID, r)
+				err = errJobPanicked
+			}
+		}()
+		return fn(ctx, &jobProgress{manager: m, jobID: jobID})
+	}()
+
+	finished := time.Now()
+	m.update(jobID, func(job *Job) {
+		job.FinishedAt = &finished
+		switch {
+		case err == nil:
+			job.Status = JobCompleted
+		case ctx.Err() == context.Canceled:
+			job.Status = JobCancelled
+		default:
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+	})
+
+	m.mu.Lock()
+	if cancel, ok := m.cancels[jobID]; ok {
+		cancel()
+		delete(m.cancels, jobID)
+	}
+	m.mu.Unlock()
+}
+
+// update applies fn to a job under the manager lock
+func (m *JobManager) update(jobID string, fn func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[jobID]; ok {
+		fn(job)
+	}
+}
+
+// snapshot copies a job so callers never share state with the running job
+func (m *JobManager) snapshot(job *Job) *Job {
+	copied := *job
+	copied.Result = make(map[string]interface{}, len(job.Result))
+	for key, value := range job.Result {
+		copied.Result[key] = value
+	}
+	return &copied
+}
+
+// cleanup periodically removes finished jobs older than the retention period
+func (m *JobManager) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var expired []*Job
+
+		m.mu.Lock()
+		cutoff := time.Now().Add(-m.retention)
+		for jobID, job := range m.jobs {
+			if job.Done() && job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+				expired = append(expired, job)
+				delete(m.jobs, jobID)
+			}
+		}
+		hooks := m.onExpire
+		m.mu.Unlock()
+
+		for _, job := range expired {
+			for _, hook := range hooks {
+				hook(job)
+			}
+		}
+	}
+}
+
+// jobProgress implements JobProgress for a managed job
+type jobProgress struct {
+	manager *JobManager
+	jobID   string
+}
+
+func (p *jobProgress) SetTotal(total int64) {
+	p.manager.update(p.jobID, func(job *Job) {
+		job.Total = total
+	})
+}
+
+func (p *jobProgress) Add(processed, failed int64) {
+	p.manager.update(p.jobID, func(job *Job) {
+		job.Processed += processed
+		job.Errors += failed
+	})
+}
+
+func (p *jobProgress) SetResult(key string, value interface{}) {
+	p.manager.update(p.jobID, func(job *Job) {
+		job.Result[key] = value
+	})
+}
@@ -0,0 +1,590 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// planLimits are the quotas of each plan; organizations without a known plan get the
+// metering service's default one
+var planLimits = map[string]models.PlanLimits{
+	models.PlanLegacy: {
+		Plan:              models.PlanLegacy,
+		RequestsPerMinute: 10_000 / 60, // the 10,000 an hour API keys had before plans
+	},
+	models.PlanFree: {
+		Plan:              models.PlanFree,
+		TracesPerPeriod:   10_000,
+		SpansPerPeriod:    100_000,
+		BytesPerPeriod:    1 << 30,
+		RequestsPerMinute: 100,
+	},
+	models.PlanPro: {
+		Plan:              models.PlanPro,
+		TracesPerPeriod:   1_000_000,
+		SpansPerPeriod:    10_000_000,
+		BytesPerPeriod:    50 << 30,
+		RequestsPerMinute: 1000,
+	},
+	models.PlanEnterprise: {
+		Plan:              models.PlanEnterprise,
+		RequestsPerMinute: 5000,
+	},
+}
+
+// billingPeriodLayout is how billing periods are named in requests and exports
+const billingPeriodLayout = "2006-01"
+
+// QuotaExceededError is returned when ingesting a trace would go over a plan quota
+type QuotaExceededError struct {
+	Metric string
+	Usage  *models.Usage
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: the %s plan's monthly %s quota is used up", ErrQuotaExceeded, e.Usage.Plan, e.Metric)
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// usageCounts are metered quantities
+type usageCounts struct {
+	traces int64
+	spans  int64
+	bytes  int64
+}
+
+func (u *usageCounts) add(other usageCounts) {
+	u.traces += other.traces
+	u.spans += other.spans
+	u.bytes += other.bytes
+}
+
+// remove takes other away without going below zero, e.g. a reservation made before a refresh
+func (u *usageCounts) remove(other usageCounts) {
+	u.traces = max(0, u.traces-other.traces)
+	u.spans = max(0, u.spans-other.spans)
+	u.bytes = max(0, u.bytes-other.bytes)
+}
+
+// periodUsage is an organization's usage in one billing period
+type periodUsage struct {
+	periodStart time.Time
+	counts      usageCounts
+	reserved    usageCounts // of admitted traces not yet recorded or released
+}
+
+// usageKey identifies the usage of an organization on one day
+type usageKey struct {
+	orgID string
+	date  time.Time
+}
+
+// MeteringService counts the traces, spans and bytes each organization ingests per monthly
+// billing period and enforces the quotas of its plan. Counts are kept in memory, flushed to
+// the database and reloaded from it periodically, so instances sharing a database converge
+// on the same totals; usage not yet flushed is lost if the process crashes.
+type MeteringService struct {
+	repo        repository.Repository
+	defaultPlan string // of organizations without a stored plan
+
+	mu      sync.Mutex
+	plans   map[string]string         // by organization, until the next refresh
+	usage   map[string]*periodUsage   // by organization, in the current period
+	pending map[usageKey]*usageCounts // recorded but not yet flushed
+}
+
+// NewMeteringService creates a metering service; call Refresh to load the current period.
+// Organizations without a stored plan are on defaultPlan, the legacy plan when it is unknown.
+func NewMeteringService(repo repository.Repository, defaultPlan string) *MeteringService {
+	if _, ok := planLimits[defaultPlan]; !ok {
+		log.Printf("⚠️  Unknown default plan %q; organizations without a plan get the %s plan", defaultPlan, models.PlanLegacy)
+		defaultPlan = models.PlanLegacy
+	}
+
+	return &MeteringService{
+		repo:        repo,
+		defaultPlan: defaultPlan,
+		plans:       make(map[string]string),
+		usage:       make(map[string]*periodUsage),
+		pending:     make(map[usageKey]*usageCounts),
+	}
+}
+
+// Refresh reloads every organization's usage in the current period and forgets cached plans
+func (s *MeteringService) Refresh(ctx context.Context) error {
+	start, end := budgetPeriod(models.BudgetPeriodMonthly, time.Now())
+	records, err := s.repo.GetUsage(ctx, "", start, end)
+	if err != nil {
+		return err
+	}
+
+	usage := make(map[string]*periodUsage)
+	for _, record := range records {
+		orgUsage := usage[record.OrganizationID]
+		if orgUsage == nil {
+			orgUsage = &periodUsage{periodStart: start}
+			usage[record.OrganizationID] = orgUsage
+		}
+		orgUsage.counts.add(usageCounts{traces: record.Traces, spans: record.Spans, bytes: record.Bytes})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Traces admitted before the refresh are still in flight
+	for orgID, previous := range s.usage {
+		if previous.periodStart.Equal(start) && previous.reserved != (usageCounts{}) {
+			orgUsage := usage[orgID]
+			if orgUsage == nil {
+				orgUsage = &periodUsage{periodStart: start}
+				usage[orgID] = orgUsage
+			}
+			orgUsage.reserved = previous.reserved
+		}
+	}
+
+	// Usage recorded since the last flush is not in the database yet
+	for key, counts := range s.pending {
+		if key.date.Before(start) {
+			continue
+		}
+		orgUsage := usage[key.orgID]
+		if orgUsage == nil {
+			orgUsage = &periodUsage{periodStart: start}
+			usage[key.orgID] = orgUsage
+		}
+		orgUsage.counts.add(*counts)
+	}
+
+	s.usage = usage
+	s.plans = make(map[string]string)
+	return nil
+}
+
+// Flush writes the usage recorded since the last flush to the database
+func (s *MeteringService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]*usageCounts)
+	s.mu.Unlock()
+
+	records := make([]*models.UsageRecord, 0, len(pending))
+	for key, counts := range pending {
+		records = append(records, &models.UsageRecord{
+			OrganizationID: key.orgID,
+			Date:           key.date,
+			Traces:         counts.traces,
+			Spans:          counts.spans,
+			Bytes:          counts.bytes,
+		})
+	}
+
+	if err := s.repo.SaveUsage(ctx, records); err != nil {
+		// Keep the usage for the next flush
+		s.mu.Lock()
+		for key, counts := range pending {
+			s.addPending(key, *counts)
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Start flushes usage and reloads the totals in the background until ctx is cancelled
+func (s *MeteringService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := s.Flush(context.Background()); err != nil {
+					log.Printf("❌ Failed to flush usage: %v", err)
+				}
+				return
+			case <-ticker.C:
+				if err := s.Flush(ctx); err != nil {
+					log.Printf("❌ Failed to flush usage: %v", err)
+				}
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh usage: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Limits returns the quotas of an organization's plan
+func (s *MeteringService) Limits(ctx context.Context, orgID string) (models.PlanLimits, error) {
+	s.mu.Lock()
+	plan, cached := s.plans[orgID]
+	s.mu.Unlock()
+
+	if !cached {
+		org, err := s.repo.GetOrganization(ctx, orgID)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			plan = ""
+		case err != nil:
+			return models.PlanLimits{}, fmt.Errorf("failed to look up the plan of organization %s: %w", orgID, err)
+		default:
+			plan = org.Plan
+		}
+
+		s.mu.Lock()
+		s.plans[orgID] = plan
+		s.mu.Unlock()
+	}
+
+	limits, ok := planLimits[plan]
+	if !ok {
+		limits = planLimits[s.defaultPlan]
+	}
+	return limits, nil
+}
+
+// SetPlan changes an organization's plan, storing the organization when it has no record yet
+func (s *MeteringService) SetPlan(ctx context.Context, orgID, plan string) (models.PlanLimits, error) {
+	if orgID == "" {
+		return models.PlanLimits{}, invalidArgument("organization_id is required")
+	}
+	limits, ok := planLimits[plan]
+	if !ok {
+		return models.PlanLimits{}, invalidArgument("plan must be %s, %s, %s or %s", models.PlanLegacy, models.PlanFree, models.PlanPro, models.PlanEnterprise)
+	}
+
+	now := time.Now()
+	org, err := s.repo.GetOrganization(ctx, orgID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		org = &models.Organization{ID: orgID, Name: orgID, CreatedAt: now}
+	case err != nil:
+		return models.PlanLimits{}, err
+	}
+
+	// Saving the organization again records a new version
+	updated := *org
+	updated.Plan = plan
+	updated.UpdatedAt = now
+	if err := s.repo.CreateOrganization(ctx, &updated); err != nil {
+		return models.PlanLimits{}, err
+	}
+
+	s.mu.Lock()
+	s.plans[orgID] = plan
+	s.mu.Unlock()
+	return limits, nil
+}
+
+// RequestsPerMinute returns the API rate limit of an organization's plan
+func (s *MeteringService) RequestsPerMinute(orgID string) int {
+	limits, err := s.Limits(context.Background(), orgID)
+	if err != nil {
+		log.Printf("❌ %v; applying the %s plan's rate limit", err, s.defaultPlan)
+		return planLimits[s.defaultPlan].RequestsPerMinute
+	}
+	return limits.RequestsPerMinute
+}
+
+// Admit returns a QuotaExceededError when storing a trace would go over a quota of the
+// organization's plan. An admitted trace's counts are reserved until it is recorded, or
+// released if it could not be stored, so concurrent traces can't overrun a quota. When the
+// plan can't be looked up the trace is admitted rather than failing ingestion.
+func (s *MeteringService) Admit(ctx context.Context, trace *models.Trace) error {
+	limits, err := s.Limits(ctx, trace.OrganizationID)
+	if err != nil {
+		log.Printf("❌ %v; admitting the trace without checking quotas", err)
+		return nil
+	}
+
+	counts := traceUsage(trace)
+	start, end := budgetPeriod(models.BudgetPeriodMonthly, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgUsage := s.current(trace.OrganizationID, start)
+	used := orgUsage.counts
+	used.add(orgUsage.reserved)
+
+	quotas := []struct {
+		metric      string
+		used, added int64
+		limit       int64
+	}{
+		{models.UsageMetricTraces, used.traces, counts.traces, limits.TracesPerPeriod},
+		{models.UsageMetricSpans, used.spans, counts.spans, limits.SpansPerPeriod},
+		{models.UsageMetricBytes, used.bytes, counts.bytes, limits.BytesPerPeriod},
+	}
+	for _, quota := range quotas {
+		if quota.limit > 0 && quota.used+quota.added > quota.limit {
+			return &QuotaExceededError{
+				Metric: quota.metric,
+				Usage:  newUsage(trace.OrganizationID, limits, start, end, orgUsage.counts),
+			}
+		}
+	}
+
+	orgUsage.reserved.add(counts)
+	return nil
+}
+
+// Record counts a stored trace towards its organization's usage
+func (s *MeteringService) Record(trace *models.Trace) {
+	counts := traceUsage(trace)
+	now := time.Now().UTC()
+	start, _ := budgetPeriod(models.BudgetPeriodMonthly, now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgUsage := s.current(trace.OrganizationID, start)
+	orgUsage.reserved.remove(counts)
+	orgUsage.counts.add(counts)
+	s.addPending(usageKey{orgID: trace.OrganizationID, date: now.Truncate(24 * time.Hour)}, counts)
+}
+
+// Release drops the reservation of an admitted trace that could not be stored
+func (s *MeteringService) Release(trace *models.Trace) {
+	start, _ := budgetPeriod(models.BudgetPeriodMonthly, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current(trace.OrganizationID, start).reserved.remove(traceUsage(trace))
+}
+
+// GetUsage returns an organization's usage against its plan in a billing period (YYYY-MM),
+// the current one when period is empty
+func (s *MeteringService) GetUsage(ctx context.Context, orgID, period string) (*models.Usage, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	start, end, err := billingPeriod(period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := s.Limits(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	orgUsage, counted := s.usage[orgID]
+	s.mu.Unlock()
+
+	// The running count covers the current period; earlier ones come from the database
+	var used usageCounts
+	if counted && orgUsage.periodStart.Equal(start) {
+		used = orgUsage.counts
+	} else {
+		records, err := s.usageRecords(ctx, orgID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			used.add(usageCounts{traces: record.Traces, spans: record.Spans, bytes: record.Bytes})
+		}
+	}
+
+	return newUsage(orgID, limits, start, end, used), nil
+}
+
+// ExportUsage returns an organization's daily usage in a billing period (YYYY-MM) for
+// billing systems, the current period when period is empty
+func (s *MeteringService) ExportUsage(ctx context.Context, orgID, period string) ([]models.UsageExportRecord, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+
+	start, end, err := billingPeriod(period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := s.Limits(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.usageRecords(ctx, orgID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	export := make([]models.UsageExportRecord, 0, len(records))
+	for _, record := range records {
+		date := record.Date.Format("2006-01-02")
+		export = append(export, models.UsageExportRecord{
+			ID:             orgID + ":" + date,
+			OrganizationID: orgID,
+			Plan:           limits.Plan,
+			Period:         start.Format(billingPeriodLayout),
+			Date:           date,
+			Traces:         record.Traces,
+			Spans:          record.Spans,
+			Bytes:          record.Bytes,
+		})
+	}
+	return export, nil
+}
+
+// usageRecords returns an organization's stored daily usage merged with the usage not yet flushed
+func (s *MeteringService) usageRecords(ctx context.Context, orgID string, start, end time.Time) ([]*models.UsageRecord, error) {
+	records, err := s.repo.GetUsage(ctx, orgID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[time.Time]*models.UsageRecord, len(records))
+	for _, record := range records {
+		byDate[record.Date.UTC()] = record
+	}
+
+	s.mu.Lock()
+	for key, counts := range s.pending {
+		if key.orgID != orgID || key.date.Before(start) || !key.date.Before(end) {
+			continue
+		}
+		record := byDate[key.date]
+		if record == nil {
+			record = &models.UsageRecord{OrganizationID: orgID, Date: key.date}
+			byDate[key.date] = record
+			records = append(records, record)
+		}
+		record.Traces += counts.traces
+		record.Spans += counts.spans
+		record.Bytes += counts.bytes
+	}
+	s.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Date.Before(records[j].Date)
+	})
+	return records, nil
+}
+
+// current returns an organization's running usage in the period starting at start; the
+// caller holds s.mu
+func (s *MeteringService) current(orgID string, start time.Time) *periodUsage {
+	orgUsage := s.usage[orgID]
+	if orgUsage == nil || !orgUsage.periodStart.Equal(start) {
+		orgUsage = &periodUsage{periodStart: start}
+		s.usage[orgID] = orgUsage
+	}
+	return orgUsage
+}
+
+// addPending adds usage to be flushed; the caller holds s.mu
+func (s *MeteringService) addPending(key usageKey, counts usageCounts) {
+	pending := s.pending[key]
+	if pending == nil {
+		pending = &usageCounts{}
+		s.pending[key] = pending
+	}
+	pending.add(counts)
+}
+
+// WriteUsageCSV writes a usage export as CSV with a header row
+func WriteUsageCSV(w io.Writer, records []models.UsageExportRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "organization_id", "plan", "period", "date", "traces", "spans", "bytes"}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := writer.Write([]string{
+			record.ID,
+			record.OrganizationID,
+			record.Plan,
+			record.Period,
+			record.Date,
+			strconv.FormatInt(record.Traces, 10),
+			strconv.FormatInt(record.Spans, 10),
+			strconv.FormatInt(record.Bytes, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// traceUsage returns what a trace counts towards usage; its size is that of its JSON encoding
+func traceUsage(trace *models.Trace) usageCounts {
+	counts := usageCounts{traces: 1, spans: int64(len(trace.Spans))}
+	if data, err := json.Marshal(trace); err == nil {
+		counts.bytes = int64(len(data))
+	}
+	return counts
+}
+
+// newUsage reports usage against the quotas of a plan
+func newUsage(orgID string, limits models.PlanLimits, start, end time.Time, used usageCounts) *models.Usage {
+	return &models.Usage{
+		OrganizationID: orgID,
+		Plan:           limits.Plan,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		Traces:         used.traces,
+		Spans:          used.spans,
+		Bytes:          used.bytes,
+		Limits:         limits,
+		Quotas: []models.QuotaStatus{
+			quotaStatus(models.UsageMetricTraces, used.traces, limits.TracesPerPeriod),
+			quotaStatus(models.UsageMetricSpans, used.spans, limits.SpansPerPeriod),
+			quotaStatus(models.UsageMetricBytes, used.bytes, limits.BytesPerPeriod),
+		},
+	}
+}
+
+// quotaStatus reports the consumption of one quota; a zero limit is unlimited
+func quotaStatus(metric string, used, limit int64) models.QuotaStatus {
+	status := models.QuotaStatus{Metric: metric, Used: used, Limit: limit}
+	if limit <= 0 {
+		return status
+	}
+
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	status.Remaining = &remaining
+	status.PercentUsed = float64(used) / float64(limit) * 100
+	status.Exceeded = used >= limit
+	return status
+}
+
+// billingPeriod resolves a billing period named YYYY-MM to its UTC month, the month of now
+// when period is empty
+func billingPeriod(period string, now time.Time) (time.Time, time.Time, error) {
+	if period == "" {
+		start, end := budgetPeriod(models.BudgetPeriodMonthly, now)
+		return start, end, nil
+	}
+
+	start, err := time.Parse(billingPeriodLayout, period)
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidArgument("invalid period %q; use YYYY-MM", period)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestBillingPeriod(t *testing.T) {
+	now := time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	start, end, err := billingPeriod("", now)
+	if err != nil || !start.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the current month, got %v to %v (%v)", start, end, err)
+	}
+
+	start, end, err = billingPeriod("2024-12", now)
+	if err != nil || !start.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected December 2024, got %v to %v (%v)", start, end, err)
+	}
+
+	if _, _, err := billingPeriod("2024-13", now); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an invalid period, got %v", err)
+	}
+}
+
+func TestQuotaRejectsTraces(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	saved := 0
+	repo := &mockRepository{
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			saved++
+			return nil
+		},
+		organizations: map[string]*models.Organization{
+			"org-big": {ID: "org-big", Plan: models.PlanEnterprise},
+		},
+		usage: []*models.UsageRecord{
+			{OrganizationID: "org-1", Date: today, Traces: 9999, Spans: 20000, Bytes: 1 << 20},
+			{OrganizationID: "org-big", Date: today, Traces: 5000000},
+		},
+	}
+	meter := NewMeteringService(repo, models.PlanFree)
+	if err := meter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	service := NewTraceService(repo, nil, NewPricingService(repo), nil, meter)
+
+	trace := func(orgID string) *models.TraceRequest {
+		return &models.TraceRequest{
+			OrganizationID: orgID,
+			ProjectID:      "proj-1",
+			TraceType:      "single_call",
+			Spans:          []models.SpanRequest{{Name: "call", Model: "gpt-4", Provider: "openai", PromptTokens: 10, Status: "success"}},
+		}
+	}
+
+	// An organization without a stored plan is on the default, here the free plan of 10,000 traces a month
+	if _, err := service.CreateTrace(context.Background(), trace("org-1")); err != nil {
+		t.Fatalf("expected the last trace of the quota to be accepted, got %v", err)
+	}
+
+	_, err := service.CreateTrace(context.Background(), trace("org-1"))
+	var exceeded *QuotaExceededError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &exceeded) || exceeded.Metric != models.UsageMetricTraces {
+		t.Fatalf("expected the traces quota to reject the trace, got %v", err)
+	}
+	if exceeded.Usage.Plan != models.PlanFree || exceeded.Usage.Traces != 10000 || !exceeded.Usage.Quotas[0].Exceeded {
+		t.Errorf("unexpected usage in the rejection %+v", exceeded.Usage)
+	}
+	if saved != 1 {
+		t.Errorf("a rejected trace must not be stored, got %d saved", saved)
+	}
+
+	if _, err := service.CreateTrace(context.Background(), trace("org-big")); err != nil {
+		t.Errorf("enterprise quotas are unlimited, got %v", err)
+	}
+
+	if limit := meter.RequestsPerMinute("org-big"); limit != planLimits[models.PlanEnterprise].RequestsPerMinute {
+		t.Errorf("expected the enterprise rate limit, got %d", limit)
+	}
+}
+
+func TestQuotaReservesAdmittedTraces(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	saveErr := errors.New("clickhouse unavailable")
+	repo := &mockRepository{
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			return saveErr
+		},
+		usage: []*models.UsageRecord{{OrganizationID: "org-1", Date: today, Traces: 9999}},
+	}
+	meter := NewMeteringService(repo, models.PlanFree)
+	if err := meter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two traces admitted before either is stored can't both take the last one of the quota
+	first := &models.Trace{OrganizationID: "org-1"}
+	if err := meter.Admit(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	if err := meter.Admit(context.Background(), &models.Trace{OrganizationID: "org-1"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected the reservation to use up the quota, got %v", err)
+	}
+
+	// A refresh keeps the reservation of a trace still in flight
+	if err := meter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := meter.Admit(context.Background(), &models.Trace{OrganizationID: "org-1"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected the reservation to survive a refresh, got %v", err)
+	}
+	meter.Release(first)
+
+	// A trace that fails to save gives its reservation back
+	service := NewTraceService(repo, nil, NewPricingService(repo), nil, meter)
+	request := &models.TraceRequest{OrganizationID: "org-1", TraceType: "single_call", Spans: []models.SpanRequest{{Name: "call", Status: "success"}}}
+	if _, err := service.CreateTrace(context.Background(), request); !errors.Is(err, saveErr) {
+		t.Fatalf("expected the save to fail, got %v", err)
+	}
+	if err := meter.Admit(context.Background(), &models.Trace{OrganizationID: "org-1"}); err != nil {
+		t.Errorf("expected the failed trace's reservation to be released, got %v", err)
+	}
+}
+
+func TestLegacyPlanAndSetPlan(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	repo := &mockRepository{usage: []*models.UsageRecord{{OrganizationID: "org-1", Date: today, Traces: 50000}}}
+	meter := NewMeteringService(repo, models.PlanLegacy)
+	if err := meter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	trace := &models.Trace{OrganizationID: "org-1", Spans: make([]models.Span, 1)}
+
+	// Organizations without a plan keep ingesting, at the rate limit API keys had before
+	if err := meter.Admit(ctx, trace); err != nil {
+		t.Errorf("expected the legacy plan to have no quotas, got %v", err)
+	}
+	if limit := meter.RequestsPerMinute("org-1"); limit != 166 {
+		t.Errorf("expected 10,000 requests an hour, got %d a minute", limit)
+	}
+
+	if _, err := meter.SetPlan(ctx, "org-1", "platinum"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an unknown plan, got %v", err)
+	}
+	limits, err := meter.SetPlan(ctx, "org-1", models.PlanFree)
+	if err != nil || limits.Plan != models.PlanFree || repo.organizations["org-1"].Plan != models.PlanFree {
+		t.Fatalf("expected the free plan to be stored, got %+v, %v", limits, err)
+	}
+	if err := meter.Admit(ctx, trace); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected the free plan's quota to apply at once, got %v", err)
+	}
+
+	// A failed plan lookup admits the trace rather than failing ingestion
+	other := NewMeteringService(&mockRepository{orgErr: errors.New("connection refused")}, models.PlanFree)
+	if err := other.Admit(ctx, trace); err != nil {
+		t.Errorf("expected the trace to be admitted, got %v", err)
+	}
+}
+
+func TestUsageFlushAndExport(t *testing.T) {
+	repo := &mockRepository{organizations: map[string]*models.Organization{
+		"org-1": {ID: "org-1", Plan: models.PlanPro},
+	}}
+	meter := NewMeteringService(repo, models.PlanFree)
+
+	for i := 0; i < 3; i++ {
+		meter.Record(&models.Trace{TraceID: "t", OrganizationID: "org-1", Spans: make([]models.Span, 2)})
+	}
+
+	usage, err := meter.GetUsage(context.Background(), "org-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Plan != models.PlanPro || usage.Traces != 3 || usage.Spans != 6 || usage.Bytes == 0 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if quota := usage.Quotas[0]; quota.Limit != 1000000 || *quota.Remaining != 999997 || quota.Exceeded {
+		t.Errorf("unexpected traces quota %+v", quota)
+	}
+
+	// Usage not yet flushed is part of the export
+	export, err := meter.ExportUsage(context.Background(), "org-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export) != 1 || export[0].Traces != 3 || export[0].Plan != models.PlanPro || export[0].ID != "org-1:"+export[0].Date {
+		t.Fatalf("unexpected export %+v", export)
+	}
+
+	if err := meter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.usage) != 1 || repo.usage[0].Traces != 3 || repo.usage[0].Spans != 6 {
+		t.Fatalf("expected one flushed record, got %+v", repo.usage)
+	}
+
+	// After a flush and refresh the totals come from the database alone
+	if err := meter.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if usage, _ := meter.GetUsage(context.Background(), "org-1", ""); usage.Traces != 3 {
+		t.Errorf("expected usage to survive a flush, got %d traces", usage.Traces)
+	}
+	if export, _ := meter.ExportUsage(context.Background(), "org-1", ""); len(export) != 1 || export[0].Traces != 3 {
+		t.Errorf("expected flushed usage not to be counted twice, got %+v", export)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteUsageCSV(&buf, export); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "id,organization_id,plan,period,date,traces,spans,bytes" || !strings.HasPrefix(lines[1], "org-1:") {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+
+	if usage, err := meter.GetUsage(context.Background(), "org-1", "2001-01"); err != nil || usage.Traces != 0 {
+		t.Errorf("expected no usage in an earlier period, got %+v, %v", usage, err)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	maxMetricBatchSize   = 1000
+	maxMetricTags        = 20
+	maxMetricTagValueLen = 256
+
+	// metricRetention matches the TTL of the metrics table
+	metricRetention = 90 * 24 * time.Hour
+	// maxMetricClockSkew is how far in the future a data point may be timestamped
+	maxMetricClockSkew = time.Hour
+)
+
+var (
+	metricNamePattern   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.]{0,127}$`)
+	metricTagKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]{0,63}$`)
+)
+
+// reservedMetricNames are recorded for traces by traceMetrics
+var reservedMetricNames = map[string]bool{
+	"requests":       true,
+	"errors":         true,
+	"cost_usd":       true,
+	"tokens":         true,
+	"latency_ms":     true,
+	"unpriced_spans": true,
+}
+
+// metricAggregations lists the aggregations a custom metric query supports
+var metricAggregations = map[string]bool{
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+	"count": true,
+	"rate":  true,
+	"p50":   true,
+	"p90":   true,
+	"p95":   true,
+	"p99":   true,
+}
+
+// MetricService handles application-level metrics sent alongside traces
+type MetricService struct {
+	repo repository.Repository
+}
+
+// NewMetricService creates a new metric service
+func NewMetricService(repo repository.Repository) *MetricService {
+	return &MetricService{
+		repo: repo,
+	}
+}
+
+// RecordMetric validates and stores a single data point
+func (s *MetricService) RecordMetric(ctx context.Context, req *models.MetricRequest) error {
+	metric, err := buildMetric(req, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SaveMetric(ctx, metric); err != nil {
+		return fmt.Errorf("failed to save metric: %w", err)
+	}
+	return nil
+}
+
+// RecordMetrics validates a batch and stores its valid data points together.
+// Invalid data points are rejected individually without failing the batch.
+func (s *MetricService) RecordMetrics(ctx context.Context, reqs []models.MetricRequest) (*models.BatchMetricResponse, error) {
+	if len(reqs) == 0 {
+		return nil, invalidArgument("batch must contain at least one metric")
+	}
+	if len(reqs) > maxMetricBatchSize {
+		return nil, invalidArgument("batch size cannot exceed %d metrics", maxMetricBatchSize)
+	}
+
+	now := time.Now()
+	response := &models.BatchMetricResponse{}
+	metrics := make([]*models.Metric, 0, len(reqs))
+
+	for i := range reqs {
+		metric, err := buildMetric(&reqs[i], now)
+		if err != nil {
+			response.Rejected++
+			response.Errors = append(response.Errors, fmt.Sprintf("Metric %d: %v", i, err))
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+
+	if err := s.repo.SaveMetrics(ctx, metrics); err != nil {
+		return nil, fmt.Errorf("failed to save metrics: %w", err)
+	}
+	response.Accepted = len(metrics)
+
+	return response, nil
+}
+
+// buildMetric validates a data point and converts its tags to strings
+func buildMetric(req *models.MetricRequest, now time.Time) (*models.Metric, error) {
+	if req.OrganizationID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if !metricNamePattern.MatchString(req.Name) {
+		return nil, invalidArgument("metric name %q must start with a letter and contain only letters, digits, '_' and '.'", req.Name)
+	}
+	if reservedMetricNames[req.Name] {
+		return nil, invalidArgument("metric name %q is reserved for trace metrics", req.Name)
+	}
+	if math.IsNaN(req.Value) || math.IsInf(req.Value, 0) {
+		return nil, invalidArgument("metric value must be a finite number")
+	}
+
+	timestamp := now
+	if req.Timestamp != nil {
+		timestamp = *req.Timestamp
+	}
+	if timestamp.After(now.Add(maxMetricClockSkew)) {
+		return nil, invalidArgument("metric timestamp is in the future")
+	}
+	if timestamp.Before(now.Add(-metricRetention)) {
+		return nil, invalidArgument("metric timestamp is older than the %d day retention", int(metricRetention.Hours()/24))
+	}
+
+	tags, err := metricTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	metric := &models.Metric{
+		MetricName:     req.Name,
+		MetricValue:    req.Value,
+		Timestamp:      timestamp,
+		OrganizationID: req.OrganizationID,
+		ProjectID:      req.ProjectID,
+		Tags:           tags,
+	}
+	metric.Model, _ = tags["model"].(string)
+	metric.Provider, _ = tags["provider"].(string)
+
+	return metric, nil
+}
+
+// metricTags validates tags and stores every value as a string so tags filter and group alike
+func metricTags(raw map[string]interface{}) (map[string]interface{}, error) {
+	if len(raw) > maxMetricTags {
+		return nil, invalidArgument("at most %d tags are allowed", maxMetricTags)
+	}
+
+	tags := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		if !metricTagKeyPattern.MatchString(key) {
+			return nil, invalidArgument("tag key %q must start with a letter or '_' and contain only letters, digits, '_', '.' and '-'", key)
+		}
+
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			text = strconv.FormatBool(v)
+		default:
+			return nil, invalidArgument("tag %q must be a string, number or boolean", key)
+		}
+
+		if len(text) > maxMetricTagValueLen {
+			return nil, invalidArgument("tag %q is longer than %d characters", key, maxMetricTagValueLen)
+		}
+		tags[key] = text
+	}
+
+	return tags, nil
+}
+
+// QueryMetrics aggregates a custom metric over time. Long ranges are read from the
+// metrics_hourly rollup when it can answer the query, and from raw metrics otherwise.
+func (s *MetricService) QueryMetrics(ctx context.Context, query *models.CustomMetricQuery) (*MetricSeriesReport, error) {
+	if err := s.validateMetricQuery(query); err != nil {
+		return nil, err
+	}
+
+	series, err := s.repo.QueryMetrics(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+
+	// An ungrouped series always has one line, even over a period without data
+	if query.GroupBy == "" && len(series) == 0 {
+		series = []*models.TimeSeries{{}}
+	}
+
+	for _, group := range series {
+		group.Points = fillSeriesGaps(group.Points, query.StartTime, query.EndTime, query.Granularity)
+	}
+
+	return &MetricSeriesReport{
+		Metric:      query.MetricName,
+		Aggregation: query.Aggregation,
+		Interval:    query.Granularity,
+		GroupBy:     query.GroupBy,
+		Source:      query.Source,
+		StartTime:   query.StartTime,
+		EndTime:     query.EndTime,
+		Series:      series,
+	}, nil
+}
+
+// validateMetricQuery checks a custom metric query, fills in its defaults and picks its source
+func (s *MetricService) validateMetricQuery(query *models.CustomMetricQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return invalidArgument("start_time must be before end_time")
+	}
+	if !metricNamePattern.MatchString(query.MetricName) {
+		return invalidArgument("a valid metric name is required")
+	}
+
+	if query.Aggregation == "" {
+		query.Aggregation = "sum"
+	}
+	if !metricAggregations[query.Aggregation] {
+		return invalidArgument("aggregation must be sum, avg, min, max, count, rate, p50, p90, p95 or p99")
+	}
+
+	if query.GroupBy != "" && query.GroupBy != "project" && !metricTagKeyPattern.MatchString(query.GroupBy) {
+		return invalidArgument("group_by must be project or a tag key")
+	}
+	for key := range query.Tags {
+		if !metricTagKeyPattern.MatchString(key) {
+			return invalidArgument("invalid tag filter %q", key)
+		}
+	}
+
+	interval, err := validateSeriesInterval(query.Granularity, query.StartTime, query.EndTime)
+	if err != nil {
+		return err
+	}
+	query.Granularity = interval
+	query.Limit = seriesGroupLimit(query.Limit)
+
+	query.Source = metricSeriesSource(query)
+	return nil
+}
+
+// metricSeriesSource picks metrics_hourly for long ranges it can answer, and raw metrics otherwise.
+// The rollup keeps only the model and provider tags, no extremes, and no sub-hour buckets.
+func metricSeriesSource(query *models.CustomMetricQuery) string {
+	if query.EndTime.Sub(query.StartTime) <= hourlyRollupThreshold || query.Granularity == "minute" {
+		return repository.SeriesSourceMetrics
+	}
+	if query.Aggregation == "min" || query.Aggregation == "max" {
+		return repository.SeriesSourceMetrics
+	}
+	switch query.GroupBy {
+	case "", "project", "model", "provider":
+	default:
+		return repository.SeriesSourceMetrics
+	}
+	for key := range query.Tags {
+		if key != "model" && key != "provider" {
+			return repository.SeriesSourceMetrics
+		}
+	}
+	return repository.SeriesSourceHourly
+}
+
+// MetricSeriesReport is one custom metric aggregated over time for each group
+type MetricSeriesReport struct {
+	Metric      string               `json:"metric"`
+	Aggregation string               `json:"aggregation"`
+	Interval    string               `json:"interval"`
+	GroupBy     string               `json:"group_by,omitempty"`
+	Source      string               `json:"source"` // metrics, metrics_hourly
+	StartTime   time.Time            `json:"start_time"`
+	EndTime     time.Time            `json:"end_time"`
+	Series      []*models.TimeSeries `json:"series"`
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	var saved []*models.Metric
+	repo := &mockRepository{
+		saveMetricFunc: func(ctx context.Context, metric *models.Metric) error {
+			saved = append(saved, metric)
+			return nil
+		},
+	}
+
+	future := time.Now().Add(2 * time.Hour)
+	response, err := NewMetricService(repo).RecordMetrics(context.Background(), []models.MetricRequest{
+		{OrganizationID: "org-1", Name: "cache.hit_rate", Value: 0.8, Tags: map[string]interface{}{"model": "gpt-4", "shard": float64(3), "warm": true}},
+		{OrganizationID: "org-1", Name: "thumbs_up", Value: 1},
+		{OrganizationID: "org-1", Name: "latency_ms", Value: 10},
+		{OrganizationID: "org-1", Name: "guardrail", Value: math.NaN()},
+		{OrganizationID: "org-1", Name: "guardrail", Value: 1, Timestamp: &future},
+		{OrganizationID: "org-1", Name: "guardrail", Value: 1, Tags: map[string]interface{}{"bad key": "x"}},
+		{OrganizationID: "org-1", Name: "guardrail", Value: 1, Tags: map[string]interface{}{"nested": map[string]interface{}{}}},
+		{Name: "guardrail", Value: 1},
+	})
+	if err != nil {
+		t.Fatalf("RecordMetrics() error = %v", err)
+	}
+
+	if response.Accepted != 2 || response.Rejected != 6 || len(response.Errors) != 6 {
+		t.Fatalf("expected 2 accepted and 6 rejected, got %+v", response)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("expected 2 saved metrics, got %d", len(saved))
+	}
+	if saved[0].Tags["shard"] != "3" || saved[0].Tags["warm"] != "true" || saved[0].Model != "gpt-4" {
+		t.Errorf("expected tags stored as strings, got %+v", saved[0])
+	}
+	if saved[1].Timestamp.IsZero() {
+		t.Error("expected a missing timestamp to default to now")
+	}
+}
+
+func TestRecordMetricsBatchSize(t *testing.T) {
+	service := NewMetricService(&mockRepository{})
+
+	if _, err := service.RecordMetrics(context.Background(), nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an empty batch, got %v", err)
+	}
+	if _, err := service.RecordMetrics(context.Background(), make([]models.MetricRequest, maxMetricBatchSize+1)); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an oversized batch, got %v", err)
+	}
+}
+
+func TestQueryMetricsSource(t *testing.T) {
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		window      time.Duration
+		aggregation string
+		groupBy     string
+		tags        map[string]string
+		want        string
+	}{
+		{"short range", 24 * time.Hour, "avg", "", nil, "metrics"},
+		{"long range", 30 * 24 * time.Hour, "p95", "model", map[string]string{"provider": "openai"}, "metrics_hourly"},
+		{"long range extremes", 30 * 24 * time.Hour, "max", "", nil, "metrics"},
+		{"long range by custom tag", 30 * 24 * time.Hour, "sum", "feature", nil, "metrics"},
+		{"long range filtered by custom tag", 30 * 24 * time.Hour, "rate", "", map[string]string{"env": "prod"}, "metrics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			report, err := NewMetricService(repo).QueryMetrics(context.Background(), &models.CustomMetricQuery{
+				MetricQuery: models.MetricQuery{
+					OrganizationID: "org-1",
+					MetricName:     "cache.hit_rate",
+					StartTime:      end.Add(-tt.window),
+					EndTime:        end,
+				},
+				Aggregation: tt.aggregation,
+				GroupBy:     tt.groupBy,
+				Tags:        tt.tags,
+			})
+			if err != nil {
+				t.Fatalf("QueryMetrics() error = %v", err)
+			}
+			if got := repo.metricQueries[0].Source; got != tt.want {
+				t.Errorf("source = %s, want %s", got, tt.want)
+			}
+			if tt.groupBy == "" && (len(report.Series) != 1 || len(report.Series[0].Points) == 0) {
+				t.Errorf("expected one zero-filled series, got %+v", report.Series)
+			}
+		})
+	}
+}
+
+func TestQueryMetricsValidation(t *testing.T) {
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query models.CustomMetricQuery
+	}{
+		{"missing name", models.CustomMetricQuery{}},
+		{"unknown aggregation", models.CustomMetricQuery{MetricQuery: models.MetricQuery{MetricName: "cache"}, Aggregation: "median"}},
+		{"bad group", models.CustomMetricQuery{MetricQuery: models.MetricQuery{MetricName: "cache"}, GroupBy: "a b"}},
+		{"bad tag filter", models.CustomMetricQuery{MetricQuery: models.MetricQuery{MetricName: "cache"}, Tags: map[string]string{"x'y": "1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.OrganizationID = "org-1"
+			query.StartTime, query.EndTime = end.Add(-24*time.Hour), end
+			_, err := NewMetricService(&mockRepository{}).QueryMetrics(context.Background(), &query)
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+
+	if _, err := buildMetric(&models.MetricRequest{OrganizationID: "org-1", Name: "x", Tags: map[string]interface{}{"long": strings.Repeat("a", maxMetricTagValueLen+1)}}, end); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a long tag value, got %v", err)
+	}
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+const (
+	defaultNotificationAttempts = 3
+	defaultNotificationBackoff  = time.Second
+	notificationSendTimeout     = 10 * time.Second
+	notificationDeliveryTimeout = 2 * time.Minute
+	defaultDeliveryLimit        = 100
+	maxDeliveryLimit            = 1000
+
+	// maskedSecret replaces secrets in channels returned by the API. Sending it back in
+	// an update keeps the stored secret.
+	maskedSecret = "********"
+)
+
+// notificationKinds lists the kinds a channel can subscribe to
+var notificationKinds = map[string]bool{
+	models.NotificationKindAlert:  true,
+	models.NotificationKindBudget: true,
+	models.NotificationKindTest:   true,
+}
+
+// Notifier sends notifications to one type of channel
+type Notifier interface {
+	// Validate checks a channel's configuration before it is stored
+	Validate(config *models.NotificationChannelConfig) error
+	// Send delivers one notification. Errors wrapped in a permanentError are not retried.
+	Send(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) error
+}
+
+// permanentError marks a delivery failure that retrying will not fix, such as a 4xx response
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// NotificationService stores notification channels and delivers alert and budget
+// notifications to them. Deliveries run in the background, are retried with exponential
+// backoff and are recorded in the delivery log.
+type NotificationService struct {
+	repo      repository.Repository
+	notifiers map[string]Notifier
+
+	maxAttempts int
+	backoff     time.Duration // before the second attempt; doubles after each one
+
+	wg sync.WaitGroup
+}
+
+// NewNotificationService creates a notification service with the webhook, Slack, email
+// and events notifiers. HTTP channels may only reach internal addresses of allowedHosts.
+func NewNotificationService(repo repository.Repository, allowedHosts []string) *NotificationService {
+	guard := newHostGuard(allowedHosts)
+	client := guard.client()
+	return &NotificationService{
+		repo: repo,
+		notifiers: map[string]Notifier{
+			models.ChannelTypeWebhook: &webhookNotifier{client: client, guard: guard},
+			models.ChannelTypeSlack:   &slackNotifier{client: client, guard: guard},
+			models.ChannelTypeEmail:   &emailNotifier{guard: guard},
+			models.ChannelTypeEvents:  &eventsNotifier{client: client, guard: guard},
+		},
+		maxAttempts: defaultNotificationAttempts,
+		backoff:     defaultNotificationBackoff,
+	}
+}
+
+// RegisterNotifier adds or replaces the notifier of a channel type
+func (s *NotificationService) RegisterNotifier(channelType string, notifier Notifier) {
+	s.notifiers[channelType] = notifier
+}
+
+// ListChannels returns an organization's channels with their secrets masked
+func (s *NotificationService) ListChannels(ctx context.Context, orgID string) ([]*models.NotificationChannel, error) {
+	channels, err := s.repo.ListNotificationChannels(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		redactChannel(channel)
+	}
+	return channels, nil
+}
+
+// GetChannel returns one channel with its secrets masked
+func (s *NotificationService) GetChannel(ctx context.Context, orgID, id string) (*models.NotificationChannel, error) {
+	channel, err := s.findChannel(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	redactChannel(channel)
+	return channel, nil
+}
+
+// UpsertChannel creates a channel, or replaces the one with the request's ID
+func (s *NotificationService) UpsertChannel(ctx context.Context, orgID string, req *models.NotificationChannelRequest, updatedBy string) (*models.NotificationChannel, error) {
+	now := time.Now()
+	channel := &models.NotificationChannel{
+		ID:             req.ID,
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Type:           req.Type,
+		Config:         req.Config,
+		Template:       req.Template,
+		Kinds:          req.Kinds,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		CreatedBy:      updatedBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if req.ID == "" {
+		channel.ID = uuid.New().String()
+	} else {
+		existing, err := s.findChannel(ctx, orgID, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		channel.CreatedBy = existing.CreatedBy
+		channel.CreatedAt = existing.CreatedAt
+		keepSecrets(&channel.Config, &existing.Config)
+	}
+
+	if err := s.validateChannel(channel); err != nil {
+		return nil, err
+	}
+	if channel.Kinds == nil {
+		channel.Kinds = []string{}
+	}
+
+	if err := s.repo.SaveNotificationChannel(ctx, channel); err != nil {
+		return nil, err
+	}
+	redactChannel(channel)
+	return channel, nil
+}
+
+// DeleteChannel removes a channel
+func (s *NotificationService) DeleteChannel(ctx context.Context, orgID, id string) error {
+	channel, err := s.findChannel(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	channel.Deleted = true
+	channel.UpdatedAt = time.Now()
+	return s.repo.SaveNotificationChannel(ctx, channel)
+}
+
+// TestChannel sends a test notification to a channel, enabled or not, and returns its
+// delivery once every attempt has been made
+func (s *NotificationService) TestChannel(ctx context.Context, orgID, id, requestedBy string) (*models.NotificationDelivery, error) {
+	channel, err := s.findChannel(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	message := "This is a test notification from Clarity."
+	if requestedBy != "" {
+		message += " It was sent by " + requestedBy + "."
+	}
+	n := &models.Notification{
+		Kind:           models.NotificationKindTest,
+		OrganizationID: orgID,
+		Title:          fmt.Sprintf("Test notification for channel %q", channel.Name),
+		Message:        message,
+		Severity:       models.AlertSeverityInfo,
+		State:          "test",
+		DedupKey:       "test-" + channel.ID,
+		Timestamp:      time.Now().UTC(),
+	}
+	return s.deliver(ctx, channel, n), nil
+}
+
+// Notify sends a notification to every enabled channel of its organization that takes its
+// kind. Delivery happens in the background; use Wait to let it finish.
+func (s *NotificationService) Notify(n *models.Notification) {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), notificationDeliveryTimeout)
+		defer cancel()
+
+		channels, err := s.repo.ListNotificationChannels(ctx, n.OrganizationID)
+		if err != nil {
+			log.Printf("❌ Failed to load notification channels of organization %s: %v", n.OrganizationID, err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, channel := range channels {
+			if !channel.Enabled || !channelTakes(channel, n.Kind) {
+				continue
+			}
+			wg.Add(1)
+			go func(channel *models.NotificationChannel) {
+				defer wg.Done()
+				s.deliver(ctx, channel, n)
+			}(channel)
+		}
+		wg.Wait()
+	}()
+}
+
+// Wait blocks until every notification sent so far has been delivered or given up on
+func (s *NotificationService) Wait() {
+	s.wg.Wait()
+}
+
+// ListDeliveries returns an organization's delivery log, newest first
+func (s *NotificationService) ListDeliveries(ctx context.Context, query *models.NotificationDeliveryQuery) ([]*models.NotificationDelivery, error) {
+	if query.OrganizationID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	if query.Status != "" && query.Status != models.DeliveryStatusDelivered && query.Status != models.DeliveryStatusFailed {
+		return nil, invalidArgument("status must be %s or %s", models.DeliveryStatusDelivered, models.DeliveryStatusFailed)
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultDeliveryLimit
+	}
+	if query.Limit > maxDeliveryLimit {
+		query.Limit = maxDeliveryLimit
+	}
+	return s.repo.ListNotificationDeliveries(ctx, query)
+}
+
+// deliver sends a notification to a channel, retrying failures with backoff, and records
+// the outcome in the delivery log
+func (s *NotificationService) deliver(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) *models.NotificationDelivery {
+	delivery := &models.NotificationDelivery{
+		ID:             uuid.New().String(),
+		OrganizationID: channel.OrganizationID,
+		ChannelID:      channel.ID,
+		ChannelType:    channel.Type,
+		Kind:           n.Kind,
+		Title:          n.Title,
+		CreatedAt:      time.Now(),
+	}
+
+	var err error
+	if notifier := s.notifiers[channel.Type]; notifier == nil {
+		err = fmt.Errorf("unknown channel type %q", channel.Type)
+	} else {
+		backoff := s.backoff
+		for delivery.Attempts < s.maxAttempts {
+			if delivery.Attempts > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+			if ctx.Err() != nil {
+				break
+			}
+
+			delivery.Attempts++
+			if err = notifier.Send(ctx, channel, n); err == nil {
+				break
+			}
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				break
+			}
+		}
+		if delivery.Attempts == 0 {
+			err = ctx.Err()
+		}
+	}
+
+	delivery.Status = models.DeliveryStatusDelivered
+	if err != nil {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.Error = err.Error()
+		log.Printf("❌ Failed to deliver %s notification to channel %s after %d attempts: %v",
+			n.Kind, channel.ID, delivery.Attempts, err)
+	}
+	delivery.CompletedAt = time.Now()
+
+	// Record the outcome even when ctx ran out during the attempts
+	saveCtx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+	if err := s.repo.SaveNotificationDelivery(saveCtx, delivery); err != nil {
+		log.Printf("❌ Failed to record notification delivery %s: %v", delivery.ID, err)
+	}
+	return delivery
+}
+
+// validateChannel checks a channel before it is stored
+func (s *NotificationService) validateChannel(channel *models.NotificationChannel) error {
+	if channel.Name == "" {
+		return invalidArgument("name is required")
+	}
+	notifier := s.notifiers[channel.Type]
+	if notifier == nil {
+		return invalidArgument("unknown channel type %q", channel.Type)
+	}
+	for _, kind := range channel.Kinds {
+		if !notificationKinds[kind] {
+			return invalidArgument("unknown notification kind %q", kind)
+		}
+	}
+	if channel.Template != "" {
+		if _, err := template.New("channel").Parse(channel.Template); err != nil {
+			return invalidArgument("invalid template: %v", err)
+		}
+	}
+	if err := notifier.Validate(&channel.Config); err != nil {
+		return invalidArgument("%v", err)
+	}
+	return nil
+}
+
+// findChannel returns a channel of the organization, with its secrets
+func (s *NotificationService) findChannel(ctx context.Context, orgID, id string) (*models.NotificationChannel, error) {
+	channels, err := s.repo.ListNotificationChannels(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		if channel.ID == id {
+			return channel, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// AlertNotification builds the notification of an alert rule firing or resolving
+func AlertNotification(event *models.AlertEvent) *models.Notification {
+	return &models.Notification{
+		Kind:           models.NotificationKindAlert,
+		OrganizationID: event.OrganizationID,
+		ProjectID:      event.ProjectID,
+		Title:          fmt.Sprintf("[%s] %s is %s", strings.ToUpper(event.State), event.RuleName, event.State),
+		Message: fmt.Sprintf("%s is %.4g (threshold %s %.4g) in project %s",
+			event.Metric, event.Value, event.Comparator, event.Threshold, event.ProjectID),
+		Severity: event.Severity,
+		State:    event.State,
+		DedupKey: "alert-" + event.RuleID,
+		Fields: map[string]string{
+			"rule_id":    event.RuleID,
+			"metric":     event.Metric,
+			"value":      fmt.Sprintf("%.4g", event.Value),
+			"comparator": event.Comparator,
+			"threshold":  fmt.Sprintf("%.4g", event.Threshold),
+		},
+		Timestamp: event.Timestamp,
+	}
+}
+
+// BudgetNotification builds the notification of a budget crossing one of its thresholds
+func BudgetNotification(budget *models.Budget, threshold, spent float64, at time.Time) *models.Notification {
+	severity, state := models.AlertSeverityWarning, models.BudgetStateWarning
+	if threshold >= 100 {
+		severity, state = models.AlertSeverityCritical, models.BudgetStateExceeded
+	}
+
+	return &models.Notification{
+		Kind:           models.NotificationKindBudget,
+		OrganizationID: budget.OrganizationID,
+		Title:          fmt.Sprintf("Budget %s reached %.0f%%", budget.Name, threshold),
+		Message: fmt.Sprintf("$%.2f of the %s limit of $%.2f has been spent (%s enforcement)",
+			spent, budget.Period, budget.LimitUSD, budget.Enforcement),
+		Severity: severity,
+		State:    state,
+		DedupKey: "budget-" + budget.ID,
+		Fields: map[string]string{
+			"budget_id": budget.ID,
+			"scope":     budget.Scope,
+			"threshold": fmt.Sprintf("%.0f", threshold),
+			"spent_usd": fmt.Sprintf("%.2f", spent),
+			"limit_usd": fmt.Sprintf("%.2f", budget.LimitUSD),
+		},
+		Timestamp: at.UTC(),
+	}
+}
+
+// renderNotification renders a channel's template, or fallback when it has none
+func renderNotification(channel *models.NotificationChannel, n *models.Notification, fallback string) (string, error) {
+	text := channel.Template
+	if text == "" {
+		text = fallback
+	}
+
+	tmpl, err := template.New(channel.ID).Parse(text)
+	if err != nil {
+		return "", &permanentError{fmt.Errorf("invalid template: %w", err)}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return "", &permanentError{fmt.Errorf("failed to render template: %w", err)}
+	}
+	return buf.String(), nil
+}
+
+// channelTakes reports whether a channel subscribes to a notification kind
+func channelTakes(channel *models.NotificationChannel, kind string) bool {
+	if len(channel.Kinds) == 0 {
+		return true
+	}
+	for _, k := range channel.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// redactChannel masks a channel's secrets, including the path of its URL: the URL of a
+// Slack incoming webhook is the only credential needed to post to it
+func redactChannel(channel *models.NotificationChannel) {
+	for _, secret := range []*string{&channel.Config.Secret, &channel.Config.RoutingKey, &channel.Config.Password} {
+		if *secret != "" {
+			*secret = maskedSecret
+		}
+	}
+	channel.Config.URL = maskURL(channel.Config.URL)
+}
+
+// maskURL keeps the scheme and host of a URL and masks the rest
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.User == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return u.Scheme + "://" + u.Host + "/" + maskedSecret
+}
+
+// keepSecrets copies the stored secrets into an update that left them masked
+func keepSecrets(config, stored *models.NotificationChannelConfig) {
+	for _, pair := range [][2]*string{
+		{&config.Secret, &stored.Secret},
+		{&config.RoutingKey, &stored.RoutingKey},
+		{&config.Password, &stored.Password},
+	} {
+		if *pair[0] == maskedSecret {
+			*pair[0] = *pair[1]
+		}
+	}
+	if config.URL != "" && config.URL == maskURL(stored.URL) {
+		config.URL = stored.URL
+	}
+}
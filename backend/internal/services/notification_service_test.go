@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/notifystub"
+)
+
+// newTestNotificationService returns a service that retries without waiting, and a stub
+// receiving its HTTP notifications
+func newTestNotificationService(t *testing.T) (*NotificationService, *mockRepository, *notifystub.Server, string) {
+	t.Helper()
+	stub := notifystub.NewServer("s3cret")
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	repo := &mockRepository{}
+	service := NewNotificationService(repo, []string{"127.0.0.1"})
+	service.backoff = time.Millisecond
+	return service, repo, stub, server.URL
+}
+
+func createChannel(t *testing.T, service *NotificationService, req models.NotificationChannelRequest) *models.NotificationChannel {
+	t.Helper()
+	channel, err := service.UpsertChannel(context.Background(), "org-1", &req, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return channel
+}
+
+func TestNotificationChannelValidation(t *testing.T) {
+	service, _, _, url := newTestNotificationService(t)
+
+	invalid := []models.NotificationChannelRequest{
+		{Name: "", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: url}},
+		{Name: "Pager", Type: "sms"},
+		{Name: "Hook", Type: models.ChannelTypeWebhook, Config: models.NotificationChannelConfig{URL: url}},
+		{Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: "ftp://example.com"}},
+		{Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: url}, Template: "{{.Title"},
+		{Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: url}, Kinds: []string{"deploy"}},
+		{Name: "Pager", Type: models.ChannelTypeEvents},
+		{Name: "Mail", Type: models.ChannelTypeEmail, Config: models.NotificationChannelConfig{SMTPHost: "localhost", From: "clarity@example.com"}},
+	}
+	for i, req := range invalid {
+		if _, err := service.UpsertChannel(context.Background(), "org-1", &req, ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("case %d: expected ErrInvalidArgument, got %v", i, err)
+		}
+	}
+}
+
+func TestNotificationInternalHostsRefused(t *testing.T) {
+	stub := notifystub.NewServer("")
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	service := NewNotificationService(&mockRepository{}, nil)
+
+	for _, target := range []string{server.URL, "http://localhost:8025/hook", "http://169.254.169.254/latest", "http://10.0.0.1/hook", "http://[::1]/hook"} {
+		req := models.NotificationChannelRequest{Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: target}}
+		if _, err := service.UpsertChannel(context.Background(), "org-1", &req, ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", target, err)
+		}
+	}
+	for _, host := range []string{"127.0.0.1", "localhost", "192.168.1.10"} {
+		req := models.NotificationChannelRequest{Name: "Mail", Type: models.ChannelTypeEmail, Config: models.NotificationChannelConfig{
+			SMTPHost: host, From: "clarity@example.com", To: []string{"oncall@example.com"},
+		}}
+		if _, err := service.UpsertChannel(context.Background(), "org-1", &req, ""); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("smtp host %s: expected ErrInvalidArgument, got %v", host, err)
+		}
+	}
+
+	// Names are resolved when connecting, so one pointing at an internal address is refused too
+	guard := newHostGuard(nil)
+	resp, err := guard.client().Post(strings.Replace(server.URL, "127.0.0.1", "localhost", 1), "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the connection to be refused")
+	}
+	var permanent *permanentError
+	if !errors.As(err, &permanent) || len(stub.Messages()) != 0 {
+		t.Errorf("expected a permanent failure before anything was sent, got %v", err)
+	}
+}
+
+func TestNotificationChannelSecretsMasked(t *testing.T) {
+	service, repo, _, url := newTestNotificationService(t)
+	ctx := context.Background()
+
+	channel := createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Hook", Type: models.ChannelTypeWebhook,
+		Config: models.NotificationChannelConfig{URL: url + "/services/T1/B2/xyz", Secret: "s3cret"},
+	})
+	if channel.Config.Secret != maskedSecret || channel.Config.URL != url+"/"+maskedSecret || !channel.Enabled {
+		t.Fatalf("expected an enabled channel with its secret and URL path masked, got %+v", channel)
+	}
+
+	// Sending the masked secret back keeps the stored one
+	disabled := false
+	updated, err := service.UpsertChannel(ctx, "org-1", &models.NotificationChannelRequest{
+		ID: channel.ID, Name: "Renamed", Type: models.ChannelTypeWebhook, Config: channel.Config, Enabled: &disabled,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored := repo.channels[len(repo.channels)-1]; stored.Config.Secret != "s3cret" || stored.Config.URL != url+"/services/T1/B2/xyz" || stored.Name != "Renamed" || stored.Enabled {
+		t.Errorf("expected the secret to be kept, got %+v", stored)
+	}
+	if !updated.CreatedAt.Equal(channel.CreatedAt) || updated.CreatedBy != "admin@example.com" {
+		t.Errorf("expected the creation to be kept, got %+v", updated)
+	}
+
+	if _, err := service.GetChannel(ctx, "org-2", channel.ID); err == nil {
+		t.Error("expected channels of other organizations to be hidden")
+	}
+}
+
+func TestWebhookNotificationSigned(t *testing.T) {
+	service, repo, stub, url := newTestNotificationService(t)
+	createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Hook", Type: models.ChannelTypeWebhook,
+		Config: models.NotificationChannelConfig{URL: url + "/hook", Secret: "s3cret"},
+	})
+
+	event := &models.AlertEvent{
+		OrganizationID: "org-1", ProjectID: "proj-1", RuleID: "rule-1", RuleName: "Errors",
+		Metric: models.AlertMetricErrorRate, Comparator: models.AlertComparatorGT, Threshold: 5,
+		Severity: models.AlertSeverityCritical, State: models.AlertStateFiring, Value: 12.5, Timestamp: time.Now(),
+	}
+	service.Notify(AlertNotification(event))
+	service.Wait()
+
+	messages := stub.Messages()
+	if len(messages) != 1 || !messages[0].Signed || messages[0].Path != "/hook" {
+		t.Fatalf("expected one signed webhook, got %+v", messages)
+	}
+	var n models.Notification
+	if err := json.Unmarshal([]byte(messages[0].Body), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Kind != models.NotificationKindAlert || n.State != models.AlertStateFiring || n.Fields["value"] != "12.5" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	deliveries, _ := service.ListDeliveries(context.Background(), &models.NotificationDeliveryQuery{OrganizationID: "org-1"})
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryStatusDelivered || deliveries[0].Attempts != 1 {
+		t.Errorf("expected one delivery in the log, got %+v", repo.deliveries)
+	}
+}
+
+func TestNotificationKindsAndTemplates(t *testing.T) {
+	service, _, stub, url := newTestNotificationService(t)
+	createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Budgets", Type: models.ChannelTypeSlack, Kinds: []string{models.NotificationKindBudget},
+		Config:   models.NotificationChannelConfig{URL: url + "/slack"},
+		Template: "{{.Title}} ({{index .Fields \"spent_usd\"}} spent)",
+	})
+	createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Pager", Type: models.ChannelTypeEvents, Kinds: []string{models.NotificationKindAlert},
+		Config: models.NotificationChannelConfig{URL: url + "/events", RoutingKey: "key-1"},
+	})
+
+	budget := &models.Budget{ID: "b-1", OrganizationID: "org-1", Name: "Monthly", Period: models.BudgetPeriodMonthly, LimitUSD: 100}
+	service.Notify(BudgetNotification(budget, 80, 81.5, time.Now()))
+	service.Notify(AlertNotification(&models.AlertEvent{OrganizationID: "org-1", RuleID: "rule-1", RuleName: "Latency", State: models.AlertStateResolved, Severity: models.AlertSeverityWarning}))
+	service.Wait()
+
+	var slack, events map[string]interface{}
+	for _, msg := range stub.Messages() {
+		switch msg.Path {
+		case "/slack":
+			json.Unmarshal([]byte(msg.Body), &slack)
+		case "/events":
+			json.Unmarshal([]byte(msg.Body), &events)
+		}
+	}
+	if len(stub.Messages()) != 2 {
+		t.Fatalf("expected each channel to get only its kind, got %+v", stub.Messages())
+	}
+	if slack["text"] != "Budget Monthly reached 80% (81.50 spent)" {
+		t.Errorf("expected the channel template to be used, got %v", slack)
+	}
+	if events["routing_key"] != "key-1" || events["event_action"] != "resolve" || events["dedup_key"] != "alert-rule-1" {
+		t.Errorf("expected a resolve event, got %v", events)
+	}
+}
+
+func TestNotificationRetries(t *testing.T) {
+	service, _, stub, url := newTestNotificationService(t)
+	channel := createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: url},
+	})
+
+	// Server errors and rate limits are retried
+	stub.FailNext(http.StatusInternalServerError, http.StatusTooManyRequests)
+	delivery, err := service.TestChannel(context.Background(), "org-1", channel.ID, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != models.DeliveryStatusDelivered || delivery.Attempts != 3 || len(stub.Messages()) != 1 {
+		t.Errorf("expected delivery on the third attempt, got %+v", delivery)
+	}
+
+	// Client errors are not
+	stub.FailNext(http.StatusNotFound)
+	delivery, _ = service.TestChannel(context.Background(), "org-1", channel.ID, "")
+	if delivery.Status != models.DeliveryStatusFailed || delivery.Attempts != 1 || !strings.Contains(delivery.Error, "404") || strings.Contains(delivery.Error, "Not Found") {
+		t.Errorf("expected a 404 to fail at once, got %+v", delivery)
+	}
+}
+
+func TestEmailNotification(t *testing.T) {
+	service, _, stub, _ := newTestNotificationService(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go stub.ServeSMTP(listener)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	channel := createChannel(t, service, models.NotificationChannelRequest{
+		Name: "Mail", Type: models.ChannelTypeEmail,
+		Config: models.NotificationChannelConfig{
+			SMTPHost: host, SMTPPort: portNumber, Username: "clarity", Password: "pw",
+			From: "clarity@example.com", To: []string{"oncall@example.com"},
+		},
+	})
+
+	delivery, err := service.TestChannel(context.Background(), "org-1", channel.ID, "")
+	if err != nil || delivery.Status != models.DeliveryStatusDelivered {
+		t.Fatalf("expected the email to be delivered, got %+v, %v", delivery, err)
+	}
+	messages := stub.Messages()
+	if len(messages) != 1 || messages[0].From != "clarity@example.com" || messages[0].To[0] != "oncall@example.com" ||
+		!strings.Contains(messages[0].Body, "Subject: Test notification for channel \"Mail\"") {
+		t.Errorf("unexpected email %+v", messages)
+	}
+}
+
+func TestEmailNotificationStuckServer(t *testing.T) {
+	// A server that accepts the connection and never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	notifier := &emailNotifier{guard: newHostGuard([]string{host})}
+	channel := &models.NotificationChannel{Name: "Mail", Type: models.ChannelTypeEmail, Config: models.NotificationChannelConfig{
+		SMTPHost: host, SMTPPort: portNumber, From: "clarity@example.com", To: []string{"oncall@example.com"},
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := notifier.Send(ctx, channel, &models.Notification{Title: "t", Message: "m", Timestamp: time.Now()}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the send to stop with its context, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("the send outlived its context by %v", elapsed)
+	}
+}
+
+func TestAlertsAndBudgetsNotify(t *testing.T) {
+	notifications, repo, stub, url := newTestNotificationService(t)
+	createChannel(t, notifications, models.NotificationChannelRequest{
+		Name: "Slack", Type: models.ChannelTypeSlack, Config: models.NotificationChannelConfig{URL: url},
+	})
+	ctx := context.Background()
+
+	repo.totals = []*models.TraceTotals{{TotalCost: 10}}
+	alerts := NewAlertService(repo, notifications)
+	if _, err := alerts.UpsertRule(ctx, "org-1", &models.AlertRuleRequest{
+		ProjectID: "proj-1", Name: "Spend", Metric: models.AlertMetricCostPerHour, Comparator: models.AlertComparatorGT, Threshold: 1,
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alerts.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	budgets := NewBudgetService(repo, notifications)
+	if _, err := budgets.UpsertBudget(ctx, "org-1", &models.BudgetRequest{Name: "Daily", Scope: models.BudgetScopeOrganization, Period: models.BudgetPeriodDaily, LimitUSD: 10}, ""); err != nil {
+		t.Fatal(err)
+	}
+	budgets.Record(&models.Trace{OrganizationID: "org-1", TotalCostUSD: 6})
+	notifications.Wait()
+
+	messages := stub.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected a firing alert and a budget warning, got %+v", messages)
+	}
+	bodies := messages[0].Body + messages[1].Body
+	if !strings.Contains(bodies, "Spend is firing") || !strings.Contains(bodies, "Budget Daily reached 50%") {
+		t.Errorf("unexpected notifications %+v", messages)
+	}
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// Webhook signature headers. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" with the channel's secret, so receivers can reject replays.
+const (
+	WebhookTimestampHeader = "X-Clarity-Timestamp"
+	WebhookSignatureHeader = "X-Clarity-Signature"
+)
+
+const (
+	defaultEventsURL      = "https://events.pagerduty.com/v2/enqueue"
+	defaultSMTPPort       = 587
+	defaultSlackTemplate  = "*{{.Title}}*\n{{.Message}}"
+	defaultEmailTemplate  = "{{.Message}}\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n"
+	defaultEventsTemplate = "{{.Title}}: {{.Message}}"
+)
+
+// SignWebhook returns the signature header value of a webhook body
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookNotifier posts the notification as JSON, or the channel's rendered template,
+// signed with the channel's secret
+type webhookNotifier struct {
+	client *http.Client
+	guard  *hostGuard
+}
+
+func (w *webhookNotifier) Validate(config *models.NotificationChannelConfig) error {
+	if err := w.guard.validateURL(config.URL); err != nil {
+		return err
+	}
+	if config.Secret == "" {
+		return errors.New("secret is required to sign webhooks")
+	}
+	return nil
+}
+
+func (w *webhookNotifier) Send(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) error {
+	var body []byte
+	if channel.Template != "" {
+		rendered, err := renderNotification(channel, n, "")
+		if err != nil {
+			return err
+		}
+		body = []byte(rendered)
+	} else {
+		var err error
+		if body, err = json.Marshal(n); err != nil {
+			return &permanentError{err}
+		}
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return postJSON(ctx, w.client, channel.Config.URL, body, map[string]string{
+		WebhookTimestampHeader: timestamp,
+		WebhookSignatureHeader: SignWebhook(channel.Config.Secret, timestamp, body),
+	})
+}
+
+// slackNotifier posts a message to a Slack incoming webhook, or anything that takes
+// Slack's {"text": ...} payload
+type slackNotifier struct {
+	client *http.Client
+	guard  *hostGuard
+}
+
+func (s *slackNotifier) Validate(config *models.NotificationChannelConfig) error {
+	return s.guard.validateURL(config.URL)
+}
+
+func (s *slackNotifier) Send(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) error {
+	text, err := renderNotification(channel, n, defaultSlackTemplate)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return &permanentError{err}
+	}
+	return postJSON(ctx, s.client, channel.Config.URL, body, nil)
+}
+
+// eventsNotifier sends incident events in the PagerDuty Events API v2 format: firing
+// alerts and crossed budgets trigger an incident, resolved alerts resolve it
+type eventsNotifier struct {
+	client *http.Client
+	guard  *hostGuard
+}
+
+// eventsPayload is an Events API v2 event
+type eventsPayload struct {
+	RoutingKey  string              `json:"routing_key"`
+	EventAction string              `json:"event_action"`
+	DedupKey    string              `json:"dedup_key"`
+	Payload     eventsPayloadDetail `json:"payload"`
+}
+
+type eventsPayloadDetail struct {
+	Summary       string            `json:"summary"`
+	Severity      string            `json:"severity"`
+	Source        string            `json:"source"`
+	Timestamp     string            `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (e *eventsNotifier) Validate(config *models.NotificationChannelConfig) error {
+	if config.URL != "" {
+		if err := e.guard.validateURL(config.URL); err != nil {
+			return err
+		}
+	}
+	if config.RoutingKey == "" {
+		return errors.New("routing_key is required")
+	}
+	return nil
+}
+
+func (e *eventsNotifier) Send(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) error {
+	summary, err := renderNotification(channel, n, defaultEventsTemplate)
+	if err != nil {
+		return err
+	}
+
+	action := "trigger"
+	if n.State == models.AlertStateResolved {
+		action = "resolve"
+	}
+	body, err := json.Marshal(eventsPayload{
+		RoutingKey:  channel.Config.RoutingKey,
+		EventAction: action,
+		DedupKey:    n.DedupKey,
+		Payload: eventsPayloadDetail{
+			Summary:       summary,
+			Severity:      n.Severity,
+			Source:        "clarity",
+			Timestamp:     n.Timestamp.Format(time.RFC3339),
+			CustomDetails: n.Fields,
+		},
+	})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	target := channel.Config.URL
+	if target == "" {
+		target = defaultEventsURL
+	}
+	return postJSON(ctx, e.client, target, body, nil)
+}
+
+// emailNotifier sends plain-text email over SMTP, with STARTTLS when the server offers it
+type emailNotifier struct {
+	guard *hostGuard
+}
+
+func (e *emailNotifier) Validate(config *models.NotificationChannelConfig) error {
+	if config.SMTPHost == "" {
+		return errors.New("smtp_host is required")
+	}
+	if err := e.guard.validateHost(config.SMTPHost); err != nil {
+		return fmt.Errorf("smtp_%w", err)
+	}
+	if config.SMTPPort < 0 || config.SMTPPort > 65535 {
+		return fmt.Errorf("invalid smtp_port %d", config.SMTPPort)
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return fmt.Errorf("invalid from address %q", config.From)
+	}
+	if len(config.To) == 0 {
+		return errors.New("at least one to address is required")
+	}
+	for _, to := range config.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to address %q", to)
+		}
+	}
+	return nil
+}
+
+func (e *emailNotifier) Send(ctx context.Context, channel *models.NotificationChannel, n *models.Notification) error {
+	text, err := renderNotification(channel, n, defaultEmailTemplate)
+	if err != nil {
+		return err
+	}
+
+	config := channel.Config
+	port := config.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.SMTPHost)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n"))
+
+	addr := net.JoinHostPort(config.SMTPHost, strconv.Itoa(port))
+	conn, err := e.guard.dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	// The SMTP client takes no context; a deadline bounds the exchange and cancelling ctx
+	// closes the connection, so a stuck server cannot hold on to it
+	conn.SetDeadline(time.Now().Add(notificationSendTimeout))
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err = sendMail(conn, config.SMTPHost, auth, config.From, config.To, msg.Bytes())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// sendMail does what smtp.SendMail does, over a connection that is already open
+func sendMail(conn net.Conn, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return &permanentError{errors.New("smtp server does not support AUTH")}
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// postJSON posts a JSON body; 4xx responses other than 429 are permanent failures
+func postJSON(ctx context.Context, client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Clarity-Notifications/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Only the host and status: the URLs of Slack-style webhooks are secrets themselves, and
+	// response bodies could leak what an internal endpoint returns into the delivery log
+	err = fmt.Errorf("%s responded %d", req.URL.Host, resp.StatusCode)
+	if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// hostGuard keeps notifications from reaching the server's own network: hosts that are,
+// or resolve to, loopback, private, link-local or unspecified addresses are refused unless
+// they are allowlisted. Resolved addresses are checked when connecting, so a public name
+// pointing at an internal address, or a redirect to one, is refused too.
+type hostGuard struct {
+	allowed map[string]bool // lower-case host names and IPs
+	dialer  *net.Dialer
+}
+
+func newHostGuard(allowedHosts []string) *hostGuard {
+	g := &hostGuard{allowed: make(map[string]bool), dialer: &net.Dialer{Timeout: notificationSendTimeout}}
+	for _, host := range allowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			g.allowed[host] = true
+		}
+	}
+	return g
+}
+
+// client returns an HTTP client that connects through the guard
+func (g *hostGuard) client() *http.Client {
+	return &http.Client{
+		Timeout:   notificationSendTimeout,
+		Transport: &http.Transport{DialContext: g.dialContext, TLSHandshakeTimeout: notificationSendTimeout},
+	}
+}
+
+// validateURL checks that a channel URL is an absolute http or https URL to a host
+// notifications may be sent to
+func (g *hostGuard) validateURL(raw string) error {
+	if raw == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http or https URL", maskURL(raw))
+	}
+
+	if err := g.validateHost(u.Hostname()); err != nil {
+		return fmt.Errorf("url %w", err)
+	}
+	return nil
+}
+
+// validateHost checks that a host name or IP is not an internal address, unless allowlisted
+func (g *hostGuard) validateHost(host string) error {
+	host = strings.ToLower(host)
+	if g.allowed[host] {
+		return nil
+	}
+	if ip := net.ParseIP(host); (ip != nil && internalIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("host %s is an internal address; add it to NOTIFICATION_ALLOWED_HOSTS to allow it", host)
+	}
+	return nil
+}
+
+// dialContext connects to addr, refusing internal addresses of hosts not allowlisted
+func (g *hostGuard) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if g.allowed[strings.ToLower(host)] {
+		return g.dialer.DialContext(ctx, network, addr)
+	}
+
+	dialer := *g.dialer
+	dialer.Control = func(network, address string, _ syscall.RawConn) error {
+		ipHost, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(ipHost); ip == nil || internalIP(ip) {
+			return &permanentError{fmt.Errorf("%s resolves to an internal address", host)}
+		}
+		return nil
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// internalIP reports whether an address belongs to the server's own networks
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
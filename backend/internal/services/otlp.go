@@ -0,0 +1,446 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// OTLP JSON encoding of traces (opentelemetry-proto, JSON mapping).
+// Only the fields needed to round-trip LLM traces are modelled.
+
+// OTLP status codes
+const (
+	otlpStatusUnset = 0
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// OTLPTracesData is the top-level OTLP JSON trace document
+type OTLPTracesData struct {
+	ResourceSpans []OTLPResourceSpans `json:"resourceSpans"`
+}
+
+// OTLPResourceSpans groups spans emitted by a single resource
+type OTLPResourceSpans struct {
+	Resource   OTLPResource     `json:"resource"`
+	ScopeSpans []OTLPScopeSpans `json:"scopeSpans"`
+}
+
+// OTLPResource describes the entity producing the spans
+type OTLPResource struct {
+	Attributes []OTLPKeyValue `json:"attributes"`
+}
+
+// OTLPScopeSpans groups spans by instrumentation scope
+type OTLPScopeSpans struct {
+	Scope OTLPScope  `json:"scope"`
+	Spans []OTLPSpan `json:"spans"`
+}
+
+// OTLPScope identifies the instrumentation library
+type OTLPScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// OTLPSpan is a single OTLP span
+type OTLPSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              OTLPEnum       `json:"kind"`
+	StartTimeUnixNano OTLPNumber     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   OTLPNumber     `json:"endTimeUnixNano"`
+	Attributes        []OTLPKeyValue `json:"attributes"`
+	Status            OTLPStatus     `json:"status"`
+}
+
+// OTLPStatus is the OTLP span status
+type OTLPStatus struct {
+	Code    OTLPEnum `json:"code"`
+	Message string   `json:"message,omitempty"`
+}
+
+// OTLPKeyValue is an OTLP attribute
+type OTLPKeyValue struct {
+	Key   string       `json:"key"`
+	Value OTLPAnyValue `json:"value"`
+}
+
+// OTLPAnyValue is an OTLP attribute value; exactly one field is set
+type OTLPAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *OTLPNumber `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+}
+
+// OTLPNumber is a 64-bit integer, encoded as a string in OTLP JSON.
+// Decoding also accepts bare numbers, which some exporters emit.
+type OTLPNumber string
+
+// UnmarshalJSON accepts both quoted and bare numbers
+func (n *OTLPNumber) UnmarshalJSON(data []byte) error {
+	*n = OTLPNumber(strings.Trim(string(data), `"`))
+	return nil
+}
+
+// Int64 returns the number as an int64, or 0 if it is not a valid integer
+func (n OTLPNumber) Int64() int64 {
+	v, _ := strconv.ParseInt(string(n), 10, 64)
+	return v
+}
+
+// OTLPEnum is an enum encoded as a number; decoding also accepts the enum names
+type OTLPEnum int
+
+// otlpEnumNames maps the protobuf enum names to their values
+var otlpEnumNames = map[string]OTLPEnum{
+	"STATUS_CODE_UNSET":     otlpStatusUnset,
+	"STATUS_CODE_OK":        otlpStatusOK,
+	"STATUS_CODE_ERROR":     otlpStatusError,
+	"SPAN_KIND_UNSPECIFIED": 0,
+	"SPAN_KIND_INTERNAL":    1,
+	"SPAN_KIND_SERVER":      2,
+	"SPAN_KIND_CLIENT":      3,
+	"SPAN_KIND_PRODUCER":    4,
+	"SPAN_KIND_CONSUMER":    5,
+}
+
+// UnmarshalJSON accepts either the numeric value or the enum name
+func (e *OTLPEnum) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*e = otlpEnumNames[name]
+		return nil
+	}
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid OTLP enum %s", data)
+	}
+	*e = OTLPEnum(value)
+	return nil
+}
+
+// Attribute keys used when mapping traces to OTLP (GenAI semantic conventions where they exist)
+const (
+	otlpAttrServiceName    = "service.name"
+	otlpAttrOrganizationID = "clarity.organization_id"
+	otlpAttrProjectID      = "clarity.project_id"
+	otlpAttrTraceType      = "clarity.trace_type"
+	otlpAttrEndUserID      = "enduser.id"
+	otlpAttrSystem         = "gen_ai.system"
+	otlpAttrRequestModel   = "gen_ai.request.model"
+	otlpAttrInputTokens    = "gen_ai.usage.input_tokens"
+	otlpAttrOutputTokens   = "gen_ai.usage.output_tokens"
+	otlpAttrCostUSD        = "clarity.cost_usd"
+	otlpAttrInput          = "gen_ai.prompt"
+	otlpAttrOutput         = "gen_ai.completion"
+	otlpAttrSpanStatus     = "clarity.status"
+	otlpAttrMetadataPrefix = "clarity.metadata."
+)
+
+const (
+	otlpScopeName            = "clarity"
+	otlpServiceNameDefault   = "clarity"
+	otlpSpanKindInternal     = 1
+	otlpTraceIDHexLength     = 32
+	otlpSpanIDHexLength      = 16
+	otlpMaxAttributeValueLen = 1 << 20
+)
+
+// traceToOTLP converts a stored trace into one OTLP resourceSpans entry
+func traceToOTLP(trace *models.Trace) OTLPResourceSpans {
+	serviceName := trace.ProjectID
+	if serviceName == "" {
+		serviceName = otlpServiceNameDefault
+	}
+
+	resourceAttrs := []OTLPKeyValue{
+		otlpString(otlpAttrServiceName, serviceName),
+		otlpString(otlpAttrOrganizationID, trace.OrganizationID),
+		otlpString(otlpAttrProjectID, trace.ProjectID),
+		otlpString(otlpAttrTraceType, trace.TraceType),
+	}
+	if trace.UserID != "" {
+		resourceAttrs = append(resourceAttrs, otlpString(otlpAttrEndUserID, trace.UserID))
+	}
+	for key, value := range trace.Metadata {
+		resourceAttrs = append(resourceAttrs, otlpString(otlpAttrMetadataPrefix+key, value))
+	}
+
+	traceID := toOTLPID(trace.TraceID, otlpTraceIDHexLength)
+	spans := make([]OTLPSpan, 0, len(trace.Spans))
+
+	for _, span := range trace.Spans {
+		attrs := []OTLPKeyValue{
+			otlpString(otlpAttrSystem, span.Provider),
+			otlpString(otlpAttrRequestModel, span.Model),
+			otlpInt(otlpAttrInputTokens, int64(span.PromptTokens)),
+			otlpInt(otlpAttrOutputTokens, int64(span.CompletionTokens)),
+			otlpDouble(otlpAttrCostUSD, span.CostUSD),
+			otlpString(otlpAttrSpanStatus, span.Status),
+		}
+		if span.Input != "" {
+			attrs = append(attrs, otlpString(otlpAttrInput, truncate(span.Input, otlpMaxAttributeValueLen)))
+		}
+		if span.Output != "" {
+			attrs = append(attrs, otlpString(otlpAttrOutput, truncate(span.Output, otlpMaxAttributeValueLen)))
+		}
+		for key, value := range span.Metadata {
+			attrs = append(attrs, otlpString(otlpAttrMetadataPrefix+key, value))
+		}
+
+		status := OTLPStatus{Code: otlpStatusOK}
+		switch span.Status {
+		case "error", "failed", "timeout":
+			status = OTLPStatus{Code: otlpStatusError, Message: span.ErrorMessage}
+		case "", "unknown":
+			status = OTLPStatus{Code: otlpStatusUnset}
+		}
+
+		otlpSpan := OTLPSpan{
+			TraceID:           traceID,
+			SpanID:            toOTLPID(span.SpanID, otlpSpanIDHexLength),
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: OTLPNumber(strconv.FormatInt(span.StartTime.UnixNano(), 10)),
+			EndTimeUnixNano:   OTLPNumber(strconv.FormatInt(span.EndTime.UnixNano(), 10)),
+			Attributes:        attrs,
+			Status:            status,
+		}
+		if span.ParentSpanID != "" {
+			otlpSpan.ParentSpanID = toOTLPID(span.ParentSpanID, otlpSpanIDHexLength)
+		}
+
+		spans = append(spans, otlpSpan)
+	}
+
+	return OTLPResourceSpans{
+		Resource: OTLPResource{Attributes: resourceAttrs},
+		ScopeSpans: []OTLPScopeSpans{
+			{
+				Scope: OTLPScope{Name: otlpScopeName},
+				Spans: spans,
+			},
+		},
+	}
+}
+
+// toOTLPID converts an ID (usually a UUID) to a fixed-length lowercase hex ID.
+// Non-hex IDs are hashed so they still map deterministically.
+func toOTLPID(id string, length int) string {
+	hexID := strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if !isHex(hexID) {
+		hexID = fnvHex(id)
+	}
+	for len(hexID) < length {
+		hexID = "0" + hexID
+	}
+	return hexID[:length]
+}
+
+// isHex reports whether s is a non-empty lowercase hex string
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// fnvHex returns the 128-bit FNV-1a hash of s as 32 hex characters
+func fnvHex(s string) string {
+	h := fnv.New128a()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func otlpString(key, value string) OTLPKeyValue {
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int64) OTLPKeyValue {
+	v := OTLPNumber(strconv.FormatInt(value, 10))
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{IntValue: &v}}
+}
+
+func otlpDouble(key string, value float64) OTLPKeyValue {
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{DoubleValue: &value}}
+}
+
+// Additional attribute keys accepted on import (older GenAI semantic convention names)
+const (
+	otlpAttrResponseModel    = "gen_ai.response.model"
+	otlpAttrPromptTokens     = "gen_ai.usage.prompt_tokens"
+	otlpAttrCompletionTokens = "gen_ai.usage.completion_tokens"
+)
+
+// otlpAttributes indexes OTLP attributes by key
+type otlpAttributes map[string]OTLPAnyValue
+
+func newOTLPAttributes(kvs []OTLPKeyValue) otlpAttributes {
+	attrs := make(otlpAttributes, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// str returns the first of the keys that is set, formatted as a string
+func (a otlpAttributes) str(keys ...string) string {
+	for _, key := range keys {
+		value, ok := a[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case value.StringValue != nil:
+			return *value.StringValue
+		case value.IntValue != nil:
+			return string(*value.IntValue)
+		case value.DoubleValue != nil:
+			return strconv.FormatFloat(*value.DoubleValue, 'f', -1, 64)
+		case value.BoolValue != nil:
+			return strconv.FormatBool(*value.BoolValue)
+		}
+	}
+	return ""
+}
+
+// number returns the first of the keys that is set as a float
+func (a otlpAttributes) number(keys ...string) float64 {
+	for _, key := range keys {
+		value, ok := a[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case value.IntValue != nil:
+			return float64(value.IntValue.Int64())
+		case value.DoubleValue != nil:
+			return *value.DoubleValue
+		case value.StringValue != nil:
+			v, _ := strconv.ParseFloat(*value.StringValue, 64)
+			return v
+		}
+	}
+	return 0
+}
+
+// metadata collects clarity.metadata.* attributes
+func (a otlpAttributes) metadata() map[string]string {
+	var metadata map[string]string
+	for key := range a {
+		if !strings.HasPrefix(key, otlpAttrMetadataPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[strings.TrimPrefix(key, otlpAttrMetadataPrefix)] = a.str(key)
+	}
+	return metadata
+}
+
+// otlpToTraces converts an OTLP trace document into traces, grouping spans by trace ID.
+// Trace-level totals are left for the importer to derive from the spans.
+func otlpToTraces(data *OTLPTracesData) ([]*models.Trace, error) {
+	byID := make(map[string]*models.Trace)
+	var order []string
+
+	for _, resourceSpans := range data.ResourceSpans {
+		resource := newOTLPAttributes(resourceSpans.Resource.Attributes)
+
+		projectID := resource.str(otlpAttrProjectID)
+		if _, ok := resource[otlpAttrProjectID]; !ok {
+			projectID = resource.str(otlpAttrServiceName)
+		}
+
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, otlpSpan := range scopeSpans.Spans {
+				if otlpSpan.TraceID == "" || otlpSpan.SpanID == "" {
+					return nil, fmt.Errorf("span %q is missing traceId or spanId", otlpSpan.Name)
+				}
+
+				trace, ok := byID[otlpSpan.TraceID]
+				if !ok {
+					trace = &models.Trace{
+						TraceID:   otlpSpan.TraceID,
+						ProjectID: projectID,
+						TraceType: resource.str(otlpAttrTraceType),
+						UserID:    resource.str(otlpAttrEndUserID),
+						Metadata:  resource.metadata(),
+					}
+					byID[otlpSpan.TraceID] = trace
+					order = append(order, otlpSpan.TraceID)
+				}
+
+				attrs := newOTLPAttributes(otlpSpan.Attributes)
+				if trace.UserID == "" {
+					trace.UserID = attrs.str(otlpAttrEndUserID)
+				}
+
+				status := attrs.str(otlpAttrSpanStatus)
+				if status == "" {
+					status = "success"
+					if otlpSpan.Status.Code == otlpStatusError {
+						status = "error"
+					}
+				}
+
+				span := models.Span{
+					SpanID:           otlpSpan.SpanID,
+					TraceID:          otlpSpan.TraceID,
+					ParentSpanID:     otlpSpan.ParentSpanID,
+					Name:             otlpSpan.Name,
+					StartTime:        time.Unix(0, otlpSpan.StartTimeUnixNano.Int64()).UTC(),
+					EndTime:          time.Unix(0, otlpSpan.EndTimeUnixNano.Int64()).UTC(),
+					Model:            attrs.str(otlpAttrRequestModel, otlpAttrResponseModel),
+					Provider:         attrs.str(otlpAttrSystem),
+					Input:            attrs.str(otlpAttrInput),
+					Output:           attrs.str(otlpAttrOutput),
+					PromptTokens:     int(attrs.number(otlpAttrInputTokens, otlpAttrPromptTokens)),
+					CompletionTokens: int(attrs.number(otlpAttrOutputTokens, otlpAttrCompletionTokens)),
+					CostUSD:          attrs.number(otlpAttrCostUSD),
+					Status:           status,
+					ErrorMessage:     otlpSpan.Status.Message,
+					Metadata:         attrs.metadata(),
+				}
+
+				trace.Spans = append(trace.Spans, span)
+			}
+		}
+	}
+
+	traces := make([]*models.Trace, 0, len(order))
+	for _, id := range order {
+		trace := byID[id]
+		sort.SliceStable(trace.Spans, func(i, j int) bool {
+			return trace.Spans[i].StartTime.Before(trace.Spans[j].StartTime)
+		})
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
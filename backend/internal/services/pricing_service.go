@@ -0,0 +1,558 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// defaultPriceCatalog is the catalog shipped with the binary
+//
+//go:embed pricing/catalog.json
+var defaultPriceCatalog []byte
+
+// priceCatalogFile is the file format of a versioned price catalog
+type priceCatalogFile struct {
+	Version int `json:"version"`
+	Models  []struct {
+		Provider         string   `json:"provider"`
+		Model            string   `json:"model"`
+		Aliases          []string `json:"aliases"`
+		MatchPrefix      bool     `json:"match_prefix"`
+		InputPerMillion  float64  `json:"input_per_million"`
+		OutputPerMillion float64  `json:"output_per_million"`
+		CachedInput      float64  `json:"cached_input_per_million"`
+		Reasoning        float64  `json:"reasoning_per_million"`
+		ImageInput       float64  `json:"image_input_per_million"`
+		AudioPerMinute   float64  `json:"audio_per_minute"`
+		BatchDiscount    float64  `json:"batch_discount"`
+		EffectiveFrom    string   `json:"effective_from"` // YYYY-MM-DD or RFC 3339
+	} `json:"models"`
+}
+
+// parsePriceCatalog reads a catalog file into price entries
+func parsePriceCatalog(data []byte) (int, []*models.ModelPrice, error) {
+	var file priceCatalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, nil, fmt.Errorf("invalid price catalog: %w", err)
+	}
+	if file.Version < 1 {
+		return 0, nil, fmt.Errorf("price catalog needs a positive version")
+	}
+
+	prices := make([]*models.ModelPrice, 0, len(file.Models))
+	for i, entry := range file.Models {
+		effectiveFrom, err := time.Parse("2006-01-02", entry.EffectiveFrom)
+		if err != nil {
+			if effectiveFrom, err = time.Parse(time.RFC3339, entry.EffectiveFrom); err != nil {
+				return 0, nil, fmt.Errorf("price catalog entry %d: invalid effective_from %q", i, entry.EffectiveFrom)
+			}
+		}
+
+		price, err := newModelPrice(&models.ModelPriceRequest{
+			Provider:              entry.Provider,
+			Model:                 entry.Model,
+			Aliases:               entry.Aliases,
+			MatchPrefix:           entry.MatchPrefix,
+			InputPerMillion:       entry.InputPerMillion,
+			OutputPerMillion:      entry.OutputPerMillion,
+			CachedInputPerMillion: entry.CachedInput,
+			ReasoningPerMillion:   entry.Reasoning,
+			ImageInputPerMillion:  entry.ImageInput,
+			AudioPerMinute:        entry.AudioPerMinute,
+			BatchDiscount:         entry.BatchDiscount,
+			EffectiveFrom:         &effectiveFrom,
+		})
+		if err != nil {
+			return 0, nil, fmt.Errorf("price catalog entry %d: %w", i, err)
+		}
+		price.Source = models.PriceSourceCatalog
+		price.CatalogVersion = file.Version
+		prices = append(prices, price)
+	}
+
+	return file.Version, prices, nil
+}
+
+// newModelPrice validates a price request and normalizes its names
+func newModelPrice(req *models.ModelPriceRequest) (*models.ModelPrice, error) {
+	price := &models.ModelPrice{
+		OrganizationID:        strings.TrimSpace(req.OrganizationID),
+		ProjectID:             strings.TrimSpace(req.ProjectID),
+		Provider:              strings.ToLower(strings.TrimSpace(req.Provider)),
+		Model:                 normalizeModelName(req.Model),
+		MatchPrefix:           req.MatchPrefix,
+		InputPerMillion:       req.InputPerMillion,
+		OutputPerMillion:      req.OutputPerMillion,
+		CachedInputPerMillion: req.CachedInputPerMillion,
+		ReasoningPerMillion:   req.ReasoningPerMillion,
+		ImageInputPerMillion:  req.ImageInputPerMillion,
+		AudioPerMinute:        req.AudioPerMinute,
+		BatchDiscount:         req.BatchDiscount,
+	}
+	if price.Provider == "" || price.Model == "" {
+		return nil, invalidArgument("provider and model are required")
+	}
+	if price.ProjectID != "" && price.OrganizationID == "" {
+		return nil, invalidArgument("a project price needs an organization_id")
+	}
+
+	rates := []float64{
+		price.InputPerMillion, price.OutputPerMillion, price.CachedInputPerMillion,
+		price.ReasoningPerMillion, price.ImageInputPerMillion, price.AudioPerMinute,
+	}
+	for _, rate := range rates {
+		if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			return nil, invalidArgument("prices must be non-negative amounts")
+		}
+	}
+	if !(price.BatchDiscount >= 0 && price.BatchDiscount < 1) {
+		return nil, invalidArgument("batch_discount must be a fraction between 0 and 1")
+	}
+
+	for _, alias := range req.Aliases {
+		if alias = normalizeModelName(alias); alias != "" && alias != price.Model {
+			price.Aliases = append(price.Aliases, alias)
+		}
+	}
+
+	if req.EffectiveFrom != nil {
+		price.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	return price, nil
+}
+
+// normalizeModelName lowercases a model name and drops a routing prefix such as openai/ or models/
+func normalizeModelName(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return model
+}
+
+// priceIndex finds the price of a model name
+type priceIndex struct {
+	byName   map[string][]*models.ModelPrice // model names and aliases, entries oldest first
+	prefixes []string                        // names matched as prefixes, longest first
+}
+
+// newPriceIndex indexes price entries by model name and alias
+func newPriceIndex(prices []*models.ModelPrice) *priceIndex {
+	index := &priceIndex{byName: make(map[string][]*models.ModelPrice)}
+	isPrefix := make(map[string]bool)
+
+	for _, price := range prices {
+		for _, name := range append([]string{price.Model}, price.Aliases...) {
+			index.byName[name] = append(index.byName[name], price)
+			if price.MatchPrefix && !isPrefix[name] {
+				isPrefix[name] = true
+				index.prefixes = append(index.prefixes, name)
+			}
+		}
+	}
+
+	for _, entries := range index.byName {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].EffectiveFrom.Before(entries[j].EffectiveFrom) })
+	}
+	sort.Slice(index.prefixes, func(i, j int) bool {
+		if len(index.prefixes[i]) != len(index.prefixes[j]) {
+			return len(index.prefixes[i]) > len(index.prefixes[j])
+		}
+		return index.prefixes[i] < index.prefixes[j]
+	})
+	return index
+}
+
+// lookup returns the price of a model at a time. An exact name or alias wins over the longest
+// prefix such as gpt-4o for gpt-4o-2024-08-06. Entries of the given provider are preferred,
+// so models served through another provider still find their list price. A strict lookup,
+// used for negotiated rates, only matches the provider and entries already in effect.
+func (index *priceIndex) lookup(provider, model string, at time.Time, strict bool) (*models.ModelPrice, bool) {
+	name := normalizeModelName(model)
+	if name == "" {
+		return nil, false
+	}
+
+	entries := index.byName[name]
+	if len(entries) == 0 {
+		for _, prefix := range index.prefixes {
+			if strings.HasPrefix(name, prefix+"-") || strings.HasPrefix(name, prefix+"@") || strings.HasPrefix(name, prefix+":") {
+				for _, entry := range index.byName[prefix] {
+					if entry.MatchPrefix {
+						entries = append(entries, entry)
+					}
+				}
+				break
+			}
+		}
+	}
+
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	var sameProvider []*models.ModelPrice
+	for _, entry := range entries {
+		if entry.Provider == provider {
+			sameProvider = append(sameProvider, entry)
+		}
+	}
+	if len(sameProvider) > 0 || strict {
+		entries = sameProvider
+	}
+	if len(entries) == 0 || (strict && entries[0].EffectiveFrom.After(at)) {
+		return nil, false
+	}
+
+	// The latest entry in effect; calls before the first entry use the first
+	price := entries[0]
+	for _, entry := range entries[1:] {
+		if entry.EffectiveFrom.After(at) {
+			break
+		}
+		price = entry
+	}
+	return price, true
+}
+
+// priceScope keys the prices of an organization or project; the global prices have an empty key
+func priceScope(orgID, projectID string) string {
+	if orgID == "" {
+		return ""
+	}
+	return orgID + "/" + projectID
+}
+
+// newPriceIndexes indexes price entries per scope
+func newPriceIndexes(prices []*models.ModelPrice) map[string]*priceIndex {
+	scoped := make(map[string][]*models.ModelPrice)
+	for _, price := range prices {
+		key := priceScope(price.OrganizationID, price.ProjectID)
+		scoped[key] = append(scoped[key], price)
+	}
+
+	indexes := make(map[string]*priceIndex, len(scoped))
+	for key, entries := range scoped {
+		indexes[key] = newPriceIndex(entries)
+	}
+	if indexes[""] == nil {
+		indexes[""] = newPriceIndex(nil)
+	}
+	return indexes
+}
+
+// priceSource names where a price came from, as recorded on spans
+func priceSource(price *models.ModelPrice) string {
+	switch {
+	case price.ProjectID != "":
+		return models.PriceSourceProject
+	case price.OrganizationID != "":
+		return models.PriceSourceOrganization
+	default:
+		return price.Source
+	}
+}
+
+// PricingService prices LLM calls from a catalog of model prices. The catalog ships as a
+// versioned file, is stored in the database, and can be edited through the admin API.
+// Organizations and projects can override it with their own rates.
+type PricingService struct {
+	repo    repository.Repository
+	mu      sync.RWMutex
+	indexes map[string]*priceIndex // by priceScope
+	warned  sync.Map               // provider/model pairs already logged as unpriced
+}
+
+// NewPricingService creates a pricing service serving the embedded catalog until prices
+// are loaded from the database
+func NewPricingService(repo repository.Repository) *PricingService {
+	_, prices, err := parsePriceCatalog(defaultPriceCatalog)
+	if err != nil {
+		panic(fmt.Sprintf("embedded price catalog: %v", err))
+	}
+
+	return &PricingService{
+		repo:    repo,
+		indexes: newPriceIndexes(prices),
+	}
+}
+
+// LoadCatalog stores a catalog file in the database when it is newer than the stored catalog,
+// then serves the stored prices. An empty path loads the embedded catalog. Entries edited
+// through the admin API are kept.
+func (s *PricingService) LoadCatalog(ctx context.Context, path string) error {
+	data := defaultPriceCatalog
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read price catalog: %w", err)
+		}
+	}
+
+	version, prices, err := parsePriceCatalog(data)
+	if err != nil {
+		return err
+	}
+	if err := s.syncCatalog(ctx, version, prices, time.Now()); err != nil {
+		return err
+	}
+	return s.Refresh(ctx)
+}
+
+// syncCatalog writes the entries of a newer catalog version, skipping admin-edited entries
+func (s *PricingService) syncCatalog(ctx context.Context, version int, prices []*models.ModelPrice, now time.Time) error {
+	stored, err := s.repo.ListModelPrices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list stored prices: %w", err)
+	}
+
+	storedVersion := 0
+	edited := make(map[string]bool)
+	for _, price := range stored {
+		if price.OrganizationID != "" {
+			continue
+		}
+		switch price.Source {
+		case models.PriceSourceCatalog:
+			if price.CatalogVersion > storedVersion {
+				storedVersion = price.CatalogVersion
+			}
+		case models.PriceSourceAdmin:
+			edited[modelPriceKey(price)] = true
+		}
+	}
+	if version <= storedVersion {
+		return nil
+	}
+
+	updates := make([]*models.ModelPrice, 0, len(prices))
+	for _, price := range prices {
+		if !edited[modelPriceKey(price)] {
+			price.UpdatedAt = now
+			updates = append(updates, price)
+		}
+	}
+
+	if err := s.repo.SaveModelPrices(ctx, updates); err != nil {
+		return fmt.Errorf("failed to store price catalog: %w", err)
+	}
+	log.Printf("💲 Price catalog v%d stored (%d entries)", version, len(updates))
+	return nil
+}
+
+// modelPriceKey identifies a price entry
+func modelPriceKey(price *models.ModelPrice) string {
+	return priceScope(price.OrganizationID, price.ProjectID) + ":" + price.Provider + "/" + price.Model + "@" + price.EffectiveFrom.UTC().Format(time.RFC3339)
+}
+
+// Refresh reloads prices from the database; without stored global prices the current
+// global prices are kept
+func (s *PricingService) Refresh(ctx context.Context) error {
+	prices, err := s.repo.ListModelPrices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load prices: %w", err)
+	}
+
+	indexes := newPriceIndexes(prices)
+	s.mu.Lock()
+	if len(indexes[""].byName) == 0 {
+		indexes[""] = s.indexes[""]
+	}
+	s.indexes = indexes
+	s.mu.Unlock()
+	return nil
+}
+
+// Start refreshes prices once per interval until the context is cancelled, so edits made
+// through other instances are picked up
+func (s *PricingService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("❌ Failed to refresh prices: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Lookup returns the price of a model at the given time, preferring the project's rate,
+// then the organization's, then the global price
+func (s *PricingService) Lookup(orgID, projectID, provider, model string, at time.Time) (*models.ModelPrice, bool) {
+	s.mu.RLock()
+	indexes := s.indexes
+	s.mu.RUnlock()
+
+	if orgID != "" {
+		scopes := []string{priceScope(orgID, "")}
+		if projectID != "" {
+			scopes = []string{priceScope(orgID, projectID), scopes[0]}
+		}
+		for _, scope := range scopes {
+			if index, ok := indexes[scope]; ok {
+				if price, ok := index.lookup(provider, model, at, true); ok {
+					return price, true
+				}
+			}
+		}
+	}
+
+	return indexes[""].lookup(provider, model, at, false)
+}
+
+// Cost prices a call at the given time, splitting the cost by usage category, and names
+// the source of the price. It reports false, and logs the model once, when the model has
+// no price.
+func (s *PricingService) Cost(orgID, projectID, provider, model string, usage models.TokenUsage, at time.Time) (models.CostComponents, string, bool) {
+	price, ok := s.Lookup(orgID, projectID, provider, model, at)
+	if !ok {
+		if model != "" {
+			if _, logged := s.warned.LoadOrStore(provider+"/"+model, true); !logged {
+				log.Printf("⚠️  No price for model %q (provider %q); its calls are recorded at $0", model, provider)
+			}
+		}
+		return models.CostComponents{}, "", false
+	}
+
+	return priceUsage(price, usage), priceSource(price), true
+}
+
+// priceSpan sets the cost, components and price source of a span from its usage, priced
+// for the organization and project at the span's start time
+func (s *PricingService) priceSpan(span *models.Span, orgID, projectID string) {
+	costs, source, priced := s.Cost(orgID, projectID, span.Provider, span.Model, models.TokenUsage{
+		PromptTokens:       span.PromptTokens,
+		CompletionTokens:   span.CompletionTokens,
+		CachedPromptTokens: span.CachedPromptTokens,
+		ReasoningTokens:    span.ReasoningTokens,
+		ImageTokens:        span.ImageTokens,
+		AudioSeconds:       span.AudioSeconds,
+		Batch:              span.Batch,
+	}, span.StartTime)
+
+	span.CostUSD = costTotal(costs)
+	span.CostComponents = costs
+	span.PriceSource = source
+	span.Unpriced = !priced
+}
+
+// priceUsage prices each usage category at its own rate. Cached and image tokens are taken
+// out of the prompt tokens and reasoning tokens out of the completion tokens, so nothing
+// is billed twice.
+func priceUsage(price *models.ModelPrice, usage models.TokenUsage) models.CostComponents {
+	perToken := func(tokens int, perMillion float64) float64 {
+		return float64(tokens) / 1000000.0 * perMillion
+	}
+	orRate := func(rate, fallback float64) float64 {
+		if rate > 0 {
+			return rate
+		}
+		return fallback
+	}
+
+	prompt := max(usage.PromptTokens, 0)
+	cached := min(max(usage.CachedPromptTokens, 0), prompt)
+	image := min(max(usage.ImageTokens, 0), prompt-cached)
+	completion := max(usage.CompletionTokens, 0)
+	reasoning := min(max(usage.ReasoningTokens, 0), completion)
+
+	cachedRate := orRate(price.CachedInputPerMillion, price.InputPerMillion)
+	costs := models.CostComponents{
+		Input:        perToken(prompt-cached-image, price.InputPerMillion),
+		CachedInput:  perToken(cached, cachedRate),
+		Output:       perToken(completion-reasoning, price.OutputPerMillion),
+		Reasoning:    perToken(reasoning, orRate(price.ReasoningPerMillion, price.OutputPerMillion)),
+		Image:        perToken(image, orRate(price.ImageInputPerMillion, price.InputPerMillion)),
+		Audio:        math.Max(usage.AudioSeconds, 0) / 60 * price.AudioPerMinute,
+		CacheSavings: perToken(cached, math.Max(price.InputPerMillion-cachedRate, 0)),
+	}
+
+	if usage.Batch && price.BatchDiscount > 0 {
+		keep := 1 - price.BatchDiscount
+		costs.BatchSavings = costTotal(costs) * price.BatchDiscount
+		costs.Input *= keep
+		costs.CachedInput *= keep
+		costs.Output *= keep
+		costs.Reasoning *= keep
+		costs.Image *= keep
+		costs.Audio *= keep
+		costs.CacheSavings *= keep
+	}
+	return costs
+}
+
+// costTotal adds up the billed categories of a cost
+func costTotal(costs models.CostComponents) float64 {
+	return costs.Input + costs.CachedInput + costs.Output + costs.Reasoning + costs.Image + costs.Audio
+}
+
+// ListPrices returns the global price entries
+func (s *PricingService) ListPrices(ctx context.Context) ([]*models.ModelPrice, error) {
+	return s.listPrices(ctx, "")
+}
+
+// ListOverrides returns the rates of an organization and its projects
+func (s *PricingService) ListOverrides(ctx context.Context, orgID string) ([]*models.ModelPrice, error) {
+	if orgID == "" {
+		return nil, invalidArgument("organization_id is required")
+	}
+	return s.listPrices(ctx, orgID)
+}
+
+// listPrices returns the stored entries of one organization, or the global entries
+func (s *PricingService) listPrices(ctx context.Context, orgID string) ([]*models.ModelPrice, error) {
+	stored, err := s.repo.ListModelPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+
+	prices := []*models.ModelPrice{}
+	for _, price := range stored {
+		if price.OrganizationID == orgID {
+			prices = append(prices, price)
+		}
+	}
+	return prices, nil
+}
+
+// UpsertPrice adds a price, or replaces the entry with the same scope, provider, model and
+// effective date, and serves it immediately. A request with an organization sets that
+// organization's rate.
+func (s *PricingService) UpsertPrice(ctx context.Context, req *models.ModelPriceRequest, updatedBy string) (*models.ModelPrice, error) {
+	now := time.Now().UTC()
+	if req.EffectiveFrom == nil {
+		req.EffectiveFrom = &now
+	}
+
+	price, err := newModelPrice(req)
+	if err != nil {
+		return nil, err
+	}
+	price.Source = models.PriceSourceAdmin
+	price.UpdatedBy = updatedBy
+	price.UpdatedAt = now
+
+	if err := s.repo.SaveModelPrices(ctx, []*models.ModelPrice{price}); err != nil {
+		return nil, fmt.Errorf("failed to save price: %w", err)
+	}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
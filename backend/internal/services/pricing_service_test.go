@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+func TestPriceLookup(t *testing.T) {
+	service := NewPricingService(&mockRepository{})
+	at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		provider  string
+		model     string
+		wantModel string
+		wantInput float64
+	}{
+		{"exact", "openai", "gpt-4", "gpt-4", 30},
+		{"dated snapshot", "openai", "gpt-4o-2024-08-06", "gpt-4o", 2.5},
+		{"longest prefix", "openai", "gpt-4o-mini-2024-07-18", "gpt-4o-mini", 0.15},
+		{"anthropic snapshot", "anthropic", "claude-3-5-sonnet-20241022", "claude-3-5-sonnet", 3},
+		{"alias", "anthropic", "claude-3.5-sonnet", "claude-3-5-sonnet", 3},
+		{"routing prefix and case", "openai", "openai/GPT-4o", "gpt-4o", 2.5},
+		{"other provider", "azure", "gpt-4-turbo", "gpt-4-turbo", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := service.Lookup("", "", tt.provider, tt.model, at)
+			if !ok {
+				t.Fatalf("expected a price for %s", tt.model)
+			}
+			if price.Model != tt.wantModel || price.InputPerMillion != tt.wantInput {
+				t.Errorf("expected %s at %v, got %s at %v", tt.wantModel, tt.wantInput, price.Model, price.InputPerMillion)
+			}
+		})
+	}
+
+	for _, model := range []string{"", "gpt-4ox", "my-finetune"} {
+		if _, ok := service.Lookup("", "", "openai", model, at); ok {
+			t.Errorf("expected no price for %q", model)
+		}
+	}
+}
+
+func TestPriceEffectiveDates(t *testing.T) {
+	service := NewPricingService(&mockRepository{})
+
+	tests := []struct {
+		at        time.Time
+		wantInput float64
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 5}, // before the first entry
+		{time.Date(2024, 9, 30, 23, 0, 0, 0, time.UTC), 5},
+		{time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), 2.5},
+	}
+
+	for _, tt := range tests {
+		price, ok := service.Lookup("", "", "openai", "gpt-4o", tt.at)
+		if !ok || price.InputPerMillion != tt.wantInput {
+			t.Errorf("at %v: expected input price %v, got %+v", tt.at, tt.wantInput, price)
+		}
+	}
+}
+
+func TestPricingCost(t *testing.T) {
+	service := NewPricingService(&mockRepository{})
+
+	costs, _, ok := service.Cost("", "", "anthropic", "claude-3-haiku-20240307", models.TokenUsage{PromptTokens: 1000000, CompletionTokens: 1000000}, time.Now())
+	if cost := costTotal(costs); !ok || math.Abs(cost-1.5) > 1e-9 {
+		t.Errorf("expected $1.50, got %v (priced %v)", cost, ok)
+	}
+	if costs, _, ok := service.Cost("", "", "openai", "my-finetune", models.TokenUsage{PromptTokens: 1000, CompletionTokens: 1000}, time.Now()); ok || costTotal(costs) != 0 {
+		t.Errorf("expected an unpriced call at $0, got %+v (priced %v)", costs, ok)
+	}
+}
+
+func TestPriceUsage(t *testing.T) {
+	price := &models.ModelPrice{
+		InputPerMillion:       2,
+		OutputPerMillion:      8,
+		CachedInputPerMillion: 0.5,
+		AudioPerMinute:        0.006,
+		BatchDiscount:         0.5,
+	}
+
+	tests := []struct {
+		name  string
+		usage models.TokenUsage
+		want  models.CostComponents
+	}{
+		{
+			name:  "cached and reasoning tokens",
+			usage: models.TokenUsage{PromptTokens: 1000000, CachedPromptTokens: 600000, ImageTokens: 100000, CompletionTokens: 500000, ReasoningTokens: 200000},
+			want:  models.CostComponents{Input: 0.6, CachedInput: 0.3, Image: 0.2, Output: 2.4, Reasoning: 1.6, CacheSavings: 0.9},
+		},
+		{
+			name:  "counts above the prompt are capped",
+			usage: models.TokenUsage{PromptTokens: 1000000, CachedPromptTokens: 2000000},
+			want:  models.CostComponents{CachedInput: 0.5, CacheSavings: 1.5},
+		},
+		{
+			name:  "audio",
+			usage: models.TokenUsage{AudioSeconds: 90},
+			want:  models.CostComponents{Audio: 0.009},
+		},
+		{
+			name:  "batch discount",
+			usage: models.TokenUsage{PromptTokens: 1000000, CompletionTokens: 1000000, Batch: true},
+			want:  models.CostComponents{Input: 1, Output: 4, BatchSavings: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := priceUsage(price, tt.usage)
+			pairs := [][2]float64{
+				{got.Input, tt.want.Input}, {got.CachedInput, tt.want.CachedInput}, {got.Output, tt.want.Output},
+				{got.Reasoning, tt.want.Reasoning}, {got.Image, tt.want.Image}, {got.Audio, tt.want.Audio},
+				{got.CacheSavings, tt.want.CacheSavings}, {got.BatchSavings, tt.want.BatchSavings},
+			}
+			for _, pair := range pairs {
+				if math.Abs(pair[0]-pair[1]) > 1e-9 {
+					t.Fatalf("priceUsage() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	ctx := context.Background()
+	edited := &models.ModelPrice{
+		Provider:         "openai",
+		Model:            "gpt-4",
+		InputPerMillion:  25,
+		OutputPerMillion: 50,
+		EffectiveFrom:    time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC),
+		Source:           models.PriceSourceAdmin,
+	}
+	repo := &mockRepository{prices: []*models.ModelPrice{edited}}
+	service := NewPricingService(repo)
+
+	if err := service.LoadCatalog(ctx, ""); err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+
+	if len(repo.prices) < 2 {
+		t.Fatalf("expected the catalog to be stored, got %d prices", len(repo.prices))
+	}
+	// Admin edits survive the catalog sync
+	if price, _ := service.Lookup("", "", "openai", "gpt-4", time.Now()); price.InputPerMillion != 25 {
+		t.Errorf("expected the admin price to be kept, got %+v", price)
+	}
+
+	// The same catalog version is not written again
+	version, _, _ := parsePriceCatalog(defaultPriceCatalog)
+	repo.prices = repo.prices[:1]
+	repo.prices = append(repo.prices, &models.ModelPrice{Provider: "openai", Model: "gpt-4o", Source: models.PriceSourceCatalog, CatalogVersion: version})
+	if err := service.LoadCatalog(ctx, ""); err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	if len(repo.prices) != 2 {
+		t.Errorf("expected the stored catalog to be left alone, got %d prices", len(repo.prices))
+	}
+}
+
+func TestUpsertPrice(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockRepository{}
+	service := NewPricingService(repo)
+
+	price, err := service.UpsertPrice(ctx, &models.ModelPriceRequest{
+		Provider:         "OpenAI",
+		Model:            "ft:my-finetune",
+		InputPerMillion:  1,
+		OutputPerMillion: 2,
+	}, "user-1")
+	if err != nil {
+		t.Fatalf("UpsertPrice() error = %v", err)
+	}
+	if price.Provider != "openai" || price.Source != models.PriceSourceAdmin || price.UpdatedBy != "user-1" {
+		t.Errorf("unexpected price %+v", price)
+	}
+	if _, ok := service.Lookup("", "", "openai", "ft:my-finetune", time.Now()); !ok {
+		t.Error("expected the new price to be served immediately")
+	}
+
+	invalid := []models.ModelPriceRequest{
+		{Provider: "openai", InputPerMillion: 1},
+		{Model: "gpt-4", InputPerMillion: 1},
+		{Provider: "openai", Model: "gpt-4", InputPerMillion: -1},
+		{Provider: "openai", Model: "gpt-4", OutputPerMillion: math.Inf(1)},
+		{Provider: "openai", Model: "gpt-4", BatchDiscount: 1},
+	}
+	for _, req := range invalid {
+		if _, err := service.UpsertPrice(ctx, &req, "user-1"); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%+v: expected ErrInvalidArgument, got %v", req, err)
+		}
+	}
+}
+
+func TestCreateTraceUnpricedSpan(t *testing.T) {
+	var metrics []*models.Metric
+	var saved *models.Trace
+	repo := &mockRepository{
+		saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+			saved = trace
+			return nil
+		},
+		saveMetricFunc: func(ctx context.Context, metric *models.Metric) error {
+			metrics = append(metrics, metric)
+			return nil
+		},
+	}
+	service := NewTraceService(repo, nil, NewPricingService(repo), nil, nil)
+
+	_, err := service.CreateTrace(context.Background(), &models.TraceRequest{
+		OrganizationID: "org-1",
+		TraceType:      "multi_step",
+		Spans: []models.SpanRequest{
+			{Name: "known", Model: "gpt-4", Provider: "openai", PromptTokens: 100, CompletionTokens: 50, Status: "success"},
+			{Name: "unknown", Model: "my-finetune", Provider: "openai", PromptTokens: 100, CompletionTokens: 50, Status: "success"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTrace() error = %v", err)
+	}
+
+	if saved.Spans[0].Unpriced || !saved.Spans[1].Unpriced {
+		t.Errorf("expected only the unknown model to be flagged, got %v and %v", saved.Spans[0].Unpriced, saved.Spans[1].Unpriced)
+	}
+
+	unpriced := 0
+	for _, metric := range metrics {
+		if metric.MetricName == "unpriced_spans" {
+			unpriced++
+			if metric.Tags["model"] != "my-finetune" {
+				t.Errorf("expected the unpriced metric tagged with the model, got %v", metric.Tags)
+			}
+		}
+	}
+	if unpriced != 1 {
+		t.Errorf("expected one unpriced span metric, got %d", unpriced)
+	}
+}
+
+func TestPriceOverrides(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockRepository{}
+	service := NewPricingService(repo)
+	negotiated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	overrides := []models.ModelPriceRequest{
+		{OrganizationID: "org-1", Provider: "openai", Model: "gpt-4o", MatchPrefix: true, InputPerMillion: 2, OutputPerMillion: 8, EffectiveFrom: &negotiated},
+		{OrganizationID: "org-1", ProjectID: "proj-internal", Provider: "self-hosted", Model: "llama-3-70b", InputPerMillion: 0.2, OutputPerMillion: 0.2, EffectiveFrom: &negotiated},
+	}
+	for i := range overrides {
+		if _, err := service.UpsertPrice(ctx, &overrides[i], "admin-1"); err != nil {
+			t.Fatalf("UpsertPrice() error = %v", err)
+		}
+	}
+
+	at := negotiated.AddDate(0, 1, 0)
+	tests := []struct {
+		name       string
+		orgID      string
+		projectID  string
+		provider   string
+		model      string
+		at         time.Time
+		wantInput  float64
+		wantSource string
+	}{
+		{"organization rate", "org-1", "proj-1", "openai", "gpt-4o-2024-08-06", at, 2, models.PriceSourceOrganization},
+		{"project rate", "org-1", "proj-internal", "self-hosted", "llama-3-70b", at, 0.2, models.PriceSourceProject},
+		{"other organization", "org-2", "", "openai", "gpt-4o", at, 2.5, models.PriceSourceCatalog},
+		{"other provider", "org-1", "", "azure", "gpt-4o", at, 2.5, models.PriceSourceCatalog},
+		{"before the negotiated rate", "org-1", "", "openai", "gpt-4o", negotiated.AddDate(0, 0, -1), 2.5, models.PriceSourceCatalog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := service.Lookup(tt.orgID, tt.projectID, tt.provider, tt.model, tt.at)
+			if !ok {
+				t.Fatalf("expected a price for %s", tt.model)
+			}
+			if price.InputPerMillion != tt.wantInput || priceSource(price) != tt.wantSource {
+				t.Errorf("expected %v from %s, got %v from %s", tt.wantInput, tt.wantSource, price.InputPerMillion, priceSource(price))
+			}
+		})
+	}
+
+	// The project rate is not visible to the organization's other projects
+	if _, ok := service.Lookup("org-1", "proj-1", "self-hosted", "llama-3-70b", at); ok {
+		t.Error("expected no price outside the project")
+	}
+
+	listed, err := service.ListOverrides(ctx, "org-1")
+	if err != nil || len(listed) != 2 {
+		t.Errorf("expected the organization's two overrides, got %d (%v)", len(listed), err)
+	}
+	if global, _ := service.ListPrices(ctx); len(global) != 0 {
+		t.Errorf("expected overrides to be left out of the global prices, got %d", len(global))
+	}
+
+	if _, err := service.UpsertPrice(ctx, &models.ModelPriceRequest{ProjectID: "proj-1", Provider: "openai", Model: "gpt-4o"}, "admin-1"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected a project price without an organization to be rejected, got %v", err)
+	}
+}
+
+func TestCreateTraceClientCost(t *testing.T) {
+	var saved *models.Trace
+	repo := &mockRepository{saveTraceFunc: func(ctx context.Context, trace *models.Trace) error {
+		saved = trace
+		return nil
+	}}
+	service := NewTraceService(repo, nil, NewPricingService(repo), nil, nil)
+	clientCost := 0.42
+
+	_, err := service.CreateTrace(context.Background(), &models.TraceRequest{
+		OrganizationID: "org-1",
+		TraceType:      "multi_step",
+		Spans: []models.SpanRequest{
+			{Name: "billed", Model: "my-finetune", Provider: "openai", PromptTokens: 100, CostUSD: &clientCost, Status: "success"},
+			{Name: "listed", Model: "gpt-4", Provider: "openai", PromptTokens: 100, Status: "success"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTrace() error = %v", err)
+	}
+
+	billed := saved.Spans[0]
+	if billed.CostUSD != clientCost || billed.PriceSource != models.PriceSourceClient || billed.Unpriced {
+		t.Errorf("expected the client's cost to be kept, got %+v", billed)
+	}
+	if saved.Spans[1].PriceSource != models.PriceSourceCatalog {
+		t.Errorf("expected the catalog price, got %q", saved.Spans[1].PriceSource)
+	}
+	if saved.PriceSource != models.PriceSourceMixed {
+		t.Errorf("expected a mixed trace price source, got %q", saved.PriceSource)
+	}
+
+	negative := -1.0
+	_, err = service.CreateTrace(context.Background(), &models.TraceRequest{
+		OrganizationID: "org-1",
+		TraceType:      "single_call",
+		Spans:          []models.SpanRequest{{Name: "bad", CostUSD: &negative}},
+	})
+	if err == nil {
+		t.Error("expected a negative cost_usd to be rejected")
+	}
+}
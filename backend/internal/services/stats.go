@@ -0,0 +1,141 @@
+package services
+
+import "math"
+
+// z95 is the two-sided 95% critical value of the standard normal distribution
+const z95 = 1.959963984540054
+
+// wilsonInterval returns the Wilson score interval for a binomial proportion
+func wilsonInterval(successes, n int64, z float64) (float64, float64) {
+	if n == 0 {
+		return 0, 0
+	}
+
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	z2 := z * z
+
+	center := (p + z2/(2*nf)) / (1 + z2/nf)
+	margin := z / (1 + z2/nf) * math.Sqrt(p*(1-p)/nf+z2/(4*nf*nf))
+
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// welchTTest tests whether two samples given by their mean, standard deviation and
+// size have different means. ok is false when the samples are too small or constant.
+func welchTTest(mean1, sd1 float64, n1 int64, mean2, sd2 float64, n2 int64) (t, df, p float64, ok bool) {
+	if n1 < 2 || n2 < 2 {
+		return 0, 0, 0, false
+	}
+
+	v1 := sd1 * sd1 / float64(n1)
+	v2 := sd2 * sd2 / float64(n2)
+	se := math.Sqrt(v1 + v2)
+
+	// Both samples are constant, so there is no spread to test against
+	if se == 0 {
+		return 0, 0, 0, false
+	}
+
+	t = (mean1 - mean2) / se
+	df = (v1 + v2) * (v1 + v2) / (v1*v1/float64(n1-1) + v2*v2/float64(n2-1))
+	p = regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+
+	return t, df, p, true
+}
+
+// twoProportionZTest tests whether two binomial proportions differ using the pooled
+// z-test. ok is false when either sample is empty.
+func twoProportionZTest(x1, n1, x2, n2 int64) (z, p float64, ok bool) {
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, false
+	}
+
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		// Both proportions are 0% or both are 100%
+		return 0, 1, true
+	}
+
+	z = (p1 - p2) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2), true
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with the continued fraction expansion
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only below the mean; use symmetry above it
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+// betaContinuedFraction evaluates the incomplete beta continued fraction with Lentz's method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+
+	for m := 1; m <= maxIterations; m++ {
+		mf := float64(m)
+
+		// Even step
+		numerator := mf * (b - mf) * x / ((a + 2*mf - 1) * (a + 2*mf))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		result *= d * c
+
+		// Odd step
+		numerator = -(a + mf) * (a + b + mf) * x / ((a + 2*mf) * (a + 2*mf + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWilsonInterval(t *testing.T) {
+	lower, upper := wilsonInterval(5, 100, z95)
+	if math.Abs(lower-0.0215) > 0.0005 || math.Abs(upper-0.1118) > 0.0005 {
+		t.Errorf("wilsonInterval(5, 100) = [%v, %v], want about [0.0215, 0.1118]", lower, upper)
+	}
+
+	lower, upper = wilsonInterval(0, 10, z95)
+	if lower != 0 || upper <= 0 {
+		t.Errorf("expected a non-degenerate interval for zero errors, got [%v, %v]", lower, upper)
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tStat, df, p, ok := welchTTest(20, 5, 30, 22, 5, 30)
+	if !ok {
+		t.Fatal("expected the test to run")
+	}
+	if math.Abs(tStat+1.5492) > 1e-4 || math.Abs(df-58) > 1e-9 || math.Abs(p-0.12678) > 1e-4 {
+		t.Errorf("welchTTest() = t %v, df %v, p %v; want -1.5492, 58, 0.1268", tStat, df, p)
+	}
+
+	if _, _, _, ok := welchTTest(20, 5, 1, 22, 5, 30); ok {
+		t.Error("expected a single-call sample to be rejected")
+	}
+}
+
+func TestTwoProportionZTest(t *testing.T) {
+	z, p, ok := twoProportionZTest(10, 100, 20, 100)
+	if !ok || math.Abs(z+1.9803) > 1e-4 || math.Abs(p-0.04767) > 1e-4 {
+		t.Errorf("twoProportionZTest() = z %v, p %v; want -1.9803, 0.0477", z, p)
+	}
+
+	if _, p, ok := twoProportionZTest(0, 50, 0, 80); !ok || p != 1 {
+		t.Errorf("expected p = 1 when neither model errors, got %v", p)
+	}
+}
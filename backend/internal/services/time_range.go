@@ -0,0 +1,194 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRangeSpec is an analytics time range as given by a caller. Either Range or
+// Start/End is set; Start and End are absolute times or expressions relative to now.
+type TimeRangeSpec struct {
+	Range    string // 24h, last_7d, today, last_week, month_to_date, ...
+	Start    string // 2025-03-01, 2025-03-01T09:00:00Z, now-6h, -15m
+	End      string
+	TimeZone string // IANA name used for calendar periods and local times; UTC when empty
+}
+
+// TimeRange is a resolved half-open time range [Start, End)
+type TimeRange struct {
+	Start    time.Time
+	End      time.Time
+	Location *time.Location
+}
+
+// relativeTimePattern matches now, now-6h and -15m
+var relativeTimePattern = regexp.MustCompile(`^(now)?(?:([+-])(\d+)([smhdw]))?$`)
+
+// rangeDurationPattern matches trailing windows such as 15m, 24h, 7d and last_2w
+var rangeDurationPattern = regexp.MustCompile(`^(?:last_)?(\d+)([smhdw])$`)
+
+// localTimeFormats are the absolute time formats without a zone, read in the range's time zone
+var localTimeFormats = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// LoadTimeZone loads an IANA time zone, UTC when name is empty. "Local" is rejected: it is
+// the server's zone, which callers can't know and ClickHouse doesn't accept by that name.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, invalidArgument("time zone must be an IANA name such as Europe/Berlin, not Local")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, invalidArgument("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// ResolveTimeRange turns a time range spec into absolute times. With neither a range nor
+// start/end, the trailing defaultWindow ending at now is used.
+func ResolveTimeRange(spec TimeRangeSpec, defaultWindow time.Duration, now time.Time) (TimeRange, error) {
+	loc, err := LoadTimeZone(spec.TimeZone)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	now = now.In(loc)
+
+	if spec.Range != "" {
+		if spec.Start != "" || spec.End != "" {
+			return TimeRange{}, invalidArgument("use either time_range or start_time/end_time, not both")
+		}
+		start, end, err := resolveNamedRange(spec.Range, now)
+		if err != nil {
+			return TimeRange{}, err
+		}
+		return TimeRange{Start: start, End: end, Location: loc}, nil
+	}
+
+	end := now
+	if spec.End != "" {
+		var err error
+		if end, err = parseTimeExpression(spec.End, now); err != nil {
+			return TimeRange{}, err
+		}
+	}
+
+	start := end.Add(-defaultWindow)
+	if spec.Start != "" {
+		var err error
+		if start, err = parseTimeExpression(spec.Start, now); err != nil {
+			return TimeRange{}, err
+		}
+	}
+
+	if !start.Before(end) {
+		return TimeRange{}, invalidArgument("start_time must be before end_time")
+	}
+	return TimeRange{Start: start, End: end, Location: loc}, nil
+}
+
+// resolveNamedRange resolves a trailing window or a calendar period in now's time zone.
+// Weeks start on Monday.
+func resolveNamedRange(name string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	firstOfYear := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	switch name {
+	case "today":
+		return today, now, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	case "this_week", "week_to_date":
+		return monday, now, nil
+	case "last_week":
+		return monday.AddDate(0, 0, -7), monday, nil
+	case "this_month", "month_to_date":
+		return firstOfMonth, now, nil
+	case "last_month":
+		return firstOfMonth.AddDate(0, -1, 0), firstOfMonth, nil
+	case "this_year", "year_to_date":
+		return firstOfYear, now, nil
+	case "last_year":
+		return firstOfYear.AddDate(-1, 0, 0), firstOfYear, nil
+
+	// Trailing windows kept for existing clients
+	case "last_hour":
+		return now.Add(-time.Hour), now, nil
+	case "week":
+		return now.AddDate(0, 0, -7), now, nil
+	case "month":
+		return now.AddDate(0, 0, -30), now, nil
+	}
+
+	match := rangeDurationPattern.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, time.Time{}, invalidArgument("unsupported time range %q", name)
+	}
+	window, err := unitDuration(match[1], match[2])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return now.Add(-window), now, nil
+}
+
+// parseTimeExpression parses an absolute time or an offset from now such as now-6h or -15m.
+// Times without a zone are read in now's time zone.
+func parseTimeExpression(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if match := relativeTimePattern.FindStringSubmatch(value); match != nil && (match[1] != "" || match[2] != "") {
+		if match[2] == "" {
+			return now, nil
+		}
+		offset, err := unitDuration(match[3], match[4])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if match[2] == "-" {
+			offset = -offset
+		}
+		return now.Add(offset), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, format := range localTimeFormats {
+		if t, err := time.ParseInLocation(format, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, invalidArgument("invalid time %q; use RFC 3339, YYYY-MM-DD or an offset such as now-6h", value)
+}
+
+// maxTimeOffset bounds relative offsets and trailing windows
+const maxTimeOffset = 10 * 366 * 24 * time.Hour
+
+// timeUnits maps each relative time unit onto its duration
+var timeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// unitDuration converts an amount of s, m, h, d or w units into a duration
+func unitDuration(amount, unit string) (time.Duration, error) {
+	n, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil || n <= 0 || n > int64(maxTimeOffset/timeUnits[unit]) {
+		return 0, invalidArgument("invalid duration %s%s", amount, unit)
+	}
+	return time.Duration(n) * timeUnits[unit], nil
+}
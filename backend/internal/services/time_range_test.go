@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolveTimeRange(t *testing.T) {
+	// Wednesday 2025-03-12 10:30 UTC is 06:30 in New York (EDT, UTC-4)
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		spec      TimeRangeSpec
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"default window", TimeRangeSpec{}, now.Add(-24 * time.Hour), now},
+		{"trailing window", TimeRangeSpec{Range: "7d"}, now.Add(-7 * 24 * time.Hour), now},
+		{"legacy trailing window", TimeRangeSpec{Range: "last_30d"}, now.Add(-30 * 24 * time.Hour), now},
+		{"relative start", TimeRangeSpec{Start: "now-6h"}, now.Add(-6 * time.Hour), now},
+		{"relative start and end", TimeRangeSpec{Start: "-15m", End: "now-5m"}, now.Add(-15 * time.Minute), now.Add(-5 * time.Minute)},
+		{"absolute range", TimeRangeSpec{Start: "2025-03-01T00:00:00Z", End: "2025-03-02T00:00:00Z"},
+			time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"local dates", TimeRangeSpec{Start: "2025-03-01", End: "2025-03-02", TimeZone: "America/New_York"},
+			time.Date(2025, 3, 1, 0, 0, 0, 0, newYork), time.Date(2025, 3, 2, 0, 0, 0, 0, newYork)},
+		{"today", TimeRangeSpec{Range: "today", TimeZone: "America/New_York"}, time.Date(2025, 3, 12, 0, 0, 0, 0, newYork), now},
+		{"yesterday", TimeRangeSpec{Range: "yesterday"}, time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"last week", TimeRangeSpec{Range: "last_week"}, time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{"month to date", TimeRangeSpec{Range: "month_to_date", TimeZone: "America/New_York"}, time.Date(2025, 3, 1, 0, 0, 0, 0, newYork), now},
+		{"last month", TimeRangeSpec{Range: "last_month"}, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTimeRange(tt.spec, 24*time.Hour, now)
+			if err != nil {
+				t.Fatalf("ResolveTimeRange() error = %v", err)
+			}
+			if !got.Start.Equal(tt.wantStart) || !got.End.Equal(tt.wantEnd) {
+				t.Errorf("got [%v, %v), want [%v, %v)", got.Start, got.End, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestResolveTimeRangeDaylightSaving(t *testing.T) {
+	// New York moved to daylight saving time on 2025-03-09, so that day is 23 hours long
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	got, err := ResolveTimeRange(TimeRangeSpec{Start: "2025-03-09", End: "2025-03-10", TimeZone: "America/New_York"}, 0, now)
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	if d := got.End.Sub(got.Start); d != 23*time.Hour {
+		t.Errorf("expected a 23 hour day, got %v", d)
+	}
+}
+
+func TestResolveTimeRangeErrors(t *testing.T) {
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		spec TimeRangeSpec
+	}{
+		{"unknown range", TimeRangeSpec{Range: "1y"}},
+		{"unknown time zone", TimeRangeSpec{Range: "today", TimeZone: "Mars/Olympus"}},
+		{"server time zone", TimeRangeSpec{Range: "today", TimeZone: "Local"}},
+		{"range and start", TimeRangeSpec{Range: "24h", Start: "now-1h"}},
+		{"bad time", TimeRangeSpec{Start: "yesterday-ish"}},
+		{"start after end", TimeRangeSpec{Start: "now", End: "now-1h"}},
+		{"zero offset", TimeRangeSpec{Start: "now-0m"}},
+		{"huge offset", TimeRangeSpec{Start: "now-999999999w"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ResolveTimeRange(tt.spec, 24*time.Hour, now); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// Span change kinds reported by DiffTraces
+const (
+	SpanUnchanged = "unchanged"
+	SpanModified  = "modified"
+	SpanAdded     = "added"
+	SpanRemoved   = "removed"
+)
+
+// maxDiffLines bounds the quadratic time of input/output text diffs
+const maxDiffLines = 2000
+
+// DiffTraces compares two traces span by span
+func (s *TraceService) DiffTraces(ctx context.Context, orgID, traceIDA, traceIDB string) (*TraceDiff, error) {
+	if traceIDA == "" || traceIDB == "" {
+		return nil, invalidArgument("both trace ids (a and b) are required")
+	}
+
+	a, err := s.getTraceForOrg(ctx, orgID, traceIDA)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.getTraceForOrg(ctx, orgID, traceIDB)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffTraces(a, b), nil
+}
+
+// getTraceForOrg loads a trace and hides it when it belongs to another organization
+func (s *TraceService) getTraceForOrg(ctx context.Context, orgID, traceID string) (*models.Trace, error) {
+	trace, err := s.repo.GetTraceByID(ctx, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace %s: %w", traceID, err)
+	}
+	if trace == nil || (orgID != "" && trace.OrganizationID != orgID) {
+		return nil, fmt.Errorf("trace %s: %w", traceID, repository.ErrNotFound)
+	}
+	return trace, nil
+}
+
+// diffTraces aligns the spans of two traces and computes per-span deltas
+func diffTraces(a, b *models.Trace) *TraceDiff {
+	diff := &TraceDiff{
+		TraceA: summarizeTrace(a),
+		TraceB: summarizeTrace(b),
+		Summary: TraceDelta{
+			DurationMs:    b.DurationMs - a.DurationMs,
+			Tokens:        int64(b.TotalTokens - a.TotalTokens),
+			CostUSD:       b.TotalCostUSD - a.TotalCostUSD,
+			StatusChanged: a.Status != b.Status,
+		},
+		Spans: []SpanDiff{},
+	}
+
+	spansA := alignSpans(a.Spans)
+	spansB := alignSpans(b.Spans)
+
+	// Walk A's order first, then anything only present in B
+	var paths []string
+	seen := make(map[string]bool)
+	for _, ordered := range [][]string{spansA.ordered, spansB.ordered} {
+		for _, path := range ordered {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		spanA, inA := spansA.byPath[path]
+		spanB, inB := spansB.byPath[path]
+
+		switch {
+		case inA && inB:
+			d := compareSpans(path, spanA, spanB)
+			diff.Spans = append(diff.Spans, d)
+			if d.Change == SpanModified {
+				diff.Summary.ModifiedSpans++
+			}
+		case inA:
+			diff.Spans = append(diff.Spans, SpanDiff{
+				Path:    path,
+				Name:    spanA.Name,
+				Change:  SpanRemoved,
+				SpanIDA: spanA.SpanID,
+				StatusA: spanA.Status,
+			})
+			diff.Summary.RemovedSpans++
+		default:
+			diff.Spans = append(diff.Spans, SpanDiff{
+				Path:    path,
+				Name:    spanB.Name,
+				Change:  SpanAdded,
+				SpanIDB: spanB.SpanID,
+				StatusB: spanB.Status,
+			})
+			diff.Summary.AddedSpans++
+		}
+	}
+
+	// Spans caught in a parent cycle can't be aligned, so they show as removed or added
+	for _, span := range spansA.unaligned {
+		diff.Spans = append(diff.Spans, SpanDiff{
+			Path:    unalignedPath(span),
+			Name:    span.Name,
+			Change:  SpanRemoved,
+			SpanIDA: span.SpanID,
+			StatusA: span.Status,
+		})
+		diff.Summary.RemovedSpans++
+	}
+	for _, span := range spansB.unaligned {
+		diff.Spans = append(diff.Spans, SpanDiff{
+			Path:    unalignedPath(span),
+			Name:    span.Name,
+			Change:  SpanAdded,
+			SpanIDB: span.SpanID,
+			StatusB: span.Status,
+		})
+		diff.Summary.AddedSpans++
+	}
+
+	return diff
+}
+
+// unalignedPath names a span outside the tree by its ID
+func unalignedPath(span *models.Span) string {
+	return fmt.Sprintf("(cycle)/%s#%s", span.Name, span.SpanID)
+}
+
+// compareSpans computes the deltas between two spans aligned at the same path
+func compareSpans(path string, a, b *models.Span) SpanDiff {
+	d := SpanDiff{
+		Path:           path,
+		Name:           a.Name,
+		Change:         SpanUnchanged,
+		SpanIDA:        a.SpanID,
+		SpanIDB:        b.SpanID,
+		LatencyDeltaMs: b.DurationMs - a.DurationMs,
+		TokenDelta:     int64(b.TotalTokens - a.TotalTokens),
+		CostDeltaUSD:   b.CostUSD - a.CostUSD,
+		StatusA:        a.Status,
+		StatusB:        b.Status,
+		ModelA:         a.Model,
+		ModelB:         b.Model,
+		InputChanged:   a.Input != b.Input,
+		OutputChanged:  a.Output != b.Output,
+	}
+
+	if d.InputChanged {
+		d.InputDiff = textDiff(a.Input, b.Input)
+	}
+	if d.OutputChanged {
+		d.OutputDiff = textDiff(a.Output, b.Output)
+	}
+
+	if d.LatencyDeltaMs != 0 || d.TokenDelta != 0 || d.CostDeltaUSD != 0 ||
+		a.Status != b.Status || a.Model != b.Model || d.InputChanged || d.OutputChanged {
+		d.Change = SpanModified
+	}
+
+	return d
+}
+
+// spanIndex holds a trace's span paths in tree order and the spans by path. Spans whose
+// parents form a cycle have no path from the root and are kept apart, unaligned.
+type spanIndex struct {
+	ordered   []string
+	byPath    map[string]*models.Span
+	unaligned []*models.Span
+}
+
+// alignSpans assigns every span a path such as "agent[0]/llm_call[1]".
+// The ordinal counts earlier siblings with the same name, so repeated
+// calls line up by position while renamed or inserted steps show as added/removed.
+func alignSpans(spans []models.Span) spanIndex {
+	ids := make(map[string]bool, len(spans))
+	for _, span := range spans {
+		ids[span.SpanID] = true
+	}
+
+	children := make(map[string][]*models.Span)
+	for i := range spans {
+		parent := spans[i].ParentSpanID
+		if !ids[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], &spans[i])
+	}
+
+	for _, siblings := range children {
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].StartTime.Before(siblings[j].StartTime)
+		})
+	}
+
+	index := spanIndex{byPath: make(map[string]*models.Span, len(spans))}
+	visited := make(map[string]bool, len(spans))
+
+	var walk func(parentID, prefix string)
+	walk = func(parentID, prefix string) {
+		ordinals := make(map[string]int)
+		for _, span := range children[parentID] {
+			if visited[span.SpanID] {
+				continue
+			}
+			visited[span.SpanID] = true
+
+			path := fmt.Sprintf("%s%s[%d]", prefix, span.Name, ordinals[span.Name])
+			ordinals[span.Name]++
+
+			index.ordered = append(index.ordered, path)
+			index.byPath[path] = span
+			walk(span.SpanID, path+"/")
+		}
+	}
+	walk("", "")
+
+	for i := range spans {
+		if !visited[spans[i].SpanID] {
+			visited[spans[i].SpanID] = true
+			index.unaligned = append(index.unaligned, &spans[i])
+		}
+	}
+	return index
+}
+
+// textDiff returns a line-based diff with "-", "+" and "  " prefixes. Lines are aligned on
+// a longest common subsequence found with Hirschberg's algorithm, in linear space.
+func textDiff(a, b string) string {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+
+	var sb strings.Builder
+	if len(linesA) > maxDiffLines || len(linesB) > maxDiffLines {
+		for _, line := range linesA {
+			sb.WriteString("- " + line + "\n")
+		}
+		for _, line := range linesB {
+			sb.WriteString("+ " + line + "\n")
+		}
+		return sb.String()
+	}
+
+	diffLines(&sb, linesA, linesB)
+	return sb.String()
+}
+
+// diffLines writes the diff of two line slices, splitting a in half and b where the
+// halves' LCS lengths add up to the whole one
+func diffLines(sb *strings.Builder, a, b []string) {
+	// Common prefixes and suffixes need no search
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		sb.WriteString("  " + a[0] + "\n")
+		a, b = a[1:], b[1:]
+	}
+	var suffix []string
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, a[len(a)-1])
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	defer func() {
+		for i := len(suffix) - 1; i >= 0; i-- {
+			sb.WriteString("  " + suffix[i] + "\n")
+		}
+	}()
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			sb.WriteString("+ " + line + "\n")
+		}
+		return
+	case len(b) == 0:
+		for _, line := range a {
+			sb.WriteString("- " + line + "\n")
+		}
+		return
+	case len(a) == 1:
+		// The ends differ, so a's only line matches at most one inner line of b
+		for k := range b {
+			if b[k] == a[0] {
+				for _, line := range b[:k] {
+					sb.WriteString("+ " + line + "\n")
+				}
+				sb.WriteString("  " + a[0] + "\n")
+				for _, line := range b[k+1:] {
+					sb.WriteString("+ " + line + "\n")
+				}
+				return
+			}
+		}
+		sb.WriteString("- " + a[0] + "\n")
+		for _, line := range b {
+			sb.WriteString("+ " + line + "\n")
+		}
+		return
+	}
+
+	mid := len(a) / 2
+	forward := lcsLengths(a[:mid], b, false)
+	backward := lcsLengths(a[mid:], b, true)
+
+	split, best := 0, -1
+	for k := 0; k <= len(b); k++ {
+		if total := forward[k] + backward[k]; total > best {
+			split, best = k, total
+		}
+	}
+
+	diffLines(sb, a[:mid], b[:split])
+	diffLines(sb, a[mid:], b[split:])
+}
+
+// lcsLengths returns, for every k, the LCS length of a and b[:k], or of a and b[k:] when
+// reversed, keeping two rows of the table only
+func lcsLengths(a, b []string, reversed bool) []int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for i := range a {
+		line := a[i]
+		if reversed {
+			line = a[len(a)-1-i]
+		}
+		for j := 1; j <= len(b); j++ {
+			other := b[j-1]
+			if reversed {
+				other = b[len(b)-j]
+			}
+			switch {
+			case line == other:
+				curr[j] = prev[j-1] + 1
+			case prev[j] >= curr[j-1]:
+				curr[j] = prev[j]
+			default:
+				curr[j] = curr[j-1]
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	if reversed {
+		// prev[j] covers the last j lines of b, that is b[len(b)-j:]
+		for i, j := 0, len(prev)-1; i < j; i, j = i+1, j-1 {
+			prev[i], prev[j] = prev[j], prev[i]
+		}
+	}
+	return prev
+}
+
+// summarizeTrace extracts the trace-level fields shown in a diff header
+func summarizeTrace(t *models.Trace) TraceSummary {
+	return TraceSummary{
+		TraceID:    t.TraceID,
+		Timestamp:  t.Timestamp,
+		Model:      t.Model,
+		Status:     t.Status,
+		DurationMs: t.DurationMs,
+		Tokens:     t.TotalTokens,
+		CostUSD:    t.TotalCostUSD,
+		SpanCount:  len(t.Spans),
+	}
+}
+
+// TraceDiff is the side-by-side comparison of two traces
+type TraceDiff struct {
+	TraceA  TraceSummary `json:"trace_a"`
+	TraceB  TraceSummary `json:"trace_b"`
+	Summary TraceDelta   `json:"summary"`
+	Spans   []SpanDiff   `json:"spans"`
+}
+
+// TraceSummary holds the trace-level fields of one side of a diff
+type TraceSummary struct {
+	TraceID    string    `json:"trace_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Model      string    `json:"model"`
+	Status     string    `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	Tokens     int       `json:"total_tokens"`
+	CostUSD    float64   `json:"total_cost_usd"`
+	SpanCount  int       `json:"span_count"`
+}
+
+// TraceDelta holds trace-level deltas (B minus A)
+type TraceDelta struct {
+	DurationMs    int64   `json:"duration_ms"`
+	Tokens        int64   `json:"tokens"`
+	CostUSD       float64 `json:"cost_usd"`
+	StatusChanged bool    `json:"status_changed"`
+	ModifiedSpans int     `json:"modified_spans"`
+	AddedSpans    int     `json:"added_spans"`
+	RemovedSpans  int     `json:"removed_spans"`
+}
+
+// SpanDiff describes how one aligned span changed between the two traces
+type SpanDiff struct {
+	Path           string  `json:"path"`
+	Name           string  `json:"name"`
+	Change         string  `json:"change"` // unchanged, modified, added, removed
+	SpanIDA        string  `json:"span_id_a,omitempty"`
+	SpanIDB        string  `json:"span_id_b,omitempty"`
+	LatencyDeltaMs int64   `json:"latency_delta_ms"`
+	TokenDelta     int64   `json:"token_delta"`
+	CostDeltaUSD   float64 `json:"cost_delta_usd"`
+	StatusA        string  `json:"status_a,omitempty"`
+	StatusB        string  `json:"status_b,omitempty"`
+	ModelA         string  `json:"model_a,omitempty"`
+	ModelB         string  `json:"model_b,omitempty"`
+	InputChanged   bool    `json:"input_changed"`
+	OutputChanged  bool    `json:"output_changed"`
+	InputDiff      string  `json:"input_diff,omitempty"`
+	OutputDiff     string  `json:"output_diff,omitempty"`
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+)
+
+// TestDiffTraces tests span alignment and deltas between two runs
+func TestDiffTraces(t *testing.T) {
+	start := time.Now()
+
+	a := &models.Trace{
+		TraceID:      "trace-a",
+		Status:       "success",
+		DurationMs:   300,
+		TotalTokens:  150,
+		TotalCostUSD: 0.01,
+		Spans: []models.Span{
+			{SpanID: "a1", Name: "agent", StartTime: start, DurationMs: 300, Status: "success"},
+			{SpanID: "a2", ParentSpanID: "a1", Name: "llm_call", StartTime: start, DurationMs: 100, TotalTokens: 100, Input: "hello", Output: "hi", Status: "success"},
+			{SpanID: "a3", ParentSpanID: "a1", Name: "retrieval", StartTime: start.Add(time.Millisecond), DurationMs: 50, Status: "success"},
+		},
+	}
+
+	b := &models.Trace{
+		TraceID:      "trace-b",
+		Status:       "error",
+		DurationMs:   500,
+		TotalTokens:  250,
+		TotalCostUSD: 0.02,
+		Spans: []models.Span{
+			{SpanID: "b1", Name: "agent", StartTime: start, DurationMs: 300, Status: "success"},
+			{SpanID: "b2", ParentSpanID: "b1", Name: "llm_call", StartTime: start, DurationMs: 180, TotalTokens: 200, Input: "hello", Output: "hi there", Status: "error"},
+			{SpanID: "b3", ParentSpanID: "b1", Name: "llm_call", StartTime: start.Add(time.Millisecond), DurationMs: 20, Status: "success"},
+		},
+	}
+
+	diff := diffTraces(a, b)
+
+	if diff.Summary.DurationMs != 200 || diff.Summary.Tokens != 100 || !diff.Summary.StatusChanged {
+		t.Errorf("unexpected summary: %+v", diff.Summary)
+	}
+
+	changes := make(map[string]SpanDiff)
+	for _, d := range diff.Spans {
+		changes[d.Path] = d
+	}
+
+	tests := []struct {
+		path   string
+		change string
+	}{
+		{"agent[0]", SpanUnchanged},
+		{"agent[0]/llm_call[0]", SpanModified},
+		{"agent[0]/retrieval[0]", SpanRemoved},
+		{"agent[0]/llm_call[1]", SpanAdded},
+	}
+
+	for _, tt := range tests {
+		d, ok := changes[tt.path]
+		if !ok {
+			t.Errorf("missing span diff for %s", tt.path)
+			continue
+		}
+		if d.Change != tt.change {
+			t.Errorf("%s: change = %s, want %s", tt.path, d.Change, tt.change)
+		}
+	}
+
+	llm := changes["agent[0]/llm_call[0]"]
+	if llm.LatencyDeltaMs != 80 || llm.TokenDelta != 100 {
+		t.Errorf("unexpected llm_call deltas: %+v", llm)
+	}
+	if llm.InputChanged || !llm.OutputChanged {
+		t.Errorf("expected only output to change: %+v", llm)
+	}
+	if !strings.Contains(llm.OutputDiff, "- hi\n") || !strings.Contains(llm.OutputDiff, "+ hi there\n") {
+		t.Errorf("unexpected output diff: %q", llm.OutputDiff)
+	}
+
+	if diff.Summary.AddedSpans != 1 || diff.Summary.RemovedSpans != 1 || diff.Summary.ModifiedSpans != 1 {
+		t.Errorf("unexpected span counts: %+v", diff.Summary)
+	}
+}
+
+// TestTextDiff tests the line-based diff output
+func TestTextDiff(t *testing.T) {
+	got := textDiff("one\ntwo\nthree", "one\n2\nthree")
+	want := "  one\n- two\n+ 2\n  three\n"
+	if got != want {
+		t.Errorf("textDiff() = %q, want %q", got, want)
+	}
+}
+
+// TestTextDiffMinimal checks the linear-space diff keeps a longest common subsequence
+func TestTextDiffMinimal(t *testing.T) {
+	cases := [][2]string{
+		{"a\nb\nc\nd\ne", "x\nb\ny\nd\nz"},
+		{"a\nb\na\nb\na", "b\na\nb\na\nb\na"},
+		{"", "one\ntwo"},
+		{"one\ntwo\nthree\nfour", "four\nthree\ntwo\none"},
+		{"same\nsame\nsame", "same\nsame"},
+	}
+
+	for _, c := range cases {
+		linesA, linesB := strings.Split(c[0], "\n"), strings.Split(c[1], "\n")
+		var gotA, gotB []string
+		common := 0
+		for _, line := range strings.Split(strings.TrimSuffix(textDiff(c[0], c[1]), "\n"), "\n") {
+			prefix, text := line[:2], line[2:]
+			if prefix != "+ " {
+				gotA = append(gotA, text)
+			}
+			if prefix != "- " {
+				gotB = append(gotB, text)
+			}
+			if prefix == "  " {
+				common++
+			}
+		}
+		if strings.Join(gotA, "\n") != c[0] || strings.Join(gotB, "\n") != c[1] {
+			t.Errorf("diff of %q and %q does not rebuild both sides", c[0], c[1])
+		}
+
+		// Quadratic reference LCS
+		lcs := make([][]int, len(linesA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(linesB)+1)
+		}
+		for i := len(linesA) - 1; i >= 0; i-- {
+			for j := len(linesB) - 1; j >= 0; j-- {
+				if linesA[i] == linesB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		if common != lcs[0][0] {
+			t.Errorf("diff of %q and %q keeps %d common lines, want %d", c[0], c[1], common, lcs[0][0])
+		}
+	}
+}
+
+// TestDiffTracesParentCycle reports spans whose parents form a cycle instead of dropping them
+func TestDiffTracesParentCycle(t *testing.T) {
+	a := &models.Trace{Spans: []models.Span{
+		{SpanID: "root", Name: "agent"},
+		{SpanID: "x", ParentSpanID: "y", Name: "loop"},
+		{SpanID: "y", ParentSpanID: "x", Name: "loop"},
+	}}
+	b := &models.Trace{Spans: []models.Span{{SpanID: "root", Name: "agent"}}}
+
+	diff := diffTraces(a, b)
+	if diff.Summary.RemovedSpans != 2 || len(diff.Spans) != 3 {
+		t.Fatalf("expected the cycle's spans to show as removed, got %+v", diff.Spans)
+	}
+	if last := diff.Spans[2]; last.Change != SpanRemoved || last.SpanIDA != "y" {
+		t.Errorf("unexpected span %+v", last)
+	}
+}
@@ -3,6 +3,8 @@ package services
 import (
     "context"
     "fmt"
+    "log"
+    "math"
     "time"
 
     "github.com/google/uuid"
@@ -14,12 +16,20 @@ import (
 type TraceService struct {
     repo     repository.Repository
     producer *kafka.Producer
+    pricing  *PricingService
+    budgets  *BudgetService
+    meter    *MeteringService
 }
 
-func NewTraceService(repo repository.Repository, producer *kafka.Producer) *TraceService {
+// NewTraceService creates a trace service; budgets and meter may be nil to ingest without
+// budgets or plan quotas
+func NewTraceService(repo repository.Repository, producer *kafka.Producer, pricing *PricingService, budgets *BudgetService, meter *MeteringService) *TraceService {
     return &TraceService{
         repo:     repo,
         producer: producer,
+        pricing:  pricing,
+        budgets:  budgets,
+        meter:    meter,
     }
 }
 
@@ -46,33 +56,38 @@ func (s *TraceService) CreateTrace(ctx context.Context, req *models.TraceRequest
         startTime := now.Add(time.Duration(i) * time.Millisecond * 100)
         endTime := startTime.Add(time.Duration(spanReq.DurationMs) * time.Millisecond)
 
-        // Calculate cost for this span
-        cost := s.calculateCost(spanReq.Model, spanReq.Provider, int(spanReq.PromptTokens), int(spanReq.CompletionTokens))
-
         span := models.Span{
-            SpanID:           spanID,
-            TraceID:          traceID,
-            ParentSpanID:     spanReq.ParentSpanID,
-            Name:             spanReq.Name,
-            StartTime:        startTime,
-            EndTime:          endTime,
-            DurationMs:       int64(spanReq.DurationMs),
-            Model:            spanReq.Model,
-            Provider:         spanReq.Provider,
-            Input:            spanReq.Input,
-            Output:           spanReq.Output,
-            PromptTokens:     int(spanReq.PromptTokens),
-            CompletionTokens: int(spanReq.CompletionTokens),
-            TotalTokens:      int(spanReq.PromptTokens + spanReq.CompletionTokens),
-            CostUSD:          cost,
-            Status:           spanReq.Status,
-            Metadata:         spanReq.Tags,
+            SpanID:             spanID,
+            TraceID:            traceID,
+            ParentSpanID:       spanReq.ParentSpanID,
+            Name:               spanReq.Name,
+            StartTime:          startTime,
+            EndTime:            endTime,
+            DurationMs:         int64(spanReq.DurationMs),
+            Model:              spanReq.Model,
+            Provider:           spanReq.Provider,
+            Input:              spanReq.Input,
+            Output:             spanReq.Output,
+            PromptTokens:       int(spanReq.PromptTokens),
+            CompletionTokens:   int(spanReq.CompletionTokens),
+            TotalTokens:        int(spanReq.PromptTokens + spanReq.CompletionTokens),
+            CachedPromptTokens: spanReq.CachedPromptTokens,
+            ReasoningTokens:    spanReq.ReasoningTokens,
+            ImageTokens:        spanReq.ImageTokens,
+            AudioSeconds:       spanReq.AudioSeconds,
+            Batch:              spanReq.Batch,
+            Status:             spanReq.Status,
+            ErrorMessage:       spanReq.ErrorMessage,
+            Metadata:           spanReq.Tags,
         }
 
+        // Calculate cost for this span
+        s.priceSpan(&span, req.OrganizationID, req.ProjectID, spanReq.CostUSD)
+
         spans = append(spans, span)
         totalTokens += span.TotalTokens
         totalDuration += span.DurationMs
-        totalCost += cost
+        totalCost += span.CostUSD
 
         // Publish span created event to Kafka
         if s.producer != nil {
@@ -93,19 +108,58 @@ func (s *TraceService) CreateTrace(ctx context.Context, req *models.TraceRequest
         DurationMs:     totalDuration,
         Status:         status,
         TotalCostUSD:   totalCost,
+        PriceSource:    tracePriceSource(spans),
         TotalTokens:    totalTokens,
         Model:          req.Model,
         Provider:       req.Provider,
         UserID:         req.UserID,
+        APIKeyID:       req.APIKeyID,
         Metadata:       req.Metadata,
         Spans:          spans,
     }
 
-    // Save to database
+    // A used-up plan quota or hard budget rejects the trace before it is stored
+    if s.meter != nil {
+        if err := s.meter.Admit(ctx, trace); err != nil {
+            return nil, err
+        }
+    }
+    if s.budgets != nil {
+        if check := s.budgets.Admit(trace); !check.Allowed {
+            if s.meter != nil {
+                s.meter.Release(trace)
+            }
+            return nil, &BudgetExceededError{Check: check}
+        }
+    }
+
+    // Save to database; the usage and cost reserved by Admit are given back if that fails
     if err := s.repo.SaveTrace(ctx, trace); err != nil {
+        if s.meter != nil {
+            s.meter.Release(trace)
+        }
+        if s.budgets != nil {
+            s.budgets.Release(trace)
+        }
         return nil, fmt.Errorf("failed to save trace: %w", err)
     }
 
+    // Metrics feed the hourly rollups; losing them must not fail ingestion
+    if err := s.repo.SaveMetrics(ctx, traceMetrics(trace)); err != nil {
+        log.Printf("❌ Failed to record metrics for trace %s: %v", traceID, err)
+    }
+
+    // Usage and spend are counted once the trace is stored
+    if s.meter != nil {
+        s.meter.Record(trace)
+    }
+    var budget *models.BudgetCheck
+    if s.budgets != nil {
+        if budget = s.budgets.Record(trace); len(budget.Budgets) == 0 {
+            budget = nil
+        }
+    }
+
     // Publish trace created event to Kafka
     if s.producer != nil {
         _ = s.producer.PublishTraceCreated(
@@ -132,9 +186,68 @@ func (s *TraceService) CreateTrace(ctx context.Context, req *models.TraceRequest
         Timestamp:      now.Format(time.RFC3339),
         CreatedAt:      now.Format(time.RFC3339),
         Message:        "Trace created successfully",
+        Budget:         budget,
     }, nil
 }
 
+// traceMetrics returns the per-trace data points recorded in the metrics table
+func traceMetrics(trace *models.Trace) []*models.Metric {
+    tags := map[string]interface{}{
+        "trace_id": trace.TraceID,
+        "user_id":  trace.UserID,
+        "model":    trace.Model,
+        "provider": trace.Provider,
+        "status":   trace.Status,
+    }
+
+    errorCount := 0.0
+    if trace.Status == "error" {
+        errorCount = 1
+    }
+
+    values := []struct {
+        name  string
+        value float64
+    }{
+        {"requests", 1},
+        {"errors", errorCount},
+        {"cost_usd", trace.TotalCostUSD},
+        {"tokens", float64(trace.TotalTokens)},
+        {"latency_ms", float64(trace.DurationMs)},
+    }
+
+    metrics := make([]*models.Metric, len(values))
+    for i, v := range values {
+        metrics[i] = &models.Metric{
+            MetricName:     v.name,
+            MetricValue:    v.value,
+            Timestamp:      trace.Timestamp,
+            OrganizationID: trace.OrganizationID,
+            ProjectID:      trace.ProjectID,
+            Tags:           tags,
+        }
+    }
+
+    // Spans of models missing from the pricing catalog are recorded at $0
+    for _, span := range trace.Spans {
+        if span.Unpriced {
+            metrics = append(metrics, &models.Metric{
+                MetricName:     "unpriced_spans",
+                MetricValue:    1,
+                Timestamp:      trace.Timestamp,
+                OrganizationID: trace.OrganizationID,
+                ProjectID:      trace.ProjectID,
+                Tags: map[string]interface{}{
+                    "trace_id": trace.TraceID,
+                    "model":    span.Model,
+                    "provider": span.Provider,
+                },
+            })
+        }
+    }
+    return metrics
+}
+
 func (s *TraceService) validateTraceRequest(req *models.TraceRequest) error {
     if req.OrganizationID == "" {
         return fmt.Errorf("organization_id is required")
@@ -145,33 +258,41 @@ func (s *TraceService) validateTraceRequest(req *models.TraceRequest) error {
     if req.TraceType != "single_call" && req.TraceType != "multi_step" && req.TraceType != "streaming" {
         return fmt.Errorf("invalid trace_type: must be single_call, multi_step, or streaming")
     }
+    for _, span := range req.Spans {
+        if span.CostUSD != nil && !(*span.CostUSD >= 0 && !math.IsInf(*span.CostUSD, 1)) {
+            return fmt.Errorf("invalid cost_usd for span %q: must be a non-negative amount", span.Name)
+        }
+    }
     return nil
 }
 
-func (s *TraceService) calculateCost(model, provider string, promptTokens, completionTokens int) float64 {
-    // Pricing per 1M tokens
-    pricing := map[string]map[string][2]float64{
-        "openai": {
-            "gpt-4":          {30.0, 60.0},   // prompt, completion per 1M tokens
-            "gpt-4-turbo":    {10.0, 30.0},
-            "gpt-3.5-turbo":  {0.5, 1.5},
-        },
-        "anthropic": {
-            "claude-3-opus":   {15.0, 75.0},
-            "claude-3-sonnet": {3.0, 15.0},
-            "claude-3-haiku":  {0.25, 1.25},
-        },
-    }
-
-    rates, ok := pricing[provider][model]
-    if !ok {
-        return 0.0
-    }
-
-    promptCost := (float64(promptTokens) / 1000000.0) * rates[0]
-    completionCost := (float64(completionTokens) / 1000000.0) * rates[1]
-    
-    return promptCost + completionCost
+// priceSpan sets the cost and price source of a span: the client's own cost when it sent
+// one, otherwise its usage priced for the organization and project
+func (s *TraceService) priceSpan(span *models.Span, orgID, projectID string, clientCost *float64) {
+    if clientCost != nil {
+        span.CostUSD = *clientCost
+        span.CostComponents = models.CostComponents{}
+        span.PriceSource = models.PriceSourceClient
+        span.Unpriced = false
+        return
+    }
+
+    s.pricing.priceSpan(span, orgID, projectID)
+}
+
+// tracePriceSource is the price source shared by a trace's priced spans, or mixed
+func tracePriceSource(spans []models.Span) string {
+    source := ""
+    for _, span := range spans {
+        switch {
+        case span.PriceSource == "" || span.PriceSource == source:
+        case source == "":
+            source = span.PriceSource
+        default:
+            return models.PriceSourceMixed
+        }
+    }
+    return source
 }
 
 func (s *TraceService) determineTraceStatus(spans []models.Span) string {
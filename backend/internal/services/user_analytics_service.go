@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aditya-Pimpalkar/clarity/internal/models"
+	"github.com/Aditya-Pimpalkar/clarity/internal/repository"
+)
+
+// UserAnalyticsService handles analytics keyed on the end-user user_id sent with traces
+type UserAnalyticsService struct {
+	repo repository.Repository
+}
+
+// NewUserAnalyticsService creates a new end-user analytics service
+func NewUserAnalyticsService(repo repository.Repository) *UserAnalyticsService {
+	return &UserAnalyticsService{
+		repo: repo,
+	}
+}
+
+// GetTopUsers returns the heaviest end users for the requested window
+func (s *UserAnalyticsService) GetTopUsers(ctx context.Context, query *models.UserAnalyticsQuery) ([]*models.UserStats, error) {
+	if err := s.validateQuery(query); err != nil {
+		return nil, err
+	}
+
+	users, err := s.repo.GetTopUsers(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top users: %w", err)
+	}
+
+	if users == nil {
+		users = []*models.UserStats{}
+	}
+	return users, nil
+}
+
+// GetUserDetail returns the summary and time series for a single end user
+func (s *UserAnalyticsService) GetUserDetail(ctx context.Context, query *models.UserAnalyticsQuery) (*UserDetail, error) {
+	if err := s.validateQuery(query); err != nil {
+		return nil, err
+	}
+	if query.UserID == "" {
+		return nil, invalidArgument("user_id is required")
+	}
+
+	stats, err := s.repo.GetUserStats(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	series, err := s.repo.GetUserTimeSeries(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user time series: %w", err)
+	}
+
+	if series == nil {
+		series = []*models.UserTimeSeriesPoint{}
+	}
+
+	return &UserDetail{
+		Stats:       stats,
+		Granularity: query.Granularity,
+		TimeSeries:  series,
+	}, nil
+}
+
+// GetUserTraces returns the traces recorded for a single end user
+func (s *UserAnalyticsService) GetUserTraces(ctx context.Context, query *models.TraceQuery) ([]*models.Trace, int64, error) {
+	if query.OrganizationID == "" {
+		return nil, 0, invalidArgument("organization_id is required")
+	}
+	if query.UserID == "" {
+		return nil, 0, invalidArgument("user_id is required")
+	}
+
+	traces, err := s.repo.GetTraces(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.repo.GetTraceCount(ctx, query)
+	if err != nil {
+		return traces, 0, err
+	}
+
+	return traces, count, nil
+}
+
+// validateQuery checks the common end-user query parameters
+func (s *UserAnalyticsService) validateQuery(query *models.UserAnalyticsQuery) error {
+	if query.OrganizationID == "" {
+		return invalidArgument("organization_id is required")
+	}
+	if !query.EndTime.After(query.StartTime) {
+		return invalidArgument("end_time must be after start_time")
+	}
+
+	switch query.Granularity {
+	case "":
+		query.Granularity = "hour"
+	case "hour", "day":
+	default:
+		return invalidArgument("granularity must be hour or day")
+	}
+
+	return nil
+}
+
+// UserDetail contains the drill-down view for a single end user
+type UserDetail struct {
+	Stats       *models.UserStats             `json:"stats"`
+	Granularity string                        `json:"granularity"`
+	TimeSeries  []*models.UserTimeSeriesPoint `json:"time_series"`
+}
@@ -0,0 +1,3 @@
+USE llm_observability;
+
+DROP TABLE IF EXISTS user_stats_hourly;
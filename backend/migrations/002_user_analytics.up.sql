@@ -0,0 +1,42 @@
+USE llm_observability;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS user_stats_hourly
+ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(hour)
+ORDER BY (organization_id, project_id, user_id, hour)
+TTL hour + INTERVAL 90 DAY
+AS SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    user_id,
+    countState() AS request_count,
+    countIfState(status = 'error') AS error_count,
+    sumState(total_cost_usd) AS total_cost,
+    sumState(toUInt64(total_tokens)) AS total_tokens,
+    sumState(toUInt64(duration_ms)) AS total_duration_ms,
+    quantilesState(0.50, 0.95, 0.99)(duration_ms) AS latency_quantiles,
+    minState(timestamp) AS first_seen,
+    maxState(timestamp) AS last_seen
+FROM traces
+WHERE user_id != ''
+GROUP BY hour, organization_id, project_id, user_id;
+
+-- Backfill existing traces into the view's target table
+INSERT INTO user_stats_hourly
+SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    user_id,
+    countState() AS request_count,
+    countIfState(status = 'error') AS error_count,
+    sumState(total_cost_usd) AS total_cost,
+    sumState(toUInt64(total_tokens)) AS total_tokens,
+    sumState(toUInt64(duration_ms)) AS total_duration_ms,
+    quantilesState(0.50, 0.95, 0.99)(duration_ms) AS latency_quantiles,
+    minState(timestamp) AS first_seen,
+    maxState(timestamp) AS last_seen
+FROM traces
+WHERE user_id != ''
+GROUP BY hour, organization_id, project_id, user_id;
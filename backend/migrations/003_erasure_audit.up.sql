@@ -0,0 +1,19 @@
+USE llm_observability;
+
+-- Audit log of right-to-erasure requests; each status change inserts a new version
+CREATE TABLE IF NOT EXISTS erasure_audit (
+    id String,
+    organization_id String,
+    subject_type String,
+    subject_id String,
+    requested_by String,
+    status String,
+    traces_deleted UInt64,
+    spans_deleted UInt64,
+    error String,
+    requested_at DateTime64(3),
+    completed_at Nullable(DateTime64(3)),
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (organization_id, id)
+SETTINGS index_granularity = 8192;
@@ -0,0 +1,28 @@
+USE llm_observability;
+
+DROP TABLE IF EXISTS metrics_hourly;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS metrics_hourly
+ENGINE = SummingMergeTree()
+PARTITION BY toYYYYMM(hour)
+ORDER BY (organization_id, project_id, metric_name, hour)
+AS SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    metric_name,
+    sum(metric_value) AS total_value,
+    count() AS count
+FROM metrics
+GROUP BY hour, organization_id, project_id, metric_name;
+
+INSERT INTO metrics_hourly
+SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    metric_name,
+    sum(metric_value) AS total_value,
+    count() AS count
+FROM metrics
+GROUP BY hour, organization_id, project_id, metric_name;
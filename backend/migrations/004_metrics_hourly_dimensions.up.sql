@@ -0,0 +1,60 @@
+USE llm_observability;
+
+-- Rebuild metrics_hourly with model/provider dimensions and latency quantiles
+DROP TABLE IF EXISTS metrics_hourly;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS metrics_hourly
+ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(hour)
+ORDER BY (organization_id, project_id, metric_name, model, provider, hour)
+AS SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    metric_name,
+    JSONExtractString(tags, 'model') AS model,
+    JSONExtractString(tags, 'provider') AS provider,
+    sumState(metric_value) AS total_value,
+    countState() AS count,
+    quantilesState(0.50, 0.90, 0.95, 0.99)(metric_value) AS quantiles
+FROM metrics
+GROUP BY hour, organization_id, project_id, metric_name, model, provider;
+
+-- Backfill metrics already recorded into the view's target table
+INSERT INTO metrics_hourly
+SELECT
+    toStartOfHour(timestamp) AS hour,
+    organization_id,
+    project_id,
+    metric_name,
+    JSONExtractString(tags, 'model') AS model,
+    JSONExtractString(tags, 'provider') AS provider,
+    sumState(metric_value) AS total_value,
+    countState() AS count,
+    quantilesState(0.50, 0.90, 0.95, 0.99)(metric_value) AS quantiles
+FROM metrics
+GROUP BY hour, organization_id, project_id, metric_name, model, provider;
+
+-- Record per-trace metrics for existing traces; the view picks them up on insert
+INSERT INTO metrics (timestamp, organization_id, project_id, metric_name, metric_value, tags)
+SELECT
+    timestamp,
+    organization_id,
+    project_id,
+    metric.1,
+    metric.2,
+    toJSONString(map(
+        'trace_id', trace_id,
+        'user_id', user_id,
+        'model', model,
+        'provider', provider,
+        'status', status
+    ))
+FROM traces
+ARRAY JOIN [
+    ('requests', 1.0),
+    ('errors', toFloat64(status = 'error')),
+    ('cost_usd', total_cost_usd),
+    ('tokens', toFloat64(total_tokens)),
+    ('latency_ms', toFloat64(duration_ms))
+] AS metric;
@@ -0,0 +1,24 @@
+USE llm_observability;
+
+-- Anomalies found by the background detector; re-detecting a run inserts a new version
+CREATE TABLE IF NOT EXISTS anomalies (
+    id String,
+    organization_id String,
+    project_id String,
+    model String,
+    metric String,
+    direction String,
+    severity String,
+    score Float64,
+    value Float64,
+    baseline Float64,
+    started_at DateTime64(3),
+    last_seen_at DateTime64(3),
+    ongoing UInt8,
+    explanation String,
+    detected_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(detected_at)
+PARTITION BY toYYYYMM(started_at)
+ORDER BY (organization_id, id)
+TTL toDateTime(started_at) + INTERVAL 90 DAY
+SETTINGS index_granularity = 8192;
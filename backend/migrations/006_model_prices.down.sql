@@ -0,0 +1,5 @@
+USE llm_observability;
+
+ALTER TABLE spans DROP COLUMN IF EXISTS unpriced;
+
+DROP TABLE IF EXISTS model_prices;
@@ -0,0 +1,21 @@
+USE llm_observability;
+
+-- Model price catalog; an update inserts a new version of the entry
+CREATE TABLE IF NOT EXISTS model_prices (
+    provider String,
+    model String,
+    aliases Array(String),
+    match_prefix UInt8,
+    input_per_million Float64,
+    output_per_million Float64,
+    effective_from DateTime64(3),
+    source String,
+    catalog_version UInt32,
+    updated_by String,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (provider, model, effective_from)
+SETTINGS index_granularity = 8192;
+
+-- Spans whose model had no price when they were ingested
+ALTER TABLE spans ADD COLUMN IF NOT EXISTS unpriced UInt8 DEFAULT 0;
@@ -0,0 +1,25 @@
+USE llm_observability;
+
+ALTER TABLE model_prices
+    DROP COLUMN IF EXISTS cached_input_per_million,
+    DROP COLUMN IF EXISTS reasoning_per_million,
+    DROP COLUMN IF EXISTS image_input_per_million,
+    DROP COLUMN IF EXISTS audio_per_minute,
+    DROP COLUMN IF EXISTS batch_discount;
+
+ALTER TABLE spans
+    DROP COLUMN IF EXISTS input_cost_usd,
+    DROP COLUMN IF EXISTS cached_input_cost_usd,
+    DROP COLUMN IF EXISTS output_cost_usd,
+    DROP COLUMN IF EXISTS reasoning_cost_usd,
+    DROP COLUMN IF EXISTS image_cost_usd,
+    DROP COLUMN IF EXISTS audio_cost_usd,
+    DROP COLUMN IF EXISTS cache_savings_usd,
+    DROP COLUMN IF EXISTS batch_savings_usd;
+
+ALTER TABLE spans
+    DROP COLUMN IF EXISTS cached_prompt_tokens,
+    DROP COLUMN IF EXISTS reasoning_tokens,
+    DROP COLUMN IF EXISTS image_tokens,
+    DROP COLUMN IF EXISTS audio_seconds,
+    DROP COLUMN IF EXISTS batch;
@@ -0,0 +1,29 @@
+USE llm_observability;
+
+-- Usage billed at their own rates, as parts of prompt_tokens and completion_tokens
+ALTER TABLE spans
+    ADD COLUMN IF NOT EXISTS cached_prompt_tokens UInt32 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS reasoning_tokens UInt32 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS image_tokens UInt32 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS audio_seconds Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS batch UInt8 DEFAULT 0;
+
+-- Cost of each category; they add up to cost_usd
+ALTER TABLE spans
+    ADD COLUMN IF NOT EXISTS input_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cached_input_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS output_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS reasoning_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS image_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS audio_cost_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cache_savings_usd Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS batch_savings_usd Float64 DEFAULT 0;
+
+-- Rates of those categories; 0 bills cached and image tokens at the input rate and
+-- reasoning tokens at the output rate
+ALTER TABLE model_prices
+    ADD COLUMN IF NOT EXISTS cached_input_per_million Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS reasoning_per_million Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS image_input_per_million Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS audio_per_minute Float64 DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS batch_discount Float64 DEFAULT 0;
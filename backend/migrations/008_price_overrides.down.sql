@@ -0,0 +1,38 @@
+USE llm_observability;
+
+ALTER TABLE traces DROP COLUMN IF EXISTS price_source;
+ALTER TABLE spans DROP COLUMN IF EXISTS price_source;
+
+-- Sorting key columns cannot be dropped, so the table is rebuilt with the global prices
+CREATE TABLE model_prices_global (
+    provider String,
+    model String,
+    aliases Array(String),
+    match_prefix UInt8,
+    input_per_million Float64,
+    output_per_million Float64,
+    effective_from DateTime64(3),
+    source String,
+    catalog_version UInt32,
+    updated_by String,
+    updated_at DateTime64(3),
+    cached_input_per_million Float64 DEFAULT 0,
+    reasoning_per_million Float64 DEFAULT 0,
+    image_input_per_million Float64 DEFAULT 0,
+    audio_per_minute Float64 DEFAULT 0,
+    batch_discount Float64 DEFAULT 0
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (provider, model, effective_from)
+SETTINGS index_granularity = 8192;
+
+INSERT INTO model_prices_global
+SELECT
+    provider, model, aliases, match_prefix, input_per_million, output_per_million,
+    effective_from, source, catalog_version, updated_by, updated_at,
+    cached_input_per_million, reasoning_per_million, image_input_per_million,
+    audio_per_minute, batch_discount
+FROM model_prices FINAL
+WHERE organization_id = '' AND project_id = '';
+
+DROP TABLE model_prices;
+RENAME TABLE model_prices_global TO model_prices;
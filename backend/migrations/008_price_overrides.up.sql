@@ -0,0 +1,12 @@
+USE llm_observability;
+
+-- Organization and project rates layered over the global prices
+ALTER TABLE model_prices
+    ADD COLUMN IF NOT EXISTS organization_id String DEFAULT '',
+    ADD COLUMN IF NOT EXISTS project_id String DEFAULT '',
+    MODIFY ORDER BY (provider, model, effective_from, organization_id, project_id);
+
+-- Where the cost of a span or trace came from: catalog, admin, organization, project,
+-- client or mixed
+ALTER TABLE spans ADD COLUMN IF NOT EXISTS price_source LowCardinality(String) DEFAULT '';
+ALTER TABLE traces ADD COLUMN IF NOT EXISTS price_source LowCardinality(String) DEFAULT '';
@@ -0,0 +1,26 @@
+USE llm_observability;
+
+-- Cost recomputation backfills; each checkpoint or status change inserts a new version
+CREATE TABLE IF NOT EXISTS cost_backfills (
+    id String,
+    organization_id String,
+    model String,
+    start_time DateTime64(3),
+    end_time DateTime64(3),
+    requested_by String,
+    status String,
+    traces_total UInt64,
+    traces_processed UInt64,
+    traces_updated UInt64,
+    spans_repriced UInt64,
+    cost_before Float64,
+    cost_after Float64,
+    cursor_timestamp DateTime64(3),
+    cursor_trace_id String,
+    error String,
+    requested_at DateTime64(3),
+    completed_at Nullable(DateTime64(3)),
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (organization_id, id)
+SETTINGS index_granularity = 8192;
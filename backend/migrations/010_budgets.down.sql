@@ -0,0 +1,5 @@
+USE llm_observability;
+
+ALTER TABLE traces DROP COLUMN IF EXISTS api_key_id;
+
+DROP TABLE IF EXISTS budgets;
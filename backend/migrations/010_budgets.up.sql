@@ -0,0 +1,23 @@
+USE llm_observability;
+
+-- Spending limits; each edit inserts a new version and deletes set the deleted flag
+CREATE TABLE IF NOT EXISTS budgets (
+    id String,
+    organization_id String,
+    name String,
+    scope LowCardinality(String),
+    scope_value String,
+    period LowCardinality(String),
+    limit_usd Float64,
+    thresholds Array(Float64),
+    enforcement LowCardinality(String),
+    created_by String,
+    created_at DateTime64(3),
+    updated_at DateTime64(3),
+    deleted UInt8 DEFAULT 0
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (organization_id, id)
+SETTINGS index_granularity = 8192;
+
+-- The API key a trace was ingested with, for API key budgets
+ALTER TABLE traces ADD COLUMN IF NOT EXISTS api_key_id String DEFAULT '';
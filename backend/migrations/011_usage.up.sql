@@ -0,0 +1,14 @@
+USE llm_observability;
+
+-- Metered ingestion per organization and day; instances flush their counts as partial rows
+-- that the engine sums up
+CREATE TABLE IF NOT EXISTS usage_daily (
+    organization_id String,
+    date Date,
+    traces UInt64,
+    spans UInt64,
+    bytes UInt64
+) ENGINE = SummingMergeTree((traces, spans, bytes))
+PARTITION BY toYYYYMM(date)
+ORDER BY (organization_id, date)
+SETTINGS index_granularity = 8192;
@@ -0,0 +1,5 @@
+USE llm_observability;
+
+DROP TABLE IF EXISTS currency_settings;
+
+DROP TABLE IF EXISTS exchange_rates;
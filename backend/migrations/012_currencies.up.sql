@@ -0,0 +1,23 @@
+USE llm_observability;
+
+-- Daily exchange rates as units of the currency per USD; loading a day again replaces it
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency LowCardinality(String),
+    date Date,
+    rate Float64,
+    source String,
+    updated_by String,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (currency, date)
+SETTINGS index_granularity = 8192;
+
+-- The currency each organization's costs are displayed in; USD when unset
+CREATE TABLE IF NOT EXISTS currency_settings (
+    organization_id String,
+    currency LowCardinality(String),
+    updated_by String,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY organization_id
+SETTINGS index_granularity = 8192;
@@ -0,0 +1,13 @@
+USE llm_observability;
+
+-- How each organization's spend is attributed to teams by metadata tags
+CREATE TABLE IF NOT EXISTS chargeback_configs (
+    organization_id String,
+    hierarchy Array(String),
+    untagged_policy LowCardinality(String),
+    default_bucket String,
+    updated_by String,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY organization_id
+SETTINGS index_granularity = 8192;
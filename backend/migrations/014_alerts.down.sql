@@ -0,0 +1,5 @@
+USE llm_observability;
+
+DROP TABLE IF EXISTS alert_events;
+
+DROP TABLE IF EXISTS alert_rules;
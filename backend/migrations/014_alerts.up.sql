@@ -0,0 +1,42 @@
+USE llm_observability;
+
+-- Alert rules per project; each edit inserts a new version and deletes set the deleted flag
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id String,
+    organization_id String,
+    project_id String,
+    name String,
+    metric LowCardinality(String),
+    comparator LowCardinality(String),
+    threshold Float64,
+    window_minutes UInt32,
+    for_minutes UInt32,
+    severity LowCardinality(String),
+    enabled UInt8,
+    created_by String,
+    created_at DateTime64(3),
+    updated_at DateTime64(3),
+    deleted UInt8 DEFAULT 0
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (organization_id, id)
+SETTINGS index_granularity = 8192;
+
+-- Every state change of an alert rule
+CREATE TABLE IF NOT EXISTS alert_events (
+    id String,
+    organization_id String,
+    project_id String,
+    rule_id String,
+    rule_name String,
+    metric LowCardinality(String),
+    comparator LowCardinality(String),
+    threshold Float64,
+    severity LowCardinality(String),
+    state LowCardinality(String),
+    value Float64,
+    timestamp DateTime64(3)
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (organization_id, timestamp, rule_id)
+TTL toDateTime(timestamp) + INTERVAL 180 DAY
+SETTINGS index_granularity = 8192;
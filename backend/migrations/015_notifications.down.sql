@@ -0,0 +1,5 @@
+USE llm_observability;
+
+DROP TABLE IF EXISTS notification_deliveries;
+
+DROP TABLE IF EXISTS notification_channels;
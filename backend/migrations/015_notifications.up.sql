@@ -0,0 +1,38 @@
+USE llm_observability;
+
+-- Where alerts and budget warnings are sent; each edit inserts a new version and deletes set the deleted flag
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id String,
+    organization_id String,
+    name String,
+    type LowCardinality(String),
+    config String,
+    template String,
+    kinds Array(String),
+    enabled UInt8,
+    created_by String,
+    created_at DateTime64(3),
+    updated_at DateTime64(3),
+    deleted UInt8 DEFAULT 0
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (organization_id, id)
+SETTINGS index_granularity = 8192;
+
+-- The outcome of every notification sent to a channel
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id String,
+    organization_id String,
+    channel_id String,
+    channel_type LowCardinality(String),
+    kind LowCardinality(String),
+    title String,
+    status LowCardinality(String),
+    attempts UInt16,
+    error String,
+    created_at DateTime64(3),
+    completed_at DateTime64(3)
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(created_at)
+ORDER BY (organization_id, created_at, channel_id)
+TTL toDateTime(created_at) + INTERVAL 90 DAY
+SETTINGS index_granularity = 8192;
@@ -0,0 +1,3 @@
+USE llm_observability;
+
+ALTER TABLE spans DROP COLUMN IF EXISTS organization_id;
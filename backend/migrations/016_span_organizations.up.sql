@@ -0,0 +1,21 @@
+USE llm_observability;
+
+-- Spans carry their trace's organization so span reads and deletes can't reach a trace
+-- with the same ID in another organization
+ALTER TABLE spans ADD COLUMN IF NOT EXISTS organization_id String DEFAULT '';
+
+-- Backfill existing spans from their trace; an ID stored by several organizations takes one of them
+CREATE TABLE span_trace_organizations
+ENGINE = Join(ANY, LEFT, trace_id)
+AS SELECT trace_id, any(organization_id) AS organization_id
+FROM traces
+GROUP BY trace_id;
+
+SET mutations_sync = 2;
+SET allow_nondeterministic_mutations = 1;
+
+ALTER TABLE spans
+    UPDATE organization_id = joinGet('span_trace_organizations', 'organization_id', trace_id)
+    WHERE organization_id = '';
+
+DROP TABLE span_trace_organizations;
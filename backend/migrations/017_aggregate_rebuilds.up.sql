@@ -0,0 +1,18 @@
+USE llm_observability;
+
+-- Aggregate buckets an erasure or cost backfill touched while they were still open; each is
+-- rebuilt once its day has closed. Finishing a rebuild inserts a version with done set.
+CREATE TABLE IF NOT EXISTS aggregate_rebuilds (
+    id String,
+    organization_id String,
+    projects Array(String),
+    users Array(String),
+    first_timestamp DateTime64(3),
+    last_timestamp DateTime64(3),
+    due_at DateTime64(3),
+    done UInt8 DEFAULT 0,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY id
+TTL toDateTime(due_at) + INTERVAL 30 DAY
+SETTINGS index_granularity = 8192;